@@ -10,6 +10,8 @@ type Node interface {
 
 type Program struct {
 	Statements []Statement
+	Positions  map[Node]token.Position
+	Ends       map[Node]token.Position
 }
 
 func (p Program) node() {
@@ -32,7 +34,7 @@ func (s ExpressionStatement) statement() {
 
 type VariableDeclaration struct {
 	Identifier *Identifier
-	Type       *Identifier
+	Type       Expression
 	Expression Expression
 }
 
@@ -42,6 +44,271 @@ func (s VariableDeclaration) node() {
 func (s VariableDeclaration) statement() {
 }
 
+type ShortVariableDeclaration struct {
+	Identifiers []*Identifier
+	Expressions []Expression
+}
+
+func (s ShortVariableDeclaration) node() {
+}
+
+func (s ShortVariableDeclaration) statement() {
+}
+
+type AssignmentStatement struct {
+	LExpressions []Expression
+	RExpressions []Expression
+}
+
+func (s AssignmentStatement) node() {
+}
+
+func (s AssignmentStatement) statement() {
+}
+
+type IncDecStatement struct {
+	Expression Expression
+	Operator   IncDecOperator
+}
+
+type IncDecOperator string
+
+const (
+	Increment IncDecOperator = "++"
+	Decrement                = "--"
+)
+
+var IncDecOperators = map[token.Type]IncDecOperator{
+	token.Increment: Increment,
+	token.Decrement: Decrement,
+}
+
+func (s IncDecStatement) node() {
+}
+
+func (s IncDecStatement) statement() {
+}
+
+type SendStatement struct {
+	Channel    Expression
+	Expression Expression
+}
+
+func (s SendStatement) node() {
+}
+
+func (s SendStatement) statement() {
+}
+
+type SwitchStatement struct {
+	Initializer Statement
+	Tag         Expression
+	Clauses     []*CaseClause
+}
+
+func (s SwitchStatement) node() {
+}
+
+func (s SwitchStatement) statement() {
+}
+
+type TypeSwitchStatement struct {
+	Initializer Statement
+	Identifier  *Identifier
+	Expression  Expression
+	Clauses     []*CaseClause
+}
+
+func (s TypeSwitchStatement) node() {
+}
+
+func (s TypeSwitchStatement) statement() {
+}
+
+type CaseClause struct {
+	IsDefault   bool
+	Expressions []Expression
+	Statements  []Statement
+}
+
+func (s CaseClause) node() {
+}
+
+type FallthroughStatement struct {
+}
+
+func (s FallthroughStatement) node() {
+}
+
+func (s FallthroughStatement) statement() {
+}
+
+type BreakStatement struct {
+}
+
+func (s BreakStatement) node() {
+}
+
+func (s BreakStatement) statement() {
+}
+
+type ContinueStatement struct {
+}
+
+func (s ContinueStatement) node() {
+}
+
+func (s ContinueStatement) statement() {
+}
+
+type SelectStatement struct {
+	Clauses []*CommunicationClause
+}
+
+func (s SelectStatement) node() {
+}
+
+func (s SelectStatement) statement() {
+}
+
+type CommunicationClause struct {
+	IsDefault     bool
+	Communication Statement
+	Statements    []Statement
+}
+
+func (s CommunicationClause) node() {
+}
+
+type ForStatement struct {
+	Initializer Statement
+	Condition   Expression
+	Post        Statement
+	Body        *BlockStatement
+}
+
+func (s ForStatement) node() {
+}
+
+func (s ForStatement) statement() {
+}
+
+type RangeStatement struct {
+	Key        *Identifier
+	Value      *Identifier
+	IsDefine   bool
+	Expression Expression
+	Body       *BlockStatement
+}
+
+func (s RangeStatement) node() {
+}
+
+func (s RangeStatement) statement() {
+}
+
+type FunctionDeclaration struct {
+	Identifier     *Identifier
+	TypeParameters []*Parameter
+	Parameters     []*Parameter
+	Results        []*Parameter
+	Body           *BlockStatement
+}
+
+func (s FunctionDeclaration) node() {
+}
+
+func (s FunctionDeclaration) statement() {
+}
+
+type PackageClause struct {
+	Name *Identifier
+}
+
+func (s PackageClause) node() {
+}
+
+func (s PackageClause) statement() {
+}
+
+type ImportDeclaration struct {
+	Specs []*ImportSpec
+}
+
+func (s ImportDeclaration) node() {
+}
+
+func (s ImportDeclaration) statement() {
+}
+
+type ImportSpec struct {
+	Name *Identifier
+	Path *String
+}
+
+func (s ImportSpec) node() {
+}
+
+type TypeDeclaration struct {
+	Identifier     *Identifier
+	TypeParameters []*Parameter
+	Type           Expression
+}
+
+func (s TypeDeclaration) node() {
+}
+
+func (s TypeDeclaration) statement() {
+}
+
+type Parameter struct {
+	Identifier *Identifier
+	Type       Expression
+}
+
+func (p Parameter) node() {
+}
+
+type BlockStatement struct {
+	Statements []Statement
+}
+
+func (s BlockStatement) node() {
+}
+
+func (s BlockStatement) statement() {
+}
+
+type ReturnStatement struct {
+	Expression Expression
+}
+
+func (s ReturnStatement) node() {
+}
+
+func (s ReturnStatement) statement() {
+}
+
+type DeferStatement struct {
+	Call *CallExpression
+}
+
+func (s DeferStatement) node() {
+}
+
+func (s DeferStatement) statement() {
+}
+
+type GoStatement struct {
+	Call *CallExpression
+}
+
+func (s GoStatement) node() {
+}
+
+func (s GoStatement) statement() {
+}
+
 type BadStatement struct {
 	Message string
 }
@@ -65,13 +332,17 @@ type PrefixExpression struct {
 type PrefixOperator string
 
 const (
-	Negative PrefixOperator = "-"
-	Not                     = "!"
+	Negative    PrefixOperator = "-"
+	Not                        = "!"
+	Receive                    = "<-"
+	Dereference                = "*"
 )
 
 var PrefixOperators = map[token.Type]PrefixOperator{
-	token.Minus: Negative,
-	token.Not:   Not,
+	token.Minus:    Negative,
+	token.Not:      Not,
+	token.Arrow:    Receive,
+	token.Asterisk: Dereference,
 }
 
 func (e PrefixExpression) node() {
@@ -83,17 +354,29 @@ func (e PrefixExpression) expression() {
 type InfixOperator string
 
 const (
-	Plus     InfixOperator = "+"
-	Minus                  = "-"
-	Asterisk               = "*"
-	Slash                  = "/"
+	Plus               InfixOperator = "+"
+	Minus                            = "-"
+	Asterisk                         = "*"
+	Slash                            = "/"
+	Equal                            = "=="
+	NotEqual                         = "!="
+	LessThan                         = "<"
+	GreaterThan                      = ">"
+	LessThanOrEqual                  = "<="
+	GreaterThanOrEqual               = ">="
 )
 
 var InfixOperators = map[token.Type]InfixOperator{
-	token.Plus:     Plus,
-	token.Minus:    Minus,
-	token.Asterisk: Asterisk,
-	token.Slash:    Slash,
+	token.Plus:               Plus,
+	token.Minus:              Minus,
+	token.Asterisk:           Asterisk,
+	token.Slash:              Slash,
+	token.Equal:              Equal,
+	token.NotEqual:           NotEqual,
+	token.LessThan:           LessThan,
+	token.GreaterThan:        GreaterThan,
+	token.LessThanOrEqual:    LessThanOrEqual,
+	token.GreaterThanOrEqual: GreaterThanOrEqual,
 }
 
 type InfixExpression struct {
@@ -108,8 +391,80 @@ func (e InfixExpression) node() {
 func (e InfixExpression) expression() {
 }
 
+type SelectorExpression struct {
+	Expression Expression
+	Selector   *Identifier
+}
+
+func (e SelectorExpression) node() {
+}
+
+func (e SelectorExpression) expression() {
+}
+
+type TypeAssertionExpression struct {
+	Expression Expression
+	Type       *Identifier
+}
+
+func (e TypeAssertionExpression) node() {
+}
+
+func (e TypeAssertionExpression) expression() {
+}
+
+type IndexExpression struct {
+	Expression Expression
+	Indices    []Expression
+}
+
+func (e IndexExpression) node() {
+}
+
+func (e IndexExpression) expression() {
+}
+
+type SliceExpression struct {
+	Expression Expression
+	Low        Expression
+	High       Expression
+}
+
+func (e SliceExpression) node() {
+}
+
+func (e SliceExpression) expression() {
+}
+
+type CallExpression struct {
+	Function    Expression
+	Arguments   []Expression
+	HasEllipsis bool
+}
+
+func (e CallExpression) node() {
+}
+
+func (e CallExpression) expression() {
+}
+
+type FunctionLiteral struct {
+	Parameters []*Parameter
+	Results    []*Parameter
+	Body       *BlockStatement
+}
+
+func (e FunctionLiteral) node() {
+}
+
+func (e FunctionLiteral) expression() {
+}
+
 type Identifier struct {
-	Name string
+	Name     string
+	Resolved bool
+	Depth    int
+	Slot     int
 }
 
 func (e Identifier) node() {
@@ -127,3 +482,107 @@ func (e Integer) node() {
 
 func (e Integer) expression() {
 }
+
+type String struct {
+	Value string
+}
+
+func (e String) node() {
+}
+
+func (e String) expression() {
+}
+
+type CompositeLiteral struct {
+	Type     Expression
+	Elements []*KeyedElement
+}
+
+func (e CompositeLiteral) node() {
+}
+
+func (e CompositeLiteral) expression() {
+}
+
+type KeyedElement struct {
+	Key   Expression
+	Value Expression
+}
+
+type SliceType struct {
+	Element Expression
+}
+
+func (e SliceType) node() {
+}
+
+func (e SliceType) expression() {
+}
+
+type MapType struct {
+	Key   Expression
+	Value Expression
+}
+
+func (e MapType) node() {
+}
+
+func (e MapType) expression() {
+}
+
+type PointerType struct {
+	Element Expression
+}
+
+func (e PointerType) node() {
+}
+
+func (e PointerType) expression() {
+}
+
+type ChannelType struct {
+	Direction ChannelDirection
+	Element   Expression
+}
+
+type ChannelDirection string
+
+const (
+	Bidirectional ChannelDirection = ""
+	SendOnly                       = "chan<-"
+	ReceiveOnly                    = "<-chan"
+)
+
+func (e ChannelType) node() {
+}
+
+func (e ChannelType) expression() {
+}
+
+type InterfaceType struct {
+	Elements []*UnionType
+}
+
+func (e InterfaceType) node() {
+}
+
+func (e InterfaceType) expression() {
+}
+
+type UnionType struct {
+	Terms []*TypeTerm
+}
+
+func (e UnionType) node() {
+}
+
+func (e UnionType) expression() {
+}
+
+type TypeTerm struct {
+	IsTilde bool
+	Type    Expression
+}
+
+func (t TypeTerm) node() {
+}
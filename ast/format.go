@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func Format(node Expression) string {
+	switch node := node.(type) {
+	case *Identifier:
+		return node.Name
+	case *Integer:
+		return strconv.FormatInt(node.Value, 10)
+	case *String:
+		return strconv.Quote(node.Value)
+	case *PrefixExpression:
+		return string(node.Operator) + Format(node.RExpression)
+	case *InfixExpression:
+		return fmt.Sprintf("%s %s %s", Format(node.LExpression), node.Operator, Format(node.RExpression))
+	case *CallExpression:
+		if node.HasEllipsis {
+			return fmt.Sprintf("%s(%s...)", Format(node.Function), FormatList(node.Arguments))
+		}
+		return fmt.Sprintf("%s(%s)", Format(node.Function), FormatList(node.Arguments))
+	case *IndexExpression:
+		return fmt.Sprintf("%s[%s]", Format(node.Expression), FormatList(node.Indices))
+	case *SliceExpression:
+		var low, high string
+		if node.Low != nil {
+			low = Format(node.Low)
+		}
+		if node.High != nil {
+			high = Format(node.High)
+		}
+		return fmt.Sprintf("%s[%s:%s]", Format(node.Expression), low, high)
+	case *SelectorExpression:
+		return fmt.Sprintf("%s.%s", Format(node.Expression), node.Selector.Name)
+	case *TypeAssertionExpression:
+		if node.Type == nil {
+			return fmt.Sprintf("%s.(type)", Format(node.Expression))
+		}
+		return fmt.Sprintf("%s.(%s)", Format(node.Expression), node.Type.Name)
+	case *CompositeLiteral:
+		if node.Type == nil {
+			return "{…}"
+		}
+		return fmt.Sprintf("%s{…}", Format(node.Type))
+	case *SliceType:
+		return "[]" + Format(node.Element)
+	case *MapType:
+		return fmt.Sprintf("map[%s]%s", Format(node.Key), Format(node.Value))
+	case *PointerType:
+		return "*" + Format(node.Element)
+	case *ChannelType:
+		if node.Direction == Bidirectional {
+			return fmt.Sprintf("chan %s", Format(node.Element))
+		}
+		return fmt.Sprintf("%s %s", node.Direction, Format(node.Element))
+	case *FunctionLiteral:
+		return "func literal"
+	default:
+		return fmt.Sprintf("%T", node)
+	}
+}
+
+func FormatList(nodes []Expression) string {
+	ss := make([]string, len(nodes))
+	for i, node := range nodes {
+		ss[i] = Format(node)
+	}
+
+	return strings.Join(ss, ", ")
+}
@@ -0,0 +1,1198 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/token"
+)
+
+var predeclaredTypes = map[string]Type{
+	"bool":       &basic{name: "bool", info: booleanInfo},
+	"string":     &basic{name: "string", info: stringInfo},
+	"int":        &basic{name: "int", info: integerInfo},
+	"int8":       &basic{name: "int8", info: integerInfo},
+	"int16":      &basic{name: "int16", info: integerInfo},
+	"int32":      &basic{name: "int32", info: integerInfo},
+	"int64":      &basic{name: "int64", info: integerInfo},
+	"uint":       &basic{name: "uint", info: integerInfo},
+	"uint8":      &basic{name: "uint8", info: integerInfo},
+	"uint16":     &basic{name: "uint16", info: integerInfo},
+	"uint32":     &basic{name: "uint32", info: integerInfo},
+	"uint64":     &basic{name: "uint64", info: integerInfo},
+	"uintptr":    &basic{name: "uintptr", info: integerInfo},
+	"float32":    &basic{name: "float32", info: floatInfo},
+	"float64":    &basic{name: "float64", info: floatInfo},
+	"complex64":  &basic{name: "complex64", info: complexInfo},
+	"complex128": &basic{name: "complex128", info: complexInfo},
+	"any":        &interfaceType{},
+	"comparable": &interfaceType{
+		isComparable: true,
+	},
+}
+
+func init() {
+	predeclaredTypes["byte"] = predeclaredTypes["uint8"]
+	predeclaredTypes["rune"] = predeclaredTypes["int32"]
+}
+
+type scope struct {
+	outer   *scope
+	symbols map[string]*symbol
+}
+
+type symbol struct {
+	kind symbolKind
+	typ  Type
+}
+
+type symbolKind int
+
+const (
+	variableSymbol symbolKind = iota
+	functionSymbol
+	typeSymbol
+	builtinSymbol
+	packageSymbol
+)
+
+func newUniverse() *scope {
+	s := newScope(nil)
+	for name, typ := range predeclaredTypes {
+		s.declare(name, typeSymbol, typ)
+	}
+	s.declare("true", variableSymbol, untypedBool)
+	s.declare("false", variableSymbol, untypedBool)
+	s.declare("nil", variableSymbol, untypedNil)
+	s.declare("iota", variableSymbol, untypedInt)
+	for _, name := range []string{"append", "cap", "clear", "close", "copy", "delete", "len", "make", "max", "min", "new", "panic", "print", "println", "recover"} {
+		s.declare(name, builtinSymbol, invalidType)
+	}
+
+	return s
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{
+		outer:   outer,
+		symbols: make(map[string]*symbol),
+	}
+}
+
+func (s *scope) declare(name string, kind symbolKind, typ Type) {
+	if name == "_" {
+		return
+	}
+
+	s.symbols[name] = &symbol{
+		kind: kind,
+		typ:  typ,
+	}
+}
+
+func (s *scope) lookUp(name string) (*symbol, bool) {
+	if sym, ok := s.symbols[name]; ok {
+		return sym, true
+	}
+	if s.outer != nil {
+		return s.outer.lookUp(name)
+	}
+
+	return nil, false
+}
+
+type Checker struct {
+	scope              *scope
+	strict             bool
+	importer           Importer
+	function           *signature
+	namedResult        bool
+	allowedFallthrough ast.Statement
+	loops              int
+	switches           int
+	err                error
+	positions          map[ast.Node]token.Position
+	ends               map[ast.Node]token.Position
+	position           token.Position
+	end                token.Position
+}
+
+type Error struct {
+	Message  string
+	Position token.Position
+	End      token.Position
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type Option func(*Checker)
+
+func WithStrict() Option {
+	return func(c *Checker) {
+		c.strict = true
+	}
+}
+
+func New(opts ...Option) *Checker {
+	c := &Checker{
+		scope: newScope(newUniverse()),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *Checker) Check(program *ast.Program) error {
+	c.err = nil
+	c.positions, c.position = program.Positions, token.Position{}
+	c.ends, c.end = program.Ends, token.Position{}
+	if c.strict {
+		c.checkFile(program)
+	}
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.BadStatement); ok || c.err != nil {
+			break
+		}
+		c.checkStatement(stmt)
+	}
+
+	return c.err
+}
+
+func (c *Checker) errorf(format string, args ...interface{}) {
+	if c.err == nil {
+		c.err = &Error{
+			Message:  fmt.Sprintf(format, args...),
+			Position: c.position,
+			End:      c.end,
+		}
+	}
+}
+
+func (c *Checker) enter(node ast.Node) func() {
+	pos, ok := c.positions[node]
+	if !ok {
+		return func() {}
+	}
+
+	outer, outerEnd := c.position, c.end
+	c.position, c.end = pos, c.ends[node]
+	return func() {
+		c.position, c.end = outer, outerEnd
+	}
+}
+
+func (c *Checker) checkStatements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		if c.err != nil {
+			return
+		}
+		c.checkStatement(stmt)
+	}
+}
+
+func (c *Checker) checkStatement(node ast.Statement) {
+	defer c.enter(node)()
+
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		typ := c.checkValue(node.Expression)
+		if c.strict && c.err == nil {
+			c.checkUsed(node.Expression, typ)
+		}
+	case *ast.VariableDeclaration:
+		c.checkVariableDeclaration(node)
+	case *ast.ShortVariableDeclaration:
+		c.checkShortVariableDeclaration(node)
+	case *ast.AssignmentStatement:
+		c.checkAssignmentStatement(node)
+	case *ast.IncDecStatement:
+		c.checkValue(node.Expression)
+	case *ast.SendStatement:
+		c.checkSendStatement(node)
+	case *ast.GoStatement:
+		c.checkValue(node.Call)
+	case *ast.DeferStatement:
+		c.checkValue(node.Call)
+	case *ast.ReturnStatement:
+		c.checkReturnStatement(node)
+	case *ast.ImportDeclaration:
+		c.checkImportDeclaration(node)
+	case *ast.FallthroughStatement:
+		if c.strict && node != c.allowedFallthrough {
+			c.errorf("fallthrough statement out of place")
+		}
+	case *ast.BreakStatement:
+		if c.strict && c.loops == 0 && c.switches == 0 {
+			c.errorf("break is not in a loop, switch, or select")
+		}
+	case *ast.ContinueStatement:
+		if c.strict && c.loops == 0 {
+			c.errorf("continue is not in a loop")
+		}
+	case *ast.BlockStatement:
+		c.withScope(func() {
+			c.checkStatements(node.Statements)
+		})
+	case *ast.ForStatement:
+		c.checkForStatement(node)
+	case *ast.RangeStatement:
+		c.checkRangeStatement(node)
+	case *ast.SwitchStatement:
+		c.checkSwitchStatement(node)
+	case *ast.TypeSwitchStatement:
+		c.checkTypeSwitchStatement(node)
+	case *ast.SelectStatement:
+		c.checkSelectStatement(node)
+	case *ast.FunctionDeclaration:
+		c.checkFunctionDeclaration(node)
+	case *ast.TypeDeclaration:
+		c.checkTypeDeclaration(node)
+	}
+}
+
+func (c *Checker) checkVariableDeclaration(node *ast.VariableDeclaration) {
+	typ := c.checkVariableType(node.Type)
+	if node.Expression != nil {
+		valueType := c.checkValue(node.Expression)
+		if node.Type == nil {
+			typ = defaultType(valueType)
+		} else if c.strict {
+			c.checkAssignable(node.Expression, valueType, typ, "variable declaration")
+		}
+	}
+
+	c.declare(node.Identifier, variableSymbol, typ)
+}
+
+func (c *Checker) checkShortVariableDeclaration(node *ast.ShortVariableDeclaration) {
+	types := c.checkValues(node.Expressions)
+	if len(types) == 1 && len(node.Identifiers) == 2 {
+		types = append(types, predeclaredTypes["bool"])
+	}
+	if c.strict {
+		c.checkNewVariables(node.Identifiers)
+	}
+
+	for i, ident := range node.Identifiers {
+		var typ Type = invalidType
+		if len(types) == len(node.Identifiers) {
+			typ = defaultType(types[i])
+		}
+		c.scope.declare(ident.Name, variableSymbol, typ)
+	}
+}
+
+func (c *Checker) checkAssignmentStatement(node *ast.AssignmentStatement) {
+	ltypes, rtypes := c.checkValues(node.LExpressions), c.checkValues(node.RExpressions)
+	if !c.strict || len(ltypes) != len(rtypes) {
+		return
+	}
+
+	for i, expr := range node.RExpressions {
+		c.checkAssignable(expr, rtypes[i], ltypes[i], "assignment")
+	}
+}
+
+func (c *Checker) checkSendStatement(node *ast.SendStatement) {
+	typ, value := c.checkValue(node.Channel), c.checkValue(node.Expression)
+	ch, ok := underlying(typ).(*channel)
+	if !c.strict || !ok {
+		return
+	}
+
+	c.checkAssignable(node.Expression, value, ch.element, "send")
+}
+
+func (c *Checker) checkReturnStatement(node *ast.ReturnStatement) {
+	var typ Type
+	if node.Expression != nil {
+		typ = c.checkValue(node.Expression)
+	}
+	if !c.strict || c.function == nil || c.err != nil {
+		return
+	}
+
+	switch result := c.function.result; {
+	case result == nil && typ != nil:
+		c.errorf("too many return values")
+	case result != nil && typ == nil && !c.namedResult:
+		c.errorf("not enough return values")
+	case result != nil && typ != nil:
+		c.checkAssignable(node.Expression, typ, result, "return statement")
+	}
+}
+
+func (c *Checker) checkForStatement(node *ast.ForStatement) {
+	c.withScope(func() {
+		if node.Initializer != nil {
+			c.checkStatement(node.Initializer)
+		}
+		if node.Condition != nil {
+			c.checkValue(node.Condition)
+		}
+		if node.Post != nil {
+			c.checkStatement(node.Post)
+		}
+		c.loops++
+		c.checkStatement(node.Body)
+		c.loops--
+	})
+}
+
+func (c *Checker) checkRangeStatement(node *ast.RangeStatement) {
+	c.withScope(func() {
+		typ := c.checkValue(node.Expression)
+		if node.IsDefine {
+			var key, value Type = invalidType, invalidType
+			switch typ := underlying(typ).(type) {
+			case *channel:
+				key = typ.element
+			case *slice:
+				key, value = predeclaredTypes["int"], typ.element
+			case *mapType:
+				key, value = typ.key, typ.value
+			case *basic:
+				key = defaultType(typ)
+				if typ.info&stringInfo != 0 {
+					key, value = predeclaredTypes["int"], predeclaredTypes["rune"]
+				}
+			}
+			c.scope.declare(node.Key.Name, variableSymbol, key)
+			if node.Value != nil {
+				c.scope.declare(node.Value.Name, variableSymbol, value)
+			}
+		}
+		c.loops++
+		c.checkStatement(node.Body)
+		c.loops--
+	})
+}
+
+func (c *Checker) checkSwitchStatement(node *ast.SwitchStatement) {
+	c.withScope(func() {
+		if node.Initializer != nil {
+			c.checkStatement(node.Initializer)
+		}
+		if node.Tag != nil {
+			c.checkValue(node.Tag)
+		}
+		c.switches++
+		for _, clause := range node.Clauses {
+			c.checkValues(clause.Expressions)
+			c.withScope(func() {
+				c.withFallthrough(clause.Statements)
+			})
+		}
+		c.switches--
+	})
+}
+
+func (c *Checker) checkTypeSwitchStatement(node *ast.TypeSwitchStatement) {
+	c.withScope(func() {
+		if node.Initializer != nil {
+			c.checkStatement(node.Initializer)
+		}
+		typ := c.checkValue(node.Expression)
+		c.switches++
+		for _, clause := range node.Clauses {
+			types := make([]Type, len(clause.Expressions))
+			for i, expr := range clause.Expressions {
+				types[i] = c.checkType(expr)
+			}
+			c.withScope(func() {
+				if node.Identifier != nil {
+					identType := typ
+					if len(types) == 1 {
+						identType = types[0]
+					}
+					c.scope.declare(node.Identifier.Name, variableSymbol, identType)
+				}
+				c.checkStatements(clause.Statements)
+			})
+		}
+		c.switches--
+	})
+}
+
+func (c *Checker) checkSelectStatement(node *ast.SelectStatement) {
+	c.switches++
+	for _, clause := range node.Clauses {
+		c.withScope(func() {
+			if clause.Communication != nil {
+				c.checkStatement(clause.Communication)
+			}
+			c.checkStatements(clause.Statements)
+		})
+	}
+	c.switches--
+}
+
+func (c *Checker) checkFunctionDeclaration(node *ast.FunctionDeclaration) {
+	var sig *signature
+	c.withScope(func() {
+		sig = c.checkSignature(node.TypeParameters, node.Parameters, node.Results)
+	})
+	c.declare(node.Identifier, functionSymbol, sig)
+
+	c.checkFunctionBody(sig, node.Parameters, node.Results, node.Body)
+}
+
+func (c *Checker) checkSignature(typeParams, params, results []*ast.Parameter) *signature {
+	sig := &signature{
+		typeParameters: c.declareTypeParameters(typeParams),
+		parameters:     make([]Type, len(params)),
+	}
+	for i, param := range params {
+		sig.parameters[i] = c.checkVariableType(param.Type)
+	}
+	if 0 < len(results) {
+		sig.result = c.checkVariableType(results[0].Type)
+	}
+
+	return sig
+}
+
+func (c *Checker) declareTypeParameters(nodes []*ast.Parameter) []*typeParameter {
+	params := make([]*typeParameter, len(nodes))
+	for i, node := range nodes {
+		params[i] = &typeParameter{
+			name: node.Identifier.Name,
+		}
+		c.scope.declare(params[i].name, typeSymbol, params[i])
+	}
+	for i, node := range nodes {
+		params[i].constraint = c.checkType(node.Type)
+	}
+
+	return params
+}
+
+func (c *Checker) checkFunctionBody(sig *signature, params, results []*ast.Parameter, body *ast.BlockStatement) {
+	function, namedResult, allowedFallthrough := c.function, c.namedResult, c.allowedFallthrough
+	c.function, c.namedResult, c.allowedFallthrough = sig, 0 < len(results) && results[0].Identifier != nil, nil
+	loops, switches := c.loops, c.switches
+	c.loops, c.switches = 0, 0
+	defer func() {
+		c.function, c.namedResult, c.allowedFallthrough = function, namedResult, allowedFallthrough
+		c.loops, c.switches = loops, switches
+	}()
+
+	c.withScope(func() {
+		for _, param := range sig.typeParameters {
+			c.scope.declare(param.name, typeSymbol, param)
+		}
+		for i, param := range params {
+			c.declare(param.Identifier, variableSymbol, sig.parameters[i])
+		}
+		if c.namedResult {
+			c.declare(results[0].Identifier, variableSymbol, sig.result)
+		}
+
+		c.checkStatements(body.Statements)
+	})
+	if c.strict && c.err == nil && sig.result != nil && !isTerminating(body) {
+		c.errorAtEnd(body, "missing return")
+	}
+}
+
+func (c *Checker) checkTypeDeclaration(node *ast.TypeDeclaration) {
+	typ := &named{
+		name: node.Identifier.Name,
+	}
+	c.declare(node.Identifier, typeSymbol, typ)
+
+	c.withScope(func() {
+		typ.typeParameters = c.declareTypeParameters(node.TypeParameters)
+		typ.underlying = underlying(c.checkType(node.Type))
+	})
+	if typ.underlying == nil {
+		typ.underlying = invalidType
+	}
+}
+
+func (c *Checker) declare(ident *ast.Identifier, kind symbolKind, typ Type) {
+	if _, ok := c.scope.symbols[ident.Name]; ok && c.strict {
+		c.errorAt(ident, "%s redeclared in this block", ident.Name)
+	}
+
+	c.scope.declare(ident.Name, kind, typ)
+}
+
+func (c *Checker) withScope(check func()) {
+	outer := c.scope
+	c.scope = newScope(outer)
+	defer func() {
+		c.scope = outer
+	}()
+
+	check()
+}
+
+func (c *Checker) checkValues(nodes []ast.Expression) []Type {
+	types := make([]Type, len(nodes))
+	for i, node := range nodes {
+		types[i] = c.checkValue(node)
+	}
+
+	return types
+}
+
+func (c *Checker) checkValue(node ast.Expression) Type {
+	typ := c.checkExpression(node)
+	if sig, ok := typ.(*signature); ok && sig.isGeneric() {
+		c.errorf("cannot use generic function %s without instantiation", ast.Format(node))
+		return invalidType
+	}
+
+	return typ
+}
+
+func (c *Checker) checkExpression(node ast.Expression) Type {
+	defer c.enter(node)()
+
+	switch node := node.(type) {
+	case *ast.Integer:
+		return untypedInt
+	case *ast.String:
+		return untypedString
+	case *ast.Identifier:
+		sym, ok := c.scope.lookUp(node.Name)
+		if !ok || sym.kind != variableSymbol && sym.kind != functionSymbol {
+			return invalidType
+		}
+		return sym.typ
+	case *ast.PrefixExpression:
+		return c.checkPrefixExpression(node)
+	case *ast.InfixExpression:
+		return c.checkInfixExpression(node)
+	case *ast.TypeAssertionExpression:
+		c.checkValue(node.Expression)
+		if node.Type == nil {
+			return invalidType
+		}
+		return c.checkType(node.Type)
+	case *ast.IndexExpression:
+		return c.checkIndexExpression(node)
+	case *ast.SelectorExpression:
+		if pkg, ok := c.packageOf(node.Expression); ok {
+			return c.checkPackageMember(node, pkg)
+		}
+		c.checkExpression(node.Expression)
+		return invalidType
+	case *ast.SliceExpression:
+		c.checkValue(node.Expression)
+		for _, index := range []ast.Expression{node.Low, node.High} {
+			if index != nil {
+				c.checkValue(index)
+			}
+		}
+		return invalidType
+	case *ast.CallExpression:
+		return c.checkCallExpression(node)
+	case *ast.CompositeLiteral:
+		var typ Type = invalidType
+		if node.Type != nil {
+			typ = c.checkVariableType(node.Type)
+		}
+		for _, elem := range node.Elements {
+			if elem.Key != nil {
+				c.checkValue(elem.Key)
+			}
+			c.checkValue(elem.Value)
+		}
+		return typ
+	case *ast.FunctionLiteral:
+		var sig *signature
+		c.withScope(func() {
+			sig = c.checkSignature(nil, node.Parameters, node.Results)
+		})
+		c.checkFunctionBody(sig, node.Parameters, node.Results, node.Body)
+		return sig
+	default:
+		return invalidType
+	}
+}
+
+func (c *Checker) checkPrefixExpression(node *ast.PrefixExpression) Type {
+	typ := c.checkValue(node.RExpression)
+	switch node.Operator {
+	case ast.Receive:
+		if ch, ok := underlying(typ).(*channel); ok {
+			return ch.element
+		}
+		return invalidType
+	case ast.Dereference:
+		if p, ok := underlying(typ).(*pointer); ok {
+			return p.base
+		}
+		return invalidType
+	case ast.Negative:
+		c.checkOperator(node.RExpression, typ, string(node.Operator), numericInfo)
+		return typ
+	default:
+		return typ
+	}
+}
+
+func (c *Checker) checkInfixExpression(node *ast.InfixExpression) Type {
+	left, right := c.checkValue(node.LExpression), c.checkValue(node.RExpression)
+	if c.strict && c.err == nil {
+		c.checkOperands(node, left, right)
+	}
+	if c.err != nil {
+		return invalidType
+	}
+
+	switch node.Operator {
+	case ast.Equal, ast.NotEqual:
+		for _, typ := range []Type{left, right} {
+			if _, ok := typ.(*typeParameter); ok && !isComparable(typ) {
+				c.errorf("invalid operation: %s (incomparable types in type set)", ast.Format(node))
+			}
+		}
+		return untypedBool
+	case ast.LessThan, ast.GreaterThan, ast.LessThanOrEqual, ast.GreaterThanOrEqual:
+		for _, typ := range []Type{left, right} {
+			if param, ok := typ.(*typeParameter); ok && !typeSetOf(param.constraint).every(orderedInfo) {
+				c.errorf("invalid operation: %s (type parameter %s is not comparable with %s)", ast.Format(node), param, node.Operator)
+			}
+		}
+		return untypedBool
+	case ast.Plus:
+		c.checkOperator(node.LExpression, left, string(node.Operator), numericInfo|stringInfo)
+		c.checkOperator(node.RExpression, right, string(node.Operator), numericInfo|stringInfo)
+	default:
+		c.checkOperator(node.LExpression, left, string(node.Operator), numericInfo)
+		c.checkOperator(node.RExpression, right, string(node.Operator), numericInfo)
+	}
+
+	if isUntyped(left) {
+		return right
+	}
+
+	return left
+}
+
+func (c *Checker) checkOperator(node ast.Expression, typ Type, operator string, info basicInfo) {
+	param, ok := typ.(*typeParameter)
+	if !ok && c.strict {
+		c.checkBasicOperator(node, typ, operator, info)
+	}
+	if !ok || typeSetOf(param.constraint).every(info) {
+		return
+	}
+
+	c.errorf("invalid operation: operator %s not defined on %s (variable of type %s constrained by %s)", operator, ast.Format(node), param, param.constraint)
+}
+
+func (c *Checker) checkIndexExpression(node *ast.IndexExpression) Type {
+	typ := c.checkExpression(node.Expression)
+	sig, ok := typ.(*signature)
+	if !ok || !sig.isGeneric() {
+		c.checkValues(node.Indices)
+		return elementOf(typ)
+	}
+
+	typeArgs := c.checkTypeArguments(node.Indices)
+	if len(typeArgs) < len(sig.typeParameters) {
+		c.errorf("not enough type arguments for func %s: have %d, want %d", ast.Format(node.Expression), len(typeArgs), len(sig.typeParameters))
+		return invalidType
+	}
+
+	return c.instantiateSignature(ast.Format(node.Expression), sig, typeArgs)
+}
+
+func elementOf(typ Type) Type {
+	switch typ := underlying(typ).(type) {
+	case *slice:
+		return typ.element
+	case *mapType:
+		return typ.value
+	case *basic:
+		if typ.info&stringInfo != 0 {
+			return predeclaredTypes["byte"]
+		}
+	}
+
+	return invalidType
+}
+
+func (c *Checker) checkTypeArguments(nodes []ast.Expression) []Type {
+	types := make([]Type, len(nodes))
+	for i, node := range nodes {
+		types[i] = c.checkVariableType(node)
+	}
+
+	return types
+}
+
+func (c *Checker) instantiateSignature(name string, sig *signature, typeArgs []Type) Type {
+	if len(sig.typeParameters) < len(typeArgs) {
+		c.errorf("too many type arguments for func %s: have %d, want %d", name, len(typeArgs), len(sig.typeParameters))
+		return invalidType
+	}
+
+	args := make(map[*typeParameter]Type)
+	for i, param := range sig.typeParameters {
+		args[param] = typeArgs[i]
+	}
+	if !c.verify(sig.typeParameters, args) {
+		return invalidType
+	}
+
+	return substitute(sig, args)
+}
+
+func (c *Checker) verify(params []*typeParameter, args map[*typeParameter]Type) bool {
+	for _, param := range params {
+		arg := args[param]
+		if arg == invalidType {
+			continue
+		}
+		if err := satisfies(arg, substitute(param.constraint, args)); err != nil {
+			c.errorf("%s", err)
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Checker) checkCallExpression(node *ast.CallExpression) Type {
+	if c.denotesType(node.Function) {
+		typ := c.checkVariableType(node.Function)
+		c.checkValues(node.Arguments)
+		return typ
+	}
+	if ident, ok := node.Function.(*ast.Identifier); ok {
+		if sym, ok := c.scope.lookUp(ident.Name); ok && sym.kind == builtinSymbol {
+			return c.checkBuiltinCall(ident.Name, node.Arguments)
+		}
+	}
+
+	function, explicit := node.Function, []ast.Expression(nil)
+	if index, ok := node.Function.(*ast.IndexExpression); ok {
+		function, explicit = index.Expression, index.Indices
+	}
+	typ := c.checkExpression(function)
+	sig, ok := typ.(*signature)
+	if !ok && c.strict && !hasInvalid(typ) && !isTypeParameter(typ) {
+		c.errorf("invalid operation: cannot call non-function %s", c.describe(function, typ))
+		return invalidType
+	}
+	if !ok || !sig.isGeneric() {
+		if explicit != nil {
+			c.checkIndexExpression(node.Function.(*ast.IndexExpression))
+		}
+		types := c.checkValues(node.Arguments)
+		if !ok {
+			return invalidType
+		}
+		if sig.isVariadic {
+			c.checkVariadicArguments(node, sig, types)
+		} else {
+			c.checkArguments(ast.Format(node.Function), sig, node.Arguments, types)
+		}
+		return resultOf(sig)
+	}
+
+	typeArgs := c.checkTypeArguments(explicit)
+	if len(sig.typeParameters) < len(typeArgs) {
+		c.errorf("too many type arguments for func %s: have %d, want %d", ast.Format(function), len(typeArgs), len(sig.typeParameters))
+		return invalidType
+	}
+
+	types := c.checkValues(node.Arguments)
+	if c.err != nil || !c.checkArity(ast.Format(node.Function), sig, node.Arguments) {
+		return invalidType
+	}
+	inferred, ok := c.infer(ast.Format(function), sig, typeArgs, node.Arguments, types)
+	if !ok {
+		return invalidType
+	}
+
+	instantiated := c.instantiateSignature(ast.Format(function), sig, inferred)
+	if sig, ok := instantiated.(*signature); ok {
+		c.checkArguments(ast.Format(node.Function), sig, node.Arguments, types)
+	}
+
+	return resultOf(instantiated)
+}
+
+func (c *Checker) checkArity(name string, sig *signature, nodes []ast.Expression) bool {
+	if len(nodes) == 1 && 1 < len(sig.parameters) {
+		if _, ok := nodes[0].(*ast.CallExpression); ok {
+			return false
+		}
+	}
+
+	switch {
+	case len(nodes) < len(sig.parameters):
+		c.errorf("not enough arguments in call to %s", name)
+		return false
+	case len(sig.parameters) < len(nodes):
+		c.errorf("too many arguments in call to %s", name)
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *Checker) checkArguments(name string, sig *signature, nodes []ast.Expression, types []Type) {
+	if c.err != nil || !c.checkArity(name, sig, nodes) {
+		return
+	}
+
+	for i, param := range sig.parameters {
+		c.checkAssignable(nodes[i], types[i], param, "argument to "+name)
+	}
+}
+
+func (c *Checker) checkVariadicArguments(node *ast.CallExpression, sig *signature, types []Type) {
+	name, n := ast.Format(node.Function), len(sig.parameters)-1
+	if c.err != nil {
+		return
+	}
+	if len(node.Arguments) < n {
+		c.errorf("not enough arguments in call to %s", name)
+		return
+	}
+
+	for i, arg := range node.Arguments {
+		param := sig.parameters[min(i, n)]
+		if node.HasEllipsis && i == n {
+			param = &slice{
+				element: param,
+			}
+		}
+		c.checkAssignable(arg, types[i], param, "argument to "+name)
+	}
+}
+
+func (c *Checker) checkAssignable(node ast.Expression, v, t Type, context string) {
+	if c.err != nil || assignable(v, t) {
+		return
+	}
+
+	defer c.enter(node)()
+	c.errorf("cannot use %s as %s value in %s", c.describe(node, v), t, context)
+}
+
+func (c *Checker) describe(node ast.Expression, typ Type) string {
+	switch {
+	case typ == untypedNil:
+		return ast.Format(node)
+	case isUntyped(typ):
+		return fmt.Sprintf("%s (%s constant)", ast.Format(node), typ)
+	}
+	if ident, ok := node.(*ast.Identifier); ok {
+		if sym, ok := c.scope.lookUp(ident.Name); !ok || sym.kind != functionSymbol {
+			return fmt.Sprintf("%s (variable of type %s)", ast.Format(node), typ)
+		}
+	}
+
+	return fmt.Sprintf("%s (value of type %s)", ast.Format(node), typ)
+}
+
+func resultOf(typ Type) Type {
+	sig, ok := typ.(*signature)
+	if !ok || sig.result == nil {
+		return invalidType
+	}
+
+	return sig.result
+}
+
+func (c *Checker) infer(name string, sig *signature, explicit []Type, nodes []ast.Expression, types []Type) ([]Type, bool) {
+	args := make(map[*typeParameter]Type)
+	for i, typ := range explicit {
+		args[sig.typeParameters[i]] = typ
+	}
+
+	hasInvalid := false
+	for i, param := range sig.parameters {
+		switch {
+		case types[i] == invalidType:
+			hasInvalid = true
+		case !isUntyped(types[i]):
+			if !c.unify(sig, args, param, types[i], nodes[i]) {
+				return nil, false
+			}
+		}
+	}
+
+	untypeds := make(map[*typeParameter]Type)
+	for i, param := range sig.parameters {
+		param, ok := param.(*typeParameter)
+		if !ok || !isUntyped(types[i]) || types[i] == untypedNil {
+			continue
+		}
+		if _, ok := args[param]; ok {
+			continue
+		}
+		if prev, ok := untypeds[param]; ok && prev != types[i] {
+			c.errorf("mismatched types %s and %s (cannot infer %s)", prev, types[i], param)
+			return nil, false
+		}
+		untypeds[param] = types[i]
+	}
+	for param, typ := range untypeds {
+		args[param] = defaultType(typ)
+	}
+
+	typeArgs := make([]Type, len(sig.typeParameters))
+	for i, param := range sig.typeParameters {
+		arg, ok := args[param]
+		if !ok {
+			if !hasInvalid {
+				c.errorf("in call to %s, cannot infer %s", name, param)
+			}
+			return nil, false
+		}
+		typeArgs[i] = arg
+	}
+
+	return typeArgs, true
+}
+
+func (c *Checker) unify(sig *signature, args map[*typeParameter]Type, param, arg Type, node ast.Expression) bool {
+	switch param := param.(type) {
+	case *typeParameter:
+		if !isTypeParameterOf(sig, param) {
+			return true
+		}
+		if inferred, ok := args[param]; ok && !identical(inferred, arg) {
+			c.errorf("type %s of %s does not match inferred type %s for %s", arg, ast.Format(node), inferred, param)
+			return false
+		}
+		args[param] = arg
+		return true
+	case *channel:
+		ch, ok := underlying(arg).(*channel)
+		if !ok {
+			c.errorf("type %s of %s does not match %s", arg, ast.Format(node), param)
+			return false
+		}
+		return c.unify(sig, args, param.element, ch.element, node)
+	case *slice:
+		s, ok := underlying(arg).(*slice)
+		if !ok {
+			c.errorf("type %s of %s does not match %s", arg, ast.Format(node), param)
+			return false
+		}
+		return c.unify(sig, args, param.element, s.element, node)
+	case *mapType:
+		m, ok := underlying(arg).(*mapType)
+		if !ok {
+			c.errorf("type %s of %s does not match %s", arg, ast.Format(node), param)
+			return false
+		}
+		return c.unify(sig, args, param.key, m.key, node) && c.unify(sig, args, param.value, m.value, node)
+	case *pointer:
+		p, ok := underlying(arg).(*pointer)
+		if !ok {
+			c.errorf("type %s of %s does not match %s", arg, ast.Format(node), param)
+			return false
+		}
+		return c.unify(sig, args, param.base, p.base, node)
+	case *named:
+		other, ok := arg.(*named)
+		if param.origin == nil || !ok || other.origin != param.origin {
+			return true
+		}
+		for i := range param.typeArguments {
+			if !c.unify(sig, args, param.typeArguments[i], other.typeArguments[i], node) {
+				return false
+			}
+		}
+		return true
+	case *signature:
+		other, ok := arg.(*signature)
+		if !ok || len(param.parameters) != len(other.parameters) {
+			return true
+		}
+		for i := range param.parameters {
+			if !c.unify(sig, args, param.parameters[i], other.parameters[i], node) {
+				return false
+			}
+		}
+		if param.result != nil && other.result != nil {
+			return c.unify(sig, args, param.result, other.result, node)
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+func isTypeParameterOf(sig *signature, param *typeParameter) bool {
+	for _, p := range sig.typeParameters {
+		if p == param {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Checker) checkBuiltinCall(name string, nodes []ast.Expression) Type {
+	if (name == "make" || name == "new") && 0 < len(nodes) {
+		typ := c.checkVariableType(nodes[0])
+		c.checkValues(nodes[1:])
+		if name == "new" {
+			return &pointer{
+				base: typ,
+			}
+		}
+		return typ
+	}
+
+	types := c.checkValues(nodes)
+	switch name {
+	case "recover":
+		return predeclaredTypes["any"]
+	case "len", "cap", "copy":
+		return predeclaredTypes["int"]
+	case "append":
+		if 0 < len(types) {
+			return types[0]
+		}
+	}
+
+	return invalidType
+}
+
+func (c *Checker) denotesType(node ast.Expression) bool {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		sym, ok := c.scope.lookUp(node.Name)
+		return ok && sym.kind == typeSymbol
+	case *ast.IndexExpression:
+		return c.denotesType(node.Expression)
+	case *ast.SliceType, *ast.MapType, *ast.PointerType, *ast.ChannelType, *ast.InterfaceType:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Checker) checkVariableType(node ast.Expression) Type {
+	typ := c.checkType(node)
+	if _, ok := typ.(*typeParameter); ok || !isConstraint(typ) {
+		return typ
+	}
+
+	if underlying(typ).(*interfaceType).isComparable {
+		c.errorf("cannot use type %s outside a type constraint: interface is (or embeds) comparable", typ)
+	} else {
+		c.errorf("cannot use type %s outside a type constraint: interface contains type constraints", typ)
+	}
+
+	return invalidType
+}
+
+func (c *Checker) checkType(node ast.Expression) Type {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		sym, ok := c.scope.lookUp(node.Name)
+		if !ok && c.strict {
+			c.errorAt(node, "undefined: %s", node.Name)
+		}
+		if !ok || sym.kind != typeSymbol {
+			return invalidType
+		}
+		if named, ok := sym.typ.(*named); ok && named.isGeneric() {
+			c.errorf("cannot use generic type %s without instantiation", named.declaration())
+			return invalidType
+		}
+		return sym.typ
+	case *ast.IndexExpression:
+		return c.checkGenericType(node)
+	case *ast.ChannelType:
+		return &channel{
+			direction: node.Direction,
+			element:   c.checkVariableType(node.Element),
+		}
+	case *ast.SliceType:
+		return &slice{
+			element: c.checkVariableType(node.Element),
+		}
+	case *ast.MapType:
+		return &mapType{
+			key:   c.checkVariableType(node.Key),
+			value: c.checkVariableType(node.Value),
+		}
+	case *ast.PointerType:
+		return &pointer{
+			base: c.checkVariableType(node.Element),
+		}
+	case *ast.InterfaceType:
+		return c.checkInterfaceType(node)
+	default:
+		return invalidType
+	}
+}
+
+func (c *Checker) checkGenericType(node *ast.IndexExpression) Type {
+	ident, ok := node.Expression.(*ast.Identifier)
+	if !ok {
+		return invalidType
+	}
+	sym, ok := c.scope.lookUp(ident.Name)
+	if !ok || sym.kind != typeSymbol {
+		return invalidType
+	}
+	typ, ok := sym.typ.(*named)
+	if !ok || !typ.isGeneric() {
+		c.errorf("%s is not a generic type", ident.Name)
+		return invalidType
+	}
+
+	typeArgs := c.checkTypeArguments(node.Indices)
+	if len(typeArgs) < len(typ.typeParameters) {
+		c.errorf("not enough type arguments for type %s: have %d, want %d", typ.name, len(typeArgs), len(typ.typeParameters))
+		return invalidType
+	}
+	if len(typ.typeParameters) < len(typeArgs) {
+		c.errorf("too many type arguments for type %s: have %d, want %d", typ.name, len(typeArgs), len(typ.typeParameters))
+		return invalidType
+	}
+
+	args := make(map[*typeParameter]Type)
+	for i, param := range typ.typeParameters {
+		args[param] = typeArgs[i]
+	}
+	if !c.verify(typ.typeParameters, args) {
+		return invalidType
+	}
+
+	return instantiate(typ, typeArgs)
+}
+
+func (c *Checker) checkInterfaceType(node *ast.InterfaceType) Type {
+	iface := &interfaceType{
+		elements: make([][]*term, len(node.Elements)),
+	}
+	for i, union := range node.Elements {
+		for _, t := range union.Terms {
+			typ := c.checkType(t.Type)
+			if t.IsTilde && typ != invalidType && underlying(typ) != typ {
+				c.errorf("invalid use of ~ (underlying type of %s is %s)", typ, underlying(typ))
+			}
+			if embedded, ok := typ.(*interfaceType); ok && embedded.isComparable && len(union.Terms) == 1 {
+				iface.isComparable = true
+			}
+			iface.elements[i] = append(iface.elements[i], &term{
+				isTilde: t.IsTilde,
+				typ:     typ,
+			})
+		}
+	}
+
+	return iface
+}
@@ -0,0 +1,387 @@
+package checker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"type Number interface { ~int | ~float64 }; func Sum[T Number](a, b T) T { return a + b; } Sum(1, 2); type MyInt int; var x MyInt = 1; Sum(x, x); Sum[float64](1, 2);",
+			"",
+		},
+		{
+			"func F[T ~int](x T) T { return x; } func G[U ~int | ~string](y U) U { return y; } func H[V ~int](z V) V { return G(F(z)); }",
+			"",
+		},
+		{
+			"func First[T any](c chan T) T { return <-c; } c := make(chan string); var s string = First(c); First[string](c);",
+			"",
+		},
+		{
+			"type Pair[K comparable, V any] chan V; var p Pair[string, chan int];",
+			"",
+		},
+		{
+			"1 + \"a\"; x := y; z[0];",
+			"",
+		},
+		{
+			"import (\"fmt\"; \"strings\"); m := map[string][]int{\"a\": {1}}; fmt.Println(strings.ToUpper(\"a\"), m[\"a\"][0:]);",
+			"",
+		},
+		{
+			"import \"fmt\"; func Max[T any](x T) T { return x; } fmt.Println(Max);",
+			"cannot use generic function Max without instantiation",
+		},
+		{
+			"type Number interface { ~int | ~float64 }; func Sum[T Number](a, b T) T { return a + b; } Sum(\"a\", \"b\");",
+			"string does not satisfy Number (string missing in ~int | ~float64)",
+		},
+		{
+			"func F[T comparable](a, b T) bool { return a == b; } F(func() {}, func() {});",
+			"func() does not satisfy comparable",
+		},
+		{
+			"func F[T ~int | ~string](x T) T { return x; } func G[U any](y U) U { return F(y); }",
+			"U does not satisfy interface{ ~int | ~string } (U missing in ~int | ~string)",
+		},
+		{
+			"func F[T any](a, b T) bool { return a == b; }",
+			"invalid operation: a == b (incomparable types in type set)",
+		},
+		{
+			"func F[T ~int | ~bool](a, b T) bool { return a < b; }",
+			"invalid operation: a < b (type parameter T is not comparable with <)",
+		},
+		{
+			"func F[T any](a T) T { return a + a; }",
+			"invalid operation: operator + not defined on a (variable of type T constrained by any)",
+		},
+		{
+			"func F[T ~int | ~string](a, b T) {} F(1, \"a\");",
+			"mismatched types untyped int and untyped string (cannot infer T)",
+		},
+		{
+			"func F[T any](a, b T) {} var x int; var y string; F(x, y);",
+			"type string of y does not match inferred type int for T",
+		},
+		{
+			"func First[T any](c chan T) T { return <-c; } var x int; First(x);",
+			"type int of x does not match chan T",
+		},
+		{
+			"func Zero[T any]() T { var z T; return z; } Zero();",
+			"in call to Zero, cannot infer T",
+		},
+		{
+			"func Zero[T any]() T { var z T; return z; } Zero[int, int]();",
+			"too many type arguments for func Zero: have 2, want 1",
+		},
+		{
+			"func Pair[K, V any]() {} f := Pair[int];",
+			"not enough type arguments for func Pair: have 1, want 2",
+		},
+		{
+			"func F[T any](x T) T { return x; } f := F;",
+			"cannot use generic function F without instantiation",
+		},
+		{
+			"type List[T any] chan T; var l List;",
+			"cannot use generic type List[T any] without instantiation",
+		},
+		{
+			"type Pair[K comparable, V any] chan V; var p Pair[int];",
+			"not enough type arguments for type Pair: have 1, want 2",
+		},
+		{
+			"type List[T ~int] chan T; var l List[string];",
+			"string does not satisfy interface{ ~int } (string missing in ~int)",
+		},
+		{
+			"type Number interface { ~int | ~float64 }; var n Number;",
+			"cannot use type Number outside a type constraint: interface contains type constraints",
+		},
+		{
+			"func F(c comparable) {}",
+			"cannot use type comparable outside a type constraint: interface is (or embeds) comparable",
+		},
+		{
+			"type MyInt int; type I interface { ~MyInt };",
+			"invalid use of ~ (underlying type of MyInt is int)",
+		},
+		{
+			"type Num interface { ~int }; func Sum[T Num](xs []T) T { var s T; for _, x := range xs { s = s + x; } return s; } var n int = Sum([]int{1, 2, 3});",
+			"",
+		},
+		{
+			"func Keys[K comparable, V any](m map[K]V, p *V) []K { return nil; } var ks []string = Keys(map[string]int{}, new(int));",
+			"",
+		},
+		{
+			"func Id[T any](x T) T { return x; } Id[int](\"a\");",
+			"cannot use \"a\" (untyped string constant) as int value in argument to Id[int]",
+		},
+		{
+			"func Eq[T comparable](a, b T) bool { return a == b; } Eq([]int{1}, []int{1});",
+			"[]int does not satisfy comparable",
+		},
+		{
+			"func Id[T any](x T) T { return x; } Id(1, 2);",
+			"too many arguments in call to Id",
+		},
+		{
+			"func F(a, b int) {} F(1);",
+			"not enough arguments in call to F",
+		},
+		{
+			"func F(s string) {} var x int; F(x);",
+			"cannot use x (variable of type int) as string value in argument to F",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			err := New().Check(program)
+			actual := ""
+			if err != nil {
+				actual = fmt.Sprint(err)
+			}
+			if actual != test.expected {
+				t.Errorf("unexpected error: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestCheckAcrossPrograms(t *testing.T) {
+	c := New()
+	inputs := []string{
+		"type Number interface { ~int | ~float64 };",
+		"func Sum[T Number](a, b T) T { return a + b; }",
+	}
+	for _, input := range inputs {
+		if err := c.Check(parser.New(lexer.New(input)).ParseProgram()); err != nil {
+			t.Fatalf("unexpected error: %s\n", err)
+		}
+	}
+
+	err := c.Check(parser.New(lexer.New(`Sum("a", "b");`)).ParseProgram())
+	if err == nil {
+		t.Fatalf("unexpected error: got nil, but expected an error\n")
+	}
+}
+
+func TestCheckStrict(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`package main; import ("fmt"; s "strings"); var g int; func main() { x := 1; var y []int; y = append(y, x); fmt.Println(s.ToUpper("a"), y); g = 1; }`,
+			"",
+		},
+		{
+			"package main; func main() { c := make(chan int); go func() { c <- 1; }(); <-c; for i := range c { println(i); } switch v := interface{}(1).(type) { case int: println(v); } }",
+			"",
+		},
+		{
+			"package main; func main() { n := 0; f := func() { n++; }; f(); print(n); }",
+			"",
+		},
+		{
+			"func main() {}",
+			"1:1: syntax error: package statement must be first",
+		},
+		{
+			"package lib; func main() {}",
+			"1:1: package lib is not a main package",
+		},
+		{
+			"package main; x := 1; func main() {}",
+			"1:15: syntax error: non-declaration statement outside function body",
+		},
+		{
+			"package main; func f() {}",
+			"1:1: function main is undeclared in the main package",
+		},
+		{
+			"package main; func main() int { return 0; }",
+			"1:15: func main must have no arguments and no return values",
+		},
+		{
+			"package main; func main() { 5; }",
+			"1:29: 5 (untyped int constant) is not used",
+		},
+		{
+			"package main; func main() { x := 1; x + 1; }",
+			"1:37: x + 1 (value of type int) is not used",
+		},
+		{
+			"package main; func main() { var s []int; len(s); }",
+			"1:42: len(s) (value of type int) is not used",
+		},
+		{
+			"package main; func main() { var s string = 1; println(s); }",
+			"1:44: cannot use 1 (untyped int constant) as string value in variable declaration",
+		},
+		{
+			"package main; func f() {} func main() { var g int = f; println(g); }",
+			"1:53: cannot use f (value of type func()) as int value in variable declaration",
+		},
+		{
+			`package main; func main() { x := 1; x = "a"; println(x); }`,
+			`1:41: cannot use "a" (untyped string constant) as int value in assignment`,
+		},
+		{
+			`package main; func f() int { return "a"; } func main() { f(); }`,
+			`1:37: cannot use "a" (untyped string constant) as int value in return statement`,
+		},
+		{
+			"package main; func f() int { return; } func main() { f(); }",
+			"1:30: not enough return values",
+		},
+		{
+			"package main; func f() (n int) { return; } func f() { return 1; } func main() {}",
+			"1:49: f redeclared in this block",
+		},
+		{
+			"package main; func f() { return 1; } func main() { f(); }",
+			"1:26: too many return values",
+		},
+		{
+			"package main; func f() int { } func main() { f(); }",
+			"1:30: missing return",
+		},
+		{
+			"package main; func f() int { for { break; } } func main() { f(); }",
+			"1:45: missing return",
+		},
+		{
+			`package main; func f(x int) int { for { } } func g(x int) int { switch x { case 1: fallthrough; default: panic("a"); } } func main() { f(1); g(1); }`,
+			"",
+		},
+		{
+			"package main; func main() { x := 1; x := 2; println(x); }",
+			"1:37: no new variables on left side of :=",
+		},
+		{
+			"package main; func f(a, a int) {} func main() { f(1, 2); }",
+			"1:25: a redeclared in this block",
+		},
+		{
+			"package main; func main() { var x T; println(x); }",
+			"1:35: undefined: T",
+		},
+		{
+			"package main; func main() { println(true + 1); }",
+			"1:37: invalid operation: true + 1 (mismatched types untyped bool and untyped int)",
+		},
+		{
+			`package main; func main() { println("a" - "b"); }`,
+			`1:37: invalid operation: operator - not defined on "a" (untyped string constant)`,
+		},
+		{
+			"package main; func main() { fallthrough; }",
+			"1:29: fallthrough statement out of place",
+		},
+		{
+			"package main; func main() { for { fallthrough; } }",
+			"1:35: fallthrough statement out of place",
+		},
+		{
+			`package main; import "net/http"; func main() {}`,
+			"1:22: package net/http is not in std",
+		},
+		{
+			`package main; import "fmt"; func main() { fmt.Printl("a"); }`,
+			"1:47: undefined: fmt.Printl",
+		},
+		{
+			`package main; import "fmt"; func main() { fmt.println("a"); }`,
+			"1:47: name println not exported by package fmt",
+		},
+		{
+			`package main; import "strings"; func main() { strings.ToUpper(1); }`,
+			"1:63: cannot use 1 (untyped int constant) as string value in argument to strings.ToUpper",
+		},
+		{
+			`package main; import s "strings"; func main() { var xs []string; println(s.Join(xs, ",")); }`,
+			"",
+		},
+		{
+			`package main; import "fmt"; func main() { var xs []int; fmt.Println(1, "a", xs); fmt.Println(xs...); }`,
+			"1:94: cannot use xs (variable of type []int) as []any value in argument to fmt.Println",
+		},
+		{
+			`package main; func main() { c := make(chan int); c <- "a"; }`,
+			`1:55: cannot use "a" (untyped string constant) as int value in send`,
+		},
+		{
+			"package main; func main() { for { switch { default: break; } continue; } select { default: break; } }",
+			"",
+		},
+		{
+			"package main; func main() { break; }",
+			"1:29: break is not in a loop, switch, or select",
+		},
+		{
+			"package main; func main() { for { func() { continue; }(); } }",
+			"1:44: continue is not in a loop",
+		},
+		{
+			"package main; func main() { switch { default: continue; } }",
+			"1:47: continue is not in a loop",
+		},
+		{
+			"package main; func main() { x := 1; x(); }",
+			"1:37: invalid operation: cannot call non-function x (variable of type int)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			err := New(WithStrict(), WithImporter(importPackage)).Check(program)
+			actual := ""
+			if err != nil {
+				err := err.(*Error)
+				actual = fmt.Sprintf("%s: %s", err.Position, err.Message)
+			}
+			if actual != test.expected {
+				t.Errorf("unexpected error: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func importPackage(path string) (*Package, bool) {
+	anyType := &object.Type{Kind: object.BasicType, Name: "any"}
+	stringType := &object.Type{Kind: object.BasicType, Name: "string"}
+	switch path {
+	case "fmt":
+		return &Package{
+			Name: "fmt",
+			Functions: map[string]*Function{
+				"Println": {Parameters: []*object.Type{anyType}, IsVariadic: true},
+			},
+		}, true
+	case "strings":
+		return &Package{
+			Name: "strings",
+			Functions: map[string]*Function{
+				"Join":    {Parameters: []*object.Type{{Kind: object.SliceType, Name: "[]string", Element: stringType}, stringType}},
+				"ToUpper": {Parameters: []*object.Type{stringType}},
+			},
+		}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+)
+
+// Package describes a package which programs can import. The results of its
+// functions are not described, so they are left unchecked.
+type Package struct {
+	Name      string
+	Functions map[string]*Function
+	Constants []string
+}
+
+type Function struct {
+	Parameters []*object.Type
+	IsVariadic bool
+}
+
+// Importer looks up the package which the path of an import declaration
+// names.
+type Importer func(path string) (*Package, bool)
+
+func WithImporter(importer Importer) Option {
+	return func(c *Checker) {
+		c.importer = importer
+	}
+}
+
+type packageName struct {
+	pkg *Package
+}
+
+func (t packageName) String() string {
+	return t.pkg.Name
+}
+
+func (c *Checker) checkImportDeclaration(node *ast.ImportDeclaration) {
+	if c.importer == nil {
+		return
+	}
+
+	for _, spec := range node.Specs {
+		pkg, ok := c.importer(spec.Path.Value)
+		if !ok {
+			if c.strict {
+				c.errorAt(spec, "package %s is not in std", spec.Path.Value)
+			}
+			continue
+		}
+
+		name := pkg.Name
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		c.scope.declare(name, packageSymbol, &packageName{
+			pkg: pkg,
+		})
+	}
+}
+
+func (c *Checker) packageOf(node ast.Expression) (*Package, bool) {
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	sym, ok := c.scope.lookUp(ident.Name)
+	if !ok || sym.kind != packageSymbol {
+		return nil, false
+	}
+
+	return sym.typ.(*packageName).pkg, true
+}
+
+func (c *Checker) checkPackageMember(node *ast.SelectorExpression, pkg *Package) Type {
+	name := node.Selector.Name
+	if fn, ok := pkg.Functions[name]; ok {
+		sig := &signature{
+			parameters: make([]Type, len(fn.Parameters)),
+			isVariadic: fn.IsVariadic,
+			result:     invalidType,
+		}
+		for i, param := range fn.Parameters {
+			sig.parameters[i] = typeOfObject(param)
+		}
+		return sig
+	}
+	for _, constant := range pkg.Constants {
+		if constant == name {
+			return invalidType
+		}
+	}
+
+	if c.strict {
+		if r, _ := utf8.DecodeRuneInString(name); !unicode.IsUpper(r) {
+			c.errorAt(node.Selector, "name %s not exported by package %s", name, pkg.Name)
+		} else {
+			c.errorAt(node.Selector, "undefined: %s.%s", pkg.Name, name)
+		}
+	}
+
+	return invalidType
+}
+
+func typeOfObject(typ *object.Type) Type {
+	switch typ.Kind {
+	case object.SliceType:
+		return &slice{
+			element: typeOfObject(typ.Element),
+		}
+	case object.MapType:
+		return &mapType{
+			key:   typeOfObject(typ.Key),
+			value: typeOfObject(typ.Element),
+		}
+	case object.PointerType:
+		return &pointer{
+			base: typeOfObject(typ.Element),
+		}
+	case object.ChannelType:
+		return &channel{
+			direction: ast.Bidirectional,
+			element:   typeOfObject(typ.Element),
+		}
+	default:
+		if typ, ok := predeclaredTypes[typ.Name]; ok {
+			return typ
+		}
+		return invalidType
+	}
+}
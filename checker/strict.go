@@ -0,0 +1,264 @@
+package checker
+
+import (
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/token"
+)
+
+var valueBuiltins = map[string]bool{
+	"append": true,
+	"cap":    true,
+	"len":    true,
+	"make":   true,
+	"max":    true,
+	"min":    true,
+	"new":    true,
+}
+
+func (c *Checker) checkFile(program *ast.Program) {
+	if len(program.Statements) == 0 {
+		c.errorf("syntax error: package statement must be first")
+		return
+	}
+	pkg, ok := program.Statements[0].(*ast.PackageClause)
+	if !ok {
+		c.errorAt(program.Statements[0], "syntax error: package statement must be first")
+		return
+	}
+	if pkg.Name.Name != "main" {
+		c.errorAt(pkg, "package %s is not a main package", pkg.Name.Name)
+		return
+	}
+
+	var main *ast.FunctionDeclaration
+	for _, stmt := range program.Statements[1:] {
+		switch stmt := stmt.(type) {
+		case *ast.ImportDeclaration, *ast.VariableDeclaration, *ast.TypeDeclaration:
+		case *ast.FunctionDeclaration:
+			if stmt.Identifier.Name == "main" {
+				main = stmt
+			}
+		case *ast.BadStatement:
+			return
+		default:
+			c.errorAt(stmt, "syntax error: non-declaration statement outside function body")
+			return
+		}
+	}
+
+	switch {
+	case main == nil:
+		c.errorAt(pkg, "function main is undeclared in the main package")
+	case 0 < len(main.TypeParameters):
+		c.errorAt(main, "func main must have no type parameters")
+	case 0 < len(main.Parameters) || 0 < len(main.Results):
+		c.errorAt(main, "func main must have no arguments and no return values")
+	}
+}
+
+func (c *Checker) checkUsed(node ast.Expression, typ Type) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if c.denotesType(node.Function) {
+			break
+		}
+		if ident, ok := node.Function.(*ast.Identifier); ok {
+			if sym, ok := c.scope.lookUp(ident.Name); ok && sym.kind == builtinSymbol && valueBuiltins[ident.Name] {
+				break
+			}
+		}
+		return
+	case *ast.PrefixExpression:
+		if node.Operator == ast.Receive {
+			return
+		}
+	}
+
+	switch _, isIdent := node.(*ast.Identifier); {
+	case typ == invalidType || typ == untypedNil:
+		c.errorf("%s is not used", ast.Format(node))
+	case isConstant(node) && isUntyped(typ):
+		c.errorf("%s (%s constant) is not used", ast.Format(node), typ)
+	case isUntyped(typ):
+		c.errorf("%s (%s value) is not used", ast.Format(node), typ)
+	case isIdent:
+		c.errorf("%s (variable of type %s) is not used", ast.Format(node), typ)
+	default:
+		c.errorf("%s (value of type %s) is not used", ast.Format(node), typ)
+	}
+}
+
+func isConstant(node ast.Expression) bool {
+	switch node := node.(type) {
+	case *ast.Integer, *ast.String:
+		return true
+	case *ast.PrefixExpression:
+		return node.Operator != ast.Receive && isConstant(node.RExpression)
+	case *ast.InfixExpression:
+		return isConstant(node.LExpression) && isConstant(node.RExpression)
+	default:
+		return false
+	}
+}
+
+func (c *Checker) checkNewVariables(idents []*ast.Identifier) {
+	for _, ident := range idents {
+		if _, ok := c.scope.symbols[ident.Name]; !ok && ident.Name != "_" {
+			return
+		}
+	}
+
+	c.errorf("no new variables on left side of :=")
+}
+
+func (c *Checker) checkOperands(node *ast.InfixExpression, left, right Type) {
+	if hasInvalid(left) || hasInvalid(right) || isTypeParameter(left) || isTypeParameter(right) {
+		return
+	}
+
+	var ok bool
+	switch {
+	case identical(left, right):
+		ok = left != untypedNil
+	case isUntyped(left) && isUntyped(right):
+		ok = false
+	case isUntyped(left):
+		ok = assignable(left, right)
+	case isUntyped(right):
+		ok = assignable(right, left)
+	case node.Operator == ast.Equal || node.Operator == ast.NotEqual:
+		ok = assignable(left, right) || assignable(right, left)
+	}
+	if !ok {
+		c.errorf("invalid operation: %s (mismatched types %s and %s)", ast.Format(node), left, right)
+	}
+}
+
+func (c *Checker) checkBasicOperator(node ast.Expression, typ Type, operator string, info basicInfo) {
+	if hasInvalid(typ) {
+		return
+	}
+	if b, ok := underlying(typ).(*basic); ok && b.info&info != 0 {
+		return
+	}
+
+	c.errorAt(node, "invalid operation: operator %s not defined on %s", operator, c.describe(node, typ))
+}
+
+// withFallthrough checks the statements of a switch clause, whose last
+// statement may be a fallthrough.
+func (c *Checker) withFallthrough(stmts []ast.Statement) {
+	outer := c.allowedFallthrough
+	defer func() {
+		c.allowedFallthrough = outer
+	}()
+
+	c.allowedFallthrough = nil
+	if 0 < len(stmts) {
+		c.allowedFallthrough = stmts[len(stmts)-1]
+	}
+	c.checkStatements(stmts)
+}
+
+// isTerminating reports whether a statement is terminating as the Go spec
+// defines.
+func isTerminating(node ast.Statement) bool {
+	switch node := node.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		call, ok := node.Expression.(*ast.CallExpression)
+		if !ok {
+			return false
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		return ok && ident.Name == "panic"
+	case *ast.BlockStatement:
+		return isTerminatingList(node.Statements)
+	case *ast.ForStatement:
+		return node.Condition == nil && !hasBreak(node.Body.Statements)
+	case *ast.SwitchStatement:
+		return hasDefault(node.Clauses) && areTerminatingClauses(node.Clauses, true)
+	case *ast.TypeSwitchStatement:
+		return hasDefault(node.Clauses) && areTerminatingClauses(node.Clauses, false)
+	case *ast.SelectStatement:
+		for _, clause := range node.Clauses {
+			if !isTerminatingList(clause.Statements) || hasBreak(clause.Statements) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func isTerminatingList(stmts []ast.Statement) bool {
+	return 0 < len(stmts) && isTerminating(stmts[len(stmts)-1])
+}
+
+func hasDefault(clauses []*ast.CaseClause) bool {
+	for _, clause := range clauses {
+		if clause.IsDefault {
+			return true
+		}
+	}
+
+	return false
+}
+
+func areTerminatingClauses(clauses []*ast.CaseClause, fallsThrough bool) bool {
+	for _, clause := range clauses {
+		if hasBreak(clause.Statements) {
+			return false
+		}
+		if isTerminatingList(clause.Statements) {
+			continue
+		}
+		if 0 < len(clause.Statements) && fallsThrough {
+			if _, ok := clause.Statements[len(clause.Statements)-1].(*ast.FallthroughStatement); ok {
+				continue
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// hasBreak reports whether statements have a break which refers to the
+// statement enclosing them.
+func hasBreak(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.BreakStatement:
+			return true
+		case *ast.BlockStatement:
+			if hasBreak(stmt.Statements) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *Checker) errorAtEnd(node ast.Node, format string, args ...interface{}) {
+	end, ok := c.ends[node]
+	if !ok {
+		c.errorAt(node, format, args...)
+		return
+	}
+
+	outer, outerEnd := c.position, c.end
+	c.position, c.end = token.Position{Line: end.Line, Column: end.Column - 1}, end
+	defer func() {
+		c.position, c.end = outer, outerEnd
+	}()
+	c.errorf(format, args...)
+}
+
+func (c *Checker) errorAt(node ast.Node, format string, args ...interface{}) {
+	defer c.enter(node)()
+	c.errorf(format, args...)
+}
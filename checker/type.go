@@ -0,0 +1,642 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomocy/kinako/ast"
+)
+
+type Type interface {
+	String() string
+}
+
+type basic struct {
+	name string
+	info basicInfo
+}
+
+type basicInfo int
+
+const (
+	booleanInfo basicInfo = 1 << iota
+	integerInfo
+	floatInfo
+	complexInfo
+	stringInfo
+	untypedInfo
+
+	numericInfo = integerInfo | floatInfo | complexInfo
+	orderedInfo = integerInfo | floatInfo | stringInfo
+)
+
+var (
+	invalidType   = &basic{name: "invalid type"}
+	untypedBool   = &basic{name: "untyped bool", info: booleanInfo | untypedInfo}
+	untypedInt    = &basic{name: "untyped int", info: integerInfo | untypedInfo}
+	untypedString = &basic{name: "untyped string", info: stringInfo | untypedInfo}
+	untypedNil    = &basic{name: "untyped nil", info: untypedInfo}
+)
+
+func (t basic) String() string {
+	return t.name
+}
+
+type channel struct {
+	direction ast.ChannelDirection
+	element   Type
+}
+
+func (t channel) String() string {
+	if t.direction == ast.Bidirectional {
+		return fmt.Sprintf("chan %s", t.element)
+	}
+
+	return fmt.Sprintf("%s %s", t.direction, t.element)
+}
+
+type slice struct {
+	element Type
+}
+
+func (t slice) String() string {
+	return fmt.Sprintf("[]%s", t.element)
+}
+
+type mapType struct {
+	key   Type
+	value Type
+}
+
+func (t mapType) String() string {
+	return fmt.Sprintf("map[%s]%s", t.key, t.value)
+}
+
+type pointer struct {
+	base Type
+}
+
+func (t pointer) String() string {
+	return fmt.Sprintf("*%s", t.base)
+}
+
+type signature struct {
+	typeParameters []*typeParameter
+	parameters     []Type
+	isVariadic     bool
+	result         Type
+}
+
+func (t signature) String() string {
+	params := make([]string, len(t.parameters))
+	for i, param := range t.parameters {
+		params[i] = param.String()
+	}
+	if t.isVariadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+
+	s := fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	if t.result != nil {
+		s += " " + t.result.String()
+	}
+
+	return s
+}
+
+func (t signature) isGeneric() bool {
+	return 0 < len(t.typeParameters)
+}
+
+type interfaceType struct {
+	elements     [][]*term
+	isComparable bool
+}
+
+func (t interfaceType) String() string {
+	if t.isComparable {
+		return "comparable"
+	}
+	if len(t.elements) == 0 {
+		return "any"
+	}
+
+	elems := make([]string, len(t.elements))
+	for i, union := range t.elements {
+		elems[i] = unionString(union)
+	}
+
+	return fmt.Sprintf("interface{ %s }", strings.Join(elems, "; "))
+}
+
+type term struct {
+	isTilde bool
+	typ     Type
+}
+
+func (t term) String() string {
+	if t.isTilde {
+		return "~" + t.typ.String()
+	}
+
+	return t.typ.String()
+}
+
+func unionString(terms []*term) string {
+	ss := make([]string, len(terms))
+	for i, t := range terms {
+		ss[i] = t.String()
+	}
+
+	return strings.Join(ss, " | ")
+}
+
+type named struct {
+	name           string
+	typeParameters []*typeParameter
+	typeArguments  []Type
+	underlying     Type
+	origin         *named
+}
+
+func (t named) String() string {
+	if len(t.typeArguments) == 0 {
+		return t.name
+	}
+
+	args := make([]string, len(t.typeArguments))
+	for i, arg := range t.typeArguments {
+		args[i] = arg.String()
+	}
+
+	return fmt.Sprintf("%s[%s]", t.name, strings.Join(args, ", "))
+}
+
+func (t *named) isGeneric() bool {
+	return 0 < len(t.typeParameters) && t.origin == nil
+}
+
+func (t *named) declaration() string {
+	params := make([]string, len(t.typeParameters))
+	for i, param := range t.typeParameters {
+		params[i] = fmt.Sprintf("%s %s", param, param.constraint)
+	}
+
+	return fmt.Sprintf("%s[%s]", t.name, strings.Join(params, ", "))
+}
+
+type typeParameter struct {
+	name       string
+	constraint Type
+}
+
+func (t typeParameter) String() string {
+	return t.name
+}
+
+func underlying(t Type) Type {
+	if t, ok := t.(*named); ok {
+		return t.underlying
+	}
+
+	return t
+}
+
+func isUntyped(t Type) bool {
+	b, ok := t.(*basic)
+	return ok && b.info&untypedInfo != 0
+}
+
+func defaultType(t Type) Type {
+	switch t {
+	case untypedBool:
+		return predeclaredTypes["bool"]
+	case untypedInt:
+		return predeclaredTypes["int"]
+	case untypedString:
+		return predeclaredTypes["string"]
+	default:
+		return t
+	}
+}
+
+func identical(a, b Type) bool {
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *basic:
+		b, ok := b.(*basic)
+		return ok && a.name == b.name
+	case *channel:
+		b, ok := b.(*channel)
+		return ok && a.direction == b.direction && identical(a.element, b.element)
+	case *slice:
+		b, ok := b.(*slice)
+		return ok && identical(a.element, b.element)
+	case *mapType:
+		b, ok := b.(*mapType)
+		return ok && identical(a.key, b.key) && identical(a.value, b.value)
+	case *pointer:
+		b, ok := b.(*pointer)
+		return ok && identical(a.base, b.base)
+	case *signature:
+		b, ok := b.(*signature)
+		if !ok || len(a.parameters) != len(b.parameters) || a.isVariadic != b.isVariadic || (a.result == nil) != (b.result == nil) {
+			return false
+		}
+		for i := range a.parameters {
+			if !identical(a.parameters[i], b.parameters[i]) {
+				return false
+			}
+		}
+		return a.result == nil || identical(a.result, b.result)
+	case *named:
+		b, ok := b.(*named)
+		if !ok || a.origin == nil || a.origin != b.origin {
+			return false
+		}
+		for i := range a.typeArguments {
+			if !identical(a.typeArguments[i], b.typeArguments[i]) {
+				return false
+			}
+		}
+		return true
+	case *interfaceType:
+		b, ok := b.(*interfaceType)
+		return ok && len(a.elements) == 0 && len(b.elements) == 0 && a.isComparable == b.isComparable
+	default:
+		return false
+	}
+}
+
+func assignable(v, t Type) bool {
+	if hasInvalid(v) || hasInvalid(t) || identical(v, t) {
+		return true
+	}
+	if v == untypedNil {
+		switch underlying(t).(type) {
+		case *slice, *mapType, *pointer, *channel, *signature, *interfaceType:
+			return true
+		default:
+			return false
+		}
+	}
+	if isUntyped(v) {
+		return acceptsUntyped(t, v.(*basic))
+	}
+
+	switch u := underlying(t).(type) {
+	case *interfaceType:
+		return len(u.elements) == 0 && !u.isComparable
+	case *channel:
+		ch, ok := underlying(v).(*channel)
+		if ok && ch.direction == ast.Bidirectional && identical(ch.element, u.element) {
+			return true
+		}
+	}
+	if isNamed(v) && isNamed(t) || isTypeParameter(v) || isTypeParameter(t) {
+		return false
+	}
+
+	return identical(underlying(v), underlying(t))
+}
+
+func isNamed(t Type) bool {
+	switch t.(type) {
+	case *named, *basic:
+		return true
+	default:
+		return false
+	}
+}
+
+func acceptsUntyped(t Type, v *basic) bool {
+	if param, ok := t.(*typeParameter); ok {
+		set := typeSetOf(param.constraint)
+		if set.isAll || len(set.terms) == 0 {
+			return false
+		}
+		for _, term := range set.terms {
+			if !acceptsUntyped(term.typ, v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch u := underlying(t).(type) {
+	case *basic:
+		if v.info&integerInfo != 0 {
+			return u.info&numericInfo != 0
+		}
+		return u.info&v.info&^untypedInfo != 0
+	case *interfaceType:
+		return len(u.elements) == 0 && !u.isComparable
+	default:
+		return false
+	}
+}
+
+func isTypeParameter(t Type) bool {
+	_, ok := t.(*typeParameter)
+	return ok
+}
+
+func hasInvalid(t Type) bool {
+	switch t := t.(type) {
+	case *basic:
+		return t == invalidType
+	case *channel:
+		return hasInvalid(t.element)
+	case *slice:
+		return hasInvalid(t.element)
+	case *mapType:
+		return hasInvalid(t.key) || hasInvalid(t.value)
+	case *pointer:
+		return hasInvalid(t.base)
+	case *signature:
+		for _, param := range t.parameters {
+			if hasInvalid(param) {
+				return true
+			}
+		}
+		return t.result != nil && hasInvalid(t.result)
+	case *named:
+		return t.underlying == nil || hasInvalid(t.underlying)
+	default:
+		return t == nil
+	}
+}
+
+type typeSet struct {
+	isAll        bool
+	terms        []*term
+	isComparable bool
+}
+
+func typeSetOf(t Type) *typeSet {
+	iface, ok := underlying(t).(*interfaceType)
+	if !ok {
+		return &typeSet{
+			terms: []*term{
+				{
+					typ: t,
+				},
+			},
+		}
+	}
+
+	set := &typeSet{
+		isAll:        true,
+		isComparable: iface.isComparable,
+	}
+	for _, union := range iface.elements {
+		set = intersect(set, unionTypeSetOf(union))
+	}
+
+	return set
+}
+
+func unionTypeSetOf(terms []*term) *typeSet {
+	set := new(typeSet)
+	for _, t := range terms {
+		if _, ok := underlying(t.typ).(*interfaceType); !ok || t.isTilde {
+			set.terms = append(set.terms, t)
+			continue
+		}
+
+		embedded := typeSetOf(t.typ)
+		if embedded.isAll {
+			return embedded
+		}
+		set.terms = append(set.terms, embedded.terms...)
+	}
+
+	return set
+}
+
+func intersect(a, b *typeSet) *typeSet {
+	set := &typeSet{
+		isAll:        a.isAll && b.isAll,
+		isComparable: a.isComparable || b.isComparable,
+	}
+	switch {
+	case a.isAll:
+		set.terms = b.terms
+	case b.isAll:
+		set.terms = a.terms
+	default:
+		for _, x := range a.terms {
+			for _, y := range b.terms {
+				if t := intersectTerm(x, y); t != nil {
+					set.terms = append(set.terms, t)
+				}
+			}
+		}
+	}
+
+	return set
+}
+
+func intersectTerm(x, y *term) *term {
+	switch {
+	case x.isTilde && y.isTilde:
+		if identical(underlying(x.typ), underlying(y.typ)) {
+			return x
+		}
+	case x.isTilde:
+		if identical(underlying(y.typ), underlying(x.typ)) {
+			return y
+		}
+	case y.isTilde:
+		if identical(underlying(x.typ), underlying(y.typ)) {
+			return x
+		}
+	default:
+		if identical(x.typ, y.typ) {
+			return x
+		}
+	}
+
+	return nil
+}
+
+func (s typeSet) includes(t Type) bool {
+	if s.isAll {
+		return true
+	}
+	for _, term := range s.terms {
+		if term.isTilde && identical(underlying(t), underlying(term.typ)) || identical(t, term.typ) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s typeSet) covers(t *term) bool {
+	if s.isAll {
+		return true
+	}
+	for _, term := range s.terms {
+		if term.isTilde && identical(underlying(t.typ), underlying(term.typ)) || !t.isTilde && identical(t.typ, term.typ) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s typeSet) every(info basicInfo) bool {
+	if s.isAll || len(s.terms) == 0 {
+		return false
+	}
+	for _, term := range s.terms {
+		b, ok := underlying(term.typ).(*basic)
+		if !ok || b.info&info == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isComparable(t Type) bool {
+	switch t := underlying(t).(type) {
+	case *basic:
+		return t != untypedNil && t != invalidType
+	case *channel, *pointer, *interfaceType:
+		return true
+	case *typeParameter:
+		set := typeSetOf(t.constraint)
+		if set.isComparable {
+			return true
+		}
+		if set.isAll || len(set.terms) == 0 {
+			return false
+		}
+		for _, term := range set.terms {
+			if !isComparable(term.typ) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func isConstraint(t Type) bool {
+	iface, ok := underlying(t).(*interfaceType)
+	return ok && (iface.isComparable || 0 < len(iface.elements) && !typeSetOf(iface).isAll)
+}
+
+func satisfies(t, constraint Type) error {
+	set := typeSetOf(constraint)
+	if set.isComparable && !isComparable(t) {
+		return fmt.Errorf("%s does not satisfy comparable", t)
+	}
+	if set.isAll {
+		return nil
+	}
+
+	if param, ok := t.(*typeParameter); ok {
+		paramSet := typeSetOf(param.constraint)
+		if !paramSet.isAll {
+			covered := true
+			for _, term := range paramSet.terms {
+				covered = covered && set.covers(term)
+			}
+			if covered {
+				return nil
+			}
+		}
+	} else if set.includes(t) {
+		return nil
+	}
+
+	return fmt.Errorf("%s does not satisfy %s (%s missing in %s)", t, constraint, t, unionString(set.terms))
+}
+
+func substitute(t Type, args map[*typeParameter]Type) Type {
+	switch t := t.(type) {
+	case *typeParameter:
+		if arg, ok := args[t]; ok {
+			return arg
+		}
+		return t
+	case *channel:
+		return &channel{
+			direction: t.direction,
+			element:   substitute(t.element, args),
+		}
+	case *slice:
+		return &slice{
+			element: substitute(t.element, args),
+		}
+	case *mapType:
+		return &mapType{
+			key:   substitute(t.key, args),
+			value: substitute(t.value, args),
+		}
+	case *pointer:
+		return &pointer{
+			base: substitute(t.base, args),
+		}
+	case *signature:
+		sig := &signature{
+			parameters: make([]Type, len(t.parameters)),
+			isVariadic: t.isVariadic,
+		}
+		for i, param := range t.parameters {
+			sig.parameters[i] = substitute(param, args)
+		}
+		if t.result != nil {
+			sig.result = substitute(t.result, args)
+		}
+		return sig
+	case *interfaceType:
+		iface := &interfaceType{
+			elements:     make([][]*term, len(t.elements)),
+			isComparable: t.isComparable,
+		}
+		for i, union := range t.elements {
+			iface.elements[i] = make([]*term, len(union))
+			for j, t := range union {
+				iface.elements[i][j] = &term{
+					isTilde: t.isTilde,
+					typ:     substitute(t.typ, args),
+				}
+			}
+		}
+		return iface
+	case *named:
+		if t.origin == nil {
+			return t
+		}
+		typeArgs := make([]Type, len(t.typeArguments))
+		for i, arg := range t.typeArguments {
+			typeArgs[i] = substitute(arg, args)
+		}
+		return instantiate(t.origin, typeArgs)
+	default:
+		return t
+	}
+}
+
+func instantiate(origin *named, typeArgs []Type) *named {
+	args := make(map[*typeParameter]Type)
+	for i, param := range origin.typeParameters {
+		args[param] = typeArgs[i]
+	}
+
+	return &named{
+		name:           origin.name,
+		typeParameters: origin.typeParameters,
+		typeArguments:  typeArgs,
+		underlying:     substitute(origin.underlying, args),
+		origin:         origin,
+	}
+}
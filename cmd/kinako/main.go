@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tomocy/kinako"
+	"github.com/tomocy/kinako/diagnostic"
+	"github.com/tomocy/kinako/repl"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		var opts []repl.Option
+		if colors(os.Stdout) {
+			opts = append(opts, repl.WithColor())
+		}
+		repl := repl.New(os.Stdin, os.Stdout, opts...)
+		repl.Start()
+		return
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "build":
+		err = build(args)
+	case "run":
+		err = run(args)
+	case "ssa":
+		err = dumpSSA(args)
+	default:
+		err = fmt.Errorf("unknown command: %s", cmd)
+	}
+	if err != nil {
+		var srcErr *sourceError
+		if errors.As(err, &srcErr) {
+			report(srcErr)
+		} else {
+			fmt.Fprintf(os.Stderr, "kinako: %s\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func build(args []string) error {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	output := flags.String("o", "", "output file")
+	optimizes := flags.Bool("O", false, "optimize the program")
+	strict := flags.Bool("strict", false, "require a Go file and enforce Go's compile-time rules")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: kinako build [-O] [-strict] [-o output] file")
+	}
+
+	name := flags.Arg(0)
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	prog, err := compile(string(src), *optimizes, *strict)
+	if err != nil {
+		return &sourceError{name: name, src: string(src), err: err}
+	}
+
+	dest := *output
+	if dest == "" {
+		dest = strings.TrimSuffix(name, filepath.Ext(name)) + ".kbc"
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := prog.WriteTo(f); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	optimizes := flags.Bool("O", false, "optimize the program")
+	strict := flags.Bool("strict", false, "require a Go file and enforce Go's compile-time rules")
+	seed := flags.Int64("seed", -1, "run goroutines in the random order given by the seed")
+	explore := flags.Int("explore", 0, "run under the given number of seeded schedules and report the first that fails")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: kinako run [-O] [-strict] [-seed n | -explore n] file")
+	}
+
+	name := flags.Arg(0)
+	var src []byte
+	var prog *kinako.Program
+	var err error
+	if filepath.Ext(name) == ".kbc" {
+		prog, err = load(name)
+		if err != nil {
+			return err
+		}
+	} else {
+		src, err = os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		prog, err = compile(string(src), *optimizes, *strict)
+		if err != nil {
+			return &sourceError{name: name, src: string(src), err: err}
+		}
+	}
+
+	if 0 < *explore {
+		seed, err := prog.Explore(context.Background(), *explore, kinako.WithStdout(io.Discard), kinako.WithStderr(io.Discard))
+		if err != nil {
+			return &sourceError{name: name, src: string(src), err: err, seed: &seed}
+		}
+		return nil
+	}
+
+	opts := []kinako.Option{kinako.WithStdout(os.Stdout), kinako.WithStderr(os.Stderr)}
+	if 0 <= *seed {
+		opts = append(opts, kinako.WithSeed(*seed))
+	}
+	if _, err := prog.Run(context.Background(), opts...); err != nil {
+		return &sourceError{name: name, src: string(src), err: err}
+	}
+
+	return nil
+}
+
+func dumpSSA(args []string) error {
+	flags := flag.NewFlagSet("ssa", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: kinako ssa file")
+	}
+
+	name := flags.Arg(0)
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	prog, err := kinako.BuildSSA(string(src))
+	if err != nil {
+		return &sourceError{name: name, src: string(src), err: err}
+	}
+
+	_, err = prog.WriteTo(os.Stdout)
+	return err
+}
+
+func compile(src string, optimizes, strict bool) (*kinako.Program, error) {
+	var opts []kinako.CompileOption
+	if optimizes {
+		opts = append(opts, kinako.WithOptimization())
+	}
+	if strict {
+		opts = append(opts, kinako.WithMode(kinako.StrictMode))
+	}
+
+	return kinako.Compile(src, opts...)
+}
+
+func load(name string) (*kinako.Program, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prog, err := kinako.Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return prog, nil
+}
+
+type sourceError struct {
+	name string
+	src  string
+	err  error
+	seed *int64
+}
+
+func (e *sourceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.name, e.err)
+}
+
+func (e *sourceError) Unwrap() error {
+	return e.err
+}
+
+func report(err *sourceError) {
+	opts := []diagnostic.Option{diagnostic.WithFilename(err.name)}
+	if colors(os.Stderr) {
+		opts = append(opts, diagnostic.WithColor())
+	}
+
+	d := kinako.Diagnose(err.err)
+	if err.seed != nil {
+		d.Notes = append(d.Notes, fmt.Sprintf("reproduce with -seed %d", *err.seed))
+	}
+	diagnostic.New(opts...).Render(os.Stderr, err.src, d)
+}
+
+func colors(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,178 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+type Instructions []byte
+
+func (ins Instructions) String() string {
+	var b strings.Builder
+	for i := 0; i < len(ins); {
+		def, err := LookUp(Opcode(ins[i]))
+		if err != nil {
+			fmt.Fprintf(&b, "%04d ERROR: %s\n", i, err)
+			i++
+			continue
+		}
+
+		operands, n := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&b, "%04d %s", i, def.Name)
+		for _, operand := range operands {
+			fmt.Fprintf(&b, " %d", operand)
+		}
+		b.WriteString("\n")
+
+		i += 1 + n
+	}
+
+	return b.String()
+}
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpNil
+	OpTrue
+	OpPop
+	OpReturn
+	OpReturnValue
+	OpEvaluate
+	OpGet
+	OpAssign
+	OpDeclare
+	OpIncDec
+	OpMinus
+	OpNot
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpLessThan
+	OpGreaterThan
+	OpLessThanOrEqual
+	OpGreaterThanOrEqual
+	OpJump
+	OpJumpIfFalse
+	OpEnterScope
+	OpLeaveScope
+	OpCloneScope
+	OpLoop
+	OpEndLoop
+	OpBreak
+	OpContinue
+	OpYield
+	OpSwitch
+	OpEndSwitch
+	OpCase
+	OpTypeCase
+	OpBind
+	OpFunction
+	OpCall
+)
+
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:           {"OpConstant", []int{2}},
+	OpNil:                {"OpNil", nil},
+	OpTrue:               {"OpTrue", nil},
+	OpPop:                {"OpPop", nil},
+	OpReturn:             {"OpReturn", nil},
+	OpReturnValue:        {"OpReturnValue", []int{2}},
+	OpEvaluate:           {"OpEvaluate", []int{2}},
+	OpGet:                {"OpGet", []int{2}},
+	OpAssign:             {"OpAssign", []int{2}},
+	OpDeclare:            {"OpDeclare", []int{2}},
+	OpIncDec:             {"OpIncDec", []int{2}},
+	OpMinus:              {"OpMinus", []int{2}},
+	OpNot:                {"OpNot", []int{2}},
+	OpAdd:                {"OpAdd", []int{2}},
+	OpSub:                {"OpSub", []int{2}},
+	OpMul:                {"OpMul", []int{2}},
+	OpDiv:                {"OpDiv", []int{2}},
+	OpEqual:              {"OpEqual", []int{2}},
+	OpNotEqual:           {"OpNotEqual", []int{2}},
+	OpLessThan:           {"OpLessThan", []int{2}},
+	OpGreaterThan:        {"OpGreaterThan", []int{2}},
+	OpLessThanOrEqual:    {"OpLessThanOrEqual", []int{2}},
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{2}},
+	OpJump:               {"OpJump", []int{2}},
+	OpJumpIfFalse:        {"OpJumpIfFalse", []int{2, 2}},
+	OpEnterScope:         {"OpEnterScope", nil},
+	OpLeaveScope:         {"OpLeaveScope", nil},
+	OpCloneScope:         {"OpCloneScope", nil},
+	OpLoop:               {"OpLoop", []int{2, 2}},
+	OpEndLoop:            {"OpEndLoop", nil},
+	OpBreak:              {"OpBreak", nil},
+	OpContinue:           {"OpContinue", nil},
+	OpYield:              {"OpYield", []int{2}},
+	OpSwitch:             {"OpSwitch", []int{2}},
+	OpEndSwitch:          {"OpEndSwitch", nil},
+	OpCase:               {"OpCase", []int{2, 2}},
+	OpTypeCase:           {"OpTypeCase", []int{2, 2, 2}},
+	OpBind:               {"OpBind", []int{2}},
+	OpFunction:           {"OpFunction", []int{2, 2}},
+	OpCall:               {"OpCall", []int{2, 2}},
+}
+
+func LookUp(op Opcode) (*Definition, error) {
+	def, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return nil
+	}
+
+	n := 1
+	for _, width := range def.OperandWidths {
+		n += width
+	}
+	ins := make([]byte, n)
+	ins[0] = byte(op)
+
+	offset := 1
+	for i, operand := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(ins[offset:], uint16(operand))
+		}
+		offset += width
+	}
+
+	return ins
+}
+
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
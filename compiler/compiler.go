@@ -0,0 +1,455 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/resolver"
+)
+
+type Bytecode struct {
+	Instructions Instructions
+	Constants    []object.Object
+	Nodes        []ast.Node
+	Entries      []int
+	HostNames    []string
+	Members      []resolver.Member
+	Program      *ast.Program
+}
+
+type Compiler struct {
+	instructions Instructions
+	constants    []object.Object
+	integers     map[int64]int
+	strings      map[string]int
+	nodes        []ast.Node
+	nodeIndices  map[ast.Node]int
+	functions    []function
+	loops        int
+	switches     int
+	err          error
+}
+
+type function struct {
+	pos  int
+	body *ast.BlockStatement
+}
+
+func New() *Compiler {
+	return &Compiler{
+		integers:    make(map[int64]int),
+		strings:     make(map[string]int),
+		nodeIndices: make(map[ast.Node]int),
+	}
+}
+
+func (c *Compiler) Compile(program *ast.Program) (*Bytecode, error) {
+	entries := make([]int, len(program.Statements))
+	for i, stmt := range program.Statements {
+		entries[i] = len(c.instructions)
+		c.compileStatement(stmt)
+		c.emit(OpReturn)
+	}
+	for i := 0; i < len(c.functions); i++ {
+		c.compileFunctionBody(c.functions[i])
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return &Bytecode{
+		Instructions: c.instructions,
+		Constants:    c.constants,
+		Nodes:        c.nodes,
+		Entries:      entries,
+		Program:      program,
+	}, nil
+}
+
+func (c *Compiler) compileStatement(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		c.compileExpression(node.Expression)
+	case *ast.VariableDeclaration:
+		if node.Expression == nil || !isSingleValue(node.Expression) {
+			c.emit(OpEvaluate, c.addNode(node))
+			return
+		}
+		c.compileExpression(node.Expression)
+		c.emit(OpDeclare, c.addNode(node))
+	case *ast.ShortVariableDeclaration:
+		if len(node.Identifiers) != 1 || len(node.Expressions) != 1 || !isSingleValue(node.Expressions[0]) {
+			c.emit(OpEvaluate, c.addNode(node))
+			return
+		}
+		c.compileExpression(node.Expressions[0])
+		c.emit(OpDeclare, c.addNode(node))
+	case *ast.AssignmentStatement:
+		if len(node.LExpressions) != 1 || len(node.RExpressions) != 1 || !isSingleValue(node.RExpressions[0]) {
+			c.emit(OpEvaluate, c.addNode(node))
+			return
+		}
+		c.compileExpression(node.RExpressions[0])
+		c.emit(OpAssign, c.addNode(node))
+	case *ast.IncDecStatement:
+		c.compileExpression(node.Expression)
+		c.emit(OpIncDec, c.addNode(node))
+	case *ast.ForStatement:
+		c.compileForStatement(node)
+	case *ast.SwitchStatement:
+		c.compileSwitchStatement(node)
+	case *ast.TypeSwitchStatement:
+		c.compileTypeSwitchStatement(node)
+	case *ast.FunctionDeclaration:
+		c.compileFunction(node, node.Body)
+	case *ast.ReturnStatement:
+		if node.Expression == nil {
+			c.emit(OpReturnValue, 0)
+			return
+		}
+		c.compileExpression(node.Expression)
+		c.emit(OpReturnValue, 1)
+	case *ast.BreakStatement:
+		if c.loops == 0 && c.switches == 0 {
+			c.emit(OpEvaluate, c.addNode(node))
+			return
+		}
+		c.emit(OpBreak)
+	case *ast.ContinueStatement:
+		if c.loops == 0 {
+			c.emit(OpEvaluate, c.addNode(node))
+			return
+		}
+		c.emit(OpContinue)
+	default:
+		c.emit(OpEvaluate, c.addNode(node))
+	}
+}
+
+func (c *Compiler) compileForStatement(node *ast.ForStatement) {
+	n := c.addNode(node)
+
+	c.emit(OpEnterScope)
+	if node.Initializer != nil {
+		c.compileStatement(node.Initializer)
+		c.emit(OpPop)
+	}
+	loop := c.emit(OpLoop, 0, 0)
+
+	start := len(c.instructions)
+	exit := -1
+	if node.Condition != nil {
+		c.compileExpression(node.Condition)
+		exit = c.emit(OpJumpIfFalse, 0, n)
+	}
+
+	c.loops++
+	c.emit(OpEnterScope)
+	for _, stmt := range node.Body.Statements {
+		c.compileStatement(stmt)
+		c.emit(OpPop)
+	}
+	c.emit(OpLeaveScope)
+	c.loops--
+
+	cont := len(c.instructions)
+	c.emit(OpCloneScope)
+	if node.Post != nil {
+		c.compileStatement(node.Post)
+		c.emit(OpPop)
+	}
+	c.emit(OpYield, n)
+	c.emit(OpJump, start)
+
+	end := len(c.instructions)
+	if exit != -1 {
+		c.changeOperands(exit, end, n)
+	}
+	c.changeOperands(loop, end, cont)
+	c.emit(OpEndLoop)
+	c.emit(OpLeaveScope)
+	c.emit(OpNil)
+}
+
+func (c *Compiler) compileSwitchStatement(node *ast.SwitchStatement) {
+	n := c.addNode(node)
+
+	c.emit(OpEnterScope)
+	if node.Initializer != nil {
+		c.compileStatement(node.Initializer)
+		c.emit(OpPop)
+	}
+	start := c.emit(OpSwitch, 0)
+	if node.Tag != nil {
+		c.compileExpression(node.Tag)
+	} else {
+		c.emit(OpTrue)
+	}
+
+	cases := make([][]int, len(node.Clauses))
+	for i, clause := range node.Clauses {
+		for _, expr := range clause.Expressions {
+			c.compileExpression(expr)
+			cases[i] = append(cases[i], c.emit(OpCase, 0, n))
+		}
+	}
+	unmatched := c.emit(OpJump, 0)
+
+	c.switches++
+	entries := make([]int, len(node.Clauses))
+	var ends, falls []int
+	for i, clause := range node.Clauses {
+		entries[i] = c.emit(OpPop)
+		for _, pos := range falls {
+			c.changeOperand(pos, len(c.instructions))
+		}
+		c.emit(OpEnterScope)
+
+		falls = nil
+		pushed := false
+		for _, stmt := range clause.Statements {
+			if pushed {
+				c.emit(OpPop)
+				pushed = false
+			}
+			if _, ok := stmt.(*ast.FallthroughStatement); ok {
+				c.emit(OpLeaveScope)
+				falls = append(falls, c.emit(OpJump, 0))
+				break
+			}
+			c.compileStatement(stmt)
+			pushed = true
+		}
+		if falls != nil {
+			continue
+		}
+		if !pushed {
+			c.emit(OpNil)
+		}
+		c.emit(OpLeaveScope)
+		ends = append(ends, c.emit(OpJump, 0))
+	}
+	c.switches--
+
+	c.endSwitch(start, unmatched, node.Clauses, cases, entries, ends, falls)
+}
+
+func (c *Compiler) compileTypeSwitchStatement(node *ast.TypeSwitchStatement) {
+	n := c.addNode(node)
+
+	c.emit(OpEnterScope)
+	if node.Initializer != nil {
+		c.compileStatement(node.Initializer)
+		c.emit(OpPop)
+	}
+	start := c.emit(OpSwitch, 0)
+	c.compileExpression(node.Expression)
+
+	cases := make([][]int, len(node.Clauses))
+	for i, clause := range node.Clauses {
+		for _, expr := range clause.Expressions {
+			cases[i] = append(cases[i], c.emit(OpTypeCase, 0, n, c.addNode(expr)))
+		}
+	}
+	unmatched := c.emit(OpJump, 0)
+
+	c.switches++
+	entries := make([]int, len(node.Clauses))
+	var ends []int
+	for i, clause := range node.Clauses {
+		entries[i] = c.emit(OpEnterScope)
+		if node.Identifier != nil {
+			c.emit(OpBind, n)
+		}
+		c.emit(OpPop)
+
+		c.emit(OpNil)
+		for _, stmt := range clause.Statements {
+			c.emit(OpPop)
+			c.compileStatement(stmt)
+		}
+		c.emit(OpLeaveScope)
+		ends = append(ends, c.emit(OpJump, 0))
+	}
+	c.switches--
+
+	c.endSwitch(start, unmatched, node.Clauses, cases, entries, ends, nil)
+}
+
+func (c *Compiler) endSwitch(start, unmatched int, clauses []*ast.CaseClause, cases [][]int, entries, ends, falls []int) {
+	c.changeOperand(unmatched, len(c.instructions))
+	for i, clause := range clauses {
+		if clause.IsDefault {
+			c.changeOperand(unmatched, entries[i])
+		}
+		for _, pos := range cases[i] {
+			c.changeOperand(pos, entries[i])
+		}
+	}
+	c.emit(OpPop)
+
+	exit := c.emit(OpNil)
+	c.changeOperand(start, exit)
+	for _, pos := range falls {
+		c.changeOperand(pos, exit)
+	}
+	end := c.emit(OpEndSwitch)
+	for _, pos := range ends {
+		c.changeOperand(pos, end)
+	}
+	c.emit(OpLeaveScope)
+}
+
+func (c *Compiler) compileFunction(node ast.Node, body *ast.BlockStatement) {
+	c.functions = append(c.functions, function{
+		pos:  c.emit(OpFunction, 0, c.addNode(node)),
+		body: body,
+	})
+}
+
+func (c *Compiler) compileFunctionBody(fn function) {
+	c.changeOperand(fn.pos, len(c.instructions))
+	for _, stmt := range fn.body.Statements {
+		c.compileStatement(stmt)
+		c.emit(OpPop)
+	}
+	c.emit(OpNil)
+	c.emit(OpReturn)
+}
+
+func (c *Compiler) compileExpression(node ast.Expression) {
+	switch node := node.(type) {
+	case *ast.Integer:
+		c.emit(OpConstant, c.addInteger(node.Value))
+	case *ast.String:
+		c.emit(OpConstant, c.addString(node.Value))
+	case *ast.Identifier:
+		c.emit(OpGet, c.addNode(node))
+	case *ast.PrefixExpression:
+		switch node.Operator {
+		case ast.Negative:
+			c.compileExpression(node.RExpression)
+			c.emit(OpMinus, c.addNode(node))
+		case ast.Not:
+			c.compileExpression(node.RExpression)
+			c.emit(OpNot, c.addNode(node))
+		default:
+			c.emit(OpEvaluate, c.addNode(node))
+		}
+	case *ast.InfixExpression:
+		op, ok := infixOpcodes[node.Operator]
+		if !ok {
+			c.emit(OpEvaluate, c.addNode(node))
+			return
+		}
+		c.compileExpression(node.LExpression)
+		c.compileExpression(node.RExpression)
+		c.emit(op, c.addNode(node))
+	case *ast.FunctionLiteral:
+		c.compileFunction(node, node.Body)
+	case *ast.CallExpression:
+		c.compileExpression(node.Function)
+		for _, arg := range node.Arguments {
+			c.compileExpression(arg)
+		}
+		c.emit(OpCall, len(node.Arguments), c.addNode(node))
+	default:
+		c.emit(OpEvaluate, c.addNode(node))
+	}
+}
+
+var infixOpcodes = map[ast.InfixOperator]Opcode{
+	ast.Plus:               OpAdd,
+	ast.Minus:              OpSub,
+	ast.Asterisk:           OpMul,
+	ast.Slash:              OpDiv,
+	ast.Equal:              OpEqual,
+	ast.NotEqual:           OpNotEqual,
+	ast.LessThan:           OpLessThan,
+	ast.GreaterThan:        OpGreaterThan,
+	ast.LessThanOrEqual:    OpLessThanOrEqual,
+	ast.GreaterThanOrEqual: OpGreaterThanOrEqual,
+}
+
+func isSingleValue(node ast.Expression) bool {
+	switch node := node.(type) {
+	case *ast.Integer, *ast.String, *ast.Identifier, *ast.FunctionLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return (node.Operator == ast.Negative || node.Operator == ast.Not) && isSingleValue(node.RExpression)
+	case *ast.InfixExpression:
+		_, ok := infixOpcodes[node.Operator]
+		return ok && isSingleValue(node.LExpression) && isSingleValue(node.RExpression)
+	default:
+		return false
+	}
+}
+
+func (c *Compiler) addInteger(n int64) int {
+	if i, ok := c.integers[n]; ok {
+		return i
+	}
+
+	i := c.addConstant(&object.Integer{
+		Value: n,
+	})
+	c.integers[n] = i
+	return i
+}
+
+func (c *Compiler) addString(s string) int {
+	if i, ok := c.strings[s]; ok {
+		return i
+	}
+
+	i := c.addConstant(&object.String{
+		Value: s,
+	})
+	c.strings[s] = i
+	return i
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return c.checkIndex("constants", len(c.constants)-1)
+}
+
+func (c *Compiler) addNode(node ast.Node) int {
+	if i, ok := c.nodeIndices[node]; ok {
+		return i
+	}
+
+	c.nodes = append(c.nodes, node)
+	i := c.checkIndex("nodes", len(c.nodes)-1)
+	c.nodeIndices[node] = i
+	return i
+}
+
+func (c *Compiler) checkIndex(kind string, i int) int {
+	if math.MaxUint16 < i && c.err == nil {
+		c.err = fmt.Errorf("too many %s: %d", kind, i+1)
+	}
+
+	return i
+}
+
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	pos := len(c.instructions)
+	c.instructions = append(c.instructions, Make(op, operands...)...)
+	if math.MaxUint16 < len(c.instructions) && c.err == nil {
+		c.err = fmt.Errorf("too many instructions: %d", len(c.instructions))
+	}
+
+	return pos
+}
+
+func (c *Compiler) changeOperands(pos int, operands ...int) {
+	copy(c.instructions[pos:], Make(Opcode(c.instructions[pos]), operands...))
+}
+
+func (c *Compiler) changeOperand(pos, operand int) {
+	binary.BigEndian.PutUint16(c.instructions[pos+1:], uint16(operand))
+}
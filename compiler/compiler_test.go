@@ -0,0 +1,230 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/parser"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpPop, nil, []byte{byte(OpPop)}},
+		{OpLoop, []int{1, 258}, []byte{byte(OpLoop), 0, 1, 1, 2}},
+	}
+	for _, test := range tests {
+		actual := Make(test.op, test.operands...)
+		if string(actual) != string(test.expected) {
+			t.Errorf("unexpected instruction: got %v, but expected %v\n", actual, test.expected)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"1 + 2; 1;",
+			`0000 OpConstant 0
+0003 OpConstant 1
+0006 OpAdd 0
+0009 OpReturn
+0010 OpConstant 0
+0013 OpReturn
+`,
+		},
+		{
+			"x := 0; x = -x;",
+			`0000 OpConstant 0
+0003 OpDeclare 0
+0006 OpReturn
+0007 OpGet 1
+0010 OpMinus 2
+0013 OpAssign 3
+0016 OpReturn
+`,
+		},
+		{
+			"for { break; }",
+			`0000 OpEnterScope
+0001 OpLoop 17 10
+0006 OpEnterScope
+0007 OpBreak
+0008 OpPop
+0009 OpLeaveScope
+0010 OpCloneScope
+0011 OpYield 0
+0014 OpJump 6
+0017 OpEndLoop
+0018 OpLeaveScope
+0019 OpNil
+0020 OpReturn
+`,
+		},
+		{
+			"func f(x int) int { return x; } f(1);",
+			`0000 OpFunction 18 0
+0005 OpReturn
+0006 OpGet 1
+0009 OpConstant 0
+0012 OpCall 1 2
+0017 OpReturn
+0018 OpGet 3
+0021 OpReturnValue 1
+0024 OpPop
+0025 OpNil
+0026 OpReturn
+`,
+		},
+		{
+			"switch 1 { case 1: fallthrough; default: break; }",
+			`0000 OpEnterScope
+0001 OpSwitch 32
+0004 OpConstant 0
+0007 OpConstant 0
+0010 OpCase 18 0
+0015 OpJump 24
+0018 OpPop
+0019 OpEnterScope
+0020 OpLeaveScope
+0021 OpJump 25
+0024 OpPop
+0025 OpEnterScope
+0026 OpBreak
+0027 OpLeaveScope
+0028 OpJump 33
+0031 OpPop
+0032 OpNil
+0033 OpEndSwitch
+0034 OpLeaveScope
+0035 OpReturn
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			bytecode, err := New().Compile(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s\n", err)
+			}
+			if actual := bytecode.Instructions.String(); actual != test.expected {
+				t.Errorf("unexpected instructions: got\n%s\nbut expected\n%s\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	input := `import "fmt";
+x := 0;
+for i := 0; i < 3; i++ {
+	x = x + i;
+}
+fmt.Println(x, "a");`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	expected, err := New().Compile(program)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s\n", err)
+	}
+
+	data, err := expected.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error from MarshalBinary: %s\n", err)
+	}
+	actual, err := Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from Load: %s\n", err)
+	}
+
+	if !reflect.DeepEqual(actual.Program.Statements, expected.Program.Statements) {
+		t.Errorf("unexpected statements")
+	}
+	if !bytes.Equal(actual.Instructions, expected.Instructions) {
+		t.Errorf("unexpected instructions: got\n%s\nbut expected\n%s\n", actual.Instructions, expected.Instructions)
+	}
+	if !reflect.DeepEqual(actual.Constants, expected.Constants) {
+		t.Errorf("unexpected constants: got %v, but expected %v\n", actual.Constants, expected.Constants)
+	}
+	if !reflect.DeepEqual(actual.Entries, expected.Entries) {
+		t.Errorf("unexpected entries: got %v, but expected %v\n", actual.Entries, expected.Entries)
+	}
+	for i, stmt := range actual.Program.Statements {
+		if actual, expected := actual.Program.Positions[stmt], expected.Program.Positions[expected.Program.Statements[i]]; actual != expected {
+			t.Errorf("unexpected position of statement %d: got %s, but expected %s\n", i, actual, expected)
+		}
+	}
+	for i, node := range actual.Nodes {
+		if !reflect.DeepEqual(node, expected.Nodes[i]) {
+			t.Errorf("unexpected node %d: got %#v, but expected %#v\n", i, node, expected.Nodes[i])
+		}
+	}
+	if actual.Nodes[0] != ast.Node(actual.Program.Statements[0]) {
+		t.Errorf("node identity is not preserved")
+	}
+
+	again, err := actual.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error from MarshalBinary: %s\n", err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Errorf("unexpected data: encoding is not deterministic")
+	}
+}
+
+func TestUnmarshalBinary(t *testing.T) {
+	program := parser.New(lexer.New("x := 1; x + 1;")).ParseProgram()
+	bytecode, err := New().Compile(program)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s\n", err)
+	}
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error from MarshalBinary: %s\n", err)
+	}
+
+	tests := map[string]struct {
+		modify   func([]byte)
+		expected error
+	}{
+		"magic": {
+			modify: func(data []byte) {
+				data[0] = 'X'
+			},
+			expected: ErrInvalidFormat,
+		},
+		"version": {
+			modify: func(data []byte) {
+				binary.BigEndian.PutUint16(data[len(magic):], FormatVersion+1)
+			},
+			expected: ErrUnsupportedVersion,
+		},
+		"checksum": {
+			modify: func(data []byte) {
+				data[len(data)-1]++
+			},
+			expected: ErrChecksumMismatch,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			modified := append([]byte(nil), data...)
+			test.modify(modified)
+			if err := new(Bytecode).UnmarshalBinary(modified); !errors.Is(err, test.expected) {
+				t.Errorf("unexpected error: got %v, but expected %v\n", err, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/resolver"
+	"github.com/tomocy/kinako/token"
+)
+
+const FormatVersion = 7
+
+var magic = []byte("KNKO")
+
+var (
+	ErrInvalidFormat      = errors.New("invalid format")
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	ErrChecksumMismatch   = errors.New("checksum mismatch")
+)
+
+// FormatVersion must be bumped whenever nodeTypes or the fields of the nodes change.
+var nodeTypes = []reflect.Type{
+	reflect.TypeOf(ast.ExpressionStatement{}),
+	reflect.TypeOf(ast.VariableDeclaration{}),
+	reflect.TypeOf(ast.ShortVariableDeclaration{}),
+	reflect.TypeOf(ast.AssignmentStatement{}),
+	reflect.TypeOf(ast.IncDecStatement{}),
+	reflect.TypeOf(ast.SendStatement{}),
+	reflect.TypeOf(ast.SwitchStatement{}),
+	reflect.TypeOf(ast.TypeSwitchStatement{}),
+	reflect.TypeOf(ast.CaseClause{}),
+	reflect.TypeOf(ast.FallthroughStatement{}),
+	reflect.TypeOf(ast.BreakStatement{}),
+	reflect.TypeOf(ast.ContinueStatement{}),
+	reflect.TypeOf(ast.SelectStatement{}),
+	reflect.TypeOf(ast.CommunicationClause{}),
+	reflect.TypeOf(ast.ForStatement{}),
+	reflect.TypeOf(ast.RangeStatement{}),
+	reflect.TypeOf(ast.FunctionDeclaration{}),
+	reflect.TypeOf(ast.PackageClause{}),
+	reflect.TypeOf(ast.ImportDeclaration{}),
+	reflect.TypeOf(ast.ImportSpec{}),
+	reflect.TypeOf(ast.TypeDeclaration{}),
+	reflect.TypeOf(ast.Parameter{}),
+	reflect.TypeOf(ast.BlockStatement{}),
+	reflect.TypeOf(ast.ReturnStatement{}),
+	reflect.TypeOf(ast.DeferStatement{}),
+	reflect.TypeOf(ast.GoStatement{}),
+	reflect.TypeOf(ast.BadStatement{}),
+	reflect.TypeOf(ast.PrefixExpression{}),
+	reflect.TypeOf(ast.InfixExpression{}),
+	reflect.TypeOf(ast.SelectorExpression{}),
+	reflect.TypeOf(ast.TypeAssertionExpression{}),
+	reflect.TypeOf(ast.IndexExpression{}),
+	reflect.TypeOf(ast.SliceExpression{}),
+	reflect.TypeOf(ast.CallExpression{}),
+	reflect.TypeOf(ast.FunctionLiteral{}),
+	reflect.TypeOf(ast.Identifier{}),
+	reflect.TypeOf(ast.Integer{}),
+	reflect.TypeOf(ast.String{}),
+	reflect.TypeOf(ast.CompositeLiteral{}),
+	reflect.TypeOf(ast.KeyedElement{}),
+	reflect.TypeOf(ast.SliceType{}),
+	reflect.TypeOf(ast.MapType{}),
+	reflect.TypeOf(ast.PointerType{}),
+	reflect.TypeOf(ast.ChannelType{}),
+	reflect.TypeOf(ast.InterfaceType{}),
+	reflect.TypeOf(ast.UnionType{}),
+	reflect.TypeOf(ast.TypeTerm{}),
+}
+
+var nodeTags = func() map[reflect.Type]int {
+	tags := make(map[reflect.Type]int, len(nodeTypes))
+	for i, typ := range nodeTypes {
+		tags[typ] = i
+	}
+	return tags
+}()
+
+const (
+	integerConstant byte = iota
+	stringConstant
+)
+
+func (b *Bytecode) MarshalBinary() ([]byte, error) {
+	enc := newEncoder()
+	for _, stmt := range b.Program.Statements {
+		enc.collect(reflect.ValueOf(stmt))
+	}
+	for _, node := range b.Nodes {
+		enc.collect(reflect.ValueOf(node))
+	}
+
+	enc.writeUvarint(uint64(len(enc.nodes)))
+	for _, node := range enc.nodes {
+		enc.writeUvarint(uint64(nodeTags[node.Elem().Type()]))
+	}
+	for _, node := range enc.nodes {
+		if err := enc.writeFields(node.Elem()); err != nil {
+			return nil, err
+		}
+	}
+
+	enc.writeUvarint(uint64(len(b.Program.Statements)))
+	for _, stmt := range b.Program.Statements {
+		enc.writeNode(reflect.ValueOf(stmt))
+	}
+	var positions []reflect.Value
+	for _, node := range enc.nodes {
+		if node, ok := node.Interface().(ast.Node); ok {
+			if _, ok := b.Program.Positions[node]; ok {
+				positions = append(positions, reflect.ValueOf(node))
+			}
+		}
+	}
+	enc.writeUvarint(uint64(len(positions)))
+	for _, node := range positions {
+		n := node.Interface().(ast.Node)
+		pos, end := b.Program.Positions[n], b.Program.Ends[n]
+		enc.writeNode(node)
+		enc.writeUvarint(uint64(pos.Line))
+		enc.writeUvarint(uint64(pos.Column))
+		enc.writeUvarint(uint64(end.Line))
+		enc.writeUvarint(uint64(end.Column))
+	}
+
+	enc.writeBytes(b.Instructions)
+	enc.writeUvarint(uint64(len(b.Constants)))
+	for _, obj := range b.Constants {
+		switch obj := obj.(type) {
+		case *object.Integer:
+			enc.buf.WriteByte(integerConstant)
+			enc.writeVarint(obj.Value)
+		case *object.String:
+			enc.buf.WriteByte(stringConstant)
+			enc.writeBytes([]byte(obj.Value))
+		default:
+			return nil, fmt.Errorf("unsupported constant: %T", obj)
+		}
+	}
+	enc.writeUvarint(uint64(len(b.Nodes)))
+	for _, node := range b.Nodes {
+		enc.writeNode(reflect.ValueOf(node))
+	}
+	enc.writeUvarint(uint64(len(b.Entries)))
+	for _, entry := range b.Entries {
+		enc.writeUvarint(uint64(entry))
+	}
+	enc.writeUvarint(uint64(len(b.HostNames)))
+	for _, name := range b.HostNames {
+		enc.writeBytes([]byte(name))
+	}
+	enc.writeUvarint(uint64(len(b.Members)))
+	for _, member := range b.Members {
+		enc.writeBytes([]byte(member.Path))
+		enc.writeNode(reflect.ValueOf(member.Selector))
+	}
+
+	payload := enc.buf.Bytes()
+	sum := sha256.Sum256(payload)
+
+	var data bytes.Buffer
+	data.Write(magic)
+	binary.Write(&data, binary.BigEndian, uint16(FormatVersion))
+	data.Write(sum[:])
+	data.Write(payload)
+
+	return data.Bytes(), nil
+}
+
+func (b *Bytecode) UnmarshalBinary(data []byte) error {
+	if len(data) < len(magic)+2+sha256.Size || !bytes.Equal(data[:len(magic)], magic) {
+		return ErrInvalidFormat
+	}
+	data = data[len(magic):]
+	if version := binary.BigEndian.Uint16(data); version != FormatVersion {
+		return fmt.Errorf("%w: %d, expected %d", ErrUnsupportedVersion, version, FormatVersion)
+	}
+	data = data[2:]
+	var sum [sha256.Size]byte
+	copy(sum[:], data)
+	payload := data[sha256.Size:]
+	if sha256.Sum256(payload) != sum {
+		return ErrChecksumMismatch
+	}
+
+	dec := &decoder{
+		r: bufio.NewReader(bytes.NewReader(payload)),
+	}
+	decoded, err := dec.decode()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidFormat, err)
+	}
+
+	*b = *decoded
+	return nil
+}
+
+func Load(r io.Reader) (*Bytecode, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(Bytecode)
+	if err := b.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+type encoder struct {
+	buf     bytes.Buffer
+	nodes   []reflect.Value
+	indices map[uintptr]int
+}
+
+func newEncoder() *encoder {
+	return &encoder{
+		indices: make(map[uintptr]int),
+	}
+}
+
+func (e *encoder) collect(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			e.collect(v.Elem())
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if _, ok := e.indices[v.Pointer()]; ok {
+			return
+		}
+		e.indices[v.Pointer()] = len(e.nodes)
+		e.nodes = append(e.nodes, v)
+
+		elem := v.Elem()
+		for i := 0; i < elem.NumField(); i++ {
+			e.collect(elem.Field(i))
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			e.collect(v.Index(i))
+		}
+	}
+}
+
+func (e *encoder) writeFields(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			e.writeBytes([]byte(field.String()))
+		case reflect.Bool:
+			if field.Bool() {
+				e.buf.WriteByte(1)
+			} else {
+				e.buf.WriteByte(0)
+			}
+		case reflect.Int, reflect.Int64:
+			e.writeVarint(field.Int())
+		case reflect.Ptr, reflect.Interface:
+			e.writeNode(field)
+		case reflect.Slice:
+			e.writeUvarint(uint64(field.Len()))
+			for i := 0; i < field.Len(); i++ {
+				e.writeNode(field.Index(i))
+			}
+		default:
+			return fmt.Errorf("unsupported field %s of %s", v.Type().Field(i).Name, v.Type())
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) writeNode(v reflect.Value) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.IsNil() {
+		e.writeUvarint(0)
+		return
+	}
+
+	e.writeUvarint(uint64(e.indices[v.Pointer()] + 1))
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	e.writeUvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *encoder) writeUvarint(n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func (e *encoder) writeVarint(n int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf.Write(b[:binary.PutVarint(b[:], n)])
+}
+
+type decoder struct {
+	r     *bufio.Reader
+	nodes []reflect.Value
+	err   error
+}
+
+func (d *decoder) decode() (*Bytecode, error) {
+	d.nodes = make([]reflect.Value, d.readLength())
+	for i := range d.nodes {
+		tag := d.readLength()
+		if len(nodeTypes) <= tag {
+			return nil, fmt.Errorf("unknown node tag %d", tag)
+		}
+		d.nodes[i] = reflect.New(nodeTypes[tag])
+	}
+	for _, node := range d.nodes {
+		d.readFields(node.Elem())
+	}
+
+	program := &ast.Program{
+		Statements: make([]ast.Statement, d.readLength()),
+		Positions:  make(map[ast.Node]token.Position),
+		Ends:       make(map[ast.Node]token.Position),
+	}
+	for i := range program.Statements {
+		program.Statements[i], _ = d.readNode().(ast.Statement)
+	}
+	for n := d.readLength(); 0 < n; n-- {
+		node, _ := d.readNode().(ast.Node)
+		line, column := d.readLength(), d.readLength()
+		endLine, endColumn := d.readLength(), d.readLength()
+		if node != nil {
+			program.Positions[node] = token.Position{
+				Line:   line,
+				Column: column,
+			}
+			program.Ends[node] = token.Position{
+				Line:   endLine,
+				Column: endColumn,
+			}
+		}
+	}
+
+	b := &Bytecode{
+		Instructions: d.readBytes(),
+		Constants:    make([]object.Object, d.readLength()),
+		Program:      program,
+	}
+	for i := range b.Constants {
+		kind, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case integerConstant:
+			b.Constants[i] = &object.Integer{
+				Value: d.readVarint(),
+			}
+		case stringConstant:
+			b.Constants[i] = &object.String{
+				Value: string(d.readBytes()),
+			}
+		default:
+			return nil, fmt.Errorf("unknown constant kind %d", kind)
+		}
+	}
+	b.Nodes = make([]ast.Node, d.readLength())
+	for i := range b.Nodes {
+		b.Nodes[i], _ = d.readNode().(ast.Node)
+	}
+	b.Entries = make([]int, d.readLength())
+	for i := range b.Entries {
+		b.Entries[i] = d.readLength()
+	}
+	if n := d.readLength(); 0 < n {
+		b.HostNames = make([]string, n)
+		for i := range b.HostNames {
+			b.HostNames[i] = string(d.readBytes())
+		}
+	}
+	if n := d.readLength(); 0 < n {
+		b.Members = make([]resolver.Member, n)
+		for i := range b.Members {
+			b.Members[i].Path = string(d.readBytes())
+			b.Members[i].Selector, _ = d.readNode().(*ast.SelectorExpression)
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return nil, errors.New("unexpected trailing data")
+	}
+
+	return b, nil
+}
+
+func (d *decoder) readFields(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(string(d.readBytes()))
+		case reflect.Bool:
+			b, err := d.r.ReadByte()
+			d.fail(err)
+			field.SetBool(b == 1)
+		case reflect.Int, reflect.Int64:
+			field.SetInt(d.readVarint())
+		case reflect.Ptr, reflect.Interface:
+			d.setNode(field, d.readNode())
+		case reflect.Slice:
+			n := d.readLength()
+			s := reflect.MakeSlice(field.Type(), n, n)
+			for i := 0; i < n; i++ {
+				d.setNode(s.Index(i), d.readNode())
+			}
+			field.Set(s)
+		}
+	}
+}
+
+func (d *decoder) setNode(field reflect.Value, node interface{}) {
+	if node == nil {
+		return
+	}
+
+	v := reflect.ValueOf(node)
+	if !v.Type().AssignableTo(field.Type()) {
+		d.fail(fmt.Errorf("cannot use %s as %s", v.Type(), field.Type()))
+		return
+	}
+	field.Set(v)
+}
+
+func (d *decoder) readNode() interface{} {
+	i := d.readLength()
+	if i == 0 {
+		return nil
+	}
+	if len(d.nodes) < i {
+		d.fail(fmt.Errorf("node %d out of range", i-1))
+		return nil
+	}
+
+	return d.nodes[i-1].Interface()
+}
+
+func (d *decoder) readLength() int {
+	n, err := binary.ReadUvarint(d.r)
+	d.fail(err)
+	if 1<<31 < n {
+		d.fail(fmt.Errorf("length %d out of range", n))
+		return 0
+	}
+
+	return int(n)
+}
+
+func (d *decoder) readVarint() int64 {
+	n, err := binary.ReadVarint(d.r)
+	d.fail(err)
+	return n
+}
+
+func (d *decoder) readBytes() []byte {
+	b := make([]byte, d.readLength())
+	_, err := io.ReadFull(d.r, b)
+	d.fail(err)
+	return b
+}
+
+func (d *decoder) fail(err error) {
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+}
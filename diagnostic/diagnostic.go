@@ -0,0 +1,163 @@
+package diagnostic
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tomocy/kinako/token"
+)
+
+const (
+	reset = "\x1b[0m"
+	bold  = "\x1b[1m"
+	red   = "\x1b[1;31m"
+	green = "\x1b[1;32m"
+	blue  = "\x1b[1;34m"
+	cyan  = "\x1b[1;36m"
+)
+
+type Diagnostic struct {
+	Message string
+	Span    token.Span
+	Notes   []string
+	Hints   []string
+}
+
+type Renderer struct {
+	filename string
+	colors   bool
+}
+
+type Option func(*Renderer)
+
+func WithFilename(name string) Option {
+	return func(r *Renderer) {
+		r.filename = name
+	}
+}
+
+func WithColor() Option {
+	return func(r *Renderer) {
+		r.colors = true
+	}
+}
+
+func New(opts ...Option) *Renderer {
+	r := new(Renderer)
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *Renderer) Render(w io.Writer, src string, d *Diagnostic) error {
+	var buf bytes.Buffer
+	if loc := r.location(d.Span.Start); loc != "" {
+		buf.WriteString(r.paint(bold, loc+":"))
+		buf.WriteString(" ")
+	}
+	buf.WriteString(r.paint(red, d.Message))
+	buf.WriteString("\n")
+
+	var gutter string
+	if line, ok := sourceLine(src, d.Span.Start.Line); ok {
+		num := strconv.Itoa(d.Span.Start.Line)
+		gutter = strings.Repeat(" ", len(num))
+		fmt.Fprintf(&buf, "%s\n", r.paint(blue, gutter+" |"))
+		fmt.Fprintf(&buf, "%s %s\n", r.paint(blue, num+" |"), line)
+		fmt.Fprintf(&buf, "%s %s%s\n", r.paint(blue, gutter+" |"), indent(line, d.Span.Start.Column), r.paint(red, marker(line, d.Span)))
+	}
+	for _, note := range d.Notes {
+		fmt.Fprintf(&buf, "%s %s %s\n", gutter+" =", r.paint(cyan, "note:"), note)
+	}
+	for _, hint := range d.Hints {
+		fmt.Fprintf(&buf, "%s %s %s\n", gutter+" =", r.paint(green, "hint:"), hint)
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (r *Renderer) location(pos token.Position) string {
+	switch {
+	case r.filename != "" && pos.IsValid():
+		return fmt.Sprintf("%s:%s", r.filename, pos)
+	case pos.IsValid():
+		return pos.String()
+	default:
+		return r.filename
+	}
+}
+
+func (r *Renderer) paint(color, s string) string {
+	if !r.colors {
+		return s
+	}
+
+	return color + s + reset
+}
+
+func sourceLine(src string, n int) (string, bool) {
+	if src == "" || n < 1 {
+		return "", false
+	}
+
+	lines := strings.Split(src, "\n")
+	if len(lines) < n {
+		return "", false
+	}
+
+	return strings.TrimSuffix(lines[n-1], "\r"), true
+}
+
+func indent(line string, col int) string {
+	n := clamp(col-1, 0, len(line))
+
+	var b strings.Builder
+	for _, r := range line[:n] {
+		if r == '\t' {
+			b.WriteRune('\t')
+			continue
+		}
+		b.WriteRune(' ')
+	}
+	if n < col-1 {
+		b.WriteString(strings.Repeat(" ", col-1-n))
+	}
+
+	return b.String()
+}
+
+func marker(line string, span token.Span) string {
+	start := clamp(span.Start.Column-1, 0, len(line))
+	end := start
+	switch {
+	case span.End.Line == span.Start.Line:
+		end = clamp(span.End.Column-1, start, len(line))
+	case span.Start.Line < span.End.Line:
+		end = len(line)
+	}
+
+	width := utf8.RuneCountInString(line[start:end])
+	if width < 1 {
+		width = 1
+	}
+
+	return strings.Repeat("^", width)
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if max < n {
+		return max
+	}
+
+	return n
+}
@@ -0,0 +1,138 @@
+package diagnostic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tomocy/kinako/token"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		src      string
+		d        *Diagnostic
+		expected string
+	}{
+		{
+			name: "span",
+			opts: []Option{WithFilename("main.kgo")},
+			src:  "x := 1;\ny := x + \"a\";",
+			d: &Diagnostic{
+				Message: "mismatched types int and string",
+				Span: token.Span{
+					Start: token.Position{Line: 2, Column: 6},
+					End:   token.Position{Line: 2, Column: 13},
+				},
+			},
+			expected: `
+main.kgo:2:6: mismatched types int and string
+  |
+2 | y := x + "a";
+  |      ^^^^^^^
+`,
+		},
+		{
+			name: "caret",
+			src:  "fmt.Println(1;",
+			d: &Diagnostic{
+				Message: "failed to find rparen",
+				Span: token.Span{
+					Start: token.Position{Line: 1, Column: 13},
+				},
+			},
+			expected: `
+1:13: failed to find rparen
+  |
+1 | fmt.Println(1;
+  |             ^
+`,
+		},
+		{
+			name: "tab",
+			src:  "{\n\tn[\"a\"] = 1;\n}",
+			d: &Diagnostic{
+				Message: "panic: assignment to entry in nil map",
+				Span: token.Span{
+					Start: token.Position{Line: 2, Column: 2},
+					End:   token.Position{Line: 2, Column: 8},
+				},
+				Hints: []string{"initialize the map"},
+			},
+			expected: "\n2:2: panic: assignment to entry in nil map\n  |\n2 | \tn[\"a\"] = 1;\n  | \t^^^^^^\n  = hint: initialize the map\n",
+		},
+		{
+			name: "multiple lines",
+			src:  "f(1,\n2);",
+			d: &Diagnostic{
+				Message: "too many arguments",
+				Span: token.Span{
+					Start: token.Position{Line: 1, Column: 1},
+					End:   token.Position{Line: 2, Column: 3},
+				},
+			},
+			expected: `
+1:1: too many arguments
+  |
+1 | f(1,
+  | ^^^^
+`,
+		},
+		{
+			name: "notes",
+			src:  "func f() int { return 1 / 0; }\nf();",
+			d: &Diagnostic{
+				Message: "panic: runtime error: integer divide by zero",
+				Span: token.Span{
+					Start: token.Position{Line: 1, Column: 23},
+					End:   token.Position{Line: 1, Column: 28},
+				},
+				Notes: []string{"in f at 1:23", "in main at 2:1"},
+			},
+			expected: `
+1:23: panic: runtime error: integer divide by zero
+  |
+1 | func f() int { return 1 / 0; }
+  |                       ^^^^^
+  = note: in f at 1:23
+  = note: in main at 2:1
+`,
+		},
+		{
+			name: "no source",
+			opts: []Option{WithFilename("main.kbc")},
+			d: &Diagnostic{
+				Message: "fatal error: all goroutines are asleep - deadlock!",
+			},
+			expected: `
+main.kbc: fatal error: all goroutines are asleep - deadlock!
+`,
+		},
+		{
+			name: "color",
+			opts: []Option{WithColor()},
+			src:  "x;",
+			d: &Diagnostic{
+				Message: "undefined: x",
+				Span: token.Span{
+					Start: token.Position{Line: 1, Column: 1},
+					End:   token.Position{Line: 1, Column: 2},
+				},
+			},
+			expected: "\n\x1b[1m1:1:\x1b[0m \x1b[1;31mundefined: x\x1b[0m\n\x1b[1;34m  |\x1b[0m\n\x1b[1;34m1 |\x1b[0m x;\n\x1b[1;34m  |\x1b[0m \x1b[1;31m^\x1b[0m\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := New(test.opts...).Render(&buf, test.src, test.d); err != nil {
+				t.Fatalf("unexpected error: %s\n", err)
+			}
+			if actual, expected := buf.String(), strings.TrimPrefix(test.expected, "\n"); actual != expected {
+				t.Errorf("unexpected output: got\n%q\nbut expected\n%q\n", actual, expected)
+			}
+		})
+	}
+}
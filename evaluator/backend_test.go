@@ -0,0 +1,86 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/compiler"
+	"github.com/tomocy/kinako/evaluator"
+	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/optimizer"
+	"github.com/tomocy/kinako/vm"
+)
+
+func TestEvaluateOnVM(t *testing.T) {
+	evaluator.RunEvaluateTests(t, func(program *ast.Program) object.Object {
+		bytecode, err := compiler.New().Compile(program)
+		if err != nil {
+			t.Fatalf("unexpected error from Compile: %s", err)
+		}
+		return vm.New().Run(bytecode)
+	})
+}
+
+func TestEvaluateResolved(t *testing.T) {
+	evaluator.RunEvaluateTests(t, func(program *ast.Program) object.Object {
+		e := evaluator.New()
+		if err := e.Resolve(program); err != nil {
+			return err
+		}
+		return e.Evaluate(program)
+	})
+}
+
+func TestEvaluateOptimized(t *testing.T) {
+	evaluator.RunEvaluateTests(t, func(program *ast.Program) object.Object {
+		optimizer.New().Optimize(program)
+		return evaluator.New().Evaluate(program)
+	})
+}
+
+func TestEvaluateWithLimitsOnVM(t *testing.T) {
+	evaluator.RunEvaluateWithLimitsTests(t, runOnVM(t))
+}
+
+func TestEvaluateWithLimitsResolved(t *testing.T) {
+	evaluator.RunEvaluateWithLimitsTests(t, evaluateResolved)
+}
+
+func TestEvaluateWithLimitsOptimized(t *testing.T) {
+	evaluator.RunEvaluateWithLimitsTests(t, evaluateOptimized)
+}
+
+func TestEvaluateWithContextOnVM(t *testing.T) {
+	evaluator.RunEvaluateWithContextTests(t, runOnVM(t))
+}
+
+func TestEvaluateWithContextResolved(t *testing.T) {
+	evaluator.RunEvaluateWithContextTests(t, evaluateResolved)
+}
+
+func TestEvaluateWithContextOptimized(t *testing.T) {
+	evaluator.RunEvaluateWithContextTests(t, evaluateOptimized)
+}
+
+func runOnVM(t *testing.T) func(*ast.Program, ...evaluator.Option) object.Object {
+	return func(program *ast.Program, opts ...evaluator.Option) object.Object {
+		bytecode, err := compiler.New().Compile(program)
+		if err != nil {
+			t.Fatalf("unexpected error from Compile: %s", err)
+		}
+		return vm.New(opts...).Run(bytecode)
+	}
+}
+
+func evaluateResolved(program *ast.Program, opts ...evaluator.Option) object.Object {
+	e := evaluator.New(opts...)
+	if err := e.Resolve(program); err != nil {
+		return err
+	}
+	return e.Evaluate(program)
+}
+
+func evaluateOptimized(program *ast.Program, opts ...evaluator.Option) object.Object {
+	optimizer.New().Optimize(program)
+	return evaluator.New(opts...).Evaluate(program)
+}
@@ -0,0 +1,599 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+)
+
+type builtinFunction struct {
+	arity      int
+	isVariadic bool
+	call       func(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object
+}
+
+var builtinFunctions = map[string]builtinFunction{
+	"append":  {arity: 1, isVariadic: true, call: callAppend},
+	"cap":     {arity: 1, call: callCap},
+	"clear":   {arity: 1, call: callClear},
+	"close":   {arity: 1, call: callClose},
+	"copy":    {arity: 2, call: callCopy},
+	"delete":  {arity: 2, call: callDelete},
+	"len":     {arity: 1, call: callLen},
+	"make":    {arity: 1, isVariadic: true, call: callMake},
+	"max":     {arity: 1, isVariadic: true, call: callMax},
+	"min":     {arity: 1, isVariadic: true, call: callMin},
+	"new":     {arity: 1, call: callNew},
+	"panic":   {arity: 1, call: callPanic},
+	"print":   {isVariadic: true, call: callPrint},
+	"println": {isVariadic: true, call: callPrintln},
+	"recover": {call: callRecover},
+}
+
+func (e *Evaluator) callBuiltin(node *ast.CallExpression, fn *object.Builtin, args []object.Object) object.Object {
+	if native, ok := lookUpNativeFunction(fn); ok {
+		return e.callNative(node, native, fn.Receiver, args)
+	}
+
+	builtin := builtinFunctions[fn.Name]
+	if node.HasEllipsis && fn.Name != "append" {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: invalid use of ... with built-in %s", fn.Name),
+		}
+	}
+
+	var msg string
+	switch {
+	case len(args) < builtin.arity:
+		msg = "not enough"
+	case !builtin.isVariadic && builtin.arity < len(args):
+		msg = "too many"
+	}
+	if msg != "" {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: %s arguments for %s (expected %d, found %d)", msg, ast.Format(node), builtin.arity, len(args)),
+		}
+	}
+	if fn.Name == "append" {
+		if host, ok := args[0].(*object.HostValue); ok && host.Value.Kind() == reflect.Slice {
+			return e.appendHost(node, host, args[1:])
+		}
+	}
+
+	return builtin.call(e, node, args)
+}
+
+func callAppend(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	s, ok := args[0].(*object.Slice)
+	if !ok {
+		cause := fmt.Sprintf("have %s", e.describe(node.Arguments[0], args[0]))
+		if e.isNil(node.Arguments[0]) {
+			cause = "have untyped nil"
+		}
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("first argument to append must be a slice; %s", cause),
+		}
+	}
+
+	elems := args[1:]
+	if node.HasEllipsis {
+		if len(args) != 2 {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: "invalid operation: invalid use of ... with built-in append",
+			}
+		}
+		spread, ok := args[1].(*object.Slice)
+		if !ok || spread.ElementType.Name != s.ElementType.Name {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as []%s value in argument to append", e.describe(node.Arguments[1], args[1]), s.ElementType),
+			}
+		}
+		elems = spread.Elements
+	} else {
+		for i, elem := range elems {
+			if !isAssignable(elem, s.ElementType) {
+				return &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("cannot use %s as %s value in argument to append", e.describe(node.Arguments[i+1], elem), s.ElementType),
+				}
+			}
+		}
+	}
+
+	if err := e.budget.allocate(int64(len(elems))); err != nil {
+		return err
+	}
+
+	return &object.Slice{
+		ElementType: s.ElementType,
+		Elements:    append(s.Elements, elems...),
+	}
+}
+
+func callCap(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	var n int
+	switch x := args[0].(type) {
+	case *object.Slice:
+		n = cap(x.Elements)
+	case *object.Channel:
+		n = x.Capacity
+	case *object.Nil:
+		if e.isNil(node.Arguments[0]) {
+			return invalidBuiltinArgument(e, node, args, "cap")
+		}
+	default:
+		return invalidBuiltinArgument(e, node, args, "cap")
+	}
+
+	return object.NewInteger(int64(n))
+}
+
+func callClear(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	switch x := args[0].(type) {
+	case *object.Map:
+		if err := e.detectMapRace(node, x, ast.Format(node.Arguments[0]), true); err != nil {
+			return err
+		}
+		for key := range x.Pairs {
+			delete(x.Pairs, key)
+		}
+	case *object.Slice:
+		for i := range x.Elements {
+			if err := e.detectElementRace(node, x, i, ast.Format(node.Arguments[0]), true); err != nil {
+				return err
+			}
+			x.Elements[i] = zeroValueOf(x.ElementType)
+		}
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: cannot clear %s: argument must be (or constrained by) map or slice", e.describe(node.Arguments[0], x)),
+		}
+	}
+
+	return &object.Nil{}
+}
+
+func callClose(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	return e.closeChannel(args[0])
+}
+
+func callCopy(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	dst, isDstSlice := args[0].(*object.Slice)
+	src, isSrcSlice := args[1].(*object.Slice)
+	if !isDstSlice || !isSrcSlice {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: copy expects slice arguments; found %s and %s", e.describe(node.Arguments[0], args[0]), e.describe(node.Arguments[1], args[1])),
+		}
+	}
+	if dst.ElementType.Name != src.ElementType.Name {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: arguments to copy %s and %s have different element types %s and %s", e.describe(node.Arguments[0], dst), e.describe(node.Arguments[1], src), dst.ElementType, src.ElementType),
+		}
+	}
+
+	n := min(len(dst.Elements), len(src.Elements))
+	for i := 0; i < n; i++ {
+		if err := e.detectElementRace(node, src, i, ast.Format(node.Arguments[1]), false); err != nil {
+			return err
+		}
+		if err := e.detectElementRace(node, dst, i, ast.Format(node.Arguments[0]), true); err != nil {
+			return err
+		}
+	}
+
+	return object.NewInteger(int64(copy(dst.Elements, src.Elements)))
+}
+
+func callDelete(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	m, ok := args[0].(*object.Map)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: %s is not a map", e.describe(node.Arguments[0], args[0])),
+		}
+	}
+	if !isAssignable(args[1], m.KeyType) {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot use %s as %s value in argument to delete", e.describe(node.Arguments[1], args[1]), m.KeyType),
+		}
+	}
+	key, err := hashKeyOf(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := e.detectMapRace(node, m, ast.Format(node.Arguments[0]), true); err != nil {
+		return err
+	}
+	delete(m.Pairs, key)
+	return &object.Nil{}
+}
+
+func callLen(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	var n int
+	switch x := args[0].(type) {
+	case *object.String:
+		n = len(x.Value)
+	case *object.Slice:
+		n = len(x.Elements)
+	case *object.Map:
+		n = len(x.Pairs)
+	case *object.Channel:
+		n = len(x.Buffer)
+	case *object.HostValue:
+		switch x.Value.Kind() {
+		case reflect.Array, reflect.Slice, reflect.Map, reflect.String:
+			n = x.Value.Len()
+		default:
+			return invalidBuiltinArgument(e, node, args, "len")
+		}
+	case *object.Nil:
+		if e.isNil(node.Arguments[0]) {
+			return invalidBuiltinArgument(e, node, args, "len")
+		}
+	default:
+		return invalidBuiltinArgument(e, node, args, "len")
+	}
+
+	return object.NewInteger(int64(n))
+}
+
+func invalidBuiltinArgument(e *Evaluator, node *ast.CallExpression, args []object.Object, name string) *object.Error {
+	return &object.Error{
+		Kind:    object.ErrType,
+		Message: fmt.Sprintf("invalid argument: %s for built-in %s", e.describe(node.Arguments[0], args[0]), name),
+	}
+}
+
+func callMake(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	typ, ok := args[0].(*object.Type)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("%s is not a type", ast.Format(node.Arguments[0])),
+		}
+	}
+
+	var min int
+	switch typ.Kind {
+	case object.SliceType:
+		min = 2
+	case object.MapType, object.ChannelType:
+		min = 1
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: cannot make %s; type must be slice, map, or channel", ast.Format(node.Arguments[0])),
+		}
+	}
+	if len(args) < min || min+1 < len(args) {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: %s expects %d or %d arguments; found %d", ast.Format(node), min, min+1, len(args)),
+		}
+	}
+
+	sizes := make([]int64, len(args)-1)
+	for i := range sizes {
+		size, err := e.sizeOf(node.Arguments[i+1], args[i+1])
+		if err != nil {
+			return err
+		}
+		sizes[i] = size
+	}
+	if err := e.budget.allocate(maxOf(sizes) + 1); err != nil {
+		return err
+	}
+
+	switch typ.Kind {
+	case object.SliceType:
+		return makeSlice(node, typ, sizes)
+	case object.MapType:
+		return &object.Map{
+			KeyType:   typ.Key,
+			ValueType: typ.Element,
+			Pairs:     make(map[interface{}]*object.MapPair),
+		}
+	default:
+		var size int64
+		if len(sizes) == 1 {
+			size = sizes[0]
+		}
+		if size < 0 {
+			return &object.Panic{
+				Value: &object.RuntimeError{
+					Kind:    object.ErrRuntime,
+					Message: "makechan: size out of range",
+				},
+			}
+		}
+
+		return &object.Channel{
+			ElementType: typ.Element,
+			Capacity:    int(size),
+			Buffer:      make([]object.Object, 0, size),
+		}
+	}
+}
+
+func makeSlice(node *ast.CallExpression, typ *object.Type, sizes []int64) object.Object {
+	length, capacity := sizes[0], sizes[0]
+	for i, size := range sizes {
+		if isConstant(node.Arguments[i+1]) && size < 0 {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("invalid argument: index %s (constant of type int) must not be negative", ast.Format(node.Arguments[i+1])),
+			}
+		}
+	}
+	if len(sizes) == 2 {
+		capacity = sizes[1]
+		_, isLengthConstant := node.Arguments[1].(*ast.Integer)
+		_, isCapacityConstant := node.Arguments[2].(*ast.Integer)
+		if isLengthConstant && isCapacityConstant && capacity < length {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: "invalid argument: length and capacity swapped",
+			}
+		}
+	}
+	if length < 0 {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrRuntime,
+				Message: "runtime error: makeslice: len out of range",
+			},
+		}
+	}
+	if capacity < length {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrRuntime,
+				Message: "runtime error: makeslice: cap out of range",
+			},
+		}
+	}
+
+	elems := make([]object.Object, length, capacity)
+	for i := range elems {
+		elems[i] = zeroValueOf(typ.Element)
+	}
+
+	return &object.Slice{
+		ElementType: typ.Element,
+		Elements:    elems,
+	}
+}
+
+func maxOf(sizes []int64) int64 {
+	var max int64
+	for _, size := range sizes {
+		if max < size {
+			max = size
+		}
+	}
+
+	return max
+}
+
+func (e *Evaluator) sizeOf(node ast.Expression, obj object.Object) (int64, *object.Error) {
+	n, ok := obj.(*object.Integer)
+	if !ok {
+		if isConstant(node) {
+			return 0, &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot convert %s to type int", e.describe(node, obj)),
+			}
+		}
+		return 0, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: index %s must be integer", e.describe(node, obj)),
+		}
+	}
+
+	return n.Value, nil
+}
+
+func callMax(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	return e.callMinOrMax(node, args, func(x, y object.Object) bool {
+		return object.Less(y, x)
+	})
+}
+
+func callMin(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	return e.callMinOrMax(node, args, object.Less)
+}
+
+func (e *Evaluator) callMinOrMax(node *ast.CallExpression, args []object.Object, prefers func(x, y object.Object) bool) object.Object {
+	var result object.Object
+	for i, arg := range args {
+		switch arg.(type) {
+		case *object.Integer, *object.String:
+		default:
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("invalid argument: %s cannot be ordered", e.describe(node.Arguments[i], arg)),
+			}
+		}
+		if result == nil {
+			result = arg
+			continue
+		}
+		if typeName(result) != typeName(arg) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("invalid argument: mismatched types %s (previous argument) and %s (type of %s)", typeName(result), typeName(arg), ast.Format(node.Arguments[i])),
+			}
+		}
+		if prefers(arg, result) {
+			result = arg
+		}
+	}
+
+	return result
+}
+
+func callNew(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	typ, ok := args[0].(*object.Type)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("%s is not a type", ast.Format(node.Arguments[0])),
+		}
+	}
+
+	if err := e.budget.allocate(1); err != nil {
+		return err
+	}
+
+	zero := zeroValueOf(typ)
+	return &object.Pointer{
+		ElementType: typ,
+		Value:       &zero,
+	}
+}
+
+func callPanic(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	if _, ok := args[0].(*object.Nil); ok {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrPanic,
+				Message: "panic called with nil argument",
+			},
+		}
+	}
+
+	return &object.Panic{
+		Value: args[0],
+	}
+}
+
+func callPrint(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	return e.print(node, args, "", "")
+}
+
+func callPrintln(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	return e.print(node, args, " ", "\n")
+}
+
+func (e *Evaluator) print(node *ast.CallExpression, args []object.Object, separator, terminator string) object.Object {
+	ss := make([]string, len(args))
+	for i, arg := range args {
+		if e.isNil(node.Arguments[i]) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("use of untyped nil in argument to built-in %s", ast.Format(node.Function)),
+			}
+		}
+		ss[i] = printString(arg)
+	}
+
+	fmt.Fprint(e.stderr, strings.Join(ss, separator)+terminator)
+	return &object.Nil{}
+}
+
+func printString(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.Boolean, *object.String:
+		return fmt.Sprint(obj)
+	case *object.Slice:
+		if obj.Elements == nil {
+			return fmt.Sprintf("[0/%d]0x0", cap(obj.Elements))
+		}
+		return fmt.Sprintf("[%d/%d]%p", len(obj.Elements), cap(obj.Elements), obj.Elements[:cap(obj.Elements)])
+	case *object.Pointer:
+		if obj.Value == nil {
+			return "0x0"
+		}
+		return fmt.Sprintf("%p", obj.Value)
+	case *object.Nil:
+		return "0x0"
+	default:
+		return fmt.Sprintf("%p", obj)
+	}
+}
+
+func callRecover(e *Evaluator, node *ast.CallExpression, args []object.Object) object.Object {
+	return e.frame.recover()
+}
+
+func (e *Evaluator) describe(node ast.Expression, obj object.Object) string {
+	switch node := node.(type) {
+	case *ast.Integer, *ast.String:
+		return fmt.Sprintf("%s (untyped %s constant)", ast.Format(node), typeName(obj))
+	case *ast.PrefixExpression:
+		if isConstant(node) {
+			return fmt.Sprintf("%s (untyped %s constant)", ast.Format(node), typeName(obj))
+		}
+	case *ast.Identifier:
+		if _, env, ok := e.env.Lookup(node.Name); ok && env.Scope() == UniverseScope {
+			switch obj.(type) {
+			case *object.Boolean:
+				return fmt.Sprintf("%s (untyped bool constant)", node.Name)
+			case *object.Nil:
+				return node.Name
+			}
+		}
+		switch obj.(type) {
+		case *object.Type:
+			return fmt.Sprintf("%s (type)", node.Name)
+		case *object.Builtin:
+			return fmt.Sprintf("%s (built-in function %s)", node.Name, node.Name)
+		default:
+			return fmt.Sprintf("%s (variable of type %s)", node.Name, typeName(obj))
+		}
+	}
+
+	return fmt.Sprintf("%s (value of type %s)", ast.Format(node), typeName(obj))
+}
+
+func (e *Evaluator) isNil(node ast.Expression) bool {
+	ident, ok := node.(*ast.Identifier)
+	if !ok || ident.Name != "nil" {
+		return false
+	}
+	_, env, ok := e.env.Lookup(ident.Name)
+
+	return ok && env.Scope() == UniverseScope
+}
+
+func isConstant(node ast.Expression) bool {
+	switch node := node.(type) {
+	case *ast.Integer, *ast.String:
+		return true
+	case *ast.PrefixExpression:
+		_, ok := node.RExpression.(*ast.Integer)
+		return ok && node.Operator == ast.Negative
+	default:
+		return false
+	}
+}
+
+func checkNumberOfArguments(name string, args []object.Object, n int) *object.Error {
+	switch {
+	case len(args) < n:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("not enough arguments in call to %s", name),
+		}
+	case n < len(args):
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("too many arguments in call to %s", name),
+		}
+	default:
+		return nil
+	}
+}
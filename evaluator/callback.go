@@ -0,0 +1,155 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func (e *Evaluator) Call(name string, args ...interface{}) (interface{}, error) {
+	fn, err := e.lookUpFunction(name)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, err := objectOf(reflect.ValueOf(arg))
+		if err != nil {
+			return nil, fmt.Errorf("cannot use argument %d in call to %s: %w", i, name, err)
+		}
+		objs[i] = obj
+	}
+
+	obj := e.callFromHost(fn, objs)
+	if err, ok := obj.(*object.Error); ok {
+		return nil, err
+	}
+	value, ok := anyValueOf(obj)
+	if !ok {
+		return nil, fmt.Errorf("cannot use result of %s: unsupported type %s", name, typeName(obj))
+	}
+
+	return value, nil
+}
+
+func (e *Evaluator) Func(name string, ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Func {
+		return fmt.Errorf("cannot bind %s to %T: want a non-nil pointer to func", name, ptr)
+	}
+	fn, err := e.lookUpFunction(name)
+	if err != nil {
+		return err
+	}
+
+	v.Elem().Set(e.hostFuncOf(fn, v.Elem().Type()))
+	return nil
+}
+
+func (e *Evaluator) lookUpFunction(name string) (*object.Function, error) {
+	obj, ok := e.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined: %s", name)
+	}
+	fn, ok := obj.(*object.Function)
+	if !ok {
+		return nil, fmt.Errorf("cannot call non-function %s (variable of type %s)", name, typeName(obj))
+	}
+
+	return fn, nil
+}
+
+func (e *Evaluator) callFromHost(fn *object.Function, args []object.Object) object.Object {
+	if e.scheduler.isShuttingDown {
+		e.start()
+		defer e.scheduler.shutdown()
+	}
+
+	obj := e.callFunction(fn, args, nil)
+	if p, ok := obj.(*object.Panic); ok {
+		return panicError(p)
+	}
+
+	return obj
+}
+
+func (e *Evaluator) hostFuncOf(fn *object.Function, typ reflect.Type) reflect.Value {
+	return reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		args := make([]object.Object, len(in))
+		for i, v := range in {
+			arg, err := objectOf(v)
+			if err != nil {
+				return e.hostResultsOf(typ, nil, err)
+			}
+			args[i] = arg
+		}
+
+		obj := e.callFromHost(fn, args)
+		if err, ok := obj.(*object.Error); ok {
+			return e.hostResultsOf(typ, nil, err)
+		}
+
+		return e.hostResultsOf(typ, obj, nil)
+	})
+}
+
+func (e *Evaluator) hostResultsOf(typ reflect.Type, obj object.Object, err error) []reflect.Value {
+	out := make([]reflect.Value, typ.NumOut())
+	for i := range out {
+		out[i] = reflect.Zero(typ.Out(i))
+	}
+
+	n := len(out)
+	returnsError := 0 < n && typ.Out(n-1) == hostErrorType
+	if returnsError {
+		n--
+	}
+
+	if err == nil && obj != nil {
+		switch {
+		case n == 1:
+			out[0], err = e.hostValueOf(obj, typ.Out(0))
+		case n == 0 && returnsError:
+			if value, ok := obj.(*object.ErrorValue); ok {
+				err = value.Value
+			}
+		case 1 < n:
+			err = fmt.Errorf("cannot return %d values from %s", n, typ)
+		}
+	}
+
+	if err != nil {
+		if !returnsError {
+			if e.callbackErr == nil {
+				e.callbackErr = err
+			}
+			return out
+		}
+		out[len(out)-1] = reflect.ValueOf(&err).Elem()
+	}
+
+	return out
+}
+
+// Err reports and clears the first failure of a script callback whose Go
+// func type has no error result to carry it. Such a callback returns zero
+// values instead.
+func (e *Evaluator) Err() error {
+	err := e.callbackErr
+	e.callbackErr = nil
+	return err
+}
+
+func callbackError(err error) *object.Error {
+	if err, ok := err.(*object.Error); ok {
+		return err
+	}
+
+	return &object.Error{
+		Kind:    object.ErrType,
+		Message: err.Error(),
+		Err:     err,
+	}
+}
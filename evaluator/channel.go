@@ -0,0 +1,404 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+)
+
+type selection struct {
+	isClaimed bool
+	index     int
+	value     object.Object
+	ok        bool
+}
+
+type channelWaiter struct {
+	scheduler *scheduler
+	goroutine *goroutine
+	selection *selection
+	index     int
+	value     object.Object
+}
+
+func (w *channelWaiter) Claim() bool {
+	if w.selection.isClaimed {
+		return false
+	}
+
+	w.selection.isClaimed = true
+	w.selection.index = w.index
+	return true
+}
+
+func (w channelWaiter) IsClaimed() bool {
+	return w.selection.isClaimed
+}
+
+func (w channelWaiter) Value() object.Object {
+	return w.value
+}
+
+func (w *channelWaiter) Wake(value object.Object, ok bool) {
+	w.selection.value, w.selection.ok = value, ok
+	w.scheduler.ready(w.goroutine)
+}
+
+func (e *Evaluator) newChannelWaiter(sel *selection, index int, value object.Object) *channelWaiter {
+	return &channelWaiter{
+		scheduler: e.scheduler,
+		goroutine: e.goroutine,
+		selection: sel,
+		index:     index,
+		value:     value,
+	}
+}
+
+func (e *Evaluator) send(obj, value object.Object) object.Object {
+	if _, ok := obj.(*object.Nil); ok {
+		return e.blockForever()
+	}
+	ch, ok := obj.(*object.Channel)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot send to non-channel %s", typeName(obj)),
+		}
+	}
+
+	e.synchronize(ch)
+	if ready, err := trySend(ch, value); ready {
+		return err
+	}
+
+	sel := new(selection)
+	ch.Senders = append(ch.Senders, e.newChannelWaiter(sel, 0, value))
+	if !e.scheduler.park(e.goroutine) {
+		return e.scheduler.abortion()
+	}
+	e.synchronize(ch)
+	if !sel.ok {
+		return sendOnClosedChannel()
+	}
+
+	return nil
+}
+
+func trySend(ch *object.Channel, value object.Object) (bool, object.Object) {
+	if ch.Closed {
+		return true, sendOnClosedChannel()
+	}
+
+	if receiver := popClaimableWaiter(&ch.Receivers); receiver != nil {
+		receiver.Wake(value, true)
+		return true, nil
+	}
+
+	if len(ch.Buffer) < ch.Capacity {
+		ch.Buffer = append(ch.Buffer, value)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func canSend(ch *object.Channel) bool {
+	return ch.Closed || hasClaimableWaiter(&ch.Receivers) || len(ch.Buffer) < ch.Capacity
+}
+
+func sendOnClosedChannel() *object.Panic {
+	return &object.Panic{
+		Value: &object.RuntimeError{
+			Kind:    object.ErrClosedChannel,
+			Message: "send on closed channel",
+		},
+	}
+}
+
+func (e *Evaluator) receive(obj object.Object) (object.Object, bool, object.Object) {
+	if _, ok := obj.(*object.Nil); ok {
+		return nil, false, e.blockForever()
+	}
+	ch, ok := obj.(*object.Channel)
+	if !ok {
+		return nil, false, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot receive from non-channel %s", typeName(obj)),
+		}
+	}
+
+	e.synchronize(ch)
+	if value, ok, ready := tryReceive(ch); ready {
+		return value, ok, nil
+	}
+
+	sel := new(selection)
+	ch.Receivers = append(ch.Receivers, e.newChannelWaiter(sel, 0, nil))
+	if !e.scheduler.park(e.goroutine) {
+		return nil, false, e.scheduler.abortion()
+	}
+	e.synchronize(ch)
+
+	return sel.value, sel.ok, nil
+}
+
+func tryReceive(ch *object.Channel) (object.Object, bool, bool) {
+	if sender := popClaimableWaiter(&ch.Senders); sender != nil {
+		value := sender.Value()
+		if 0 < len(ch.Buffer) {
+			value, ch.Buffer = ch.Buffer[0], append(ch.Buffer[1:], value)
+		}
+		sender.Wake(nil, true)
+
+		return value, true, true
+	}
+
+	if 0 < len(ch.Buffer) {
+		value := ch.Buffer[0]
+		ch.Buffer = ch.Buffer[1:]
+		return value, true, true
+	}
+
+	if ch.Closed {
+		return zeroValueOf(ch.ElementType), false, true
+	}
+
+	return nil, false, false
+}
+
+func canReceive(ch *object.Channel) bool {
+	return hasClaimableWaiter(&ch.Senders) || 0 < len(ch.Buffer) || ch.Closed
+}
+
+func (e *Evaluator) closeChannel(obj object.Object) object.Object {
+	if _, ok := obj.(*object.Nil); ok {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrClosedChannel,
+				Message: "close of nil channel",
+			},
+		}
+	}
+	ch, ok := obj.(*object.Channel)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: non-chan argument to close: %s", typeName(obj)),
+		}
+	}
+	if ch.Closed {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrClosedChannel,
+				Message: "close of closed channel",
+			},
+		}
+	}
+
+	e.synchronize(ch)
+	ch.Closed = true
+	for receiver := popClaimableWaiter(&ch.Receivers); receiver != nil; receiver = popClaimableWaiter(&ch.Receivers) {
+		receiver.Wake(zeroValueOf(ch.ElementType), false)
+	}
+	for sender := popClaimableWaiter(&ch.Senders); sender != nil; sender = popClaimableWaiter(&ch.Senders) {
+		sender.Wake(nil, false)
+	}
+
+	return &object.Nil{}
+}
+
+func (e *Evaluator) synchronize(ch *object.Channel) {
+	if e.race != nil {
+		e.race.synchronize(e.goroutine, ch)
+	}
+}
+
+func (e *Evaluator) blockForever() object.Object {
+	e.scheduler.park(e.goroutine)
+	return e.scheduler.abortion()
+}
+
+func popClaimableWaiter(waiters *[]object.ChannelWaiter) object.ChannelWaiter {
+	for 0 < len(*waiters) {
+		w := (*waiters)[0]
+		*waiters = (*waiters)[1:]
+		if w.Claim() {
+			return w
+		}
+	}
+
+	return nil
+}
+
+func hasClaimableWaiter(waiters *[]object.ChannelWaiter) bool {
+	for 0 < len(*waiters) && (*waiters)[0].IsClaimed() {
+		*waiters = (*waiters)[1:]
+	}
+
+	return 0 < len(*waiters)
+}
+
+type communication struct {
+	clause  *ast.CommunicationClause
+	channel object.Object
+	value   object.Object
+	isSend  bool
+}
+
+func (e *Evaluator) evaluateSelectStatement(node *ast.SelectStatement) object.Object {
+	comms := make([]*communication, 0, len(node.Clauses))
+	var defaultClause *ast.CommunicationClause
+	for _, clause := range node.Clauses {
+		if clause.IsDefault {
+			defaultClause = clause
+			continue
+		}
+
+		comm, err := e.evaluateCommunication(clause)
+		if err != nil {
+			return err
+		}
+		comms = append(comms, comm)
+	}
+
+	readies := make([]int, 0, len(comms))
+	for i, comm := range comms {
+		ch, ok := comm.channel.(*object.Channel)
+		if !ok {
+			continue
+		}
+		if comm.isSend && canSend(ch) || !comm.isSend && canReceive(ch) {
+			readies = append(readies, i)
+		}
+	}
+
+	if 0 < len(readies) {
+		comm := comms[readies[e.scheduler.pick(len(readies))]]
+		ch := comm.channel.(*object.Channel)
+		e.synchronize(ch)
+		if comm.isSend {
+			if _, err := trySend(ch, comm.value); err != nil {
+				return err
+			}
+			return e.evaluateCommunicationClause(comm.clause, nil, false)
+		}
+
+		value, ok, _ := tryReceive(ch)
+		return e.evaluateCommunicationClause(comm.clause, value, ok)
+	}
+
+	if defaultClause != nil {
+		return e.evaluateCommunicationClause(defaultClause, nil, false)
+	}
+
+	sel := new(selection)
+	for i, comm := range comms {
+		ch, ok := comm.channel.(*object.Channel)
+		if !ok {
+			continue
+		}
+		e.synchronize(ch)
+		if comm.isSend {
+			ch.Senders = append(ch.Senders, e.newChannelWaiter(sel, i, comm.value))
+		} else {
+			ch.Receivers = append(ch.Receivers, e.newChannelWaiter(sel, i, nil))
+		}
+	}
+	if !e.scheduler.park(e.goroutine) {
+		return e.scheduler.abortion()
+	}
+
+	comm := comms[sel.index]
+	e.synchronize(comm.channel.(*object.Channel))
+	if comm.isSend && !sel.ok {
+		return sendOnClosedChannel()
+	}
+
+	return e.evaluateCommunicationClause(comm.clause, sel.value, sel.ok)
+}
+
+func (e *Evaluator) evaluateCommunication(clause *ast.CommunicationClause) (*communication, object.Object) {
+	comm := &communication{
+		clause: clause,
+	}
+
+	var recv ast.Expression
+	switch stmt := clause.Communication.(type) {
+	case *ast.SendStatement:
+		comm.isSend = true
+		comm.channel = e.Evaluate(stmt.Channel)
+		if isError(comm.channel) {
+			return nil, comm.channel
+		}
+		comm.value = e.Evaluate(stmt.Expression)
+		if isError(comm.value) {
+			return nil, comm.value
+		}
+
+		return comm, nil
+	case *ast.ExpressionStatement:
+		recv = stmt.Expression
+	case *ast.ShortVariableDeclaration:
+		recv = stmt.Expressions[0]
+	case *ast.AssignmentStatement:
+		recv = stmt.RExpressions[0]
+	}
+
+	comm.channel = e.Evaluate(recv.(*ast.PrefixExpression).RExpression)
+	if isError(comm.channel) {
+		return nil, comm.channel
+	}
+	if _, ok := comm.channel.(*object.Nil); ok {
+		return comm, nil
+	}
+	if _, ok := comm.channel.(*object.Channel); !ok {
+		return nil, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot receive from non-channel %s", typeName(comm.channel)),
+		}
+	}
+
+	return comm, nil
+}
+
+func (e *Evaluator) evaluateCommunicationClause(clause *ast.CommunicationClause, value object.Object, ok bool) object.Object {
+	env := NewEnclosedEnvironment(e.env)
+	values := []object.Object{
+		value,
+		object.NewBoolean(ok),
+	}
+
+	var obj object.Object
+	switch stmt := clause.Communication.(type) {
+	case *ast.ShortVariableDeclaration:
+		for i, ident := range stmt.Identifiers {
+			if ident.Name == "_" {
+				continue
+			}
+			if err := env.Declare(ident.Name, values[i]); err != nil {
+				return &object.Error{
+					Kind:    object.ErrName,
+					Message: err.Error(),
+				}
+			}
+		}
+	case *ast.AssignmentStatement:
+		obj = e.assign(stmt.LExpressions, values[:len(stmt.LExpressions)])
+	}
+	if isError(obj) {
+		return obj
+	}
+
+	obj = e.withEnvironment(env, func() object.Object {
+		return e.evaluateStatements(clause.Statements)
+	})
+	if _, ok := obj.(*object.Break); ok {
+		return &object.Nil{}
+	}
+	if obj == nil {
+		return &object.Nil{}
+	}
+
+	return obj
+}
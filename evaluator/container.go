@@ -0,0 +1,577 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+)
+
+func sliceTypeOf(elem *object.Type) *object.Type {
+	return &object.Type{
+		Kind:    object.SliceType,
+		Name:    fmt.Sprintf("[]%s", elem),
+		Element: elem,
+	}
+}
+
+func mapTypeOf(key, value *object.Type) *object.Type {
+	return &object.Type{
+		Kind:    object.MapType,
+		Name:    fmt.Sprintf("map[%s]%s", key, value),
+		Key:     key,
+		Element: value,
+	}
+}
+
+func pointerTypeOf(elem *object.Type) *object.Type {
+	return &object.Type{
+		Kind:    object.PointerType,
+		Name:    fmt.Sprintf("*%s", elem),
+		Element: elem,
+	}
+}
+
+func (e *Evaluator) evaluateCompositeLiteral(node *ast.CompositeLiteral) object.Object {
+	typ := e.evaluateType(node.Type)
+	if isError(typ) {
+		return typ
+	}
+
+	return e.evaluateCompositeLiteralOf(typ.(*object.Type), node)
+}
+
+func (e *Evaluator) evaluateCompositeLiteralOf(typ *object.Type, node *ast.CompositeLiteral) object.Object {
+	switch typ.Kind {
+	case object.SliceType:
+		return e.evaluateSliceLiteral(typ, node.Elements)
+	case object.MapType:
+		return e.evaluateMapLiteral(typ, node.Elements)
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid composite literal type %s", typ),
+		}
+	}
+}
+
+func (e *Evaluator) evaluateElement(typ *object.Type, node ast.Expression, context string) object.Object {
+	if lit, ok := node.(*ast.CompositeLiteral); ok && lit.Type == nil {
+		return e.evaluateCompositeLiteralOf(typ, lit)
+	}
+
+	obj := e.Evaluate(node)
+	if isError(obj) {
+		return obj
+	}
+	if !isAssignable(obj, typ) {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot use %s as %s value in %s", e.describe(node, obj), typ, context),
+		}
+	}
+
+	return obj
+}
+
+func (e *Evaluator) evaluateSliceLiteral(typ *object.Type, elems []*ast.KeyedElement) object.Object {
+	values := make(map[int64]object.Object)
+	var index, length int64
+	for _, elem := range elems {
+		if elem.Key != nil {
+			key, ok := elem.Key.(*ast.Integer)
+			if !ok {
+				return &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("index %s must be integer constant", ast.Format(elem.Key)),
+				}
+			}
+			index = key.Value
+		}
+		if _, ok := values[index]; ok {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("duplicate index %d in array or slice literal", index),
+			}
+		}
+
+		value := e.evaluateElement(typ.Element, elem.Value, "array or slice literal")
+		if isError(value) {
+			return value
+		}
+		values[index] = value
+
+		index++
+		if length < index {
+			length = index
+		}
+	}
+
+	if err := e.budget.allocate(length + 1); err != nil {
+		return err
+	}
+	s := &object.Slice{
+		ElementType: typ.Element,
+		Elements:    make([]object.Object, length),
+	}
+	for i := range s.Elements {
+		if value, ok := values[int64(i)]; ok {
+			s.Elements[i] = value
+			continue
+		}
+		s.Elements[i] = zeroValueOf(typ.Element)
+	}
+
+	return s
+}
+
+func (e *Evaluator) evaluateMapLiteral(typ *object.Type, elems []*ast.KeyedElement) object.Object {
+	if err := e.budget.allocate(int64(len(elems)) + 1); err != nil {
+		return err
+	}
+	m := &object.Map{
+		KeyType:   typ.Key,
+		ValueType: typ.Element,
+		Pairs:     make(map[interface{}]*object.MapPair),
+	}
+	for _, elem := range elems {
+		if elem.Key == nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: "missing key in map literal",
+			}
+		}
+
+		key := e.evaluateElement(typ.Key, elem.Key, "map literal")
+		if isError(key) {
+			return key
+		}
+		hash, err := hashKeyOf(key)
+		if err != nil {
+			return err
+		}
+		if _, ok := m.Pairs[hash]; ok {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("duplicate key %s in map literal", ast.Format(elem.Key)),
+			}
+		}
+
+		value := e.evaluateElement(typ.Element, elem.Value, "map literal")
+		if isError(value) {
+			return value
+		}
+		m.Pairs[hash] = &object.MapPair{
+			Key:   key,
+			Value: value,
+		}
+	}
+
+	return m
+}
+
+func (e *Evaluator) index(node *ast.IndexExpression, obj object.Object) object.Object {
+	if len(node.Indices) != 1 {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: more than one index for %s", e.describe(node.Expression, obj)),
+		}
+	}
+	index := e.Evaluate(node.Indices[0])
+	if isError(index) {
+		return index
+	}
+
+	switch obj := obj.(type) {
+	case *object.Slice:
+		i, err := e.integerIndexOf(node.Indices[0], index, len(obj.Elements))
+		if err != nil {
+			return err
+		}
+		if err := e.detectElementRace(node, obj, i, ast.Format(node.Expression), false); err != nil {
+			return err
+		}
+		return obj.Elements[i]
+	case *object.String:
+		i, err := e.integerIndexOf(node.Indices[0], index, len(obj.Value))
+		if err != nil {
+			return err
+		}
+		return object.NewInteger(int64(obj.Value[i]))
+	case *object.Map:
+		value, _, err := e.lookUpMap(node.Indices[0], obj, index)
+		if err != nil {
+			return err
+		}
+		if err := e.detectMapRace(node, obj, ast.Format(node.Expression), false); err != nil {
+			return err
+		}
+		return value
+	case *object.HostValue:
+		return e.indexHost(node, obj, index)
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot index %s", typeName(obj)),
+		}
+	}
+}
+
+func (e *Evaluator) integerIndexOf(node ast.Expression, obj object.Object, length int) (int, object.Object) {
+	index, ok := obj.(*object.Integer)
+	if !ok {
+		return 0, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: index %s must be integer", e.describe(node, obj)),
+		}
+	}
+	if index.Value < 0 && isConstant(node) {
+		return 0, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: index %s (constant of type int) must not be negative", ast.Format(node)),
+		}
+	}
+	if index.Value < 0 {
+		return 0, &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrIndexOutOfRange,
+				Message: fmt.Sprintf("runtime error: index out of range [%d]", index.Value),
+			},
+		}
+	}
+	if int64(length) <= index.Value {
+		return 0, &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrIndexOutOfRange,
+				Message: fmt.Sprintf("runtime error: index out of range [%d] with length %d", index.Value, length),
+			},
+		}
+	}
+
+	return int(index.Value), nil
+}
+
+func (e *Evaluator) lookUpMap(node ast.Expression, m *object.Map, key object.Object) (object.Object, bool, object.Object) {
+	if !isAssignable(key, m.KeyType) {
+		return nil, false, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot use %s as %s value in map index", e.describe(node, key), m.KeyType),
+		}
+	}
+	hash, err := hashKeyOf(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	pair, ok := m.Pairs[hash]
+	if !ok {
+		return zeroValueOf(m.ValueType), false, nil
+	}
+
+	return pair.Value, true, nil
+}
+
+func (e *Evaluator) assignIndex(node *ast.IndexExpression, value object.Object) object.Object {
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
+		return obj
+	}
+	if len(node.Indices) != 1 {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: more than one index for %s", e.describe(node.Expression, obj)),
+		}
+	}
+	index := e.Evaluate(node.Indices[0])
+	if isError(index) {
+		return index
+	}
+
+	switch obj := obj.(type) {
+	case *object.Slice:
+		if !isAssignable(value, obj.ElementType) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in assignment", typeName(value), obj.ElementType),
+			}
+		}
+		i, err := e.integerIndexOf(node.Indices[0], index, len(obj.Elements))
+		if err != nil {
+			return err
+		}
+		if err := e.detectElementRace(node, obj, i, ast.Format(node.Expression), true); err != nil {
+			return err
+		}
+		obj.Elements[i] = value
+	case *object.Map:
+		if !isAssignable(index, obj.KeyType) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in map index", e.describe(node.Indices[0], index), obj.KeyType),
+			}
+		}
+		if !isAssignable(value, obj.ValueType) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in assignment", typeName(value), obj.ValueType),
+			}
+		}
+		if obj.Pairs == nil {
+			return &object.Panic{
+				Value: &object.RuntimeError{
+					Kind:    object.ErrNilMap,
+					Message: "assignment to entry in nil map",
+				},
+			}
+		}
+		hash, err := hashKeyOf(index)
+		if err != nil {
+			return err
+		}
+		if err := e.detectMapRace(node, obj, ast.Format(node.Expression), true); err != nil {
+			return err
+		}
+		if _, ok := obj.Pairs[hash]; !ok {
+			if err := e.budget.allocate(1); err != nil {
+				return err
+			}
+		}
+		obj.Pairs[hash] = &object.MapPair{
+			Key:   index,
+			Value: value,
+		}
+	case *object.String:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot assign to %s (neither addressable nor a map index expression)", ast.Format(node)),
+		}
+	case *object.HostValue:
+		if err := e.assignHostIndex(node, obj, index, value); err != nil {
+			return err
+		}
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot index %s", e.describe(node.Expression, obj)),
+		}
+	}
+
+	return value
+}
+
+func hashKeyOf(obj object.Object) (interface{}, object.Object) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Channel:
+		return obj, nil
+	case *object.Pointer:
+		return obj.Value, nil
+	case *object.ErrorValue:
+		return obj.Value, nil
+	case *object.Nil:
+		return nil, nil
+	default:
+		return nil, &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrRuntime,
+				Message: fmt.Sprintf("runtime error: hash of unhashable type %s", typeName(obj)),
+			},
+		}
+	}
+}
+
+func (e *Evaluator) evaluateSliceExpression(node *ast.SliceExpression) object.Object {
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
+		return obj
+	}
+
+	var length, capacity int
+	switch obj := obj.(type) {
+	case *object.Slice:
+		length, capacity = len(obj.Elements), cap(obj.Elements)
+	case *object.String:
+		length, capacity = len(obj.Value), len(obj.Value)
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot slice %s", e.describe(node.Expression, obj)),
+		}
+	}
+
+	low, err := e.sliceIndexOf(node.Low, 0)
+	if err != nil {
+		return err
+	}
+	high, err := e.sliceIndexOf(node.High, length)
+	if err != nil {
+		return err
+	}
+
+	bound := "capacity"
+	if _, ok := obj.(*object.String); ok {
+		bound = "length"
+	}
+	if capacity < high {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrIndexOutOfRange,
+				Message: fmt.Sprintf("runtime error: slice bounds out of range [:%d] with %s %d", high, bound, capacity),
+			},
+		}
+	}
+	if high < low {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrIndexOutOfRange,
+				Message: fmt.Sprintf("runtime error: slice bounds out of range [%d:%d]", low, high),
+			},
+		}
+	}
+
+	switch obj := obj.(type) {
+	case *object.Slice:
+		return &object.Slice{
+			ElementType: obj.ElementType,
+			Elements:    obj.Elements[low:high],
+		}
+	default:
+		return &object.String{
+			Value: obj.(*object.String).Value[low:high],
+		}
+	}
+}
+
+func (e *Evaluator) sliceIndexOf(node ast.Expression, defaultIndex int) (int, object.Object) {
+	if node == nil {
+		return defaultIndex, nil
+	}
+	obj := e.Evaluate(node)
+	if isError(obj) {
+		return 0, obj
+	}
+
+	index, ok := obj.(*object.Integer)
+	if !ok {
+		return 0, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid argument: index %s must be integer", e.describe(node, obj)),
+		}
+	}
+	if index.Value < 0 {
+		if isConstant(node) {
+			return 0, &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("invalid argument: index %s (constant of type int) must not be negative", ast.Format(node)),
+			}
+		}
+		return 0, &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrIndexOutOfRange,
+				Message: fmt.Sprintf("runtime error: slice bounds out of range [%d:]", index.Value),
+			},
+		}
+	}
+
+	return int(index.Value), nil
+}
+
+func (e *Evaluator) dereference(node ast.Expression, obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Type:
+		return pointerTypeOf(obj)
+	case *object.Pointer:
+		if obj.Value == nil {
+			return &object.Panic{
+				Value: &object.RuntimeError{
+					Kind:    object.ErrNilDereference,
+					Message: "runtime error: invalid memory address or nil pointer dereference",
+				},
+			}
+		}
+		return *obj.Value
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot indirect %s", e.describe(node, obj)),
+		}
+	}
+}
+
+func (e *Evaluator) assignIndirect(node *ast.PrefixExpression, value object.Object) object.Object {
+	obj := e.Evaluate(node.RExpression)
+	if isError(obj) {
+		return obj
+	}
+	p, ok := obj.(*object.Pointer)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot indirect %s", e.describe(node.RExpression, obj)),
+		}
+	}
+	if p.Value == nil {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrNilDereference,
+				Message: "runtime error: invalid memory address or nil pointer dereference",
+			},
+		}
+	}
+	if !isAssignable(value, p.ElementType) {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot use %s as %s value in assignment", typeName(value), p.ElementType),
+		}
+	}
+
+	*p.Value = value
+	return value
+}
+
+func isAssignable(obj object.Object, typ *object.Type) bool {
+	if typ.Kind == object.BasicType {
+		switch typ.Name {
+		case "any", "comparable":
+			return true
+		case "error":
+			switch obj.(type) {
+			case *object.ErrorValue, *object.Nil:
+				return true
+			default:
+				return false
+			}
+		default:
+			return typeName(obj) == typ.Name
+		}
+	}
+	if _, ok := obj.(*object.Nil); ok {
+		return true
+	}
+	if typ.Kind == object.ChannelType {
+		ch, ok := obj.(*object.Channel)
+		return ok && ch.ElementType.Name == typ.Element.Name
+	}
+
+	return typeOf(obj).Name == typ.Name
+}
+
+func isNilValue(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Nil:
+		return true
+	case *object.Slice:
+		return obj.Elements == nil
+	case *object.Map:
+		return obj.Pairs == nil
+	case *object.Pointer:
+		return obj.Value == nil
+	default:
+		return false
+	}
+}
@@ -2,37 +2,321 @@ package evaluator
 
 import (
 	"fmt"
-	"log"
+	"sort"
 
 	"github.com/tomocy/kinako/object"
 )
 
-type Environment map[string]object.Object
-
-var builtins = Environment{
-	"true": &object.Boolean{
-		Value: true,
+var universe = map[string]object.Object{
+	"true":  object.NewBoolean(true),
+	"false": object.NewBoolean(false),
+	"nil":   &object.Nil{},
+	"iota":  object.NewInteger(0),
+	"any": &object.Type{
+		Kind: object.BasicType,
+		Name: "any",
+	},
+	"bool": &object.Type{
+		Kind: object.BasicType,
+		Name: "bool",
+	},
+	"comparable": &object.Type{
+		Kind: object.BasicType,
+		Name: "comparable",
+	},
+	"error": &object.Type{
+		Kind: object.BasicType,
+		Name: "error",
+	},
+	"int": &object.Type{
+		Kind: object.BasicType,
+		Name: "int",
+	},
+	"string": &object.Type{
+		Kind: object.BasicType,
+		Name: "string",
+	},
+	"append": &object.Builtin{
+		Name: "append",
+	},
+	"cap": &object.Builtin{
+		Name: "cap",
+	},
+	"clear": &object.Builtin{
+		Name: "clear",
+	},
+	"close": &object.Builtin{
+		Name: "close",
+	},
+	"copy": &object.Builtin{
+		Name: "copy",
+	},
+	"delete": &object.Builtin{
+		Name: "delete",
+	},
+	"len": &object.Builtin{
+		Name: "len",
 	},
-	"false": &object.Boolean{
-		Value: false,
+	"make": &object.Builtin{
+		Name: "make",
+	},
+	"max": &object.Builtin{
+		Name: "max",
+	},
+	"min": &object.Builtin{
+		Name: "min",
+	},
+	"new": &object.Builtin{
+		Name: "new",
+	},
+	"panic": &object.Builtin{
+		Name: "panic",
+	},
+	"print": &object.Builtin{
+		Name: "print",
+	},
+	"println": &object.Builtin{
+		Name: "println",
+	},
+	"recover": &object.Builtin{
+		Name: "recover",
 	},
 }
 
-func NewEnvironment() Environment {
-	env := make(Environment)
-	for name, obj := range builtins {
-		env[name] = obj
+var universeNames = func() []string {
+	names := make([]string, 0, len(universe))
+	for name := range universe {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return env
+	return names
+}()
+
+func PredeclaredNames() []string {
+	return append([]string(nil), universeNames...)
+}
+
+type Scope int
+
+const (
+	UniverseScope Scope = iota
+	PackageScope
+	FileScope
+	FunctionScope
+	BlockScope
+)
+
+func (s Scope) String() string {
+	switch s {
+	case UniverseScope:
+		return "universe"
+	case PackageScope:
+		return "package"
+	case FileScope:
+		return "file"
+	case FunctionScope:
+		return "function"
+	case BlockScope:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+type Environment struct {
+	scope   Scope
+	entries []entry
+	slots   map[string]int
+	outer   *Environment
+}
+
+type entry struct {
+	name  string
+	value object.Object
+}
+
+const maxUnindexedEntries = 8
+
+func NewEnvironment() *Environment {
+	env := newEnvironment(UniverseScope, nil)
+	for _, name := range universeNames {
+		env.set(name, universe[name])
+	}
+
+	return newEnvironment(FileScope, newEnvironment(PackageScope, env))
+}
+
+func NewEnclosedEnvironment(outer object.Environment) *Environment {
+	return newEnvironment(BlockScope, outer.(*Environment))
+}
+
+func NewFunctionEnvironment(outer object.Environment) *Environment {
+	return newEnvironment(FunctionScope, outer.(*Environment))
+}
+
+func newEnvironment(scope Scope, outer *Environment) *Environment {
+	return &Environment{
+		scope: scope,
+		outer: outer,
+	}
+}
+
+func (e *Environment) Scope() Scope {
+	return e.scope
+}
+
+func (e *Environment) Get(name string) (object.Object, bool) {
+	obj, _, ok := e.Lookup(name)
+	return obj, ok
+}
+
+func (e *Environment) Lookup(name string) (object.Object, *Environment, bool) {
+	for env := e; env != nil; env = env.outer {
+		if slot, ok := env.slotOf(name); ok {
+			return env.entries[slot].value, env, true
+		}
+	}
+
+	return nil, nil, false
 }
 
-func (e Environment) Set(name string, obj object.Object) error {
-	if _, ok := builtins[name]; ok {
-		log.Println(builtins)
-		return fmt.Errorf("cannot assign to %s", name)
+func (e *Environment) LookupSlot(depth, slot int) (object.Object, *Environment, bool) {
+	env := e
+	for i := 0; i < depth; i++ {
+		env = env.outer
+	}
+	if len(env.entries) <= slot {
+		return nil, nil, false
+	}
+
+	return env.entries[slot].value, env, true
+}
+
+func (e *Environment) names() []string {
+	names := make([]string, len(e.entries))
+	for i, entry := range e.entries {
+		names[i] = entry.name
+	}
+
+	return names
+}
+
+func (e *Environment) Declare(name string, obj object.Object) error {
+	if e.scope == FileScope {
+		return e.outer.Declare(name, obj)
+	}
+	if _, ok := e.slotOf(name); ok {
+		return fmt.Errorf("%s redeclared in this block", name)
 	}
 
-	e[name] = obj
+	e.set(name, obj)
 	return nil
 }
+
+func (e *Environment) Import(name string, pkg *object.Package) error {
+	env := e
+	for env.scope != FileScope {
+		if env.outer == nil {
+			return fmt.Errorf("imports must appear at file scope")
+		}
+		env = env.outer
+	}
+	if slot, ok := env.slotOf(name); ok {
+		if imported, ok := env.entries[slot].value.(*object.Package); ok && imported.Path == pkg.Path {
+			return nil
+		}
+		return fmt.Errorf("%s redeclared in this block", name)
+	}
+
+	env.set(name, pkg)
+	return nil
+}
+
+func (e *Environment) Assign(name string, obj object.Object) error {
+	return e.assign(name, obj, e.Lookup)
+}
+
+func (e *Environment) AssignSlot(depth, slot int, name string, obj object.Object) error {
+	return e.assign(name, obj, func(name string) (object.Object, *Environment, bool) {
+		return e.LookupSlot(depth, slot)
+	})
+}
+
+func (e *Environment) assign(name string, obj object.Object, lookup func(string) (object.Object, *Environment, bool)) error {
+	current, env, ok := lookup(name)
+	if !ok {
+		return fmt.Errorf("undefined variable: %s", name)
+	}
+	switch current.(type) {
+	case *object.Type, *object.GenericType:
+		return fmt.Errorf("%s (type) is not an expression", name)
+	case *object.Builtin:
+		return fmt.Errorf("%s (built-in function %s) must be called", name, name)
+	case *object.Package:
+		return fmt.Errorf("use of package %s without selector", name)
+	}
+	if env.scope == UniverseScope {
+		return fmt.Errorf("cannot assign to %s (neither addressable nor a map index expression)", name)
+	}
+
+	env.set(name, obj)
+	return nil
+}
+
+func (e *Environment) set(name string, obj object.Object) {
+	if slot, ok := e.slotOf(name); ok {
+		e.entries[slot].value = obj
+		return
+	}
+
+	e.entries = append(e.entries, entry{
+		name:  name,
+		value: obj,
+	})
+	switch {
+	case e.slots != nil:
+		e.slots[name] = len(e.entries) - 1
+	case maxUnindexedEntries < len(e.entries):
+		e.slots = make(map[string]int, len(e.entries))
+		for i, entry := range e.entries {
+			e.slots[entry.name] = i
+		}
+	}
+}
+
+func (e *Environment) slotOf(name string) (int, bool) {
+	if e.slots != nil {
+		slot, ok := e.slots[name]
+		return slot, ok
+	}
+	for i := range e.entries {
+		if e.entries[i].name == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (e *Environment) declares(name string) bool {
+	if e.scope == FileScope {
+		return e.outer.declares(name)
+	}
+
+	_, ok := e.slotOf(name)
+	return ok
+}
+
+func (e *Environment) clone() *Environment {
+	env := newEnvironment(e.scope, e.outer)
+	env.entries = append(make([]entry, 0, len(e.entries)), e.entries...)
+	if e.slots != nil {
+		env.slots = make(map[string]int, len(e.slots))
+		for name, slot := range e.slots {
+			env.slots[name] = slot
+		}
+	}
+
+	return env
+}
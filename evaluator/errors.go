@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"errors"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func init() {
+	anyType, errorType, stringType := basicType("any"), basicType("error"), basicType("string")
+	registerNativePackage(&nativePackage{
+		path: "errors",
+		functions: map[string]*nativeFunction{
+			"New": {
+				parameters: []*object.Type{stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newError(errors.New(stringOf(args[0])))
+				},
+			},
+			"Is": {
+				parameters: []*object.Type{errorType, errorType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newBoolean(errors.Is(errorOf(args[0]), errorOf(args[1])))
+				},
+			},
+			"As": {
+				parameters: []*object.Type{errorType, anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return errorAs(args[0], args[1])
+				},
+			},
+			"Unwrap": {
+				parameters: []*object.Type{errorType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newError(errors.Unwrap(errorOf(args[0])))
+				},
+			},
+			"Join": {
+				parameters: []*object.Type{errorType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					errs := make([]error, len(args))
+					for i, arg := range args {
+						errs[i] = errorOf(arg)
+					}
+					return newError(errors.Join(errs...))
+				},
+			},
+		},
+	})
+
+	nativeMethods["error"] = map[string]*nativeFunction{
+		"Error": {
+			call: func(e *Evaluator, args []object.Object) object.Object {
+				return newString(errorOf(args[0]).Error())
+			},
+		},
+	}
+}
+
+func errorAs(err, target object.Object) object.Object {
+	p, ok := target.(*object.Pointer)
+	if !ok || p.Value == nil {
+		panic("errors: target must be a non-nil pointer")
+	}
+	switch p.ElementType.Name {
+	case "error", "any":
+	default:
+		panic("errors: *target must be interface or implement error")
+	}
+	if _, ok := err.(*object.ErrorValue); !ok {
+		return newBoolean(false)
+	}
+
+	*p.Value = err
+	return newBoolean(true)
+}
@@ -2,31 +2,176 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
 
 	"github.com/tomocy/kinako/ast"
 	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/resolver"
+	"github.com/tomocy/kinako/token"
 )
 
 var zeroValues = map[string]object.Object{
-	"int": &object.Integer{
-		Value: 0,
-	},
-	"bool": &object.Boolean{
-		Value: false,
+	"int":  object.NewInteger(0),
+	"bool": object.NewBoolean(false),
+	"string": &object.String{
+		Value: "",
 	},
+	"any": &object.Nil{},
 }
 
 type Evaluator struct {
-	env Environment
+	env          *Environment
+	frame        *frame
+	scheduler    *scheduler
+	goroutine    *goroutine
+	seed         *int64
+	detectsRaces bool
+	race         *raceDetector
+	stdout       io.Writer
+	stderr       io.Writer
+	fieldAccess  func(reflect.Type, reflect.StructField) FieldAccess
+	methodAccess func(reflect.Type, reflect.Method) FieldAccess
+	spans        map[ast.Node]token.Span
+	budget       *budget
+	sandbox      *sandbox
+	code         *code
+	callbackErr  error
+}
+
+type Option func(*Evaluator)
+
+func WithSeed(seed int64) Option {
+	return func(e *Evaluator) {
+		e.seed = &seed
+	}
+}
+
+func WithRaceDetector() Option {
+	return func(e *Evaluator) {
+		e.detectsRaces = true
+	}
+}
+
+func WithStdout(w io.Writer) Option {
+	return func(e *Evaluator) {
+		e.stdout = w
+	}
+}
+
+func WithStderr(w io.Writer) Option {
+	return func(e *Evaluator) {
+		e.stderr = w
+	}
+}
+
+func WithFieldAccess(fn func(reflect.Type, reflect.StructField) FieldAccess) Option {
+	return func(e *Evaluator) {
+		e.fieldAccess = fn
+	}
+}
+
+func WithMethodAccess(fn func(reflect.Type, reflect.Method) FieldAccess) Option {
+	return func(e *Evaluator) {
+		e.methodAccess = fn
+	}
+}
+
+func New(opts ...Option) *Evaluator {
+	e := &Evaluator{
+		env:         NewEnvironment(),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		fieldAccess: defaultFieldAccess,
+		spans:       make(map[ast.Node]token.Span),
+		budget:      new(budget),
+		sandbox:     newSandbox(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.start()
+
+	return e
+}
+
+func (e *Evaluator) start() {
+	e.frame = &frame{
+		function: "main",
+	}
+	e.scheduler = newScheduler(e.seed)
+	e.goroutine = e.scheduler.main
+	e.budget.reset()
+	e.race = nil
+	if e.detectsRaces {
+		e.race = newRaceDetector(e.goroutine)
+	}
 }
 
-func New() *Evaluator {
-	return &Evaluator{
-		env: NewEnvironment(),
+func Explore(program *ast.Program, n int, opts ...Option) (int64, object.Object, bool) {
+	for seed := int64(0); seed < int64(n); seed++ {
+		obj := New(append(opts, WithSeed(seed))...).Evaluate(program)
+		if isError(obj) {
+			return seed, obj, true
+		}
 	}
+
+	return 0, nil, false
 }
 
 func (e *Evaluator) Evaluate(node ast.Node) object.Object {
+	var obj object.Object
+	if err := e.budget.step(); err != nil {
+		obj = err
+	} else {
+		obj = e.evaluate(node)
+	}
+
+	return e.locate(node, obj)
+}
+
+func (e *Evaluator) locate(node ast.Node, obj object.Object) object.Object {
+	span, ok := e.spans[node]
+	if !ok {
+		return obj
+	}
+
+	switch obj := obj.(type) {
+	case *object.Error:
+		if !obj.Position.IsValid() {
+			obj.Position, obj.End = span.Start, span.End
+			obj.Stack = e.stackTrace(span.Start)
+		}
+	case *object.Panic:
+		if !obj.Position.IsValid() {
+			obj.Position, obj.End = span.Start, span.End
+			obj.Stack = e.stackTrace(span.Start)
+		}
+	}
+
+	return obj
+}
+
+func (e *Evaluator) stackTrace(pos token.Position) []object.Frame {
+	var stack []object.Frame
+	for f := e.frame; f != nil; f = f.caller {
+		if f.function != "" {
+			stack = append(stack, object.Frame{
+				Function: f.function,
+				Position: pos,
+			})
+		}
+		if f.caller != nil {
+			pos = e.spans[f.caller.site].Start
+		}
+	}
+
+	return stack
+}
+
+func (e *Evaluator) evaluate(node ast.Node) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return e.evaluateProgram(node)
@@ -34,30 +179,220 @@ func (e *Evaluator) Evaluate(node ast.Node) object.Object {
 		return e.evaluateExpressionStatement(node)
 	case *ast.VariableDeclaration:
 		return e.evaluateVariableDeclaration(node)
+	case *ast.ShortVariableDeclaration:
+		return e.evaluateShortVariableDeclaration(node)
+	case *ast.AssignmentStatement:
+		return e.evaluateAssignmentStatement(node)
+	case *ast.IncDecStatement:
+		return e.evaluateIncDecStatement(node)
+	case *ast.SendStatement:
+		return e.evaluateSendStatement(node)
+	case *ast.GoStatement:
+		return e.evaluateGoStatement(node)
+	case *ast.ForStatement:
+		return e.evaluateForStatement(node)
+	case *ast.RangeStatement:
+		return e.evaluateRangeStatement(node)
+	case *ast.SelectStatement:
+		return e.evaluateSelectStatement(node)
+	case *ast.ContinueStatement:
+		return &object.Continue{
+			Statement: node,
+		}
+	case *ast.SwitchStatement:
+		return e.evaluateSwitchStatement(node)
+	case *ast.TypeSwitchStatement:
+		return e.evaluateTypeSwitchStatement(node)
+	case *ast.FallthroughStatement:
+		return &object.Fallthrough{
+			Statement: node,
+		}
+	case *ast.BreakStatement:
+		return &object.Break{
+			Statement: node,
+		}
+	case *ast.FunctionDeclaration:
+		return e.evaluateFunctionDeclaration(node)
+	case *ast.PackageClause:
+		return &object.Nil{}
+	case *ast.ImportDeclaration:
+		return e.evaluateImportDeclaration(node)
+	case *ast.TypeDeclaration:
+		return e.evaluateTypeDeclaration(node)
+	case *ast.ReturnStatement:
+		return e.evaluateReturnStatement(node)
+	case *ast.DeferStatement:
+		return e.evaluateDeferStatement(node)
 	case *ast.BadStatement:
 		return e.evaluateBadStatement(node)
 	case *ast.PrefixExpression:
 		return e.evaluatePrefixExpression(node)
 	case *ast.InfixExpression:
 		return e.evaluateInfixExpression(node)
+	case *ast.SelectorExpression:
+		return e.evaluateSelectorExpression(node)
+	case *ast.TypeAssertionExpression:
+		return e.evaluateTypeAssertionExpression(node)
+	case *ast.IndexExpression:
+		return e.evaluateIndexExpression(node)
+	case *ast.SliceExpression:
+		return e.evaluateSliceExpression(node)
+	case *ast.CallExpression:
+		return e.evaluateCallExpression(node)
+	case *ast.FunctionLiteral:
+		return e.evaluateFunctionLiteral(node)
 	case *ast.Identifier:
 		return e.evaluateIdentifier(node)
 	case *ast.Integer:
 		return e.evaluateInteger(node)
+	case *ast.String:
+		return e.evaluateString(node)
+	case *ast.CompositeLiteral:
+		return e.evaluateCompositeLiteral(node)
+	case *ast.SliceType:
+		return e.evaluateType(node)
+	case *ast.MapType:
+		return e.evaluateType(node)
+	case *ast.PointerType:
+		return e.evaluateType(node)
+	case *ast.ChannelType:
+		return e.evaluateType(node)
+	case *ast.InterfaceType:
+		return e.evaluateType(node)
 	default:
 		return nil
 	}
 }
 
 func (e *Evaluator) evaluateProgram(node *ast.Program) object.Object {
+	return e.runProgram(node, func(stmt ast.Statement) object.Object {
+		return e.Evaluate(stmt)
+	})
+}
+
+func (e *Evaluator) runProgram(node *ast.Program, evaluate func(ast.Statement) object.Object) object.Object {
+	for n, pos := range node.Positions {
+		e.spans[n] = token.Span{
+			Start: pos,
+			End:   node.Ends[n],
+		}
+	}
+	e.start()
+	defer e.scheduler.shutdown()
+
 	var obj object.Object
 	for _, stmt := range node.Statements {
-		obj = e.Evaluate(stmt)
+		obj = evaluate(stmt)
+		if err := e.checkUnexpectedControl(obj); err != nil {
+			return err
+		}
+		if isError(obj) {
+			break
+		}
+		if ret, ok := obj.(*object.ReturnValue); ok {
+			obj = ret.Value
+			break
+		}
+	}
+	if isMainPackage(node) && !isError(obj) {
+		obj = e.callMain()
+	}
+
+	if p, ok := obj.(*object.Panic); ok {
+		e.frame.panic = p
+		obj = &object.Nil{}
+	}
+	if err := e.runDeferredCalls(); err != nil {
+		return err
+	}
+	if e.frame.panic != nil {
+		return panicError(e.frame.panic)
 	}
 
 	return obj
 }
 
+func isMainPackage(node *ast.Program) bool {
+	if len(node.Statements) == 0 {
+		return false
+	}
+	pkg, ok := node.Statements[0].(*ast.PackageClause)
+	return ok && pkg.Name.Name == "main"
+}
+
+func (e *Evaluator) callMain() object.Object {
+	obj, _ := e.env.Get("main")
+	fn, ok := obj.(*object.Function)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: "function main is undeclared in the main package",
+		}
+	}
+
+	e.frame.function = ""
+	return e.callFunction(fn, nil, nil)
+}
+
+func panicError(p *object.Panic) *object.Error {
+	return &object.Error{
+		Kind:     p.Kind(),
+		Message:  p.String(),
+		Position: p.Position,
+		End:      p.End,
+		Stack:    p.Stack,
+	}
+}
+
+func (e *Evaluator) Resolve(node *ast.Program) *object.Error {
+	if e.env.scope != FileScope {
+		return nil
+	}
+
+	var opts []resolver.Option
+	for env := e.env; env != nil; env = env.outer {
+		if env.scope == PackageScope {
+			opts = append(opts, resolver.WithPackage(env.names()...))
+			continue
+		}
+		opts = append(opts, resolver.WithPredeclared(env.names()...))
+	}
+	r := resolver.New(opts...)
+	if err := r.Resolve(node); err != nil {
+		err := err.(*resolver.Error)
+		return &object.Error{
+			Kind:     object.ErrName,
+			Message:  err.Message,
+			Position: err.Position,
+			End:      err.End,
+		}
+	}
+
+	return e.checkMembers(node, r.Members())
+}
+
+func (e *Evaluator) checkUnexpectedControl(obj object.Object) *object.Error {
+	var node ast.Node
+	var msg string
+	switch obj := obj.(type) {
+	case *object.Break:
+		node, msg = obj.Statement, "break is not in a loop, switch, or select"
+	case *object.Fallthrough:
+		node, msg = obj.Statement, "fallthrough statement out of place"
+	case *object.Continue:
+		node, msg = obj.Statement, "continue is not in a loop"
+	default:
+		return nil
+	}
+
+	err := &object.Error{
+		Kind:    object.ErrType,
+		Message: msg,
+	}
+	e.locate(node, err)
+	return err
+}
+
 func (e *Evaluator) evaluateExpressionStatement(node *ast.ExpressionStatement) object.Object {
 	return e.Evaluate(node.Expression)
 }
@@ -65,13 +400,21 @@ func (e *Evaluator) evaluateExpressionStatement(node *ast.ExpressionStatement) o
 func (e *Evaluator) evaluateVariableDeclaration(node *ast.VariableDeclaration) object.Object {
 	var obj object.Object
 	if node.Expression == nil {
-		obj = zeroValues[node.Type.Name]
+		obj = e.zeroValueOf(node.Type)
 	} else {
 		obj = e.Evaluate(node.Expression)
 	}
+	if isError(obj) {
+		return obj
+	}
+
+	return e.declareVariable(node, obj)
+}
 
-	if err := e.env.Set(node.Identifier.Name, obj); err != nil {
+func (e *Evaluator) declareVariable(node *ast.VariableDeclaration, obj object.Object) object.Object {
+	if err := e.env.Declare(node.Identifier.Name, obj); err != nil {
 		return &object.Error{
+			Kind:    object.ErrName,
 			Message: err.Error(),
 		}
 	}
@@ -79,96 +422,1723 @@ func (e *Evaluator) evaluateVariableDeclaration(node *ast.VariableDeclaration) o
 	return obj
 }
 
-func (e *Evaluator) evaluateBadStatement(node *ast.BadStatement) object.Object {
-	return &object.Error{
-		Message: node.Message,
+func (e *Evaluator) evaluateShortVariableDeclaration(node *ast.ShortVariableDeclaration) object.Object {
+	if err := e.checkShortVariableDeclaration(node); err != nil {
+		return err
 	}
+	objs := e.evaluateAssignedValues(len(node.Identifiers), node.Expressions)
+	if len(objs) == 1 && isError(objs[0]) {
+		return objs[0]
+	}
+
+	return e.declareShortVariables(node, objs)
 }
 
-func (e *Evaluator) evaluatePrefixExpression(node *ast.PrefixExpression) object.Object {
-	obj := e.Evaluate(node.RExpression)
-	switch node.Operator {
-	case ast.Negative:
-		return e.evaluateNegativeInteger(obj.(*object.Integer))
-	case ast.Not:
-		return e.evaluateNOT(obj.(*object.Boolean))
-	default:
-		panic(fmt.Sprintf("failed to assert prefix operator type because of developer. contact him or her to inform the missing type is %s", node.Operator))
+func (e *Evaluator) declareShortVariables(node *ast.ShortVariableDeclaration, objs []object.Object) object.Object {
+	for i, ident := range node.Identifiers {
+		if ident.Name == "_" {
+			continue
+		}
+		if !e.env.declares(ident.Name) {
+			if err := e.env.Declare(ident.Name, objs[i]); err != nil {
+				return &object.Error{
+					Kind:    object.ErrName,
+					Message: err.Error(),
+				}
+			}
+			continue
+		}
+
+		if err := e.assignIdentifier(ident, objs[i]); err != nil {
+			return &object.Error{
+				Kind:    object.ErrName,
+				Message: err.Error(),
+			}
+		}
+		if err := e.detectRace(ident, true); err != nil {
+			return err
+		}
 	}
+
+	return objs[0]
 }
 
-func (e *Evaluator) evaluateNegativeInteger(obj *object.Integer) *object.Integer {
-	return &object.Integer{
-		Value: -1 * obj.Value,
+func (e *Evaluator) checkShortVariableDeclaration(node *ast.ShortVariableDeclaration) *object.Error {
+	seen := make(map[string]bool)
+	hasNew := false
+	for _, ident := range node.Identifiers {
+		if ident.Name == "_" {
+			continue
+		}
+		if seen[ident.Name] {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("%s repeated on left side of :=", ident.Name),
+			}
+		}
+		seen[ident.Name] = true
+		hasNew = hasNew || !e.env.declares(ident.Name)
+	}
+	if !hasNew {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: "no new variables on left side of :=",
+		}
 	}
+
+	return nil
 }
 
-func (e *Evaluator) evaluateNOT(obj *object.Boolean) *object.Boolean {
-	return &object.Boolean{
-		Value: !obj.Value,
+func (e *Evaluator) evaluateAssignmentStatement(node *ast.AssignmentStatement) object.Object {
+	objs := e.evaluateAssignedValues(len(node.LExpressions), node.RExpressions)
+	if len(objs) == 1 && isError(objs[0]) {
+		return objs[0]
 	}
+
+	return e.assign(node.LExpressions, objs)
 }
 
-func (e *Evaluator) evaluateInfixExpression(node *ast.InfixExpression) object.Object {
-	left := e.Evaluate(node.LExpression)
-	right := e.Evaluate(node.RExpression)
-	switch node.Operator {
-	case ast.Plus:
-		return e.evaluateAddition(left, right)
-	case ast.Minus:
-		return e.evaluateSubtraction(left, right)
-	case ast.Asterisk:
-		return e.evaluateMultiplication(left, right)
-	case ast.Slash:
-		return e.evaluateDivision(left, right)
-	default:
-		panic(fmt.Sprintf("failed to assert infix operator type because of developer. contact him or her to inform the missing type is %s", node.Operator))
+func (e *Evaluator) evaluateAssignedValues(n int, exprs []ast.Expression) []object.Object {
+	if call, ok := exprs[0].(*ast.CallExpression); ok && len(exprs) == 1 {
+		return e.evaluateCallResults(n, call)
+	}
+	if len(exprs) == n {
+		return e.evaluateExpressions(exprs)
+	}
+	if n == 2 && len(exprs) == 1 {
+		return e.evaluateCommaOk(exprs[0])
+	}
+
+	return []object.Object{
+		&object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("assignment mismatch: %s but %s", pluralize(n, "variable"), pluralize(len(exprs), "value")),
+		},
 	}
 }
 
-func (e *Evaluator) evaluateAddition(left, right object.Object) object.Object {
-	return &object.Integer{
-		Value: left.(*object.Integer).Value + right.(*object.Integer).Value,
+func (e *Evaluator) evaluateCallResults(n int, call *ast.CallExpression) []object.Object {
+	obj := e.Evaluate(call)
+	if isError(obj) {
+		return []object.Object{obj}
+	}
+
+	values := []object.Object{obj}
+	if tuple, ok := obj.(*object.Tuple); ok {
+		values = tuple.Values
+	}
+	if len(values) != n {
+		return []object.Object{
+			&object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("assignment mismatch: %s but %s returns %s", pluralize(n, "variable"), ast.Format(call.Function), pluralize(len(values), "value")),
+			},
+		}
 	}
+
+	return values
 }
 
-func (e *Evaluator) evaluateSubtraction(left, right object.Object) object.Object {
-	return &object.Integer{
-		Value: left.(*object.Integer).Value - right.(*object.Integer).Value,
+func (e *Evaluator) evaluateCommaOk(expr ast.Expression) []object.Object {
+	var value object.Object
+	var ok bool
+	switch expr := expr.(type) {
+	case *ast.PrefixExpression:
+		if expr.Operator != ast.Receive {
+			break
+		}
+
+		ch := e.Evaluate(expr.RExpression)
+		if isError(ch) {
+			return []object.Object{ch}
+		}
+		var err object.Object
+		value, ok, err = e.receive(ch)
+		if err != nil {
+			return []object.Object{err}
+		}
+	case *ast.TypeAssertionExpression:
+		if expr.Type == nil {
+			break
+		}
+
+		value = e.Evaluate(expr.Expression)
+		if isError(value) {
+			return []object.Object{value}
+		}
+		var err object.Object
+		ok, err = e.hasType(value, expr.Type)
+		if err != nil {
+			return []object.Object{err}
+		}
+		if !ok {
+			value = e.zeroValueOf(expr.Type)
+		}
+	case *ast.IndexExpression:
+		m, isMap := e.Evaluate(expr.Expression).(*object.Map)
+		if !isMap || len(expr.Indices) != 1 {
+			break
+		}
+
+		key := e.Evaluate(expr.Indices[0])
+		if isError(key) {
+			return []object.Object{key}
+		}
+		var err object.Object
+		value, ok, err = e.lookUpMap(expr.Indices[0], m, key)
+		if err != nil {
+			return []object.Object{err}
+		}
+	}
+
+	if value == nil {
+		return []object.Object{
+			&object.Error{
+				Kind:    object.ErrType,
+				Message: "assignment mismatch: 2 variables but 1 value",
+			},
+		}
+	}
+
+	return []object.Object{
+		value,
+		object.NewBoolean(ok),
 	}
 }
 
-func (e *Evaluator) evaluateMultiplication(left, right object.Object) object.Object {
-	return &object.Integer{
-		Value: left.(*object.Integer).Value * right.(*object.Integer).Value,
+func (e *Evaluator) assign(exprs []ast.Expression, objs []object.Object) object.Object {
+	for i, expr := range exprs {
+		switch expr := expr.(type) {
+		case *ast.IndexExpression:
+			if obj := e.assignIndex(expr, objs[i]); isError(obj) {
+				return obj
+			}
+			continue
+		case *ast.SelectorExpression:
+			if obj := e.assignSelector(expr, objs[i]); isError(obj) {
+				return obj
+			}
+			continue
+		case *ast.PrefixExpression:
+			if expr.Operator != ast.Dereference {
+				break
+			}
+			if obj := e.assignIndirect(expr, objs[i]); isError(obj) {
+				return obj
+			}
+			continue
+		}
+
+		ident, ok := expr.(*ast.Identifier)
+		if !ok {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot assign to %T", expr),
+			}
+		}
+		if ident.Name == "_" {
+			continue
+		}
+
+		if err := e.assignIdentifier(ident, objs[i]); err != nil {
+			return &object.Error{
+				Kind:    object.ErrName,
+				Message: err.Error(),
+			}
+		}
+		if err := e.detectRace(ident, true); err != nil {
+			return err
+		}
 	}
+
+	return objs[0]
 }
 
-func (e *Evaluator) evaluateDivision(left, right object.Object) object.Object {
-	rightVal := right.(*object.Integer).Value
-	if rightVal == 0 {
+func (e *Evaluator) evaluateIncDecStatement(node *ast.IncDecStatement) object.Object {
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
+		return obj
+	}
+
+	return e.incDec(node, obj)
+}
+
+func (e *Evaluator) incDec(node *ast.IncDecStatement, obj object.Object) object.Object {
+	var delta int64 = 1
+	if node.Operator == ast.Decrement {
+		delta = -1
+	}
+	obj = e.evaluateAddition(obj, object.NewInteger(delta))
+	if isError(obj) {
 		return &object.Error{
-			Message: "divided by zero",
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: %s (non-numeric type)", node.Operator),
 		}
 	}
 
-	return &object.Integer{
-		Value: left.(*object.Integer).Value / rightVal,
+	return e.assign([]ast.Expression{node.Expression}, []object.Object{obj})
+}
+
+func (e *Evaluator) evaluateSendStatement(node *ast.SendStatement) object.Object {
+	ch := e.Evaluate(node.Channel)
+	if isError(ch) {
+		return ch
+	}
+	value := e.Evaluate(node.Expression)
+	if isError(value) {
+		return value
+	}
+
+	if err := e.send(ch, value); err != nil {
+		return err
 	}
+
+	return value
 }
 
-func (e *Evaluator) evaluateIdentifier(node *ast.Identifier) object.Object {
-	if obj, ok := e.env[node.Name]; ok {
+func (e *Evaluator) evaluateGoStatement(node *ast.GoStatement) object.Object {
+	fn := e.Evaluate(node.Call.Function)
+	if isError(fn) {
+		return fn
+	}
+	args := e.evaluateExpressions(node.Call.Arguments)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	env, s, race, stdout, stderr, fieldAccess, spans, budget := e.env, e.scheduler, e.race, e.stdout, e.stderr, e.fieldAccess, e.spans, e.budget
+	methodAccess, sandbox, code := e.methodAccess, e.sandbox, e.code
+	g := s.spawn(func(g *goroutine) *object.Error {
+		child := &Evaluator{
+			env:          env,
+			frame:        new(frame),
+			scheduler:    s,
+			goroutine:    g,
+			race:         race,
+			stdout:       stdout,
+			stderr:       stderr,
+			fieldAccess:  fieldAccess,
+			methodAccess: methodAccess,
+			spans:        spans,
+			budget:       budget,
+			sandbox:      sandbox,
+			code:         code,
+		}
+
+		switch obj := child.call(node.Call, fn, args).(type) {
+		case *object.Panic:
+			return panicError(obj)
+		case *object.Error:
+			return obj
+		}
+
+		return nil
+	})
+	if race != nil {
+		race.fork(e.goroutine, g)
+	}
+
+	return &object.Nil{}
+}
+
+func (e *Evaluator) evaluateForStatement(node *ast.ForStatement) object.Object {
+	return e.withEnvironment(NewEnclosedEnvironment(e.env), func() object.Object {
+		if node.Initializer != nil {
+			if obj := e.Evaluate(node.Initializer); isError(obj) {
+				return obj
+			}
+		}
+
+		for {
+			if node.Condition != nil {
+				cond := e.Evaluate(node.Condition)
+				if isError(cond) {
+					return cond
+				}
+				ok, err := e.condition(cond)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return &object.Nil{}
+				}
+			}
+
+			obj := e.evaluateBlockStatement(node.Body)
+			if done, obj := isLoopDone(obj); done {
+				return obj
+			}
+
+			e.env = e.env.clone()
+			if node.Post != nil {
+				if obj := e.Evaluate(node.Post); isError(obj) {
+					return obj
+				}
+			}
+
+			if obj := e.yield(); obj != nil {
+				return obj
+			}
+		}
+	})
+}
+
+func (e *Evaluator) evaluateRangeStatement(node *ast.RangeStatement) object.Object {
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
 		return obj
 	}
+	switch obj.(type) {
+	case *object.Integer, *object.Channel, *object.Nil:
+		if node.Value != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("range over %s permits only one iteration variable", typeName(obj)),
+			}
+		}
+	}
 
-	return &object.Error{
-		Message: fmt.Sprintf("undefined variable: %s", node.Name),
+	switch obj := obj.(type) {
+	case *object.Integer:
+		for i := int64(0); i < obj.Value; i++ {
+			result := e.evaluateRangeIteration(node, object.NewInteger(i), nil)
+			if done, result := isLoopDone(result); done {
+				return result
+			}
+			if !e.scheduler.yield(e.goroutine) {
+				return e.scheduler.abortion()
+			}
+		}
+
+		return &object.Nil{}
+	case *object.Channel, *object.Nil:
+		for {
+			value, ok, err := e.receive(obj)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return &object.Nil{}
+			}
+
+			result := e.evaluateRangeIteration(node, value, nil)
+			if done, result := isLoopDone(result); done {
+				return result
+			}
+			if !e.scheduler.yield(e.goroutine) {
+				return e.scheduler.abortion()
+			}
+		}
+	case *object.Slice:
+		elems := obj.Elements
+		for i, elem := range elems {
+			if node.Value != nil {
+				if err := e.detectElementRace(node.Expression, obj, i, ast.Format(node.Expression), false); err != nil {
+					return err
+				}
+			}
+			result := e.evaluateRangeIteration(node, object.NewInteger(int64(i)), elem)
+			if done, result := isLoopDone(result); done {
+				return result
+			}
+			if !e.scheduler.yield(e.goroutine) {
+				return e.scheduler.abortion()
+			}
+		}
+
+		return &object.Nil{}
+	case *object.String:
+		for i, r := range obj.Value {
+			result := e.evaluateRangeIteration(node, object.NewInteger(int64(i)), object.NewInteger(int64(r)))
+			if done, result := isLoopDone(result); done {
+				return result
+			}
+			if !e.scheduler.yield(e.goroutine) {
+				return e.scheduler.abortion()
+			}
+		}
+
+		return &object.Nil{}
+	case *object.Map:
+		for _, pair := range obj.SortedPairs() {
+			hash, _ := hashKeyOf(pair.Key)
+			if _, ok := obj.Pairs[hash]; !ok {
+				continue
+			}
+			if err := e.detectMapRace(node.Expression, obj, ast.Format(node.Expression), false); err != nil {
+				return err
+			}
+
+			result := e.evaluateRangeIteration(node, pair.Key, pair.Value)
+			if done, result := isLoopDone(result); done {
+				return result
+			}
+			if !e.scheduler.yield(e.goroutine) {
+				return e.scheduler.abortion()
+			}
+		}
+
+		return &object.Nil{}
+	case *object.HostValue:
+		entries, err := hostEntriesOf(obj)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			result := e.evaluateRangeIteration(node, entry.key, entry.value)
+			if done, result := isLoopDone(result); done {
+				return result
+			}
+			if !e.scheduler.yield(e.goroutine) {
+				return e.scheduler.abortion()
+			}
+		}
+
+		return &object.Nil{}
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot range over %s", typeName(obj)),
+		}
 	}
 }
 
-func (e *Evaluator) evaluateInteger(node *ast.Integer) *object.Integer {
-	return &object.Integer{
-		Value: node.Value,
+func (e *Evaluator) evaluateRangeIteration(node *ast.RangeStatement, key, value object.Object) object.Object {
+	env := NewEnclosedEnvironment(e.env)
+	for _, v := range []struct {
+		ident *ast.Identifier
+		obj   object.Object
+	}{{node.Key, key}, {node.Value, value}} {
+		if v.ident == nil || v.ident.Name == "_" {
+			continue
+		}
+
+		var err error
+		if node.IsDefine {
+			err = env.Declare(v.ident.Name, v.obj)
+		} else {
+			err = e.env.Assign(v.ident.Name, v.obj)
+		}
+		if err != nil {
+			return &object.Error{
+				Kind:    object.ErrName,
+				Message: err.Error(),
+			}
+		}
+		if !node.IsDefine {
+			if err := e.detectRace(v.ident, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.withEnvironment(env, func() object.Object {
+		return e.evaluateBlockStatement(node.Body)
+	})
+}
+
+func (e *Evaluator) condition(obj object.Object) (bool, *object.Error) {
+	b, ok := obj.(*object.Boolean)
+	if !ok {
+		return false, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("non-boolean condition in for statement: %s", typeName(obj)),
+		}
+	}
+
+	return b.Value, nil
+}
+
+func (e *Evaluator) yield() object.Object {
+	if err := e.budget.step(); err != nil {
+		return err
+	}
+	if !e.scheduler.yield(e.goroutine) {
+		return e.scheduler.abortion()
+	}
+
+	return nil
+}
+
+func isLoopDone(obj object.Object) (bool, object.Object) {
+	switch obj.(type) {
+	case *object.Break:
+		return true, &object.Nil{}
+	case *object.ReturnValue:
+		return true, obj
+	default:
+		if isError(obj) {
+			return true, obj
+		}
+
+		return false, nil
+	}
+}
+
+func (e *Evaluator) evaluateBlockStatement(node *ast.BlockStatement) object.Object {
+	return e.withEnvironment(NewEnclosedEnvironment(e.env), func() object.Object {
+		return e.evaluateStatements(node.Statements)
+	})
+}
+
+func (e *Evaluator) withEnvironment(env *Environment, evaluate func() object.Object) object.Object {
+	outer := e.env
+	e.env = env
+	defer func() {
+		e.env = outer
+	}()
+
+	return evaluate()
+}
+
+func (e *Evaluator) evaluateSwitchStatement(node *ast.SwitchStatement) object.Object {
+	return e.withEnvironment(NewEnclosedEnvironment(e.env), func() object.Object {
+		if node.Initializer != nil {
+			if obj := e.Evaluate(node.Initializer); isError(obj) {
+				return obj
+			}
+		}
+
+		var tag object.Object = object.NewBoolean(true)
+		if node.Tag != nil {
+			tag = e.Evaluate(node.Tag)
+			if isError(tag) {
+				return tag
+			}
+		}
+
+		matched, obj := e.findMatchedCaseClause(node.Clauses, func(expr ast.Expression) (bool, object.Object) {
+			obj := e.Evaluate(expr)
+			if isError(obj) {
+				return false, obj
+			}
+
+			obj = e.evaluateEqual(tag, obj)
+			if isError(obj) {
+				return false, obj
+			}
+
+			return obj.(*object.Boolean).Value, nil
+		})
+		if obj != nil {
+			return obj
+		}
+
+		return e.evaluateCaseClauses(node.Clauses[matched:], nil)
+	})
+}
+
+func (e *Evaluator) evaluateTypeSwitchStatement(node *ast.TypeSwitchStatement) object.Object {
+	return e.withEnvironment(NewEnclosedEnvironment(e.env), func() object.Object {
+		if node.Initializer != nil {
+			if obj := e.Evaluate(node.Initializer); isError(obj) {
+				return obj
+			}
+		}
+
+		obj := e.Evaluate(node.Expression)
+		if isError(obj) {
+			return obj
+		}
+
+		matched, err := e.findMatchedCaseClause(node.Clauses, func(expr ast.Expression) (bool, object.Object) {
+			return e.hasType(obj, expr.(*ast.Identifier))
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(node.Clauses) <= matched {
+			return &object.Nil{}
+		}
+
+		return e.evaluateCaseClauses(node.Clauses[matched:matched+1], func(env *Environment) {
+			if node.Identifier != nil {
+				env.set(node.Identifier.Name, obj)
+			}
+		})
+	})
+}
+
+func (e *Evaluator) findMatchedCaseClause(clauses []*ast.CaseClause, match func(ast.Expression) (bool, object.Object)) (int, object.Object) {
+	defaultIndex := len(clauses)
+	for i, clause := range clauses {
+		if clause.IsDefault {
+			defaultIndex = i
+			continue
+		}
+
+		for _, expr := range clause.Expressions {
+			matched, err := match(expr)
+			if err != nil {
+				return 0, err
+			}
+			if matched {
+				return i, nil
+			}
+		}
+	}
+
+	return defaultIndex, nil
+}
+
+func (e *Evaluator) evaluateCaseClauses(clauses []*ast.CaseClause, bind func(*Environment)) object.Object {
+	var result object.Object = &object.Nil{}
+	for _, clause := range clauses {
+		env := NewEnclosedEnvironment(e.env)
+		if bind != nil {
+			bind(env)
+		}
+
+		obj := e.withEnvironment(env, func() object.Object {
+			return e.evaluateStatements(clause.Statements)
+		})
+		switch obj.(type) {
+		case *object.Fallthrough:
+			continue
+		case *object.Break:
+			return result
+		case *object.ReturnValue, *object.Continue:
+			return obj
+		}
+		if isError(obj) {
+			return obj
+		}
+		if obj != nil {
+			result = obj
+		}
+
+		return result
+	}
+
+	return result
+}
+
+func (e *Evaluator) evaluateStatements(stmts []ast.Statement) object.Object {
+	var obj object.Object
+	for _, stmt := range stmts {
+		obj = e.Evaluate(stmt)
+		switch obj.(type) {
+		case *object.Break, *object.Continue, *object.Fallthrough, *object.ReturnValue:
+			return obj
+		}
+		if isError(obj) {
+			return obj
+		}
+	}
+
+	return obj
+}
+
+func (e *Evaluator) evaluateFunctionDeclaration(node *ast.FunctionDeclaration) object.Object {
+	fn := &object.Function{
+		Name:           node.Identifier.Name,
+		TypeParameters: node.TypeParameters,
+		Parameters:     node.Parameters,
+		Results:        node.Results,
+		Body:           node.Body,
+		Env:            e.env,
+	}
+	if err := e.env.Declare(node.Identifier.Name, fn); err != nil {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: err.Error(),
+		}
+	}
+
+	return fn
+}
+
+func (e *Evaluator) evaluateTypeDeclaration(node *ast.TypeDeclaration) object.Object {
+	var typ object.Object = &object.GenericType{
+		Name:       node.Identifier.Name,
+		Parameters: node.TypeParameters,
+		Type:       node.Type,
+		Env:        e.env,
+	}
+	if len(node.TypeParameters) == 0 {
+		typ = e.evaluateType(node.Type)
+		if isError(typ) {
+			return typ
+		}
+	}
+
+	if err := e.env.Declare(node.Identifier.Name, typ); err != nil {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: err.Error(),
+		}
+	}
+
+	return typ
+}
+
+func (e *Evaluator) evaluateReturnStatement(node *ast.ReturnStatement) object.Object {
+	ret := new(object.ReturnValue)
+	if node.Expression == nil {
+		return ret
+	}
+
+	ret.Value = e.Evaluate(node.Expression)
+	if isError(ret.Value) {
+		return ret.Value
+	}
+
+	return ret
+}
+
+func (e *Evaluator) evaluateDeferStatement(node *ast.DeferStatement) object.Object {
+	fn := e.Evaluate(node.Call.Function)
+	if isError(fn) {
+		return fn
+	}
+	args := e.evaluateExpressions(node.Call.Arguments)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	e.frame.pushDeferredCall(node.Call, fn, args)
+
+	return &object.Nil{}
+}
+
+func (e *Evaluator) hasType(obj object.Object, typ *ast.Identifier) (bool, object.Object) {
+	if typ.Name == "nil" {
+		_, ok := obj.(*object.Nil)
+		return ok, nil
+	}
+
+	t := e.evaluateType(typ)
+	if isError(t) {
+		return false, t
+	}
+	if t.(*object.Type).Name == "any" {
+		_, ok := obj.(*object.Nil)
+		return !ok, nil
+	}
+
+	return typeName(obj) == t.(*object.Type).Name, nil
+}
+
+func (e *Evaluator) evaluateBadStatement(node *ast.BadStatement) object.Object {
+	return &object.Error{
+		Kind:    object.ErrSyntax,
+		Message: node.Message,
+	}
+}
+
+func (e *Evaluator) evaluatePrefixExpression(node *ast.PrefixExpression) object.Object {
+	obj := e.Evaluate(node.RExpression)
+	if isError(obj) {
+		return obj
+	}
+
+	switch node.Operator {
+	case ast.Dereference:
+		return e.dereference(node.RExpression, obj)
+	case ast.Receive:
+		value, _, err := e.receive(obj)
+		if err != nil {
+			return err
+		}
+		return value
+	default:
+		return e.operatePrefix(node.Operator, obj)
+	}
+}
+
+func (e *Evaluator) operatePrefix(op ast.PrefixOperator, obj object.Object) object.Object {
+	switch op {
+	case ast.Negative:
+		return e.evaluateNegativeInteger(obj.(*object.Integer))
+	case ast.Not:
+		return e.evaluateNOT(obj.(*object.Boolean))
+	default:
+		panic(fmt.Sprintf("failed to assert prefix operator type because of developer. contact him or her to inform the missing type is %s", op))
+	}
+}
+
+func (e *Evaluator) evaluateNegativeInteger(obj *object.Integer) *object.Integer {
+	return object.NewInteger(-1 * obj.Value)
+}
+
+func (e *Evaluator) evaluateNOT(obj *object.Boolean) *object.Boolean {
+	return object.NewBoolean(!obj.Value)
+}
+
+func (e *Evaluator) evaluateInfixExpression(node *ast.InfixExpression) object.Object {
+	left := e.Evaluate(node.LExpression)
+	if isError(left) {
+		return left
+	}
+	right := e.Evaluate(node.RExpression)
+	if isError(right) {
+		return right
+	}
+
+	return e.operate(node.Operator, left, right)
+}
+
+func (e *Evaluator) operate(op ast.InfixOperator, left, right object.Object) object.Object {
+	switch op {
+	case ast.Plus:
+		return e.evaluateAddition(left, right)
+	case ast.Minus:
+		return e.evaluateSubtraction(left, right)
+	case ast.Asterisk:
+		return e.evaluateMultiplication(left, right)
+	case ast.Slash:
+		return e.evaluateDivision(left, right)
+	case ast.Equal:
+		return e.evaluateEqual(left, right)
+	case ast.NotEqual:
+		return e.evaluateNotEqual(left, right)
+	case ast.LessThan, ast.GreaterThan, ast.LessThanOrEqual, ast.GreaterThanOrEqual:
+		return e.evaluateComparison(op, left, right)
+	default:
+		panic(fmt.Sprintf("failed to assert infix operator type because of developer. contact him or her to inform the missing type is %s", op))
+	}
+}
+
+func (e *Evaluator) evaluateAddition(left, right object.Object) object.Object {
+	if leftStr, ok := left.(*object.String); ok {
+		rightStr, ok := right.(*object.String)
+		if !ok {
+			return mismatchedTypesError(left, right)
+		}
+
+		if err := e.budget.allocate(int64(len(leftStr.Value) + len(rightStr.Value))); err != nil {
+			return err
+		}
+		return &object.String{
+			Value: leftStr.Value + rightStr.Value,
+		}
+	}
+
+	return e.evaluateIntegerArithmetic(ast.Plus, left, right, func(left, right int64) int64 {
+		return left + right
+	})
+}
+
+func (e *Evaluator) evaluateSubtraction(left, right object.Object) object.Object {
+	return e.evaluateIntegerArithmetic(ast.Minus, left, right, func(left, right int64) int64 {
+		return left - right
+	})
+}
+
+func (e *Evaluator) evaluateMultiplication(left, right object.Object) object.Object {
+	return e.evaluateIntegerArithmetic(ast.Asterisk, left, right, func(left, right int64) int64 {
+		return left * right
+	})
+}
+
+func (e *Evaluator) evaluateIntegerArithmetic(op ast.InfixOperator, left, right object.Object, calculate func(int64, int64) int64) object.Object {
+	leftInt, ok := left.(*object.Integer)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("operator %s not defined on %s", op, typeName(left)),
+		}
+	}
+	rightInt, ok := right.(*object.Integer)
+	if !ok {
+		return mismatchedTypesError(left, right)
+	}
+
+	return object.NewInteger(calculate(leftInt.Value, rightInt.Value))
+}
+
+func (e *Evaluator) evaluateDivision(left, right object.Object) object.Object {
+	if rightInt, ok := right.(*object.Integer); ok && rightInt.Value == 0 {
+		if _, ok := left.(*object.Integer); ok {
+			return &object.Panic{
+				Value: &object.RuntimeError{
+					Kind:    object.ErrDivisionByZero,
+					Message: "runtime error: integer divide by zero",
+				},
+			}
+		}
+	}
+
+	return e.evaluateIntegerArithmetic(ast.Slash, left, right, func(left, right int64) int64 {
+		return left / right
+	})
+}
+
+func (e *Evaluator) evaluateEqual(left, right object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Integer:
+		if right, ok := right.(*object.Integer); ok {
+			return object.NewBoolean(left.Value == right.Value)
+		}
+	case *object.Boolean:
+		if right, ok := right.(*object.Boolean); ok {
+			return object.NewBoolean(left.Value == right.Value)
+		}
+	case *object.String:
+		if right, ok := right.(*object.String); ok {
+			return object.NewBoolean(left.Value == right.Value)
+		}
+	case *object.Nil:
+		return object.NewBoolean(isNilValue(right))
+	case *object.Pointer:
+		if right, ok := right.(*object.Pointer); ok && left.ElementType.Name == right.ElementType.Name {
+			return object.NewBoolean(left.Value == right.Value)
+		}
+	case *object.Channel:
+		if right, ok := right.(*object.Channel); ok {
+			return object.NewBoolean(left == right)
+		}
+	case *object.ErrorValue:
+		if right, ok := right.(*object.ErrorValue); ok {
+			return object.NewBoolean(left.Value == right.Value)
+		}
+	}
+
+	if _, ok := right.(*object.Nil); ok {
+		return object.NewBoolean(isNilValue(left))
+	}
+	switch left.(type) {
+	case *object.Slice, *object.Map, *object.Function:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: %s can only be compared to nil", typeName(left)),
+		}
+	}
+
+	return mismatchedTypesError(left, right)
+}
+
+func (e *Evaluator) evaluateNotEqual(left, right object.Object) object.Object {
+	obj := e.evaluateEqual(left, right)
+	if isError(obj) {
+		return obj
+	}
+
+	return e.evaluateNOT(obj.(*object.Boolean))
+}
+
+func (e *Evaluator) evaluateComparison(op ast.InfixOperator, left, right object.Object) object.Object {
+	var compared int
+	switch left := left.(type) {
+	case *object.Integer:
+		right, ok := right.(*object.Integer)
+		if !ok {
+			return mismatchedTypesError(left, right)
+		}
+		compared = compareIntegers(left.Value, right.Value)
+	case *object.String:
+		right, ok := right.(*object.String)
+		if !ok {
+			return mismatchedTypesError(left, right)
+		}
+		compared = strings.Compare(left.Value, right.Value)
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("operator %s not defined on %s", op, typeName(left)),
+		}
+	}
+
+	var value bool
+	switch op {
+	case ast.LessThan:
+		value = compared < 0
+	case ast.GreaterThan:
+		value = 0 < compared
+	case ast.LessThanOrEqual:
+		value = compared <= 0
+	case ast.GreaterThanOrEqual:
+		value = 0 <= compared
+	}
+
+	return object.NewBoolean(value)
+}
+
+func compareIntegers(left, right int64) int {
+	switch {
+	case left < right:
+		return -1
+	case right < left:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (e *Evaluator) evaluateTypeAssertionExpression(node *ast.TypeAssertionExpression) object.Object {
+	if node.Type == nil {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: "use of .(type) outside type switch",
+		}
+	}
+
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
+		return obj
+	}
+
+	ok, err := e.hasType(obj, node.Type)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &object.Panic{
+			Value: &object.RuntimeError{
+				Kind:    object.ErrRuntime,
+				Message: fmt.Sprintf("interface conversion: interface {} is %s, not %s", typeName(obj), node.Type.Name),
+			},
+		}
+	}
+
+	return obj
+}
+
+func (e *Evaluator) evaluateIndexExpression(node *ast.IndexExpression) object.Object {
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
+		return obj
+	}
+
+	switch obj := obj.(type) {
+	case *object.Function:
+		if len(obj.TypeParameters) == 0 {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("invalid operation: cannot index %s", obj),
+			}
+		}
+		if len(obj.TypeParameters) < len(node.Indices) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("too many type arguments for func %s: have %d, want %d", obj.Name, len(node.Indices), len(obj.TypeParameters)),
+			}
+		}
+		typeArgs, err := e.evaluateTypes(node.Indices)
+		if err != nil {
+			return err
+		}
+
+		instantiated := *obj
+		instantiated.TypeArguments = typeArgs
+		return &instantiated
+	case *object.GenericType:
+		return e.instantiate(obj, node.Indices)
+	default:
+		return e.index(node, obj)
+	}
+}
+
+func (e *Evaluator) instantiate(typ *object.GenericType, indices []ast.Expression) object.Object {
+	if len(indices) < len(typ.Parameters) {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("not enough type arguments for type %s: have %d, want %d", typ, len(indices), len(typ.Parameters)),
+		}
+	}
+	if len(typ.Parameters) < len(indices) {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("too many type arguments for type %s: have %d, want %d", typ, len(indices), len(typ.Parameters)),
+		}
+	}
+	typeArgs, err := e.evaluateTypes(indices)
+	if err != nil {
+		return err
+	}
+
+	env := NewEnclosedEnvironment(typ.Env)
+	for i, param := range typ.Parameters {
+		env.set(param.Identifier.Name, typeArgs[i])
+	}
+
+	return e.withEnvironment(env, func() object.Object {
+		return e.evaluateType(typ.Type)
+	})
+}
+
+func (e *Evaluator) evaluateTypes(nodes []ast.Expression) ([]*object.Type, object.Object) {
+	types := make([]*object.Type, len(nodes))
+	for i, node := range nodes {
+		typ := e.evaluateType(node)
+		if isError(typ) {
+			return nil, typ
+		}
+		types[i] = typ.(*object.Type)
+	}
+
+	return types, nil
+}
+
+func (e *Evaluator) evaluateCallExpression(node *ast.CallExpression) object.Object {
+	fn := e.Evaluate(node.Function)
+	if isError(fn) {
+		return fn
+	}
+	args := e.evaluateExpressions(node.Arguments)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return e.call(node, fn, args)
+}
+
+func (e *Evaluator) evaluateExpressions(exprs []ast.Expression) []object.Object {
+	objs := make([]object.Object, len(exprs))
+	for i, expr := range exprs {
+		obj := e.Evaluate(expr)
+		if isError(obj) {
+			return []object.Object{obj}
+		}
+		if tuple, ok := obj.(*object.Tuple); ok {
+			if len(exprs) == 1 {
+				return tuple.Values
+			}
+			return []object.Object{
+				&object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("multiple-value %s (value of type %s) in single-value context", ast.Format(expr), typeName(tuple)),
+				},
+			}
+		}
+		objs[i] = obj
+	}
+
+	return objs
+}
+
+func (e *Evaluator) call(node *ast.CallExpression, fn object.Object, args []object.Object) object.Object {
+	e.frame.site = node
+	switch fn := fn.(type) {
+	case *object.Function:
+		if node.HasEllipsis {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("have (...) in call to non-variadic %s", ast.Format(node.Function)),
+			}
+		}
+		return e.callFunction(fn, args, nil)
+	case *object.Builtin:
+		return e.callBuiltin(node, fn, args)
+	case *object.HostFunction:
+		return e.callHost(node, fn, args)
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot call non-function %s", fn),
+		}
+	}
+}
+
+func (e *Evaluator) callFunction(fn *object.Function, args []object.Object, deferrer *frame) object.Object {
+	name := fn.Name
+	if name == "" {
+		name = "function literal"
+	}
+	if err := checkNumberOfArguments(name, args, len(fn.Parameters)); err != nil {
+		return err
+	}
+
+	env := NewFunctionEnvironment(fn.Env)
+	if err := bindTypeArguments(env, fn, args); err != nil {
+		return err
+	}
+	for i, param := range fn.Parameters {
+		if err := env.Declare(param.Identifier.Name, args[i]); err != nil {
+			return &object.Error{
+				Kind:    object.ErrName,
+				Message: err.Error(),
+			}
+		}
+	}
+	for _, result := range fn.Results {
+		if result.Identifier == nil {
+			continue
+		}
+		zero := e.zeroValueOf(result.Type)
+		if isError(zero) {
+			return zero
+		}
+		if err := env.Declare(result.Identifier.Name, zero); err != nil {
+			return &object.Error{
+				Kind:    object.ErrName,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	if err := e.budget.enter(e.frame.depth + 1); err != nil {
+		return err
+	}
+
+	outerEnv, outerFrame := e.env, e.frame
+	e.env, e.frame = env, &frame{
+		function: name,
+		depth:    e.frame.depth + 1,
+		caller:   e.frame,
+		deferrer: deferrer,
+	}
+	defer func() {
+		e.env, e.frame = outerEnv, outerFrame
+	}()
+
+	obj := e.evaluateBody(fn.Body)
+	return e.returnFromFunction(fn, obj)
+}
+
+func bindTypeArguments(env *Environment, fn *object.Function, args []object.Object) *object.Error {
+	typeArgs := make(map[string]*object.Type)
+	for i, typeArg := range fn.TypeArguments {
+		typeArgs[fn.TypeParameters[i].Identifier.Name] = typeArg
+	}
+	for i, param := range fn.Parameters {
+		inferTypeArguments(typeArgs, fn.TypeParameters, param.Type, typeOf(args[i]))
+	}
+
+	for _, param := range fn.TypeParameters {
+		typeArg, ok := typeArgs[param.Identifier.Name]
+		if !ok {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("in call to %s, cannot infer %s", fn.Name, param.Identifier.Name),
+			}
+		}
+		env.set(param.Identifier.Name, typeArg)
+	}
+
+	return nil
+}
+
+func inferTypeArguments(typeArgs map[string]*object.Type, params []*ast.Parameter, node ast.Expression, typ *object.Type) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if _, ok := typeArgs[node.Name]; ok {
+			return
+		}
+		for _, param := range params {
+			if param.Identifier.Name == node.Name {
+				typeArgs[node.Name] = typ
+				return
+			}
+		}
+	case *ast.ChannelType:
+		if typ.Kind == object.ChannelType {
+			inferTypeArguments(typeArgs, params, node.Element, typ.Element)
+		}
+	case *ast.SliceType:
+		if typ.Kind == object.SliceType {
+			inferTypeArguments(typeArgs, params, node.Element, typ.Element)
+		}
+	case *ast.MapType:
+		if typ.Kind == object.MapType {
+			inferTypeArguments(typeArgs, params, node.Key, typ.Key)
+			inferTypeArguments(typeArgs, params, node.Value, typ.Element)
+		}
+	case *ast.PointerType:
+		if typ.Kind == object.PointerType {
+			inferTypeArguments(typeArgs, params, node.Element, typ.Element)
+		}
+	}
+}
+
+func (e *Evaluator) returnFromFunction(fn *object.Function, obj object.Object) object.Object {
+	if err := e.checkUnexpectedControl(obj); err != nil {
+		return err
+	}
+
+	var result object.Object = &object.Nil{}
+	if 0 < len(fn.Results) {
+		result = e.zeroValueOf(fn.Results[0].Type)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Error:
+		return obj
+	case *object.Panic:
+		e.frame.panic = obj
+	default:
+		if 0 < len(fn.Results) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: "missing return",
+			}
+		}
+	case *object.ReturnValue:
+		if obj.Value != nil && len(fn.Results) == 0 {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: "too many return values",
+			}
+		}
+		if obj.Value == nil && 0 < len(fn.Results) && fn.Results[0].Identifier == nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: "not enough return values",
+			}
+		}
+		if obj.Value != nil {
+			result = obj.Value
+		}
+		if obj.Value != nil && fn.Results[0].Identifier != nil {
+			if err := e.env.Assign(fn.Results[0].Identifier.Name, obj.Value); err != nil {
+				return &object.Error{
+					Kind:    object.ErrName,
+					Message: err.Error(),
+				}
+			}
+		}
+	}
+
+	if err := e.runDeferredCalls(); err != nil {
+		return err
+	}
+	if e.frame.panic != nil {
+		return e.frame.panic
+	}
+
+	if 0 < len(fn.Results) && fn.Results[0].Identifier != nil {
+		result, _ = e.env.Get(fn.Results[0].Identifier.Name)
+	}
+
+	return result
+}
+
+func (e *Evaluator) runDeferredCalls() *object.Error {
+	for e.frame.hasDeferredCalls() {
+		call := e.frame.popDeferredCall()
+
+		var obj object.Object
+		if fn, ok := call.function.(*object.Function); ok {
+			e.frame.site = call.node
+			obj = e.callFunction(fn, call.arguments, e.frame)
+		} else {
+			obj = e.call(call.node, call.function, call.arguments)
+		}
+
+		switch obj := obj.(type) {
+		case *object.Panic:
+			e.frame.panic = obj
+		case *object.Error:
+			return obj
+		}
+	}
+
+	return nil
+}
+
+func (e *Evaluator) evaluateFunctionLiteral(node *ast.FunctionLiteral) object.Object {
+	if err := e.budget.allocate(1); err != nil {
+		return err
+	}
+
+	return &object.Function{
+		Parameters: node.Parameters,
+		Results:    node.Results,
+		Body:       node.Body,
+		Env:        e.env,
+	}
+}
+
+func (e *Evaluator) evaluateIdentifier(node *ast.Identifier) object.Object {
+	obj, env, ok := e.lookUpIdentifier(node)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: fmt.Sprintf("undefined variable: %s", node.Name),
+		}
+	}
+	if node.Name == "iota" && env.Scope() == UniverseScope {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: "cannot use iota outside constant declaration",
+		}
+	}
+	if _, ok := obj.(*object.Package); ok {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: fmt.Sprintf("use of package %s without selector", node.Name),
+		}
+	}
+	if err := e.detectRace(node, false); err != nil {
+		return err
+	}
+
+	return obj
+}
+
+func (e *Evaluator) lookUpIdentifier(node *ast.Identifier) (object.Object, *Environment, bool) {
+	if node.Resolved {
+		return e.env.LookupSlot(node.Depth, node.Slot)
+	}
+
+	return e.env.Lookup(node.Name)
+}
+
+func (e *Evaluator) assignIdentifier(node *ast.Identifier, obj object.Object) error {
+	if node.Resolved {
+		return e.env.AssignSlot(node.Depth, node.Slot, node.Name, obj)
+	}
+
+	return e.env.Assign(node.Name, obj)
+}
+
+func (e *Evaluator) detectRace(node *ast.Identifier, isWrite bool) *object.Error {
+	if e.race == nil {
+		return nil
+	}
+	_, env, ok := e.env.Lookup(node.Name)
+	if !ok {
+		return nil
+	}
+
+	return e.detectRaceAt(node, variable{
+		env:  env,
+		name: node.Name,
+	}, node.Name, isWrite)
+}
+
+func (e *Evaluator) detectElementRace(node ast.Node, s *object.Slice, i int, name string, isWrite bool) *object.Error {
+	if e.race == nil {
+		return nil
+	}
+
+	return e.detectRaceAt(node, &s.Elements[i], fmt.Sprintf("%s[%d]", name, i), isWrite)
+}
+
+func (e *Evaluator) detectMapRace(node ast.Node, m *object.Map, name string, isWrite bool) *object.Error {
+	if e.race == nil {
+		return nil
+	}
+
+	return e.detectRaceAt(node, m, name, isWrite)
+}
+
+func (e *Evaluator) detectRaceAt(node ast.Node, loc interface{}, name string, isWrite bool) *object.Error {
+	return e.race.access(e.goroutine, loc, name, isWrite, e.stackTrace(e.spans[node].Start))
+}
+
+func (e *Evaluator) evaluateInteger(node *ast.Integer) *object.Integer {
+	return object.NewInteger(node.Value)
+}
+
+func (e *Evaluator) evaluateString(node *ast.String) *object.String {
+	return &object.String{
+		Value: node.Value,
+	}
+}
+
+func mismatchedTypesError(left, right object.Object) *object.Error {
+	return &object.Error{
+		Kind:    object.ErrType,
+		Message: fmt.Sprintf("mismatched types %s and %s", typeName(left), typeName(right)),
+	}
+}
+
+func (e *Evaluator) evaluateType(node ast.Expression) object.Object {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		switch typ, ok := e.env.Get(node.Name); typ := typ.(type) {
+		case *object.Type:
+			return typ
+		case *object.GenericType:
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use generic type %s without instantiation", typ),
+			}
+		default:
+			if ok {
+				return &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("%s is not a type", node.Name),
+				}
+			}
+			return &object.Error{
+				Kind:    object.ErrName,
+				Message: fmt.Sprintf("undefined type: %s", node.Name),
+			}
+		}
+	case *ast.IndexExpression:
+		typ := e.Evaluate(node.Expression)
+		if isError(typ) {
+			return typ
+		}
+		generic, ok := typ.(*object.GenericType)
+		if !ok {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("%s is not a generic type", typ),
+			}
+		}
+
+		return e.instantiate(generic, node.Indices)
+	case *ast.InterfaceType:
+		return &object.Type{
+			Kind: object.BasicType,
+			Name: "any",
+		}
+	case *ast.SliceType:
+		elem := e.evaluateType(node.Element)
+		if isError(elem) {
+			return elem
+		}
+
+		return sliceTypeOf(elem.(*object.Type))
+	case *ast.MapType:
+		key := e.evaluateType(node.Key)
+		if isError(key) {
+			return key
+		}
+		switch key.(*object.Type).Kind {
+		case object.SliceType, object.MapType:
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("invalid map key type %s", key),
+			}
+		}
+		value := e.evaluateType(node.Value)
+		if isError(value) {
+			return value
+		}
+
+		return mapTypeOf(key.(*object.Type), value.(*object.Type))
+	case *ast.PointerType:
+		elem := e.evaluateType(node.Element)
+		if isError(elem) {
+			return elem
+		}
+
+		return pointerTypeOf(elem.(*object.Type))
+	case *ast.ChannelType:
+		elem := e.evaluateType(node.Element)
+		if isError(elem) {
+			return elem
+		}
+
+		name := fmt.Sprintf("chan %s", elem)
+		if node.Direction != ast.Bidirectional {
+			name = fmt.Sprintf("%s %s", node.Direction, elem)
+		}
+
+		return &object.Type{
+			Kind:    object.ChannelType,
+			Name:    name,
+			Element: elem.(*object.Type),
+		}
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("%T is not a type", node),
+		}
+	}
+}
+
+func (e *Evaluator) zeroValueOf(node ast.Expression) object.Object {
+	typ := e.evaluateType(node)
+	if isError(typ) {
+		return typ
+	}
+
+	return zeroValueOf(typ.(*object.Type))
+}
+
+func zeroValueOf(typ *object.Type) object.Object {
+	switch typ.Kind {
+	case object.SliceType:
+		return &object.Slice{
+			ElementType: typ.Element,
+		}
+	case object.MapType:
+		return &object.Map{
+			KeyType:   typ.Key,
+			ValueType: typ.Element,
+		}
+	case object.PointerType:
+		return &object.Pointer{
+			ElementType: typ.Element,
+		}
+	}
+	if zero, ok := zeroValues[typ.Name]; ok && typ.Kind == object.BasicType {
+		return zero
+	}
+
+	return &object.Nil{}
+}
+
+func typeOf(obj object.Object) *object.Type {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.Boolean, *object.String:
+		return &object.Type{
+			Kind: object.BasicType,
+			Name: typeName(obj),
+		}
+	case *object.Slice:
+		return sliceTypeOf(obj.ElementType)
+	case *object.Map:
+		return mapTypeOf(obj.KeyType, obj.ValueType)
+	case *object.Pointer:
+		return pointerTypeOf(obj.ElementType)
+	case *object.Channel:
+		return &object.Type{
+			Kind:    object.ChannelType,
+			Name:    obj.String(),
+			Element: obj.ElementType,
+		}
+	default:
+		return &object.Type{
+			Kind: object.BasicType,
+			Name: "any",
+		}
+	}
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+func isError(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Error, *object.Panic:
+		return true
+	default:
+		return false
+	}
+}
+
+func typeName(obj object.Object) string {
+	switch obj.(type) {
+	case *object.Integer:
+		return "int"
+	case *object.Boolean:
+		return "bool"
+	case *object.String:
+		return "string"
+	case *object.Function:
+		return "func"
+	case *object.HostFunction:
+		return obj.(*object.HostFunction).Function.Type().String()
+	case *object.HostValue:
+		return obj.(*object.HostValue).Value.Type().String()
+	case *object.Slice, *object.Map, *object.Pointer:
+		return typeOf(obj).Name
+	case *object.Channel:
+		return obj.(*object.Channel).String()
+	case *object.ErrorValue:
+		return "error"
+	case *object.Tuple:
+		values := obj.(*object.Tuple).Values
+		names := make([]string, len(values))
+		for i, value := range values {
+			names[i] = typeName(value)
+		}
+		return fmt.Sprintf("(%s)", strings.Join(names, ", "))
+	case *object.Nil:
+		return "nil"
+	default:
+		return fmt.Sprintf("%T", obj)
 	}
 }
@@ -1,113 +1,1427 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/compiler"
 	"github.com/tomocy/kinako/lexer"
 	"github.com/tomocy/kinako/object"
 	"github.com/tomocy/kinako/parser"
+	"github.com/tomocy/kinako/token"
 )
 
-func TestEvaluate(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected object.Object
-	}{
-		{
-			"5;",
-			&object.Integer{
-				Value: 5,
-			},
+var evaluateTests = []struct {
+	input    string
+	expected object.Object
+}{
+	{
+		"5;",
+		&object.Integer{
+			Value: 5,
 		},
-		{
-			"-6;",
-			&object.Integer{
-				Value: -6,
-			},
+	},
+	{
+		"-6;",
+		&object.Integer{
+			Value: -6,
 		},
-		{
-			"7 + 8 - 9 * 10 / 11;",
-			&object.Integer{
-				Value: 15,
-			},
+	},
+	{
+		"7 + 8 - 9 * 10 / 11;",
+		&object.Integer{
+			Value: 7,
 		},
-		{
-			"(12 + 13) / 14;",
-			&object.Integer{
-				Value: 1,
-			},
+	},
+	{
+		"(12 + 13) / 14;",
+		&object.Integer{
+			Value: 1,
 		},
-		{
-			"var x int;",
-			&object.Integer{
-				Value: 0,
-			},
+	},
+	{
+		"var x int;",
+		&object.Integer{
+			Value: 0,
 		},
-		{
-			"var x bool;",
-			&object.Boolean{
-				Value: false,
-			},
+	},
+	{
+		"var x bool;",
+		&object.Boolean{
+			Value: false,
 		},
-		{
-			"var x int = 16;",
-			&object.Integer{
-				Value: 16,
-			},
+	},
+	{
+		"var x int = 16;",
+		&object.Integer{
+			Value: 16,
 		},
-		{
-			"var x int = 17; x;",
-			&object.Integer{
-				Value: 17,
-			},
+	},
+	{
+		"var x int = 17; x;",
+		&object.Integer{
+			Value: 17,
 		},
-		{
-			"true;",
-			&object.Boolean{
-				Value: true,
-			},
+	},
+	{
+		"true;",
+		&object.Boolean{
+			Value: true,
 		},
-		{
-			"false;",
-			&object.Boolean{
-				Value: false,
-			},
+	},
+	{
+		"false;",
+		&object.Boolean{
+			Value: false,
 		},
-		{
-			"!false;",
-			&object.Boolean{
-				Value: true,
-			},
+	},
+	{
+		"!false;",
+		&object.Boolean{
+			Value: true,
 		},
-		{
-			"0; 0",
-			&object.Error{
-				Message: "failed to find semicolon",
-			},
+	},
+	{
+		"0; 0",
+		&object.Error{
+			Message: "failed to find semicolon",
 		},
-		{
-			"5 / 0;",
-			&object.Error{
-				Message: "divided by zero",
-			},
+	},
+	{
+		"5 / 0;",
+		&object.Error{
+			Message: "panic: runtime error: integer divide by zero",
 		},
-		{
-			"y;",
-			&object.Error{
-				Message: "undefined variable: y",
-			},
+	},
+	{
+		"y;",
+		&object.Error{
+			Message: "undefined variable: y",
 		},
-	}
-	for _, test := range tests {
+	},
+	{
+		"1 + 1 == 2;",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"2 <= 1;",
+		&object.Boolean{
+			Value: false,
+		},
+	},
+	{
+		"switch 2 { case 1: 10; case 2: 20; case 3: 30; };",
+		&object.Integer{
+			Value: 20,
+		},
+	},
+	{
+		"switch x := 3; x { case 1, 2: 10; case 3, 4: 20; }",
+		&object.Integer{
+			Value: 20,
+		},
+	},
+	{
+		"switch { case 1 > 2: 10; default: 30; case 2 > 1: 20; }",
+		&object.Integer{
+			Value: 20,
+		},
+	},
+	{
+		"switch 5 { default: 30; case 1: 10; }",
+		&object.Integer{
+			Value: 30,
+		},
+	},
+	{
+		"switch 1 { case 1: 10; fallthrough; case 2: 20; fallthrough; default: 30; case 3: 40; }",
+		&object.Integer{
+			Value: 30,
+		},
+	},
+	{
+		"var x int; switch 1 { case 1: x = 10; break; x = 20; }; x;",
+		&object.Integer{
+			Value: 10,
+		},
+	},
+	{
+		"switch 1 { case 1: switch 2 { case 2: 20; break; }; 10; }",
+		&object.Integer{
+			Value: 10,
+		},
+	},
+	{
+		"switch 1 { case 2: 20; }",
+		&object.Nil{},
+	},
+	{
+		"z := 1; switch z := 3; z { case 3: z = 4; }; z;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"switch 1 { case 1: w := 2; fallthrough; case 2: w := 3; w; }",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"func f() int { y := 1; switch y := 100; y { case 100: y = y + 1; }; return y; }; f();",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"switch z := 3; z { case 3: z; }; z;",
+		&object.Error{
+			Message: "undefined variable: z",
+		},
+	},
+	{
+		"var x any = 1; func f() int { switch v := x.(type) { case int: v; }; switch v := x.(type) { case int: return v + 1; }; return 0; }; f();",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"var x any = 1; switch v := x.(type) { case int: v; }; v;",
+		&object.Error{
+			Message: "undefined variable: v",
+		},
+	},
+	{
+		"var x any = 5; switch v := x.(type) { case bool: !v; case int: v + 1; }",
+		&object.Integer{
+			Value: 6,
+		},
+	},
+	{
+		"var x any; switch x.(type) { case int: 1; case nil: 2; }",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"var x any = true; switch x.(type) { case int, bool: 1; default: 2; }",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"var x any = 1; x.(int);",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"switch 1 { case true: 1; }",
+		&object.Error{
+			Message: "mismatched types int and bool",
+		},
+	},
+	{
+		`"a" + 1;`,
+		&object.Error{
+			Message: "mismatched types string and int",
+		},
+	},
+	{
+		"switch 1 { case 1: fallthrough; }",
+		&object.Error{
+			Message: "cannot fallthrough final case in switch",
+		},
+	},
+	{
+		"switch 1 { case 1: fallthrough; 2; case 2: 3; }",
+		&object.Error{
+			Message: "fallthrough statement out of place",
+		},
+	},
+	{
+		"var x any = 1; switch x.(type) { case int: fallthrough; default: 2; }",
+		&object.Error{
+			Message: "cannot fallthrough in type switch",
+		},
+	},
+	{
+		"switch { default: 1; default: 2; }",
+		&object.Error{
+			Message: "multiple defaults in switch",
+		},
+	},
+	{
+		"break;",
+		&object.Error{
+			Message: "break is not in a loop, switch, or select",
+		},
+	},
+	{
+		"var x any = 1; x.(bool);",
+		&object.Error{
+			Message: "panic: interface conversion: interface {} is int, not bool",
+		},
+	},
+	{
+		"var x any = 1; x.(type);",
+		&object.Error{
+			Message: "use of .(type) outside type switch",
+		},
+	},
+	{
+		"func add(x, y int) int { return x + y; } add(1, 2);",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"func counter() int { x := 0; f := func() int { x = x + 1; return x; }; f(); return f(); } counter();",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"\"a\" + \"b\" == \"ab\";",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"var s string; func f() { defer func() { s = s + \"1\"; }(); defer func() { s = s + \"2\"; }(); s = s + \"0\"; } f(); s;",
+		&object.String{
+			Value: "021",
+		},
+	},
+	{
+		"var s string; func f() { x := \"a\"; defer func(v string) { s = v; }(x); x = \"b\"; } f(); s;",
+		&object.String{
+			Value: "a",
+		},
+	},
+	{
+		"func f() (r int) { defer func() { switch recover() { case nil: default: r = -1; } }(); return 10 / 0; } f();",
+		&object.Integer{
+			Value: -1,
+		},
+	},
+	{
+		"func f() (r string) { defer func() { r = recover().(string); }(); panic(\"boom\"); } f();",
+		&object.String{
+			Value: "boom",
+		},
+	},
+	{
+		"func f() (r int) { defer func() { r = r * 2; }(); return 5; } f();",
+		&object.Integer{
+			Value: 10,
+		},
+	},
+	{
+		"func f() int { x := 5; defer func() { x = x * 2; }(); return x; } f();",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"recover();",
+		&object.Nil{},
+	},
+	{
+		"defer func() { recover(); }(); panic(1);",
+		&object.Nil{},
+	},
+	{
+		"panic(\"boom\");",
+		&object.Error{
+			Message: "panic: boom",
+		},
+	},
+	{
+		"panic(nil);",
+		&object.Error{
+			Message: "panic: panic called with nil argument",
+		},
+	},
+	{
+		"func h() any { return recover(); } func f() { defer func() { h(); }(); panic(1); } f();",
+		&object.Error{
+			Message: "panic: 1",
+		},
+	},
+	{
+		"func f() { defer recover(); panic(1); } f();",
+		&object.Error{
+			Message: "panic: 1",
+		},
+	},
+	{
+		"func f() { defer func() { panic(2); }(); panic(1); } f();",
+		&object.Error{
+			Message: "panic: 2",
+		},
+	},
+	{
+		"defer 1;",
+		&object.Error{
+			Message: "expression in defer must be function call",
+		},
+	},
+	{
+		"func f(x int) {} f();",
+		&object.Error{
+			Message: "not enough arguments in call to f",
+		},
+	},
+	{
+		"func f() { return 1; } f();",
+		&object.Error{
+			Message: "too many return values",
+		},
+	},
+	{
+		"func f() int { 1; } f();",
+		&object.Error{
+			Message: "missing return",
+		},
+	},
+	{
+		"i := 0; for { i++; break; }; i;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"i := 0; for i < 10 { i++; }; i;",
+		&object.Integer{
+			Value: 10,
+		},
+	},
+	{
+		"sum := 0; for i := 0; i < 5; i++ { switch { case i == 2: continue; }; sum = sum + i; }; sum;",
+		&object.Integer{
+			Value: 8,
+		},
+	},
+	{
+		"sum := 0; for i := range 4 { sum = sum + i; }; sum;",
+		&object.Integer{
+			Value: 6,
+		},
+	},
+	{
+		"a, b := 1, 2; a, b = b, a; a - b;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"var x any = true; v, ok := x.(int); v + 1;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"x, y := 1;",
+		&object.Error{
+			Message: "assignment mismatch: 2 variables but 1 value",
+		},
+	},
+	{
+		"continue;",
+		&object.Error{
+			Message: "continue is not in a loop",
+		},
+	},
+	{
+		"ch := make(chan int); go func() { ch <- 42; }(); <-ch;",
+		&object.Integer{
+			Value: 42,
+		},
+	},
+	{
+		"ch := make(chan int, 2); ch <- 1; ch <- 2; <-ch * 10 + <-ch;",
+		&object.Integer{
+			Value: 12,
+		},
+	},
+	{
+		"ch := make(chan int, 1); close(ch); v, ok := <-ch; ok;",
+		&object.Boolean{
+			Value: false,
+		},
+	},
+	{
+		"ch := make(chan int, 1); ch <- 5; close(ch); v, ok := <-ch; v;",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"ch := make(chan int); go func() { for i := 0; i < 4; i++ { ch <- i; }; close(ch); }(); sum := 0; for v := range ch { sum = sum + v; }; sum;",
+		&object.Integer{
+			Value: 6,
+		},
+	},
+	{
+		"results := make(chan int); for i := range 3 { go func() { results <- i * i; }(); }; sum := 0; for range 3 { sum = sum + <-results; }; sum;",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"func worker(in <-chan int, out chan<- int) { for v := range in { out <- v * 2; }; close(out); } in := make(chan int); out := make(chan int); go worker(in, out); go func() { in <- 1; in <- 2; close(in); }(); sum := 0; for v := range out { sum = sum + v; }; sum;",
+		&object.Integer{
+			Value: 6,
+		},
+	},
+	{
+		"ch := make(chan int); select { case v := <-ch: v; default: -1; }",
+		&object.Integer{
+			Value: -1,
+		},
+	},
+	{
+		"ch := make(chan int, 1); ch <- 3; select { case v := <-ch: v; default: -1; }",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"a := make(chan int); b := make(chan string); go func() { b <- \"hi\"; }(); select { case v := <-a: v; case s := <-b: s; }",
+		&object.String{
+			Value: "hi",
+		},
+	},
+	{
+		"ch := make(chan int, 1); select { case ch <- 7: <-ch; }",
+		&object.Integer{
+			Value: 7,
+		},
+	},
+	{
+		"ch := make(chan int); done := make(chan bool); go func() { select { case ch <- 8: }; done <- true; }(); v := <-ch; <-done; v;",
+		&object.Integer{
+			Value: 8,
+		},
+	},
+	{
+		"done := false; go func() { done = true; }(); for !done { }; 1;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"go func() { for { } }(); 1;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"ch := make(chan int); go func() { ch <- 1; for { } }(); <-ch;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"ch := make(chan int); <-ch;",
+		&object.Error{
+			Message: "fatal error: all goroutines are asleep - deadlock!",
+		},
+	},
+	{
+		"ch := make(chan int); go func() { ch <- 1; ch <- 2; }(); <-ch; <-ch; <-ch;",
+		&object.Error{
+			Message: "fatal error: all goroutines are asleep - deadlock!",
+		},
+	},
+	{
+		"ch := make(chan int); go func() { <-ch; }(); done := make(chan int); <-done;",
+		&object.Error{
+			Message: "fatal error: all goroutines are asleep - deadlock!",
+		},
+	},
+	{
+		"ch := make(chan int); go func() {}(); <-ch;",
+		&object.Error{
+			Message: "fatal error: all goroutines are asleep - deadlock!",
+		},
+	},
+	{
+		"var ch chan int; <-ch;",
+		&object.Error{
+			Message: "fatal error: all goroutines are asleep - deadlock!",
+		},
+	},
+	{
+		"select {}",
+		&object.Error{
+			Message: "fatal error: all goroutines are asleep - deadlock!",
+		},
+	},
+	{
+		"done := make(chan int); go func() { panic(\"boom\"); }(); <-done;",
+		&object.Error{
+			Message: "panic: boom",
+		},
+	},
+	{
+		"ch := make(chan int, 1); close(ch); ch <- 1;",
+		&object.Error{
+			Message: "panic: send on closed channel",
+		},
+	},
+	{
+		"ch := make(chan int); started := make(chan bool); go func() { started <- true; ch <- 1; }(); <-started; close(ch); <-started;",
+		&object.Error{
+			Message: "panic: send on closed channel",
+		},
+	},
+	{
+		"ch := make(chan int); close(ch); close(ch);",
+		&object.Error{
+			Message: "panic: close of closed channel",
+		},
+	},
+	{
+		"var ch chan int; close(ch);",
+		&object.Error{
+			Message: "panic: close of nil channel",
+		},
+	},
+	{
+		"make(chan int, -1);",
+		&object.Error{
+			Message: "panic: makechan: size out of range",
+		},
+	},
+	{
+		"func Max[T ~int | ~string](a, b T) T { switch { case a < b: return b; } return a; } Max(1, 2);",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"func Max[T ~int | ~string](a, b T) T { switch { case a < b: return b; } return a; } Max[string](\"a\", \"b\");",
+		&object.String{
+			Value: "b",
+		},
+	},
+	{
+		"func Zero[T any]() T { var z T; return z; } Zero[string]() == \"\";",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"func First[T any](c chan T) T { return <-c; } c := make(chan string, 1); c <- \"x\"; First(c);",
+		&object.String{
+			Value: "x",
+		},
+	},
+	{
+		"func Sum[T int | string](xs []T) T { var s T; for _, x := range xs { s = s + x; } return s; } Sum([]int{1, 2});",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"func Sum[T int | string](xs []T) T { var s T; for _, x := range xs { s = s + x; } return s; } Sum([]string{\"a\", \"b\"});",
+		&object.String{
+			Value: "ab",
+		},
+	},
+	{
+		"func Get[K comparable, V any](m map[K]V, k K) V { return m[k]; } Get(map[string]int{\"a\": 1}, \"a\");",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"func Deref[T any](p *T) T { return *p; } p := new(int); *p = 5; Deref(p);",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"func Firsts[T any](xs [][]T) T { return xs[0][0]; } Firsts([][]string{{\"x\"}});",
+		&object.String{
+			Value: "x",
+		},
+	},
+	{
+		"func Is[T any](x any) bool { _, ok := x.(T); return ok; } Is[int](\"a\");",
+		&object.Boolean{
+			Value: false,
+		},
+	},
+	{
+		"type Number interface { ~int | ~string; }; func Double[T Number](x T) T { return x + x; } Double(3);",
+		&object.Integer{
+			Value: 6,
+		},
+	},
+	{
+		"type C[T any] chan T; c := make(C[int], 1); c <- 4; <-c;",
+		&object.Integer{
+			Value: 4,
+		},
+	},
+	{
+		"type MyInt int; var x MyInt = 3; x;",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"func Zero[T any]() T { var z T; return z; } Zero();",
+		&object.Error{
+			Message: "in call to Zero, cannot infer T",
+		},
+	},
+	{
+		"func Zero[T any]() T { var z T; return z; } Zero[int, int]();",
+		&object.Error{
+			Message: "too many type arguments for func Zero: have 2, want 1",
+		},
+	},
+	{
+		"type C[T any] chan T; var c C;",
+		&object.Error{
+			Message: "cannot use generic type C without instantiation",
+		},
+	},
+	{
+		"type C[T any] chan T; var c C[int, int];",
+		&object.Error{
+			Message: "too many type arguments for type C: have 2, want 1",
+		},
+	},
+	{
+		"x := 1; x[0];",
+		&object.Error{
+			Message: "invalid operation: cannot index int",
+		},
+	},
+	{
+		"x := 1; for i := 0; i < 1; i++ { x := 2; x++; } x;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"x := 1; for i := 0; i < 1; i++ { x = 2; } x;",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"x := 1; func f() int { return x; } func g() int { x := 2; return f(); } g();",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"n := 0; inc := func() { n := 10; n++; }; add := func() { n++; }; inc(); add(); add(); n;",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"x := 1; x, y := 2, 3; x + y;",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"x := 1; x := 2;",
+		&object.Error{
+			Message: "no new variables on left side of :=",
+		},
+	},
+	{
+		"_ := 1;",
+		&object.Error{
+			Message: "no new variables on left side of :=",
+		},
+	},
+	{
+		"x, x := 1, 2;",
+		&object.Error{
+			Message: "x repeated on left side of :=",
+		},
+	},
+	{
+		"var x int; var x int;",
+		&object.Error{
+			Message: "x redeclared in this block",
+		},
+	},
+	{
+		"func f() {} func f() {}",
+		&object.Error{
+			Message: "f redeclared in this block",
+		},
+	},
+	{
+		"func f(x, x int) {} f(1, 2);",
+		&object.Error{
+			Message: "x redeclared in this block",
+		},
+	},
+	{
+		"func f(x int) int { x := 2; return x; } f(1);",
+		&object.Error{
+			Message: "no new variables on left side of :=",
+		},
+	},
+	{
+		"func f(x int) int { var x int; return x; } f(1);",
+		&object.Error{
+			Message: "x redeclared in this block",
+		},
+	},
+	{
+		"func f(x int) int { for i := 0; i < 1; i++ { x := 2; x++; } return x; } f(1);",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"true := 1; true + 1;",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"var false int = 2; false;",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"func f() bool { nil := true; return nil; } f();",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"int := 1; int;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"int := 1; var x int;",
+		&object.Error{
+			Message: "int is not a type",
+		},
+	},
+	{
+		"func close(x int) int { return x; } close(3);",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"iota := 5; iota;",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"iota;",
+		&object.Error{
+			Message: "cannot use iota outside constant declaration",
+		},
+	},
+	{
+		"true = false;",
+		&object.Error{
+			Message: "cannot assign to true (neither addressable nor a map index expression)",
+		},
+	},
+	{
+		"nil = 1;",
+		&object.Error{
+			Message: "cannot assign to nil (neither addressable nor a map index expression)",
+		},
+	},
+	{
+		"iota = 1;",
+		&object.Error{
+			Message: "cannot assign to iota (neither addressable nor a map index expression)",
+		},
+	},
+	{
+		"int = 1;",
+		&object.Error{
+			Message: "int (type) is not an expression",
+		},
+	},
+	{
+		"make = 1;",
+		&object.Error{
+			Message: "make (built-in function make) must be called",
+		},
+	},
+	{
+		"true := 1; true = 2; true;",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"x := true; for i := 0; i < 1; i++ { true := false; x = true; } x;",
+		&object.Boolean{
+			Value: false,
+		},
+	},
+	{
+		"x := []int{1, 2, 3}; x[1];",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"x := []int{1, 2, 3}; x[0] = 4; x[0] + x[2];",
+		&object.Integer{
+			Value: 7,
+		},
+	},
+	{
+		"x := []string{2: \"a\"}; len(x);",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"x := []int{1, 2, 3}; y := x[1:]; y[0] = 5; x[1];",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"x := []int{1, 2, 3}; len(x[:2]);",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"\"abc\"[1:];",
+		&object.String{
+			Value: "bc",
+		},
+	},
+	{
+		"\"abc\"[1];",
+		&object.Integer{
+			Value: 98,
+		},
+	},
+	{
+		"x := [][]int{{1}, {2, 3}}; x[1][1];",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"var x []int; x == nil;",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"x := []int{}; x == nil;",
+		&object.Boolean{
+			Value: false,
+		},
+	},
+	{
+		"x := []int{1}; x[5];",
+		&object.Error{
+			Message: "panic: runtime error: index out of range [5] with length 1",
+		},
+	},
+	{
+		"x := []int{1}; x[-1];",
+		&object.Error{
+			Message: "invalid argument: index -1 (constant of type int) must not be negative",
+		},
+	},
+	{
+		"x := []int{1, 2, 3}; x[:10];",
+		&object.Error{
+			Message: "panic: runtime error: slice bounds out of range [:10] with capacity 3",
+		},
+	},
+	{
+		"[]int{0: 1, 0: 2};",
+		&object.Error{
+			Message: "duplicate index 0 in array or slice literal",
+		},
+	},
+	{
+		"[]int{\"a\"};",
+		&object.Error{
+			Message: "cannot use \"a\" (untyped string constant) as int value in array or slice literal",
+		},
+	},
+	{
+		"[]int{1} == []int{1};",
+		&object.Error{
+			Message: "invalid operation: []int can only be compared to nil",
+		},
+	},
+	{
+		"m := map[string]int{\"a\": 1}; m[\"b\"] = 2; m[\"a\"] + m[\"b\"] + m[\"c\"];",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"m := map[string]int{\"a\": 1}; _, ok := m[\"a\"]; ok;",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"m := map[string]int{\"a\": 1}; v, ok := m[\"b\"]; ok;",
+		&object.Boolean{
+			Value: false,
+		},
+	},
+	{
+		"var m map[string]int; m[\"a\"];",
+		&object.Integer{
+			Value: 0,
+		},
+	},
+	{
+		"var m map[string]int; m[\"a\"] = 1;",
+		&object.Error{
+			Message: "panic: assignment to entry in nil map",
+		},
+	},
+	{
+		"map[string]int{\"a\": 1, \"a\": 2};",
+		&object.Error{
+			Message: "duplicate key \"a\" in map literal",
+		},
+	},
+	{
+		"map[string]int{1};",
+		&object.Error{
+			Message: "missing key in map literal",
+		},
+	},
+	{
+		"map[[]int]int{};",
+		&object.Error{
+			Message: "invalid map key type []int",
+		},
+	},
+	{
+		"m := map[any]int{}; m[[]int{}] = 1;",
+		&object.Error{
+			Message: "panic: runtime error: hash of unhashable type []int",
+		},
+	},
+	{
+		"s := 0; for i, v := range []int{4, 5} { s = s + i * v; }; s;",
+		&object.Integer{
+			Value: 5,
+		},
+	},
+	{
+		"k := \"\"; for key, v := range map[string]int{\"b\": 1, \"a\": 2} { k = k + key; }; k;",
+		&object.String{
+			Value: "ab",
+		},
+	},
+	{
+		"s := 0; for _, r := range \"ab\" { s = s + r; }; s;",
+		&object.Integer{
+			Value: 195,
+		},
+	},
+	{
+		"for i, v := range 3 {};",
+		&object.Error{
+			Message: "range over int permits only one iteration variable",
+		},
+	},
+	{
+		"p := new(int); *p = 3; *p + 1;",
+		&object.Integer{
+			Value: 4,
+		},
+	},
+	{
+		"x := 1; p := new(int); q := p; *q = 2; *p;",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"new(int) == new(int);",
+		&object.Boolean{
+			Value: false,
+		},
+	},
+	{
+		"var p *int; p == nil;",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"var p *int; *p;",
+		&object.Error{
+			Message: "panic: runtime error: invalid memory address or nil pointer dereference",
+		},
+	},
+	{
+		"x := 1; *x;",
+		&object.Error{
+			Message: "invalid operation: cannot indirect x (variable of type int)",
+		},
+	},
+	{
+		"new(3);",
+		&object.Error{
+			Message: "3 is not a type",
+		},
+	},
+	{
+		"x := append([]int{1}, 2, 3); len(x);",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"x := append([]int{1}, []int{2, 3}...); x[2];",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"append(nil, 1);",
+		&object.Error{
+			Message: "first argument to append must be a slice; have untyped nil",
+		},
+	},
+	{
+		"append([]int{}, \"a\");",
+		&object.Error{
+			Message: "cannot use \"a\" (untyped string constant) as int value in argument to append",
+		},
+	},
+	{
+		"x := make([]int, 2, 5); len(x) + cap(x);",
+		&object.Integer{
+			Value: 7,
+		},
+	},
+	{
+		"c := make(chan int, 3); c <- 1; len(c) + cap(c);",
+		&object.Integer{
+			Value: 4,
+		},
+	},
+	{
+		"cap(1);",
+		&object.Error{
+			Message: "invalid argument: 1 (untyped int constant) for built-in cap",
+		},
+	},
+	{
+		"len(nil);",
+		&object.Error{
+			Message: "invalid argument: nil for built-in len",
+		},
+	},
+	{
+		"len(1, 2);",
+		&object.Error{
+			Message: "invalid operation: too many arguments for len(1, 2) (expected 1, found 2)",
+		},
+	},
+	{
+		"len();",
+		&object.Error{
+			Message: "invalid operation: not enough arguments for len() (expected 1, found 0)",
+		},
+	},
+	{
+		"len([]int{}...);",
+		&object.Error{
+			Message: "invalid operation: invalid use of ... with built-in len",
+		},
+	},
+	{
+		"x := []int{1, 2}; clear(x); x[0] + x[1];",
+		&object.Integer{
+			Value: 0,
+		},
+	},
+	{
+		"m := map[string]int{\"a\": 1}; clear(m); len(m);",
+		&object.Integer{
+			Value: 0,
+		},
+	},
+	{
+		"clear(1);",
+		&object.Error{
+			Message: "invalid argument: cannot clear 1 (untyped int constant): argument must be (or constrained by) map or slice",
+		},
+	},
+	{
+		"x := []int{0, 0}; copy(x, []int{1, 2, 3});",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"copy([]int{}, []string{});",
+		&object.Error{
+			Message: "invalid argument: arguments to copy []int{…} (value of type []int) and []string{…} (value of type []string) have different element types int and string",
+		},
+	},
+	{
+		"m := map[string]int{\"a\": 1}; delete(m, \"a\"); len(m);",
+		&object.Integer{
+			Value: 0,
+		},
+	},
+	{
+		"delete(1, 1);",
+		&object.Error{
+			Message: "invalid argument: 1 (untyped int constant) is not a map",
+		},
+	},
+	{
+		"make(int);",
+		&object.Error{
+			Message: "invalid argument: cannot make int; type must be slice, map, or channel",
+		},
+	},
+	{
+		"make(1);",
+		&object.Error{
+			Message: "1 is not a type",
+		},
+	},
+	{
+		"make([]int);",
+		&object.Error{
+			Message: "invalid operation: make([]int) expects 2 or 3 arguments; found 1",
+		},
+	},
+	{
+		"make([]int, 3, 1);",
+		&object.Error{
+			Message: "invalid argument: length and capacity swapped",
+		},
+	},
+	{
+		"make([]int, -1);",
+		&object.Error{
+			Message: "invalid argument: index -1 (constant of type int) must not be negative",
+		},
+	},
+	{
+		"n := -1; make([]int, n);",
+		&object.Error{
+			Message: "panic: runtime error: makeslice: len out of range",
+		},
+	},
+	{
+		"max(1, 3, 2);",
+		&object.Integer{
+			Value: 3,
+		},
+	},
+	{
+		"min(\"b\", \"a\", \"c\");",
+		&object.String{
+			Value: "a",
+		},
+	},
+	{
+		"max(1, \"a\");",
+		&object.Error{
+			Message: "invalid argument: mismatched types int (previous argument) and string (type of \"a\")",
+		},
+	},
+	{
+		"min(true);",
+		&object.Error{
+			Message: "invalid argument: true (untyped bool constant) cannot be ordered",
+		},
+	},
+	{
+		"print(nil);",
+		&object.Error{
+			Message: "use of untyped nil in argument to built-in print",
+		},
+	},
+	{
+		"func f() {}; f([]int{}...);",
+		&object.Error{
+			Message: "have (...) in call to non-variadic f",
+		},
+	},
+	{
+		"import \"strings\"; strings.ToUpper(strings.TrimSpace(\" kinako \"));",
+		&object.String{
+			Value: "KINAKO",
+		},
+	},
+	{
+		"import s \"strings\"; strings := s.Split(\"a,b,c\", \",\"); s.Join(strings, \"-\");",
+		&object.String{
+			Value: "a-b-c",
+		},
+	},
+	{
+		"import \"strings\"; before, after, found := strings.Cut(\"k=v\", \"=\"); found; before + after;",
+		&object.String{
+			Value: "kv",
+		},
+	},
+	{
+		"import \"strconv\"; n, err := strconv.Atoi(\"12\"); err; n;",
+		&object.Integer{
+			Value: 12,
+		},
+	},
+	{
+		"import \"strconv\"; _, err := strconv.Atoi(\"x\"); err.Error();",
+		&object.String{
+			Value: "strconv.Atoi: parsing \"x\": invalid syntax",
+		},
+	},
+	{
+		"import (\"errors\"; \"strconv\"); _, err := strconv.ParseInt(\"999\", 10, 8); errors.Is(err, strconv.ErrRange);",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"import \"math\"; math.MaxInt8 + math.MinInt8;",
+		&object.Integer{
+			Value: -1,
+		},
+	},
+	{
+		"import (\"errors\"; \"fmt\"); base := errors.New(\"base\"); err := fmt.Errorf(\"wrap: %w\", base); errors.Is(err, base);",
+		&object.Boolean{
+			Value: true,
+		},
+	},
+	{
+		"import (\"errors\"; \"fmt\"); target := new(error); errors.As(fmt.Errorf(\"%w\", errors.New(\"a\")), target); err := *target; err.Error();",
+		&object.String{
+			Value: "a",
+		},
+	},
+	{
+		"import (\"fmt\"); fmt.Sprintf(\"%d %s %q %v %T %%\", 1, \"a\", \"b\", []int{2}, true);",
+		&object.String{
+			Value: "1 a \"b\" [2] bool %",
+		},
+	},
+	{
+		"import \"sort\"; s := []int{3, 1, 2}; sort.Ints(s); sort.SearchInts(s, 3);",
+		&object.Integer{
+			Value: 2,
+		},
+	},
+	{
+		"import \"sort\"; s := []string{\"a\", \"c\", \"b\"}; sort.Slice(s, func(i, j int) bool { return s[i] > s[j]; }); s[0] + s[1] + s[2];",
+		&object.String{
+			Value: "cba",
+		},
+	},
+	{
+		"import \"slices\"; s := []int{3, 1, 2}; slices.Sort(s); i, found := slices.BinarySearch(s, 2); found; i;",
+		&object.Integer{
+			Value: 1,
+		},
+	},
+	{
+		"import \"fmt\"; fmt;",
+		&object.Error{
+			Message: "use of package fmt without selector",
+		},
+	},
+	{
+		"import \"net/http\";",
+		&object.Error{
+			Message: "package net/http is not in std",
+		},
+	},
+	{
+		"import \"fmt\"; fmt.println();",
+		&object.Error{
+			Message: "name println not exported by package fmt",
+		},
+	},
+	{
+		"import \"strings\"; strings.Reverse(\"a\");",
+		&object.Error{
+			Message: "undefined: strings.Reverse",
+		},
+	},
+	{
+		"import \"strings\"; strings.ToUpper(1);",
+		&object.Error{
+			Message: "cannot use 1 (untyped int constant) as string value in argument to strings.ToUpper",
+		},
+	},
+	{
+		"import \"strconv\"; x := strconv.Atoi(\"1\");",
+		&object.Error{
+			Message: "assignment mismatch: 1 variable but strconv.Atoi returns 2 values",
+		},
+	},
+}
+
+func TestEvaluate(t *testing.T) {
+	testEvaluate(t, func(program *ast.Program) object.Object {
+		return New().Evaluate(program)
+	})
+}
+
+func testEvaluate(t *testing.T, evaluate func(*ast.Program) object.Object) {
+	for _, test := range evaluateTests {
 		t.Run(test.input, func(t *testing.T) {
 			parser := parser.New(lexer.New(test.input))
 			program := parser.ParseProgram()
-			obj := New().Evaluate(program)
+			obj := evaluate(program)
 			switch obj := obj.(type) {
 			case *object.Integer:
 				testEvaluateInteger(t, obj, test.expected.(*object.Integer))
 			case *object.Boolean:
 				testEvaluateBoolean(t, obj, test.expected.(*object.Boolean))
+			case *object.String:
+				testEvaluateString(t, obj, test.expected.(*object.String))
+			case *object.Nil:
+				if _, ok := test.expected.(*object.Nil); !ok {
+					t.Errorf("unexpected object: got %s, but expected %v\n", obj, test.expected)
+				}
 			case *object.Error:
 				testEvaluateError(t, obj, test.expected.(*object.Error))
 			default:
@@ -129,8 +1443,938 @@ func testEvaluateBoolean(t *testing.T, actual, expected *object.Boolean) {
 	}
 }
 
+func testEvaluateString(t *testing.T, actual, expected *object.String) {
+	if actual.Value != expected.Value {
+		t.Errorf("unexpected value: got %s, but expected %s\n", actual.Value, expected.Value)
+	}
+}
+
 func testEvaluateError(t *testing.T, actual, expected *object.Error) {
 	if actual.Message != expected.Message {
 		t.Errorf("unexpected message: got %s, but expected %s\n", actual.Message, expected.Message)
 	}
+	if expected.Kind != object.ErrUnknown && actual.Kind != expected.Kind {
+		t.Errorf("unexpected kind: got %s, but expected %s\n", actual.Kind, expected.Kind)
+	}
+}
+
+func TestEvaluateErrorKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.ErrorKind
+	}{
+		{"x;", object.ErrName},
+		{"1 + \"a\";", object.ErrType},
+		{"import \"nope\";", object.ErrImport},
+		{"x := 0; 1 / x;", object.ErrDivisionByZero},
+		{"s := []int{1}; s[2];", object.ErrIndexOutOfRange},
+		{"var p *int; *p;", object.ErrNilDereference},
+		{"var m map[string]int; m[\"a\"] = 1;", object.ErrNilMap},
+		{"c := make(chan int); close(c); close(c);", object.ErrClosedChannel},
+		{"var x interface{} = 1; x.(string);", object.ErrRuntime},
+		{"panic(\"boom\");", object.ErrPanic},
+		{"c := make(chan int); <-c;", object.ErrDeadlock},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			obj := New().Evaluate(program)
+			err, ok := obj.(*object.Error)
+			if !ok {
+				t.Fatalf("unexpected object: got %s, but expected error\n", obj)
+			}
+			if err.Kind != test.expected {
+				t.Errorf("unexpected kind: got %s, but expected %s\n", err.Kind, test.expected)
+			}
+			if !errors.Is(err, test.expected) {
+				t.Errorf("unexpected error: got %v, but expected it to be %s\n", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateStackTrace(t *testing.T) {
+	input := `func f() int { return 1 / 0; }
+func g() int { defer func() {}(); return f(); }
+g();`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	bytecode, err := compiler.New().Compile(program)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s\n", err)
+	}
+	for name, obj := range map[string]object.Object{
+		"tree":     New().Evaluate(program),
+		"bytecode": New().RunBytecode(bytecode),
+	} {
+		err, ok := obj.(*object.Error)
+		if !ok {
+			t.Fatalf("unexpected object from %s: got %s, but expected error\n", name, obj)
+		}
+		expected := []object.Frame{
+			{Function: "f", Position: token.Position{Line: 1, Column: 23}},
+			{Function: "g", Position: token.Position{Line: 2, Column: 42}},
+			{Function: "main", Position: token.Position{Line: 3, Column: 1}},
+		}
+		if !reflect.DeepEqual(err.Stack, expected) {
+			t.Errorf("unexpected stack from %s: got %v, but expected %v\n", name, err.Stack, expected)
+		}
+		if actual, expected := err.Span(), (token.Span{Start: token.Position{Line: 1, Column: 23}, End: token.Position{Line: 1, Column: 28}}); actual != expected {
+			t.Errorf("unexpected span from %s: got %v, but expected %v\n", name, actual, expected)
+		}
+	}
+}
+
+func TestEvaluateMainPackage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{"package main; func main() { println(\"main\"); } println(\"init\");", "init\nmain\n", ""},
+		{"package lib; func main() { println(\"main\"); }", "", ""},
+		{"package main; println(\"init\");", "init\n", "function main is undeclared in the main package"},
+		{"package main; func main() { defer println(\"deferred\"); panic(\"boom\"); }", "deferred\n", "panic: boom"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			w := new(bytes.Buffer)
+			obj := New(WithStderr(w)).Evaluate(program)
+			if actual := w.String(); actual != test.expected {
+				t.Errorf("unexpected output: got %q, but expected %q\n", actual, test.expected)
+			}
+			err, _ := obj.(*object.Error)
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error: %s\n", err)
+			case test.err != "" && (err == nil || err.Message != test.err):
+				t.Errorf("unexpected object: got %s, but expected error %q\n", obj, test.err)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithStderr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"print(1, \"a\", true);", "1atrue"},
+		{"println(1, \"a\", true);", "1 a true\n"},
+		{"println();", "\n"},
+		{"var s []int; println(s);", "[0/0]0x0\n"},
+		{"var p *int; println(p);", "0x0\n"},
+		{"done := make(chan int); go func() { println(1); done <- 0; }(); <-done;", "1\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			w := new(bytes.Buffer)
+			New(WithStderr(w)).Evaluate(program)
+			if actual := w.String(); actual != test.expected {
+				t.Errorf("unexpected output: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithStdout(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import \"fmt\"; fmt.Print(1, \"a\", true);", "1atrue"},
+		{"import \"fmt\"; fmt.Println(1, \"a\", []string{\"b\"}, map[string]int{\"c\": 2});", "1 a [b] map[c:2]\n"},
+		{"import \"fmt\"; fmt.Printf(\"%d-%5s-%-3d|%x %t %T\\n\", 1, \"a\", 2, 255, false, \"b\");", "1-    a-2  |ff false string\n"},
+		{"import (\"errors\"; \"fmt\"); fmt.Println(fmt.Errorf(\"wrap: %w\", errors.New(\"a\")));", "wrap: a\n"},
+		{"import \"fmt\"; var err error; fmt.Println(err, nil);", "<nil> <nil>\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			w := new(bytes.Buffer)
+			New(WithStdout(w)).Evaluate(program)
+			if actual := w.String(); actual != test.expected {
+				t.Errorf("unexpected output: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithSeed(t *testing.T) {
+	input := `
+	x := 0;
+	done := make(chan int);
+	go func() { x = 1; done <- 0; }();
+	go func() { x = 2; done <- 0; }();
+	<-done; <-done;
+	x;
+	`
+	for seed := int64(0); seed < 10; seed++ {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		expected := New(WithSeed(seed)).Evaluate(program)
+		for i := 0; i < 10; i++ {
+			actual := New(WithSeed(seed)).Evaluate(program)
+			testEvaluateInteger(t, actual.(*object.Integer), expected.(*object.Integer))
+		}
+	}
+}
+
+func TestEvaluateWithLimits(t *testing.T) {
+	tests := append([]limitTest{
+		{"x := 1 + (2 + (3 + (4 + 5)));", []Option{WithMaxSteps(5)}, ErrStepLimitExceeded},
+	}, limitTests...)
+	testEvaluateWithLimits(t, tests, func(program *ast.Program, opts ...Option) object.Object {
+		return New(opts...).Evaluate(program)
+	})
+}
+
+type limitTest struct {
+	input    string
+	opts     []Option
+	expected error
+}
+
+var limitTests = []limitTest{
+	{"for {}", []Option{WithMaxSteps(100)}, ErrStepLimitExceeded},
+	{"x := 0; for i := 0; i < 10; i++ { x = x + i; }", []Option{WithMaxSteps(1000)}, nil},
+	{"f := func() int { return 1; }; x := f() + (f() + (f() + (f() + f())));", []Option{WithMaxSteps(5)}, ErrStepLimitExceeded},
+	{"done := make(chan int); go func() { for {} }(); <-done;", []Option{WithMaxSteps(100)}, ErrStepLimitExceeded},
+	{"func f() { defer func() { recover(); }(); for {} } f();", []Option{WithMaxSteps(100)}, ErrStepLimitExceeded},
+	{"func f(n int) int { return f(n + 1); } f(0);", []Option{WithMaxCallDepth(50)}, ErrCallDepthLimitExceeded},
+	{"func f(n int) int { return f(n + 1); } f(0);", []Option{WithMaxSteps(1000)}, ErrStepLimitExceeded},
+	{"func f(n int) int { switch n { case 0: return 0; }; return f(n - 1); } f(50);", []Option{WithMaxCallDepth(51)}, nil},
+	{"s := make([]int, 1000000);", []Option{WithMaxAllocations(1000)}, ErrAllocationLimitExceeded},
+	{"var s []int; for { s = append(s, 1); }", []Option{WithMaxAllocations(1000)}, ErrAllocationLimitExceeded},
+	{"m := map[string]int{\"a\": 1}; p := new(int);", []Option{WithMaxAllocations(3)}, nil},
+	{"s := \"x\"; for { s = s + s; }", []Option{WithMaxAllocations(1000)}, ErrAllocationLimitExceeded},
+	{"m := map[int]int{}; for i := 0; ; i++ { m[i] = i; }", []Option{WithMaxAllocations(1000)}, ErrAllocationLimitExceeded},
+	{"m := map[int]int{}; for { m[0] = 0; }", []Option{WithMaxAllocations(1000), WithMaxSteps(10000)}, ErrStepLimitExceeded},
+	{"import \"strings\"; s := strings.Repeat(\"x\", 1000000000000);", []Option{WithMaxAllocations(1000)}, ErrAllocationLimitExceeded},
+	{"import \"fmt\"; s := \"x\"; for { s = fmt.Sprint(s, s); }", []Option{WithMaxAllocations(1000)}, ErrAllocationLimitExceeded},
+	{"func f(n int) int { return f(n + 1); } f(0);", nil, ErrCallDepthLimitExceeded},
+}
+
+func testEvaluateWithLimits(t *testing.T, tests []limitTest, evaluate func(*ast.Program, ...Option) object.Object) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			obj := evaluate(program, test.opts...)
+			if test.expected == nil {
+				if isError(obj) {
+					t.Fatalf("unexpected error: %s", obj)
+				}
+				return
+			}
+			err, ok := obj.(*object.Error)
+			if !ok {
+				t.Fatalf("unexpected object: got %s, but expected error", obj)
+			}
+			if !errors.Is(err.Err, test.expected) {
+				t.Errorf("unexpected error: got %v, but expected %v", err.Err, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithContext(t *testing.T) {
+	testEvaluateWithContext(t, func(program *ast.Program, opts ...Option) object.Object {
+		return New(opts...).Evaluate(program)
+	})
+}
+
+func testEvaluateWithContext(t *testing.T, evaluate func(*ast.Program, ...Option) object.Object) {
+	tests := []string{
+		"for {}",
+		"func fib(n int) int { switch { case n < 2: return n; } return fib(n - 1) + fib(n - 2); } fib(100);",
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			program := parser.New(lexer.New(input)).ParseProgram()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			obj := evaluate(program, WithContext(ctx))
+			err, ok := obj.(*object.Error)
+			if !ok {
+				t.Fatalf("unexpected object: got %s, but expected error", obj)
+			}
+			if !errors.Is(err.Err, context.DeadlineExceeded) {
+				t.Errorf("unexpected error: got %v, but expected %v", err.Err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithImportPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		policy   ImportPolicy
+		expected string
+	}{
+		{"import \"strings\"; strings.ToUpper(\"a\");", AllowImports("strings"), ""},
+		{"import \"os\";", AllowImports("strings"), "use of package os is not allowed"},
+		{"import (\"strings\"; \"time\"); strings.ToUpper(\"a\");", AllowImports("strings"), "use of package time is not allowed"},
+		{"import \"strings\"; strings.ToUpper(\"a\");", AllowImports("strings.ToUpper"), ""},
+		{"import \"strings\"; strings.Repeat(\"a\", 2);", AllowImports("strings.ToUpper"), "use of strings.Repeat is not allowed"},
+		{"import s \"strings\"; s.Repeat(\"a\", 2);", AllowImports("strings.ToUpper"), "use of strings.Repeat is not allowed"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			obj := New(WithImportPolicy(test.policy)).Evaluate(program)
+			if test.expected == "" {
+				if isError(obj) {
+					t.Fatalf("unexpected error: %s", obj)
+				}
+				return
+			}
+			if err, ok := obj.(*object.Error); !ok || err.Message != test.expected {
+				t.Errorf("unexpected object: got %s, but expected %s", obj, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/a.txt": {Data: []byte("hello")},
+		"data/b.txt": {Data: []byte("world")},
+	}
+	tests := []struct {
+		input    string
+		opts     []Option
+		expected string
+	}{
+		{"import (\"fmt\"; \"os\"); s, err := os.ReadFile(\"data/a.txt\"); fmt.Print(s, err);", []Option{WithFS(fsys)}, "hello<nil>"},
+		{"import (\"fmt\"; \"os\"); names, err := os.ReadDir(\"data\"); fmt.Print(names, err);", []Option{WithFS(fsys)}, "[a.txt b.txt] <nil>"},
+		{"import (\"fmt\"; \"os\"); _, err := os.ReadFile(\"c.txt\"); fmt.Print(err);", []Option{WithFS(fsys)}, "open c.txt: file does not exist"},
+		{"import (\"fmt\"; \"os\"); _, err := os.ReadFile(\"data/a.txt\"); fmt.Print(err);", nil, "open data/a.txt: no file system is available"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			w := new(bytes.Buffer)
+			if obj := New(append(test.opts, WithStdout(w))...).Evaluate(program); isError(obj) {
+				t.Fatalf("unexpected error: %s", obj)
+			}
+			if actual := w.String(); actual != test.expected {
+				t.Errorf("unexpected output: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithClock(t *testing.T) {
+	now := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	program := parser.New(lexer.New("import \"time\"; time.Now().Unix();")).ParseProgram()
+	obj := New(WithClock(func() time.Time { return now })).Evaluate(program)
+	expected := &object.Integer{
+		Value: now.Unix(),
+	}
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("unexpected object: got %s, but expected %s", obj, expected)
+	}
+}
+
+func TestExplore(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{
+			`
+			x := 0;
+			done := make(chan int);
+			go func() { x = 1; done <- 0; }();
+			go func() { x = 2; done <- 0; }();
+			<-done; <-done;
+			switch x { case 1: panic("unexpected order"); }
+			`,
+			true,
+		},
+		{
+			`
+			x := 0;
+			done := make(chan int);
+			go func() { x = 1; done <- 0; }();
+			<-done;
+			go func() { x = 2; done <- 0; }();
+			<-done;
+			switch x { case 1: panic("unexpected order"); }
+			`,
+			false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			seed, obj, found := Explore(program, 100)
+			if found != test.expected {
+				t.Fatalf("unexpected result of exploration: got %t, but expected %t\n", found, test.expected)
+			}
+			if !found {
+				return
+			}
+			testEvaluateError(t, obj.(*object.Error), &object.Error{
+				Message: `panic: unexpected order`,
+			})
+			testEvaluateError(t, New(WithSeed(seed)).Evaluate(program).(*object.Error), obj.(*object.Error))
+		})
+	}
+}
+
+func TestEvaluateWithRaceDetector(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"x := 0; done := make(chan int); go func() { x = 1; done <- 0; }(); x = 2; <-done; x;",
+			`WARNING: DATA RACE
+Write at x by goroutine 2:
+  function literal at 1:45
+
+Previous write at x by main goroutine:
+  main at 1:68`,
+		},
+		{
+			"x := 0; done := make(chan int); func f() { x++; } go func() { f(); close(done); }(); x; <-done; x;",
+			`WARNING: DATA RACE
+Write at x by goroutine 2:
+  f at 1:44
+  function literal at 1:63
+
+Previous read at x by main goroutine:
+  main at 1:86`,
+		},
+		{
+			"x := 0; done := make(chan int); go func() { select { case done <- 0: x = 1; } }(); select { case <-done: x = 2; } x;",
+			`WARNING: DATA RACE
+Write at x by main goroutine:
+  main at 1:106
+
+Previous write at x by goroutine 2:
+  function literal at 1:70`,
+		},
+		{
+			"x := 0; done := make(chan int); go func() { x = 1; done <- 0; }(); <-done; x = 2; x;",
+			"2",
+		},
+		{
+			"x := 0; done := make(chan int, 1); go func() { x = 1; close(done); }(); <-done; x;",
+			"1",
+		},
+		{
+			"x := 0; done := make(chan int); go func() { x; done <- 0; }(); go func() { x; done <- 0; }(); <-done; <-done; x = 1;",
+			"1",
+		},
+		{
+			"s := []int{0, 0}; done := make(chan int); go func() { s[1] = 1; done <- 0; }(); s[1]; <-done;",
+			`WARNING: DATA RACE
+Write at s[1] by goroutine 2:
+  function literal at 1:55
+
+Previous read at s[1] by main goroutine:
+  main at 1:81`,
+		},
+		{
+			"s := []int{0, 0}; done := make(chan int); go func() { s[1] = 1; done <- 0; }(); s[0] = 1; <-done; s[1];",
+			"1",
+		},
+		{
+			"m := map[string]int{}; done := make(chan int); go func() { m[\"a\"] = 1; done <- 0; }(); delete(m, \"b\"); <-done;",
+			`WARNING: DATA RACE
+Write at m by goroutine 2:
+  function literal at 1:60
+
+Previous write at m by main goroutine:
+  main at 1:88`,
+		},
+		{
+			"s := []int{0}; done := make(chan int); go func() { for _, v := range s { v; } done <- 0; }(); <-done; s[0] = 1; s[0];",
+			"1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			bytecode, err := compiler.New().Compile(program)
+			if err != nil {
+				t.Fatalf("unexpected error from Compile: %s\n", err)
+			}
+			for seed := int64(0); seed < 10; seed++ {
+				actual := fmt.Sprint(New(WithSeed(seed), WithRaceDetector()).Evaluate(program))
+				if actual != test.expected {
+					t.Errorf("unexpected result with seed %d: got %s, but expected %s\n", seed, actual, test.expected)
+				}
+				actual = fmt.Sprint(New(WithSeed(seed), WithRaceDetector()).RunBytecode(bytecode))
+				if actual != test.expected {
+					t.Errorf("unexpected result from bytecode with seed %d: got %s, but expected %s\n", seed, actual, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	funcs := map[string]interface{}{
+		"greet": func(name string) string {
+			return "hello, " + name
+		},
+		"divide": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		},
+		"sum": func(ns ...uint8) int {
+			var sum int
+			for _, n := range ns {
+				sum += int(n)
+			}
+			return sum
+		},
+		"keys": func(m map[string]bool) []string {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return keys
+		},
+		"describe": func(v interface{}) string {
+			return fmt.Sprintf("%T", v)
+		},
+		"fail": func() {
+			panic("boom")
+		},
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"greet(\"kinako\");", "hello, kinako"},
+		{"q, err := divide(7, 2); err; q;", "3"},
+		{"_, err := divide(1, 0); err.Error();", "division by zero"},
+		{"sum(1, 2, 3) + sum() + sum([]int{4}...);", "10"},
+		{"keys(map[string]bool{\"b\": true, \"a\": false});", "[a b]"},
+		{"describe([]int{1});", "[]interface {}"},
+		{"sum(256);", "cannot use 256 (untyped int constant) as uint8 value in argument to sum: 256 overflows uint8"},
+		{"greet(1);", "cannot use 1 (untyped int constant) as string value in argument to greet: mismatched type int"},
+		{"greet();", "not enough arguments in call to greet"},
+		{"x := divide(1, 1);", "assignment mismatch: 1 variable but divide returns 2 values"},
+		{"fail();", "panic: boom"},
+		{"func f() (s string) { defer func() { s = recover(); }(); fail(); return \"\"; } f();", "boom"},
+		{"func greet() {}", "greet redeclared in this block"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			e := New()
+			for name, fn := range funcs {
+				if err := e.RegisterFunc(name, fn); err != nil {
+					t.Fatalf("unexpected error: %s\n", err)
+				}
+			}
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			if actual := fmt.Sprint(e.Evaluate(program)); actual != test.expected {
+				t.Errorf("unexpected result: got %s, but expected %s\n", actual, test.expected)
+			}
+		})
+	}
+
+	if err := New().RegisterFunc("f", func(chan int) {}); err == nil {
+		t.Errorf("unexpected registration of function with unsupported parameter\n")
+	}
+}
+
+type testAccount struct {
+	Name     string
+	Balance  int
+	Tags     []string
+	Limits   map[string]int
+	Owner    *testAccount
+	ID       int    `kinako:"readonly"`
+	Password string `kinako:"-"`
+	secret   string
+}
+
+func (a *testAccount) Deposit(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid amount: %d", n)
+	}
+	a.Balance += n
+	return nil
+}
+
+func (a testAccount) Greeting() string {
+	return "hello, " + a.Name
+}
+
+func (a *testAccount) Secret() string {
+	return a.Password
+}
+
+func (a *testAccount) SetPassword(password string) {
+	a.Password = password
+}
+
+func TestRegisterValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		mutated  func(*testAccount) bool
+	}{
+		{"account.Name;", "kinako", nil},
+		{"account.Owner.Name;", "tomocy", nil},
+		{"account.Greeting();", "hello, kinako", nil},
+		{"account.Balance = account.Balance + 5; account.Balance++; account.Balance;", "16", func(a *testAccount) bool {
+			return a.Balance == 16
+		}},
+		{"account.Deposit(10); account.Balance;", "20", func(a *testAccount) bool {
+			return a.Balance == 20
+		}},
+		{"account.Deposit(0).Error();", "invalid amount: 0", nil},
+		{"account.Tags[0] = \"vip\"; len(account.Tags);", "2", func(a *testAccount) bool {
+			return a.Tags[0] == "vip"
+		}},
+		{"account.Limits[\"day\"] = 3; account.Limits[\"day\"] + account.Limits[\"none\"];", "3", func(a *testAccount) bool {
+			return a.Limits["day"] == 3
+		}},
+		{"n := 0; for _, tag := range account.Tags { n = n + len(tag); } n;", "7", nil},
+		{"account.Owner.Name = \"x\"; account.Owner.Name;", "x", func(a *testAccount) bool {
+			return a.Owner.Name == "x"
+		}},
+		{"account.ID;", "1", nil},
+		{"account.ID = 2;", "cannot assign to account.ID (field ID is read-only)", nil},
+		{"account.Password;", "account.Password undefined (type *evaluator.testAccount has no field or method Password)", nil},
+		{"account.secret;", "account.secret undefined (type *evaluator.testAccount has no field or method secret)", nil},
+		{"account.Balance = \"a\";", "cannot use string as int value in assignment: mismatched type string", nil},
+		{"account.Secret();", "account.Secret undefined (type *evaluator.testAccount has no field or method Secret)", nil},
+		{"done := make(chan string); go func() { done <- account.Greeting(); }(); <-done;", "hello, kinako", nil},
+		{"account.Tags = append(account.Tags, \"a\", \"b\"); len(account.Tags);", "4", func(a *testAccount) bool {
+			return len(a.Tags) == 4 && a.Tags[3] == "b"
+		}},
+		{"account.Tags = append(account.Tags, []string{\"a\"}...); account.Tags[2];", "a", func(a *testAccount) bool {
+			return len(a.Tags) == 3
+		}},
+		{"append(account.Tags, 1);", "cannot use 1 (untyped int constant) as string value in argument to append", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			account := &testAccount{
+				Name:     "kinako",
+				Balance:  10,
+				Tags:     []string{"new", "gold"},
+				Limits:   map[string]int{},
+				Owner:    &testAccount{Name: "tomocy"},
+				ID:       1,
+				Password: "password",
+			}
+			e := New(WithMethodAccess(func(typ reflect.Type, method reflect.Method) FieldAccess {
+				if method.Name == "Secret" {
+					return FieldHidden
+				}
+				return FieldReadWrite
+			}))
+			if err := e.RegisterValue("account", account); err != nil {
+				t.Fatalf("unexpected error: %s\n", err)
+			}
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			if actual := fmt.Sprint(e.Evaluate(program)); actual != test.expected {
+				t.Errorf("unexpected result: got %s, but expected %s\n", actual, test.expected)
+			}
+			if test.mutated != nil && !test.mutated(account) {
+				t.Errorf("unexpected account: %+v\n", account)
+			}
+		})
+	}
+}
+
+func TestEvaluateMethodsOfHiddenFields(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"account.Secret();", "account.Secret undefined (type *evaluator.testAccount has no field or method Secret)"},
+		{"account.SetPassword(\"a\");", "account.SetPassword undefined (type *evaluator.testAccount has no field or method SetPassword)"},
+		{"account.Greeting();", "hello, kinako"},
+		{"account.Deposit(1); account.Balance;", "1"},
+		{"tags := []string{}; tags = append(tags, account.Name); tags[0];", "kinako"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			e := New()
+			if err := e.RegisterValue("account", &testAccount{Name: "kinako", Password: "password"}); err != nil {
+				t.Fatalf("unexpected error: %s\n", err)
+			}
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			if actual := fmt.Sprint(e.Evaluate(program)); actual != test.expected {
+				t.Errorf("unexpected result: got %s, but expected %s\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithFieldAccess(t *testing.T) {
+	e := New(WithFieldAccess(func(typ reflect.Type, field reflect.StructField) FieldAccess {
+		if field.Name == "Balance" {
+			return FieldHidden
+		}
+		return FieldReadWrite
+	}))
+	if err := e.RegisterValue("account", testAccount{Balance: 1, ID: 1}); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	program := parser.New(lexer.New("account.ID = 2; account.ID + account.Balance;")).ParseProgram()
+	expected := "account.Balance undefined (type evaluator.testAccount has no field or method Balance)"
+	if actual := fmt.Sprint(e.Evaluate(program)); actual != expected {
+		t.Errorf("unexpected result: got %s, but expected %s\n", actual, expected)
+	}
+}
+
+func TestCall(t *testing.T) {
+	input := `
+	func add(a, b int) int { return a + b; }
+	func join(ss []string) string { s := ""; for _, v := range ss { s = s + v; } return s; }
+	func rename(a *testAccount) { a.Name = "renamed"; }
+	func fail() int { panic("boom"); }
+	x := 1;
+	`
+	e := New()
+	program := parser.New(lexer.New(input)).ParseProgram()
+	if obj := e.Evaluate(program); isError(obj) {
+		t.Fatalf("unexpected error: %s\n", obj)
+	}
+
+	account := &testAccount{}
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected interface{}
+		err      string
+	}{
+		{"add", []interface{}{1, 2}, 3, ""},
+		{"join", []interface{}{[]string{"a", "b"}}, "ab", ""},
+		{"rename", []interface{}{account}, nil, ""},
+		{"add", []interface{}{1}, nil, "not enough arguments in call to add"},
+		{"fail", nil, nil, "panic: boom"},
+		{"x", nil, nil, "cannot call non-function x (variable of type int)"},
+		{"y", nil, nil, "undefined: y"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := e.Call(test.name, test.args...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("unexpected error: got %v, but expected %s\n", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s\n", err)
+			}
+			if actual != test.expected {
+				t.Errorf("unexpected result: got %v, but expected %v\n", actual, test.expected)
+			}
+		})
+	}
+	if account.Name != "renamed" {
+		t.Errorf("unexpected name: got %s, but expected renamed\n", account.Name)
+	}
+
+	_, err := e.Call("fail")
+	var runtimeErr *object.Error
+	if !errors.As(err, &runtimeErr) || !errors.Is(err, object.ErrPanic) {
+		t.Fatalf("unexpected error: got %#v, but expected a panic\n", err)
+	}
+	if expected := (token.Position{Line: 5, Column: 20}); runtimeErr.Position != expected {
+		t.Errorf("unexpected position: got %s, but expected %s\n", runtimeErr.Position, expected)
+	}
+}
+
+func TestFunc(t *testing.T) {
+	input := `
+	import "errors";
+	func double(n int) int { return n * 2; }
+	func check(n int) error { switch n { case 0: return errors.New("zero"); } return nil; }
+	func fail(n int) int { return n / 0; }
+	`
+	e := New()
+	if err := e.RegisterFunc("apply", func(f func(int) int, n int) int {
+		return f(n)
+	}); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	program := parser.New(lexer.New(input)).ParseProgram()
+	if obj := e.Evaluate(program); isError(obj) {
+		t.Fatalf("unexpected error: %s\n", obj)
+	}
+
+	var double func(int) int
+	if err := e.Func("double", &double); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if actual := double(21); actual != 42 {
+		t.Errorf("unexpected result: got %d, but expected 42\n", actual)
+	}
+
+	var check func(int) error
+	if err := e.Func("check", &check); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if err := check(1); err != nil {
+		t.Errorf("unexpected error: %s\n", err)
+	}
+	if err := check(0); err == nil || err.Error() != "zero" {
+		t.Errorf("unexpected error: got %v, but expected zero\n", err)
+	}
+
+	var fail func(int) (int, error)
+	if err := e.Func("fail", &fail); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if _, err := fail(1); err == nil || err.Error() != "panic: runtime error: integer divide by zero" {
+		t.Errorf("unexpected error: got %v, but expected panic\n", err)
+	} else if !errors.Is(err, object.ErrDivisionByZero) {
+		t.Errorf("unexpected error kind: got %v, but expected %v\n", err, object.ErrDivisionByZero)
+	}
+
+	program = parser.New(lexer.New("apply(func(n int) int { return n + 1; }, 1);")).ParseProgram()
+	if actual := fmt.Sprint(e.Evaluate(program)); actual != "2" {
+		t.Errorf("unexpected result: got %s, but expected 2\n", actual)
+	}
+
+	var failInt func(int) int
+	if err := e.Func("fail", &failInt); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if actual := failInt(1); actual != 0 {
+		t.Errorf("unexpected result: got %d, but expected 0\n", actual)
+	}
+	if err := e.Err(); !errors.Is(err, object.ErrDivisionByZero) {
+		t.Errorf("unexpected error: got %v, but expected %v\n", err, object.ErrDivisionByZero)
+	}
+	if err := e.Err(); err != nil {
+		t.Errorf("unexpected error after Err: %s\n", err)
+	}
+
+	program = parser.New(lexer.New("apply(func(n int) int { return n / 0; }, 1);")).ParseProgram()
+	obj := e.Evaluate(program)
+	if err, ok := obj.(*object.Error); !ok || err.Kind != object.ErrDivisionByZero {
+		t.Errorf("unexpected result: got %#v, but expected a division by zero\n", obj)
+	} else if expected := (token.Position{Line: 1, Column: 32}); err.Position != expected {
+		t.Errorf("unexpected position: got %s, but expected %s\n", err.Position, expected)
+	}
+
+	if err := e.Func("double", double); err == nil {
+		t.Errorf("unexpected binding to non-pointer\n")
+	}
+}
+
+func TestEnvironmentLookup(t *testing.T) {
+	file := NewEnvironment()
+	fn := NewFunctionEnvironment(file)
+	block := NewEnclosedEnvironment(fn)
+	if err := file.Declare("x", &object.Integer{Value: 1}); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if err := fn.Declare("y", &object.Integer{Value: 2}); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if err := block.Declare("x", &object.Integer{Value: 3}); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+
+	tests := []struct {
+		env      *Environment
+		name     string
+		expected Scope
+	}{
+		{file, "x", PackageScope},
+		{block, "x", BlockScope},
+		{block, "y", FunctionScope},
+		{block, "true", UniverseScope},
+	}
+	for _, test := range tests {
+		_, env, ok := test.env.Lookup(test.name)
+		if !ok {
+			t.Fatalf("failed to look up %s\n", test.name)
+		}
+		if env.Scope() != test.expected {
+			t.Errorf("unexpected scope of %s: got %s, but expected %s\n", test.name, env.Scope(), test.expected)
+		}
+	}
+
+	if _, _, ok := fn.Lookup("z"); ok {
+		t.Errorf("unexpected lookup of z: got ok, but expected not ok\n")
+	}
+}
+
+func TestEnvironmentLookupSlot(t *testing.T) {
+	file := NewEnvironment()
+	block := NewEnclosedEnvironment(file)
+	if err := file.Declare("x", &object.Integer{Value: 1}); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if err := block.Declare("y", &object.Integer{Value: 2}); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+
+	tests := []struct {
+		depth, slot int
+		expected    object.Object
+	}{
+		{0, 0, &object.Integer{Value: 2}},
+		{2, 0, &object.Integer{Value: 1}},
+		{0, 1, nil},
+		{2, 1, nil},
+	}
+	for _, test := range tests {
+		obj, _, ok := block.LookupSlot(test.depth, test.slot)
+		if ok != (test.expected != nil) {
+			t.Fatalf("unexpected lookup of %d:%d: got %t\n", test.depth, test.slot, ok)
+		}
+		if ok && obj.(*object.Integer).Value != test.expected.(*object.Integer).Value {
+			t.Errorf("unexpected object at %d:%d: got %s, but expected %s\n", test.depth, test.slot, obj, test.expected)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		if actual := NewEnvironment().outer.outer.names(); !reflect.DeepEqual(actual, universeNames) {
+			t.Fatalf("unexpected universe: got %v, but expected %v\n", actual, universeNames)
+		}
+	}
+	if !sort.StringsAreSorted(universeNames) {
+		t.Errorf("unexpected universe order: %v\n", universeNames)
+	}
+}
+
+var benchmarkPrograms = map[string]string{
+	"Loop": "sum := 0; for i := 0; i < 1000; i++ { sum = sum + i * 2 - i; } sum;",
+	"Fibonacci": `
+	func fib(n int) int {
+		switch {
+		case n < 2:
+			return n;
+		}
+		return fib(n - 1) + fib(n - 2);
+	}
+	fib(15);
+	`,
+	"Comparison": "n := 0; for i := 0; i < 1000; i++ { switch i < 500 == !(i >= 500) { case true: n++; } } n;",
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	for name, input := range benchmarkPrograms {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if obj := New().Evaluate(program); isError(obj) {
+					b.Fatalf("unexpected error: %s\n", obj)
+				}
+			}
+		})
+	}
 }
@@ -0,0 +1,20 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+)
+
+func RunEvaluateTests(t *testing.T, evaluate func(*ast.Program) object.Object) {
+	testEvaluate(t, evaluate)
+}
+
+func RunEvaluateWithLimitsTests(t *testing.T, evaluate func(*ast.Program, ...Option) object.Object) {
+	testEvaluateWithLimits(t, limitTests, evaluate)
+}
+
+func RunEvaluateWithContextTests(t *testing.T, evaluate func(*ast.Program, ...Option) object.Object) {
+	testEvaluateWithContext(t, evaluate)
+}
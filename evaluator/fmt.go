@@ -0,0 +1,179 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func init() {
+	anyType, stringType := basicType("any"), basicType("string")
+	registerNativePackage(&nativePackage{
+		path: "fmt",
+		functions: map[string]*nativeFunction{
+			"Print": {
+				parameters: []*object.Type{anyType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.printTo(fmt.Sprint(formatArgumentsOf(args)...))
+				},
+			},
+			"Println": {
+				parameters: []*object.Type{anyType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.printTo(fmt.Sprintln(formatArgumentsOf(args)...))
+				},
+			},
+			"Printf": {
+				parameters: []*object.Type{stringType, anyType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					format, fmtArgs := formatOf(stringOf(args[0]), args[1:])
+					return e.printTo(fmt.Sprintf(format, fmtArgs...))
+				},
+			},
+			"Sprint": {
+				parameters: []*object.Type{anyType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.allocateString(fmt.Sprint(formatArgumentsOf(args)...))
+				},
+			},
+			"Sprintln": {
+				parameters: []*object.Type{anyType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.allocateString(fmt.Sprintln(formatArgumentsOf(args)...))
+				},
+			},
+			"Sprintf": {
+				parameters: []*object.Type{stringType, anyType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					format, fmtArgs := formatOf(stringOf(args[0]), args[1:])
+					return e.allocateString(fmt.Sprintf(format, fmtArgs...))
+				},
+			},
+			"Errorf": {
+				parameters: []*object.Type{stringType, anyType},
+				isVariadic: true,
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					format, fmtArgs := formatOf(stringOf(args[0]), args[1:])
+					return newError(fmt.Errorf(format, fmtArgs...))
+				},
+			},
+		},
+	})
+}
+
+func (e *Evaluator) printTo(s string) object.Object {
+	n, err := fmt.Fprint(e.stdout, s)
+	return newTuple(newInteger(n), newError(err))
+}
+
+func formatOf(format string, args []object.Object) (string, []interface{}) {
+	var b strings.Builder
+	fmtArgs := formatArgumentsOf(args)
+	var argIndex int
+	for i := 0; i < len(format); i++ {
+		b.WriteByte(format[i])
+		if format[i] != '%' {
+			continue
+		}
+
+		for i++; i < len(format); i++ {
+			c := format[i]
+			if c == '*' {
+				argIndex++
+			}
+			if !strings.ContainsRune("+-# 0123456789.*", rune(c)) {
+				break
+			}
+			b.WriteByte(c)
+		}
+		if len(format) <= i {
+			break
+		}
+
+		switch verb := format[i]; {
+		case verb == '%':
+			b.WriteByte(verb)
+		case verb == 'T' && argIndex < len(args):
+			b.WriteByte('s')
+			fmtArgs[argIndex] = formatTypeNameOf(args[argIndex])
+			argIndex++
+		default:
+			b.WriteByte(verb)
+			argIndex++
+		}
+	}
+
+	return b.String(), fmtArgs
+}
+
+func formatTypeNameOf(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Nil:
+		return "<nil>"
+	case *object.ErrorValue:
+		return fmt.Sprintf("%T", obj.Value)
+	default:
+		return typeName(obj)
+	}
+}
+
+func formatArgumentsOf(objs []object.Object) []interface{} {
+	args := make([]interface{}, len(objs))
+	for i, obj := range objs {
+		args[i] = formatArgumentOf(obj)
+	}
+
+	return args
+}
+
+func formatArgumentOf(obj object.Object) interface{} {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return int(obj.Value)
+	case *object.Boolean:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Nil:
+		return nil
+	case *object.ErrorValue:
+		return obj.Value
+	case *object.RuntimeError:
+		return obj.Message
+	case *object.Slice:
+		if obj.Elements == nil {
+			return []interface{}(nil)
+		}
+		return formatArgumentsOf(obj.Elements)
+	case *object.Map:
+		if obj.Pairs == nil {
+			return map[interface{}]interface{}(nil)
+		}
+		m := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			m[formatArgumentOf(pair.Key)] = formatArgumentOf(pair.Value)
+		}
+		return m
+	case *object.Pointer:
+		return obj.Value
+	case *object.Type:
+		return obj.Name
+	case *object.HostValue:
+		return obj.Value.Interface()
+	default:
+		return formatAddress(fmt.Sprintf("%p", obj))
+	}
+}
+
+type formatAddress string
+
+func (a formatAddress) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, string(a))
+}
@@ -0,0 +1,51 @@
+package evaluator
+
+import (
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+)
+
+type frame struct {
+	function string
+	site     ast.Node
+	depth    int
+	caller   *frame
+	deferrer *frame
+	defers   []*deferredCall
+	panic    *object.Panic
+}
+
+type deferredCall struct {
+	node      *ast.CallExpression
+	function  object.Object
+	arguments []object.Object
+}
+
+func (f *frame) pushDeferredCall(node *ast.CallExpression, fn object.Object, args []object.Object) {
+	f.defers = append(f.defers, &deferredCall{
+		node:      node,
+		function:  fn,
+		arguments: args,
+	})
+}
+
+func (f *frame) popDeferredCall() *deferredCall {
+	i := len(f.defers) - 1
+	call := f.defers[i]
+	f.defers = f.defers[:i]
+	return call
+}
+
+func (f frame) hasDeferredCalls() bool {
+	return 0 < len(f.defers)
+}
+
+func (f *frame) recover() object.Object {
+	if f.deferrer == nil || f.deferrer.panic == nil {
+		return &object.Nil{}
+	}
+
+	value := f.deferrer.panic.Value
+	f.deferrer.panic = nil
+	return value
+}
@@ -0,0 +1,846 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/object"
+)
+
+var hostErrorType = reflect.TypeOf((*error)(nil)).Elem()
+
+type FieldAccess int
+
+const (
+	FieldReadWrite FieldAccess = iota
+	FieldReadOnly
+	FieldHidden
+)
+
+func defaultFieldAccess(typ reflect.Type, field reflect.StructField) FieldAccess {
+	switch field.Tag.Get("kinako") {
+	case "-":
+		return FieldHidden
+	case "readonly":
+		return FieldReadOnly
+	default:
+		return FieldReadWrite
+	}
+}
+
+func (e *Evaluator) RegisterFunc(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("cannot register %T as function %s", fn, name)
+	}
+
+	typ := v.Type()
+	for i := 0; i < typ.NumIn(); i++ {
+		if _, err := hostTypeOf(typ.In(i)); err != nil {
+			return fmt.Errorf("cannot register function %s: %w", name, err)
+		}
+	}
+	for i := 0; i < typ.NumOut(); i++ {
+		if _, err := hostTypeOf(typ.Out(i)); err != nil {
+			return fmt.Errorf("cannot register function %s: %w", name, err)
+		}
+	}
+
+	return e.env.Declare(name, &object.HostFunction{
+		Name:     name,
+		Function: v,
+	})
+}
+
+func (e *Evaluator) RegisterValue(name string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.IsValid() && (rv.Kind() == reflect.Struct || rv.Kind() == reflect.Array) {
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p.Elem()
+	}
+
+	obj, err := hostObjectOf(rv)
+	if err != nil {
+		return fmt.Errorf("cannot register value %s: %w", name, err)
+	}
+
+	return e.env.Declare(name, obj)
+}
+
+func hostTypeOf(typ reflect.Type) (*object.Type, error) {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return basicType("bool"), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return basicType("int"), nil
+	case reflect.String:
+		return basicType("string"), nil
+	case reflect.Slice:
+		elem, err := hostTypeOf(typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return sliceTypeOf(elem), nil
+	case reflect.Map:
+		key, err := hostTypeOf(typ.Key())
+		if err != nil {
+			return nil, err
+		}
+		value, err := hostTypeOf(typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return mapTypeOf(key, value), nil
+	case reflect.Interface:
+		if typ == hostErrorType {
+			return basicType("error"), nil
+		}
+		if typ.NumMethod() == 0 {
+			return basicType("any"), nil
+		}
+	case reflect.Struct, reflect.Ptr, reflect.Array, reflect.Func:
+		return &object.Type{
+			Kind: object.BasicType,
+			Name: typ.String(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", typ)
+}
+
+func (e *Evaluator) callHost(node *ast.CallExpression, fn *object.HostFunction, args []object.Object) (result object.Object) {
+	name := ast.Format(node.Function)
+	typ := fn.Function.Type()
+	if node.HasEllipsis && !typ.IsVariadic() {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("have (...) in call to non-variadic %s", name),
+		}
+	}
+
+	n := typ.NumIn()
+	if typ.IsVariadic() && !node.HasEllipsis {
+		n--
+		if len(args) < n {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("not enough arguments in call to %s", name),
+			}
+		}
+	} else if err := checkNumberOfArguments(name, args, n); err != nil {
+		return err
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		paramType := typ.In(min(i, typ.NumIn()-1))
+		if typ.IsVariadic() && !node.HasEllipsis && typ.NumIn()-1 <= i {
+			paramType = paramType.Elem()
+		}
+		v, err := e.hostValueOf(arg, paramType)
+		if err != nil {
+			desc := typeName(arg)
+			if i < len(node.Arguments) {
+				desc = e.describe(node.Arguments[i], arg)
+			}
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in argument to %s: %s", desc, paramType, name, err),
+			}
+		}
+		in[i] = v
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = &object.Panic{
+				Value: recoveredValueOf(r),
+			}
+		}
+	}()
+
+	e.callbackErr = nil
+	var out []reflect.Value
+	if node.HasEllipsis {
+		out = fn.Function.CallSlice(in)
+	} else {
+		out = fn.Function.Call(in)
+	}
+	if err := e.Err(); err != nil {
+		return callbackError(err)
+	}
+
+	results := make([]object.Object, len(out))
+	for i, v := range out {
+		obj, err := objectOf(v)
+		if err != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use result of %s: %s", name, err),
+			}
+		}
+		results[i] = obj
+	}
+
+	switch len(results) {
+	case 0:
+		return &object.Nil{}
+	case 1:
+		return results[0]
+	default:
+		return newTuple(results...)
+	}
+}
+
+func (e *Evaluator) hostValueOf(obj object.Object, typ reflect.Type) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	switch obj := obj.(type) {
+	case *object.Nil:
+		switch typ.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+			return v, nil
+		}
+	case *object.HostValue:
+		if obj.Value.Type().AssignableTo(typ) {
+			v.Set(obj.Value)
+			return v, nil
+		}
+		if typ.Kind() == reflect.Ptr && obj.Value.CanAddr() && obj.Value.Type() == typ.Elem() {
+			v.Set(obj.Value.Addr())
+			return v, nil
+		}
+		return v, fmt.Errorf("mismatched type %s", typeName(obj))
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		if obj, ok := obj.(*object.Boolean); ok {
+			v.SetBool(obj.Value)
+			return v, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if obj, ok := obj.(*object.Integer); ok {
+			if v.OverflowInt(obj.Value) {
+				return v, fmt.Errorf("%d overflows %s", obj.Value, typ)
+			}
+			v.SetInt(obj.Value)
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if obj, ok := obj.(*object.Integer); ok {
+			if obj.Value < 0 || v.OverflowUint(uint64(obj.Value)) {
+				return v, fmt.Errorf("%d overflows %s", obj.Value, typ)
+			}
+			v.SetUint(uint64(obj.Value))
+			return v, nil
+		}
+	case reflect.String:
+		if obj, ok := obj.(*object.String); ok {
+			v.SetString(obj.Value)
+			return v, nil
+		}
+	case reflect.Slice:
+		if obj, ok := obj.(*object.Slice); ok {
+			v.Set(reflect.MakeSlice(typ, len(obj.Elements), len(obj.Elements)))
+			for i, elem := range obj.Elements {
+				elemValue, err := e.hostValueOf(elem, typ.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.Index(i).Set(elemValue)
+			}
+			return v, nil
+		}
+	case reflect.Map:
+		if obj, ok := obj.(*object.Map); ok {
+			v.Set(reflect.MakeMapWithSize(typ, len(obj.Pairs)))
+			for _, pair := range obj.Pairs {
+				key, err := e.hostValueOf(pair.Key, typ.Key())
+				if err != nil {
+					return v, err
+				}
+				value, err := e.hostValueOf(pair.Value, typ.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.SetMapIndex(key, value)
+			}
+			return v, nil
+		}
+	case reflect.Func:
+		if obj, ok := obj.(*object.Function); ok {
+			v.Set(e.hostFuncOf(obj, typ))
+			return v, nil
+		}
+		if obj, ok := obj.(*object.HostFunction); ok && obj.Function.Type().AssignableTo(typ) {
+			v.Set(obj.Function)
+			return v, nil
+		}
+	case reflect.Interface:
+		if obj, ok := obj.(*object.ErrorValue); ok {
+			v.Set(reflect.ValueOf(obj.Value))
+			return v, nil
+		}
+		if typ == hostErrorType {
+			break
+		}
+		if value, ok := anyValueOf(obj); ok {
+			if value != nil {
+				v.Set(reflect.ValueOf(value))
+			}
+			return v, nil
+		}
+	}
+
+	return v, fmt.Errorf("mismatched type %s", typeName(obj))
+}
+
+func GoValueOf(obj object.Object) (interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	if tuple, ok := obj.(*object.Tuple); ok {
+		values := make([]interface{}, len(tuple.Values))
+		for i, elem := range tuple.Values {
+			value, err := GoValueOf(elem)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	value, ok := anyValueOf(obj)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %s to a Go value", typeName(obj))
+	}
+
+	return value, nil
+}
+
+func anyValueOf(obj object.Object) (interface{}, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return int(obj.Value), true
+	case *object.Boolean:
+		return obj.Value, true
+	case *object.String:
+		return obj.Value, true
+	case *object.Nil:
+		return nil, true
+	case *object.ErrorValue:
+		return obj.Value, true
+	case *object.Slice:
+		values := make([]interface{}, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			value, ok := anyValueOf(elem)
+			if !ok {
+				return nil, false
+			}
+			values[i] = value
+		}
+		return values, true
+	case *object.Map:
+		values := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := anyValueOf(pair.Key)
+			if !ok {
+				return nil, false
+			}
+			value, ok := anyValueOf(pair.Value)
+			if !ok {
+				return nil, false
+			}
+			values[key] = value
+		}
+		return values, true
+	case *object.HostValue:
+		return obj.Value.Interface(), true
+	case *object.HostFunction:
+		return obj.Function.Interface(), true
+	default:
+		return nil, false
+	}
+}
+
+func objectOf(v reflect.Value) (object.Object, error) {
+	if !v.IsValid() {
+		return &object.Nil{}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return newBoolean(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return object.NewInteger(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if math.MaxInt64 < v.Uint() {
+			return nil, fmt.Errorf("%d overflows int", v.Uint())
+		}
+		return object.NewInteger(int64(v.Uint())), nil
+	case reflect.String:
+		return newString(v.String()), nil
+	case reflect.Slice:
+		typ, err := hostTypeOf(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		s := &object.Slice{
+			ElementType: typ.Element,
+		}
+		if v.IsNil() {
+			return s, nil
+		}
+		s.Elements = make([]object.Object, v.Len())
+		for i := range s.Elements {
+			elem, err := objectOf(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			s.Elements[i] = elem
+		}
+		return s, nil
+	case reflect.Map:
+		typ, err := hostTypeOf(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		m := &object.Map{
+			KeyType:   typ.Key,
+			ValueType: typ.Element,
+		}
+		if v.IsNil() {
+			return m, nil
+		}
+		m.Pairs = make(map[interface{}]*object.MapPair, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := objectOf(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			value, err := objectOf(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			hash, hashErr := hashKeyOf(key)
+			if hashErr != nil {
+				return nil, fmt.Errorf("invalid map key %s", key)
+			}
+			m.Pairs[hash] = &object.MapPair{
+				Key:   key,
+				Value: value,
+			}
+		}
+		return m, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return &object.Nil{}, nil
+		}
+		if err, ok := v.Interface().(error); ok {
+			return newError(err), nil
+		}
+		return objectOf(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return &object.Nil{}, nil
+		}
+		return &object.HostValue{
+			Value: v,
+		}, nil
+	case reflect.Struct, reflect.Array:
+		return &object.HostValue{
+			Value: v,
+		}, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return &object.Nil{}, nil
+		}
+		return &object.HostFunction{
+			Name:     v.Type().String(),
+			Function: v,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+func hostObjectOf(v reflect.Value) (object.Object, error) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if _, err := hostTypeOf(v.Type()); err != nil {
+			return nil, err
+		}
+		return &object.HostValue{
+			Value: v,
+		}, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return &object.Nil{}, nil
+		}
+		if err, ok := v.Interface().(error); ok {
+			return newError(err), nil
+		}
+		return hostObjectOf(v.Elem())
+	default:
+		return objectOf(v)
+	}
+}
+
+func (e *Evaluator) selectHostMember(node *ast.SelectorExpression, obj *object.HostValue) object.Object {
+	if method, ok := e.hostMethodOf(obj.Value, node.Selector.Name); ok {
+		return &object.HostFunction{
+			Name:     ast.Format(node),
+			Function: method,
+		}
+	}
+
+	field, _, err := e.hostFieldOf(node, obj)
+	if err != nil {
+		return err
+	}
+	fieldObj, hostErr := hostObjectOf(field)
+	if hostErr != nil {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot use %s: %s", ast.Format(node), hostErr),
+		}
+	}
+
+	return fieldObj
+}
+
+func (e *Evaluator) hostMethodOf(v reflect.Value, name string) (reflect.Value, bool) {
+	receivers := []reflect.Value{v}
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		receivers = append(receivers, v.Addr())
+	}
+
+	for _, recv := range receivers {
+		method, ok := recv.Type().MethodByName(name)
+		if !ok {
+			continue
+		}
+		if e.methodAccessOf(recv.Type(), method) == FieldHidden {
+			return reflect.Value{}, false
+		}
+		return recv.Method(method.Index), true
+	}
+
+	return reflect.Value{}, false
+}
+
+func (e *Evaluator) methodAccessOf(typ reflect.Type, method reflect.Method) FieldAccess {
+	if e.methodAccess != nil {
+		return e.methodAccess(typ, method)
+	}
+	if e.accessesHiddenField(typ, method) {
+		return FieldHidden
+	}
+
+	return FieldReadWrite
+}
+
+// accessesHiddenField reports whether a method is named as the getter or
+// setter of a field which scripts cannot see, because the field is either
+// unexported or hidden by the field policy.
+func (e *Evaluator) accessesHiddenField(typ reflect.Type, method reflect.Method) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous || field.PkgPath == "" && e.fieldAccess(typ, field) != FieldHidden {
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(field.Name)
+		name := string(unicode.ToUpper(r)) + field.Name[size:]
+		switch method.Name {
+		case name, "Get" + name, "Set" + name:
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *Evaluator) appendHost(node *ast.CallExpression, s *object.HostValue, args []object.Object) object.Object {
+	typ := s.Value.Type()
+	var elems reflect.Value
+	if node.HasEllipsis {
+		if len(args) != 1 {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: "invalid operation: invalid use of ... with built-in append",
+			}
+		}
+		v, err := e.hostValueOf(args[0], typ)
+		if err != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in argument to append", e.describe(node.Arguments[1], args[0]), typ),
+			}
+		}
+		elems = v
+	} else {
+		elems = reflect.MakeSlice(typ, len(args), len(args))
+		for i, arg := range args {
+			v, err := e.hostValueOf(arg, typ.Elem())
+			if err != nil {
+				return &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("cannot use %s as %s value in argument to append", e.describe(node.Arguments[i+1], arg), typ.Elem()),
+				}
+			}
+			elems.Index(i).Set(v)
+		}
+	}
+
+	if err := e.budget.allocate(int64(elems.Len())); err != nil {
+		return err
+	}
+
+	return &object.HostValue{
+		Value: reflect.AppendSlice(s.Value, elems),
+	}
+}
+
+func (e *Evaluator) hostFieldOf(node *ast.SelectorExpression, obj *object.HostValue) (reflect.Value, FieldAccess, object.Object) {
+	v := obj.Value
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, FieldHidden, &object.Panic{
+				Value: &object.RuntimeError{
+					Kind:    object.ErrNilDereference,
+					Message: "runtime error: invalid memory address or nil pointer dereference",
+				},
+			}
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Struct {
+		if field, ok := v.Type().FieldByName(node.Selector.Name); ok && field.PkgPath == "" {
+			if access := e.fieldAccess(v.Type(), field); access != FieldHidden {
+				return v.FieldByIndex(field.Index), access, nil
+			}
+		}
+	}
+
+	return reflect.Value{}, FieldHidden, &object.Error{
+		Kind:    object.ErrName,
+		Message: fmt.Sprintf("%s undefined (type %s has no field or method %s)", ast.Format(node), typeName(obj), node.Selector.Name),
+	}
+}
+
+func (e *Evaluator) assignSelector(node *ast.SelectorExpression, value object.Object) object.Object {
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
+		return obj
+	}
+	host, ok := obj.(*object.HostValue)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot assign to %s (neither addressable nor a map index expression)", ast.Format(node)),
+		}
+	}
+
+	field, access, err := e.hostFieldOf(node, host)
+	if err != nil {
+		return err
+	}
+	if access == FieldReadOnly {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot assign to %s (field %s is read-only)", ast.Format(node), node.Selector.Name),
+		}
+	}
+	if !field.CanSet() {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot assign to %s (neither addressable nor a map index expression)", ast.Format(node)),
+		}
+	}
+	v, hostErr := e.hostValueOf(value, field.Type())
+	if hostErr != nil {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot use %s as %s value in assignment: %s", typeName(value), field.Type(), hostErr),
+		}
+	}
+
+	field.Set(v)
+	return value
+}
+
+func (e *Evaluator) indexHost(node *ast.IndexExpression, obj *object.HostValue, index object.Object) object.Object {
+	v := obj.Value
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		i, err := e.integerIndexOf(node.Indices[0], index, v.Len())
+		if err != nil {
+			return err
+		}
+		elem, hostErr := hostObjectOf(v.Index(i))
+		if hostErr != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s: %s", ast.Format(node), hostErr),
+			}
+		}
+		return elem
+	case reflect.Map:
+		key, hostErr := e.hostValueOf(index, v.Type().Key())
+		if hostErr != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in map index: %s", e.describe(node.Indices[0], index), v.Type().Key(), hostErr),
+			}
+		}
+		value := v.MapIndex(key)
+		if !value.IsValid() {
+			value = reflect.Zero(v.Type().Elem())
+		}
+		elem, hostErr := hostObjectOf(value)
+		if hostErr != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s: %s", ast.Format(node), hostErr),
+			}
+		}
+		return elem
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot index %s", typeName(obj)),
+		}
+	}
+}
+
+func (e *Evaluator) assignHostIndex(node *ast.IndexExpression, obj *object.HostValue, index, value object.Object) object.Object {
+	v := obj.Value
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		i, err := e.integerIndexOf(node.Indices[0], index, v.Len())
+		if err != nil {
+			return err
+		}
+		elem := v.Index(i)
+		if !elem.CanSet() {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot assign to %s (neither addressable nor a map index expression)", ast.Format(node)),
+			}
+		}
+		elemValue, hostErr := e.hostValueOf(value, elem.Type())
+		if hostErr != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in assignment: %s", typeName(value), elem.Type(), hostErr),
+			}
+		}
+		elem.Set(elemValue)
+	case reflect.Map:
+		key, hostErr := e.hostValueOf(index, v.Type().Key())
+		if hostErr != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in map index: %s", e.describe(node.Indices[0], index), v.Type().Key(), hostErr),
+			}
+		}
+		elemValue, hostErr := e.hostValueOf(value, v.Type().Elem())
+		if hostErr != nil {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in assignment: %s", typeName(value), v.Type().Elem(), hostErr),
+			}
+		}
+		if v.IsNil() {
+			return &object.Panic{
+				Value: &object.RuntimeError{
+					Kind:    object.ErrNilMap,
+					Message: "assignment to entry in nil map",
+				},
+			}
+		}
+		v.SetMapIndex(key, elemValue)
+	default:
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("invalid operation: cannot index %s", typeName(obj)),
+		}
+	}
+
+	return nil
+}
+
+type hostEntry struct {
+	key   object.Object
+	value object.Object
+}
+
+func hostEntriesOf(obj *object.HostValue) ([]hostEntry, object.Object) {
+	v := obj.Value
+	var entries []hostEntry
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			value, err := hostObjectOf(v.Index(i))
+			if err != nil {
+				return nil, &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("cannot range over %s: %s", typeName(obj), err),
+				}
+			}
+			entries = append(entries, hostEntry{
+				key:   newInteger(i),
+				value: value,
+			})
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := hostObjectOf(iter.Key())
+			if err != nil {
+				return nil, &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("cannot range over %s: %s", typeName(obj), err),
+				}
+			}
+			value, err := hostObjectOf(iter.Value())
+			if err != nil {
+				return nil, &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("cannot range over %s: %s", typeName(obj), err),
+				}
+			}
+			entries = append(entries, hostEntry{
+				key:   key,
+				value: value,
+			})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return object.Less(entries[i].key, entries[j].key)
+		})
+	default:
+		return nil, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot range over %s", typeName(obj)),
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tomocy/kinako/object"
+)
+
+var (
+	ErrStepLimitExceeded       = errors.New("step limit exceeded")
+	ErrCallDepthLimitExceeded  = errors.New("call depth limit exceeded")
+	ErrAllocationLimitExceeded = errors.New("allocation limit exceeded")
+)
+
+// defaultMaxCallDepth keeps unbounded recursion from overflowing the Go stack
+// when no call depth limit is given.
+const defaultMaxCallDepth = 10000
+
+func WithContext(ctx context.Context) Option {
+	return func(e *Evaluator) {
+		e.budget.ctx = ctx
+	}
+}
+
+func WithMaxSteps(n int64) Option {
+	return func(e *Evaluator) {
+		e.budget.maxSteps = n
+	}
+}
+
+func WithMaxCallDepth(n int) Option {
+	return func(e *Evaluator) {
+		e.budget.maxCallDepth = n
+	}
+}
+
+func WithMaxAllocations(n int64) Option {
+	return func(e *Evaluator) {
+		e.budget.maxAllocations = n
+	}
+}
+
+type budget struct {
+	ctx            context.Context
+	maxSteps       int64
+	steps          int64
+	maxCallDepth   int
+	maxAllocations int64
+	allocations    int64
+}
+
+func (b *budget) reset() {
+	b.steps, b.allocations = 0, 0
+}
+
+func (b *budget) step() *object.Error {
+	if b.ctx != nil {
+		select {
+		case <-b.ctx.Done():
+			return limitError(b.ctx.Err())
+		default:
+		}
+	}
+
+	b.steps++
+	if 0 < b.maxSteps && b.maxSteps < b.steps {
+		return limitError(ErrStepLimitExceeded)
+	}
+
+	return nil
+}
+
+func (b *budget) enter(depth int) *object.Error {
+	max := b.maxCallDepth
+	if max <= 0 {
+		max = defaultMaxCallDepth
+	}
+	if max < depth {
+		return limitError(ErrCallDepthLimitExceeded)
+	}
+
+	return nil
+}
+
+func (b *budget) allocate(n int64) *object.Error {
+	if n < 0 {
+		n = 0
+	}
+	b.allocations += n
+	if 0 < b.maxAllocations && b.maxAllocations < b.allocations {
+		return limitError(ErrAllocationLimitExceeded)
+	}
+
+	return nil
+}
+
+func limitError(err error) *object.Error {
+	kind := object.ErrLimit
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		kind = object.ErrCanceled
+	}
+
+	return &object.Error{
+		Kind:    kind,
+		Message: err.Error(),
+		Err:     err,
+	}
+}
@@ -0,0 +1,414 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/compiler"
+	"github.com/tomocy/kinako/object"
+)
+
+type code struct {
+	bytecode *compiler.Bytecode
+	bodies   map[*ast.BlockStatement]int
+}
+
+type machine struct {
+	evaluator *Evaluator
+	code      *code
+	stack     []object.Object
+	scopes    []*Environment
+	targets   []target
+}
+
+type target struct {
+	end, cont int
+	depth     int
+	height    int
+}
+
+// RunBytecode runs a program compiled by the compiler package. The function
+// bodies it compiled keep running as bytecode however they are called,
+// including from goroutines, deferred calls and host callbacks.
+func (e *Evaluator) RunBytecode(bytecode *compiler.Bytecode) object.Object {
+	if err := e.checkMembers(bytecode.Program, bytecode.Members); err != nil {
+		return err
+	}
+	e.code = &code{
+		bytecode: bytecode,
+		bodies:   make(map[*ast.BlockStatement]int),
+	}
+	entries := make(map[ast.Statement]int, len(bytecode.Entries))
+	for i, stmt := range bytecode.Program.Statements {
+		entries[stmt] = bytecode.Entries[i]
+	}
+
+	return e.runProgram(bytecode.Program, func(stmt ast.Statement) object.Object {
+		if err := e.budget.step(); err != nil {
+			return e.locate(stmt, err)
+		}
+		return e.execute(entries[stmt])
+	})
+}
+
+func (e *Evaluator) evaluateBody(node *ast.BlockStatement) object.Object {
+	if e.code != nil {
+		if ip, ok := e.code.bodies[node]; ok {
+			return e.execute(ip)
+		}
+	}
+
+	return e.evaluateStatements(node.Statements)
+}
+
+func (e *Evaluator) execute(ip int) object.Object {
+	env := e.env
+	defer func() {
+		e.env = env
+	}()
+
+	m := &machine{
+		evaluator: e,
+		code:      e.code,
+	}
+	return m.run(ip)
+}
+
+func (m *machine) run(ip int) object.Object {
+	ins, e := m.code.bytecode.Instructions, m.evaluator
+	for {
+		op := compiler.Opcode(ins[ip])
+		ip++
+
+		switch op {
+		case compiler.OpConstant:
+			m.push(m.code.bytecode.Constants[m.readOperand(&ip)])
+		case compiler.OpNil:
+			m.push(&object.Nil{})
+		case compiler.OpTrue:
+			m.push(object.NewBoolean(true))
+		case compiler.OpPop:
+			m.pop()
+		case compiler.OpReturn:
+			return m.pop()
+		case compiler.OpReturnValue:
+			ret := new(object.ReturnValue)
+			if m.readOperand(&ip) != 0 {
+				ret.Value = m.pop()
+			}
+			return ret
+		case compiler.OpEvaluate:
+			obj := e.Evaluate(m.nodeOf(&ip))
+			switch obj.(type) {
+			case *object.Break:
+				if len(m.targets) == 0 {
+					return obj
+				}
+				ip = m.breakTarget()
+				continue
+			case *object.Continue, *object.Fallthrough:
+				if !m.inLoop() {
+					return obj
+				}
+				ip = m.continueLoop()
+				continue
+			case *object.ReturnValue, *object.Error, *object.Panic:
+				return obj
+			}
+			m.push(obj)
+		case compiler.OpGet:
+			node := m.nodeOf(&ip)
+			obj := e.evaluateIdentifier(node.(*ast.Identifier))
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		case compiler.OpAssign:
+			node := m.nodeOf(&ip).(*ast.AssignmentStatement)
+			obj := e.assign(node.LExpressions, []object.Object{m.pop()})
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		case compiler.OpDeclare:
+			node := m.nodeOf(&ip)
+			var obj object.Object
+			switch node := node.(type) {
+			case *ast.VariableDeclaration:
+				obj = e.declareVariable(node, m.pop())
+			case *ast.ShortVariableDeclaration:
+				obj = m.pop()
+				if err := e.checkShortVariableDeclaration(node); err != nil {
+					obj = err
+					break
+				}
+				obj = e.declareShortVariables(node, []object.Object{obj})
+			}
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		case compiler.OpIncDec:
+			node := m.nodeOf(&ip).(*ast.IncDecStatement)
+			obj := e.incDec(node, m.pop())
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		case compiler.OpMinus, compiler.OpNot:
+			node := m.nodeOf(&ip).(*ast.PrefixExpression)
+			obj := m.operatePrefix(node.Operator, m.pop())
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv,
+			compiler.OpEqual, compiler.OpNotEqual,
+			compiler.OpLessThan, compiler.OpGreaterThan, compiler.OpLessThanOrEqual, compiler.OpGreaterThanOrEqual:
+			node := m.nodeOf(&ip).(*ast.InfixExpression)
+			right := m.pop()
+			obj := m.operate(node.Operator, m.pop(), right)
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		case compiler.OpJump:
+			ip = m.readOperand(&ip)
+		case compiler.OpJumpIfFalse:
+			target := m.readOperand(&ip)
+			node := m.nodeOf(&ip)
+			ok, err := e.condition(m.pop())
+			if err != nil {
+				return e.locate(node, err)
+			}
+			if !ok {
+				ip = target
+			}
+		case compiler.OpEnterScope:
+			m.scopes = append(m.scopes, e.env)
+			e.env = NewEnclosedEnvironment(e.env)
+		case compiler.OpLeaveScope:
+			m.leaveScope()
+		case compiler.OpCloneScope:
+			e.env = e.env.clone()
+		case compiler.OpLoop:
+			end := m.readOperand(&ip)
+			cont := m.readOperand(&ip)
+			m.pushTarget(end, cont)
+		case compiler.OpSwitch:
+			m.pushTarget(m.readOperand(&ip), -1)
+		case compiler.OpEndLoop, compiler.OpEndSwitch:
+			m.targets = m.targets[:len(m.targets)-1]
+		case compiler.OpBreak:
+			ip = m.breakTarget()
+		case compiler.OpContinue:
+			ip = m.continueLoop()
+		case compiler.OpYield:
+			node := m.nodeOf(&ip)
+			if obj := e.yield(); obj != nil {
+				return e.locate(node, obj)
+			}
+		case compiler.OpCase:
+			target := m.readOperand(&ip)
+			node := m.nodeOf(&ip)
+			value := m.pop()
+			obj := e.evaluateEqual(m.stack[len(m.stack)-1], value)
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			if obj.(*object.Boolean).Value {
+				ip = target
+			}
+		case compiler.OpTypeCase:
+			target := m.readOperand(&ip)
+			node := m.nodeOf(&ip)
+			typ := m.nodeOf(&ip).(*ast.Identifier)
+			matched, err := e.hasType(m.stack[len(m.stack)-1], typ)
+			if err != nil {
+				return e.locate(node, err)
+			}
+			if matched {
+				ip = target
+			}
+		case compiler.OpBind:
+			node := m.nodeOf(&ip).(*ast.TypeSwitchStatement)
+			e.env.set(node.Identifier.Name, m.stack[len(m.stack)-1])
+		case compiler.OpFunction:
+			entry := m.readOperand(&ip)
+			node := m.nodeOf(&ip)
+			var obj object.Object
+			switch node := node.(type) {
+			case *ast.FunctionLiteral:
+				m.code.bodies[node.Body] = entry
+				obj = e.evaluateFunctionLiteral(node)
+			case *ast.FunctionDeclaration:
+				m.code.bodies[node.Body] = entry
+				obj = e.evaluateFunctionDeclaration(node)
+			}
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		case compiler.OpCall:
+			n := m.readOperand(&ip)
+			node := m.nodeOf(&ip).(*ast.CallExpression)
+			if err := e.budget.step(); err != nil {
+				return e.locate(node, err)
+			}
+			args, err := m.arguments(node, n)
+			if err != nil {
+				return e.locate(node, err)
+			}
+			obj := e.call(node, m.pop(), args)
+			if isError(obj) {
+				return e.locate(node, obj)
+			}
+			m.push(obj)
+		}
+	}
+}
+
+func (m *machine) arguments(node *ast.CallExpression, n int) ([]object.Object, *object.Error) {
+	args := make([]object.Object, n)
+	copy(args, m.stack[len(m.stack)-n:])
+	m.stack = m.stack[:len(m.stack)-n]
+
+	for i, arg := range args {
+		tuple, ok := arg.(*object.Tuple)
+		if !ok {
+			continue
+		}
+		if n == 1 {
+			return tuple.Values, nil
+		}
+		return nil, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("multiple-value %s (value of type %s) in single-value context", ast.Format(node.Arguments[i]), typeName(tuple)),
+		}
+	}
+
+	return args, nil
+}
+
+func (m *machine) operatePrefix(op ast.PrefixOperator, obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		if op == ast.Negative {
+			return object.NewInteger(-obj.Value)
+		}
+	case *object.Boolean:
+		if op == ast.Not {
+			return object.NewBoolean(!obj.Value)
+		}
+	}
+
+	return m.evaluator.operatePrefix(op, obj)
+}
+
+func (m *machine) operate(op ast.InfixOperator, left, right object.Object) object.Object {
+	l, ok := left.(*object.Integer)
+	if !ok {
+		return m.evaluator.operate(op, left, right)
+	}
+	r, ok := right.(*object.Integer)
+	if !ok {
+		return m.evaluator.operate(op, left, right)
+	}
+
+	switch op {
+	case ast.Plus:
+		return object.NewInteger(l.Value + r.Value)
+	case ast.Minus:
+		return object.NewInteger(l.Value - r.Value)
+	case ast.Asterisk:
+		return object.NewInteger(l.Value * r.Value)
+	case ast.Slash:
+		if r.Value == 0 {
+			return m.evaluator.operate(op, left, right)
+		}
+		return object.NewInteger(l.Value / r.Value)
+	case ast.Equal:
+		return object.NewBoolean(l.Value == r.Value)
+	case ast.NotEqual:
+		return object.NewBoolean(l.Value != r.Value)
+	case ast.LessThan:
+		return object.NewBoolean(l.Value < r.Value)
+	case ast.GreaterThan:
+		return object.NewBoolean(l.Value > r.Value)
+	case ast.LessThanOrEqual:
+		return object.NewBoolean(l.Value <= r.Value)
+	case ast.GreaterThanOrEqual:
+		return object.NewBoolean(l.Value >= r.Value)
+	default:
+		return m.evaluator.operate(op, left, right)
+	}
+}
+
+func (m *machine) pushTarget(end, cont int) {
+	m.targets = append(m.targets, target{
+		end:    end,
+		cont:   cont,
+		depth:  len(m.scopes),
+		height: len(m.stack),
+	})
+}
+
+func (m *machine) breakTarget() int {
+	t := m.targets[len(m.targets)-1]
+	m.unwind(t)
+	return t.end
+}
+
+func (m *machine) inLoop() bool {
+	for _, t := range m.targets {
+		if t.cont != -1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *machine) continueLoop() int {
+	i := len(m.targets) - 1
+	for m.targets[i].cont == -1 {
+		i--
+	}
+	m.targets = m.targets[:i+1]
+
+	t := m.targets[i]
+	m.unwind(t)
+	return t.cont
+}
+
+func (m *machine) unwind(t target) {
+	for t.depth < len(m.scopes) {
+		m.leaveScope()
+	}
+	m.stack = m.stack[:t.height]
+}
+
+func (m *machine) leaveScope() {
+	m.evaluator.env = m.scopes[len(m.scopes)-1]
+	m.scopes = m.scopes[:len(m.scopes)-1]
+}
+
+func (m *machine) readOperand(ip *int) int {
+	operand := int(compiler.ReadUint16(m.code.bytecode.Instructions[*ip:]))
+	*ip += 2
+	return operand
+}
+
+func (m *machine) nodeOf(ip *int) ast.Node {
+	return m.code.bytecode.Nodes[m.readOperand(ip)]
+}
+
+func (m *machine) push(obj object.Object) {
+	m.stack = append(m.stack, obj)
+}
+
+func (m *machine) pop() object.Object {
+	obj := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return obj
+}
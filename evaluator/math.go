@@ -0,0 +1,28 @@
+package evaluator
+
+import (
+	"math"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func init() {
+	registerNativePackage(&nativePackage{
+		path: "math",
+		constants: map[string]object.Object{
+			"MaxInt":    newInteger(math.MaxInt64),
+			"MinInt":    newInteger(math.MinInt64),
+			"MaxInt8":   newInteger(math.MaxInt8),
+			"MinInt8":   newInteger(math.MinInt8),
+			"MaxInt16":  newInteger(math.MaxInt16),
+			"MinInt16":  newInteger(math.MinInt16),
+			"MaxInt32":  newInteger(math.MaxInt32),
+			"MinInt32":  newInteger(math.MinInt32),
+			"MaxInt64":  newInteger(math.MaxInt64),
+			"MinInt64":  newInteger(math.MinInt64),
+			"MaxUint8":  newInteger(math.MaxUint8),
+			"MaxUint16": newInteger(math.MaxUint16),
+			"MaxUint32": newInteger(math.MaxUint32),
+		},
+	})
+}
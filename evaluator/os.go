@@ -0,0 +1,51 @@
+package evaluator
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/tomocy/kinako/object"
+)
+
+var errNoFileSystem = errors.New("no file system is available")
+
+func init() {
+	stringType := basicType("string")
+	registerNativePackage(&nativePackage{
+		path: "os",
+		functions: map[string]*nativeFunction{
+			"ReadFile": {
+				parameters: []*object.Type{stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					if e.sandbox.fs == nil {
+						return newTuple(newString(""), newError(pathError("open", stringOf(args[0]))))
+					}
+					b, err := fs.ReadFile(e.sandbox.fs, stringOf(args[0]))
+					return newTuple(newString(string(b)), newError(err))
+				},
+			},
+			"ReadDir": {
+				parameters: []*object.Type{stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					if e.sandbox.fs == nil {
+						return newTuple(newStrings(nil), newError(pathError("open", stringOf(args[0]))))
+					}
+					entries, err := fs.ReadDir(e.sandbox.fs, stringOf(args[0]))
+					names := make([]string, len(entries))
+					for i, entry := range entries {
+						names[i] = entry.Name()
+					}
+					return newTuple(newStrings(names), newError(err))
+				},
+			},
+		},
+	})
+}
+
+func pathError(op, path string) error {
+	return &fs.PathError{
+		Op:   op,
+		Path: path,
+		Err:  errNoFileSystem,
+	}
+}
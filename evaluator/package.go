@@ -0,0 +1,390 @@
+package evaluator
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/checker"
+	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/resolver"
+)
+
+type nativePackage struct {
+	path      string
+	functions map[string]*nativeFunction
+	constants map[string]object.Object
+}
+
+func (p nativePackage) name() string {
+	return path.Base(p.path)
+}
+
+func (p nativePackage) object() *object.Package {
+	pkg := &object.Package{
+		Name:    p.name(),
+		Path:    p.path,
+		Members: make(map[string]object.Object),
+	}
+	for name, obj := range p.constants {
+		pkg.Members[name] = obj
+	}
+	for name := range p.functions {
+		pkg.Members[name] = &object.Builtin{
+			Name: fmt.Sprintf("%s.%s", pkg.Name, name),
+		}
+	}
+
+	return pkg
+}
+
+func (p nativePackage) has(name string) bool {
+	_, isConstant := p.constants[name]
+	_, isFunction := p.functions[name]
+	return isConstant || isFunction
+}
+
+type nativeFunction struct {
+	parameters []*object.Type
+	isVariadic bool
+	call       func(e *Evaluator, args []object.Object) object.Object
+}
+
+var nativePackages = make(map[string]*nativePackage)
+
+var nativeMethods = make(map[string]map[string]*nativeFunction)
+
+func registerNativePackage(pkg *nativePackage) {
+	nativePackages[pkg.path] = pkg
+}
+
+// LookUpPackage describes the native package at path so that programs
+// importing it can be checked before they run.
+func LookUpPackage(path string) (*checker.Package, bool) {
+	native, ok := nativePackages[path]
+	if !ok {
+		return nil, false
+	}
+
+	pkg := &checker.Package{
+		Name:      native.name(),
+		Functions: make(map[string]*checker.Function, len(native.functions)),
+	}
+	for name, fn := range native.functions {
+		pkg.Functions[name] = &checker.Function{
+			Parameters: fn.parameters,
+			IsVariadic: fn.isVariadic,
+		}
+	}
+	for name := range native.constants {
+		pkg.Constants = append(pkg.Constants, name)
+	}
+	sort.Strings(pkg.Constants)
+
+	return pkg, true
+}
+
+func lookUpNativeFunction(fn *object.Builtin) (*nativeFunction, bool) {
+	if fn.Receiver != nil {
+		native, ok := nativeMethods[typeName(fn.Receiver)][fn.Name]
+		return native, ok
+	}
+
+	i := strings.LastIndex(fn.Name, ".")
+	if i < 0 {
+		return nil, false
+	}
+	for _, pkg := range nativePackages {
+		if pkg.name() != fn.Name[:i] {
+			continue
+		}
+		native, ok := pkg.functions[fn.Name[i+1:]]
+		return native, ok
+	}
+
+	return nil, false
+}
+
+func (e *Evaluator) evaluateImportDeclaration(node *ast.ImportDeclaration) object.Object {
+	for _, spec := range node.Specs {
+		native, ok := nativePackages[spec.Path.Value]
+		if !ok {
+			return &object.Error{
+				Kind:    object.ErrImport,
+				Message: fmt.Sprintf("package %s is not in std", spec.Path.Value),
+			}
+		}
+		if !e.sandbox.allows(native.path, "") {
+			return &object.Error{
+				Kind:    object.ErrImport,
+				Message: fmt.Sprintf("use of package %s is not allowed", native.path),
+			}
+		}
+
+		pkg := native.object()
+		name := pkg.Name
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" {
+			continue
+		}
+
+		if err := e.env.Import(name, pkg); err != nil {
+			return &object.Error{
+				Kind:    object.ErrImport,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	return &object.Nil{}
+}
+
+func (e *Evaluator) checkMembers(program *ast.Program, members []resolver.Member) *object.Error {
+	for _, member := range members {
+		native, ok := nativePackages[member.Path]
+		if !ok || !e.sandbox.allows(native.path, "") {
+			continue
+		}
+		name := member.Selector.Selector.Name
+		if !native.has(name) || e.sandbox.allows(native.path, name) {
+			continue
+		}
+
+		pos := program.Positions[member.Selector]
+		return &object.Error{
+			Kind:     object.ErrImport,
+			Message:  fmt.Sprintf("use of %s.%s is not allowed", native.name(), name),
+			Position: pos,
+			End:      program.Ends[member.Selector],
+			Stack:    e.stackTrace(pos),
+		}
+	}
+
+	return nil
+}
+
+func (e *Evaluator) evaluateSelectorExpression(node *ast.SelectorExpression) object.Object {
+	if ident, ok := node.Expression.(*ast.Identifier); ok {
+		if pkg, ok := e.env.Get(ident.Name); ok {
+			if pkg, ok := pkg.(*object.Package); ok {
+				return e.selectPackageMember(pkg, node.Selector)
+			}
+		}
+	}
+
+	obj := e.Evaluate(node.Expression)
+	if isError(obj) {
+		return obj
+	}
+	if host, ok := obj.(*object.HostValue); ok {
+		return e.selectHostMember(node, host)
+	}
+
+	name := node.Selector.Name
+	if _, ok := nativeMethods[typeName(obj)][name]; !ok {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: fmt.Sprintf("%s undefined (type %s has no field or method %s)", ast.Format(node), typeName(obj), name),
+		}
+	}
+
+	return &object.Builtin{
+		Name:     name,
+		Receiver: obj,
+	}
+}
+
+func (e *Evaluator) selectPackageMember(pkg *object.Package, selector *ast.Identifier) object.Object {
+	if r, _ := utf8.DecodeRuneInString(selector.Name); !unicode.IsUpper(r) {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: fmt.Sprintf("name %s not exported by package %s", selector.Name, pkg.Name),
+		}
+	}
+	member, ok := pkg.Members[selector.Name]
+	if ok && !e.sandbox.allows(pkg.Path, selector.Name) {
+		return &object.Error{
+			Kind:    object.ErrImport,
+			Message: fmt.Sprintf("use of %s.%s is not allowed", pkg.Name, selector.Name),
+		}
+	}
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrName,
+			Message: fmt.Sprintf("undefined: %s.%s", pkg.Name, selector.Name),
+		}
+	}
+
+	return member
+}
+
+func (e *Evaluator) callNative(node *ast.CallExpression, native *nativeFunction, receiver object.Object, args []object.Object) (result object.Object) {
+	name := ast.Format(node.Function)
+	if node.HasEllipsis && !native.isVariadic {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("have (...) in call to non-variadic %s", name),
+		}
+	}
+
+	n := len(native.parameters)
+	if native.isVariadic && !node.HasEllipsis {
+		n--
+		if len(args) < n {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("not enough arguments in call to %s", name),
+			}
+		}
+	} else if err := checkNumberOfArguments(name, args, n); err != nil {
+		return err
+	}
+
+	for i, arg := range args {
+		typ := native.parameters[min(i, len(native.parameters)-1)]
+		if native.isVariadic && node.HasEllipsis && i == len(args)-1 {
+			typ = sliceTypeOf(typ)
+		}
+		if !isAssignable(arg, typ) {
+			return &object.Error{
+				Kind:    object.ErrType,
+				Message: fmt.Sprintf("cannot use %s as %s value in argument to %s", e.describe(node.Arguments[i], arg), typ, name),
+			}
+		}
+	}
+	if native.isVariadic && node.HasEllipsis {
+		args = append(args[:len(args)-1:len(args)-1], args[len(args)-1].(*object.Slice).Elements...)
+	}
+	if receiver != nil {
+		args = append([]object.Object{receiver}, args...)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = &object.Panic{
+				Value: recoveredValueOf(r),
+			}
+		}
+	}()
+
+	return native.call(e, args)
+}
+
+func recoveredValueOf(r interface{}) object.Object {
+	switch r := r.(type) {
+	case runtime.Error:
+		return &object.RuntimeError{
+			Kind:    object.ErrRuntime,
+			Message: r.Error(),
+		}
+	case error:
+		return &object.ErrorValue{
+			Value: r,
+		}
+	default:
+		return &object.String{
+			Value: fmt.Sprint(r),
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func basicType(name string) *object.Type {
+	return universe[name].(*object.Type)
+}
+
+func newInteger(n int) *object.Integer {
+	return object.NewInteger(int64(n))
+}
+
+func newBoolean(b bool) *object.Boolean {
+	return object.NewBoolean(b)
+}
+
+func newString(s string) *object.String {
+	return &object.String{
+		Value: s,
+	}
+}
+
+// allocateString counts the bytes of a string built by a native function
+// against the allocation limit.
+func (e *Evaluator) allocateString(s string) object.Object {
+	if err := e.budget.allocate(int64(len(s))); err != nil {
+		return err
+	}
+
+	return newString(s)
+}
+
+func newError(err error) object.Object {
+	if err == nil {
+		return &object.Nil{}
+	}
+
+	return &object.ErrorValue{
+		Value: err,
+	}
+}
+
+func errorOf(obj object.Object) error {
+	if err, ok := obj.(*object.ErrorValue); ok {
+		return err.Value
+	}
+
+	return nil
+}
+
+func newTuple(objs ...object.Object) *object.Tuple {
+	return &object.Tuple{
+		Values: objs,
+	}
+}
+
+func stringsOf(obj object.Object) []string {
+	elems := obj.(*object.Slice).Elements
+	ss := make([]string, len(elems))
+	for i, elem := range elems {
+		ss[i] = elem.(*object.String).Value
+	}
+
+	return ss
+}
+
+func newStrings(ss []string) *object.Slice {
+	s := &object.Slice{
+		ElementType: basicType("string"),
+		Elements:    make([]object.Object, len(ss)),
+	}
+	for i, v := range ss {
+		s.Elements[i] = newString(v)
+	}
+
+	return s
+}
+
+func intOf(obj object.Object) int {
+	return int(obj.(*object.Integer).Value)
+}
+
+func stringOf(obj object.Object) string {
+	return obj.(*object.String).Value
+}
+
+func boolOf(obj object.Object) bool {
+	return obj.(*object.Boolean).Value
+}
@@ -0,0 +1,163 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomocy/kinako/object"
+)
+
+type vectorClock map[int]int
+
+func (c vectorClock) copy() vectorClock {
+	copied := make(vectorClock, len(c))
+	for id, time := range c {
+		copied[id] = time
+	}
+
+	return copied
+}
+
+func (c vectorClock) join(other vectorClock) {
+	for id, time := range other {
+		if c[id] < time {
+			c[id] = time
+		}
+	}
+}
+
+type raceDetector struct {
+	main      int
+	clocks    map[int]vectorClock
+	channels  map[*object.Channel]vectorClock
+	locations map[interface{}]*shadow
+}
+
+type variable struct {
+	env  *Environment
+	name string
+}
+
+type shadow struct {
+	write *access
+	reads map[int]*access
+}
+
+type access struct {
+	goroutine int
+	time      int
+	isWrite   bool
+	name      string
+	stack     []object.Frame
+}
+
+func newRaceDetector(main *goroutine) *raceDetector {
+	return &raceDetector{
+		main: main.id,
+		clocks: map[int]vectorClock{
+			main.id: {
+				main.id: 1,
+			},
+		},
+		channels:  make(map[*object.Channel]vectorClock),
+		locations: make(map[interface{}]*shadow),
+	}
+}
+
+func (d *raceDetector) fork(parent, child *goroutine) {
+	clock := d.clocks[parent.id].copy()
+	clock[child.id] = 1
+	d.clocks[child.id] = clock
+	d.clocks[parent.id][parent.id]++
+}
+
+func (d *raceDetector) synchronize(g *goroutine, ch *object.Channel) {
+	clock, ok := d.channels[ch]
+	if !ok {
+		clock = make(vectorClock)
+		d.channels[ch] = clock
+	}
+
+	d.clocks[g.id].join(clock)
+	clock.join(d.clocks[g.id])
+	d.clocks[g.id][g.id]++
+}
+
+func (d *raceDetector) access(g *goroutine, loc interface{}, name string, isWrite bool, stack []object.Frame) *object.Error {
+	s, ok := d.locations[loc]
+	if !ok {
+		s = &shadow{
+			reads: make(map[int]*access),
+		}
+		d.locations[loc] = s
+	}
+
+	current := &access{
+		goroutine: g.id,
+		time:      d.clocks[g.id][g.id],
+		isWrite:   isWrite,
+		name:      name,
+		stack:     stack,
+	}
+	if s.write != nil && !d.happensBefore(s.write, g) {
+		return d.report(current, s.write)
+	}
+	if !isWrite {
+		s.reads[g.id] = current
+		return nil
+	}
+
+	for _, read := range s.reads {
+		if !d.happensBefore(read, g) {
+			return d.report(current, read)
+		}
+	}
+	s.write = current
+	s.reads = make(map[int]*access)
+
+	return nil
+}
+
+func (d *raceDetector) happensBefore(a *access, g *goroutine) bool {
+	return a.goroutine == g.id || a.time <= d.clocks[g.id][a.goroutine]
+}
+
+func (d *raceDetector) report(current, previous *access) *object.Error {
+	var b strings.Builder
+	b.WriteString("WARNING: DATA RACE\n")
+	fmt.Fprintf(&b, "%s at %s by %s:\n", current.kind(), current.name, d.goroutineName(current.goroutine))
+	current.writeStack(&b)
+	fmt.Fprintf(&b, "\nPrevious %s at %s by %s:\n", strings.ToLower(previous.kind()), previous.name, d.goroutineName(previous.goroutine))
+	previous.writeStack(&b)
+
+	return &object.Error{
+		Kind:    object.ErrRace,
+		Message: strings.TrimSuffix(b.String(), "\n"),
+	}
+}
+
+func (a access) kind() string {
+	if a.isWrite {
+		return "Write"
+	}
+
+	return "Read"
+}
+
+func (a access) writeStack(b *strings.Builder) {
+	for _, frame := range a.stack {
+		if !frame.Position.IsValid() {
+			fmt.Fprintf(b, "  %s\n", frame.Function)
+			continue
+		}
+		fmt.Fprintf(b, "  %s at %s\n", frame.Function, frame.Position)
+	}
+}
+
+func (d *raceDetector) goroutineName(id int) string {
+	if id == d.main {
+		return "main goroutine"
+	}
+
+	return fmt.Sprintf("goroutine %d", id)
+}
@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"io/fs"
+	"strings"
+	"time"
+)
+
+type ImportPolicy func(path, member string) bool
+
+func AllowImports(specs ...string) ImportPolicy {
+	packages, members := make(map[string]bool), make(map[string]bool)
+	for _, spec := range specs {
+		if strings.Contains(spec, ".") {
+			members[spec] = true
+			packages[spec[:strings.LastIndex(spec, ".")]] = true
+			continue
+		}
+		packages[spec] = true
+		members[spec+".*"] = true
+	}
+
+	return func(path, member string) bool {
+		if member == "" {
+			return packages[path]
+		}
+		return members[path+".*"] || members[path+"."+member]
+	}
+}
+
+func WithImportPolicy(policy ImportPolicy) Option {
+	return func(e *Evaluator) {
+		e.sandbox.policy = policy
+	}
+}
+
+func WithFS(fsys fs.FS) Option {
+	return func(e *Evaluator) {
+		e.sandbox.fs = fsys
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(e *Evaluator) {
+		e.sandbox.now = now
+	}
+}
+
+type sandbox struct {
+	policy ImportPolicy
+	fs     fs.FS
+	now    func() time.Time
+}
+
+func newSandbox() *sandbox {
+	return &sandbox{
+		policy: func(string, string) bool { return true },
+		now:    time.Now,
+	}
+}
+
+func (s *sandbox) allows(path, member string) bool {
+	return s.policy(path, member)
+}
@@ -0,0 +1,189 @@
+package evaluator
+
+import (
+	"math/rand"
+
+	"github.com/tomocy/kinako/object"
+)
+
+const deadlockMessage = "fatal error: all goroutines are asleep - deadlock!"
+
+type scheduler struct {
+	main           *goroutine
+	goroutines     map[int]*goroutine
+	runnables      []*goroutine
+	lastID         int
+	rand           *rand.Rand
+	isRandomized   bool
+	fatal          *object.Error
+	isShuttingDown bool
+}
+
+type goroutine struct {
+	id     int
+	resume chan struct{}
+	done   chan struct{}
+}
+
+func newScheduler(seed *int64) *scheduler {
+	s := &scheduler{
+		goroutines: make(map[int]*goroutine),
+	}
+	if seed != nil {
+		s.rand = rand.New(rand.NewSource(*seed))
+		s.isRandomized = true
+	}
+	s.main = s.newGoroutine()
+
+	return s
+}
+
+func (s *scheduler) newGoroutine() *goroutine {
+	s.lastID++
+	g := &goroutine{
+		id:     s.lastID,
+		resume: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	s.goroutines[g.id] = g
+
+	return g
+}
+
+func (s *scheduler) spawn(run func(*goroutine) *object.Error) *goroutine {
+	g := s.newGoroutine()
+	s.runnables = append(s.runnables, g)
+
+	go func() {
+		defer close(g.done)
+
+		<-g.resume
+		var err *object.Error
+		if s.canContinue() {
+			err = run(g)
+		}
+		s.exit(g, err)
+	}()
+
+	return g
+}
+
+func (s *scheduler) yield(g *goroutine) bool {
+	if len(s.runnables) == 0 {
+		return s.canContinue()
+	}
+
+	s.runnables = append(s.runnables, g)
+	s.switchFrom(g)
+
+	return s.canContinue()
+}
+
+func (s *scheduler) park(g *goroutine) bool {
+	if len(s.runnables) == 0 {
+		s.abort(g, &object.Error{
+			Kind:    object.ErrDeadlock,
+			Message: deadlockMessage,
+		})
+		if g != s.main {
+			<-g.resume
+		}
+
+		return false
+	}
+
+	s.switchFrom(g)
+
+	return s.canContinue()
+}
+
+func (s *scheduler) ready(g *goroutine) {
+	s.runnables = append(s.runnables, g)
+}
+
+func (s *scheduler) switchFrom(g *goroutine) {
+	next := s.next()
+	if next == g {
+		return
+	}
+
+	next.resume <- struct{}{}
+	<-g.resume
+}
+
+func (s *scheduler) exit(g *goroutine, err *object.Error) {
+	delete(s.goroutines, g.id)
+	if !s.canContinue() {
+		return
+	}
+
+	if err != nil {
+		s.abort(g, err)
+		return
+	}
+	if len(s.runnables) == 0 {
+		s.abort(g, &object.Error{
+			Kind:    object.ErrDeadlock,
+			Message: deadlockMessage,
+		})
+		return
+	}
+
+	s.next().resume <- struct{}{}
+}
+
+func (s *scheduler) next() *goroutine {
+	i := s.pick(len(s.runnables))
+	next := s.runnables[i]
+	s.runnables = append(s.runnables[:i], s.runnables[i+1:]...)
+
+	return next
+}
+
+func (s *scheduler) pick(n int) int {
+	if !s.isRandomized {
+		return 0
+	}
+
+	return s.rand.Intn(n)
+}
+
+func (s *scheduler) abort(g *goroutine, err *object.Error) {
+	if s.fatal != nil {
+		return
+	}
+
+	s.fatal = err
+	if g != s.main {
+		s.main.resume <- struct{}{}
+	}
+}
+
+func (s *scheduler) shutdown() {
+	s.isShuttingDown = true
+	delete(s.goroutines, s.main.id)
+
+	gs := make([]*goroutine, 0, len(s.goroutines))
+	for _, g := range s.goroutines {
+		gs = append(gs, g)
+	}
+	for _, g := range gs {
+		g.resume <- struct{}{}
+		<-g.done
+	}
+}
+
+func (s *scheduler) canContinue() bool {
+	return s.fatal == nil && !s.isShuttingDown
+}
+
+func (s *scheduler) abortion() object.Object {
+	if s.fatal != nil {
+		return s.fatal
+	}
+
+	return &object.Error{
+		Kind:    object.ErrRuntime,
+		Message: "goroutine terminated",
+	}
+}
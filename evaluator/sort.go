@@ -0,0 +1,320 @@
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func init() {
+	anyType, intType, stringType := basicType("any"), basicType("int"), basicType("string")
+	intsType, stringsType := sliceTypeOf(intType), sliceTypeOf(stringType)
+	registerNativePackage(&nativePackage{
+		path: "sort",
+		functions: map[string]*nativeFunction{
+			"Ints": {
+				parameters: []*object.Type{intsType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					sortElements(args[0].(*object.Slice))
+					return &object.Nil{}
+				},
+			},
+			"Strings": {
+				parameters: []*object.Type{stringsType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					sortElements(args[0].(*object.Slice))
+					return &object.Nil{}
+				},
+			},
+			"IntsAreSorted": {
+				parameters: []*object.Type{intsType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newBoolean(areElementsSorted(args[0].(*object.Slice)))
+				},
+			},
+			"StringsAreSorted": {
+				parameters: []*object.Type{stringsType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newBoolean(areElementsSorted(args[0].(*object.Slice)))
+				},
+			},
+			"SearchInts": {
+				parameters: []*object.Type{intsType, intType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newInteger(searchElements(args[0].(*object.Slice), args[1]))
+				},
+			},
+			"SearchStrings": {
+				parameters: []*object.Type{stringsType, stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newInteger(searchElements(args[0].(*object.Slice), args[1]))
+				},
+			},
+			"Slice": {
+				parameters: []*object.Type{anyType, anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.sortSlice("sort.Slice", args[0], args[1], sort.Slice)
+				},
+			},
+			"SliceStable": {
+				parameters: []*object.Type{anyType, anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.sortSlice("sort.SliceStable", args[0], args[1], sort.SliceStable)
+				},
+			},
+		},
+	})
+
+	registerNativePackage(&nativePackage{
+		path: "slices",
+		functions: map[string]*nativeFunction{
+			"Contains": {
+				parameters: []*object.Type{anyType, anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					i, err := e.indexOfElement("slices.Contains", args[0], args[1])
+					if err != nil {
+						return err
+					}
+					return newBoolean(0 <= i)
+				},
+			},
+			"Index": {
+				parameters: []*object.Type{anyType, anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					i, err := e.indexOfElement("slices.Index", args[0], args[1])
+					if err != nil {
+						return err
+					}
+					return newInteger(i)
+				},
+			},
+			"Equal": {
+				parameters: []*object.Type{anyType, anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.equalElements(args[0], args[1])
+				},
+			},
+			"Sort": {
+				parameters: []*object.Type{anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					s, err := orderedSliceOf("slices.Sort", args[0])
+					if err != nil {
+						return err
+					}
+					sortElements(s)
+					return &object.Nil{}
+				},
+			},
+			"BinarySearch": {
+				parameters: []*object.Type{anyType, anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					s, err := orderedSliceOf("slices.BinarySearch", args[0])
+					if err != nil {
+						return err
+					}
+					if !isAssignable(args[1], s.ElementType) {
+						return mismatchedElementError("slices.BinarySearch", args[1], s)
+					}
+					i := searchElements(s, args[1])
+					found := i < len(s.Elements) && !object.Less(args[1], s.Elements[i])
+					return newTuple(newInteger(i), newBoolean(found))
+				},
+			},
+			"Reverse": {
+				parameters: []*object.Type{anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					s, err := sliceOf("slices.Reverse", args[0])
+					if err != nil {
+						return err
+					}
+					for i, j := 0, len(s.Elements)-1; i < j; i, j = i+1, j-1 {
+						s.Elements[i], s.Elements[j] = s.Elements[j], s.Elements[i]
+					}
+					return &object.Nil{}
+				},
+			},
+			"Max": {
+				parameters: []*object.Type{anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return extremeElementOf("slices.Max", args[0], func(x, y object.Object) bool {
+						return object.Less(y, x)
+					})
+				},
+			},
+			"Min": {
+				parameters: []*object.Type{anyType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return extremeElementOf("slices.Min", args[0], object.Less)
+				},
+			},
+		},
+	})
+}
+
+func sortElements(s *object.Slice) {
+	sort.SliceStable(s.Elements, func(i, j int) bool {
+		return object.Less(s.Elements[i], s.Elements[j])
+	})
+}
+
+func areElementsSorted(s *object.Slice) bool {
+	return sort.SliceIsSorted(s.Elements, func(i, j int) bool {
+		return object.Less(s.Elements[i], s.Elements[j])
+	})
+}
+
+func searchElements(s *object.Slice, x object.Object) int {
+	return sort.Search(len(s.Elements), func(i int) bool {
+		return !object.Less(s.Elements[i], x)
+	})
+}
+
+func (e *Evaluator) sortSlice(name string, x, less object.Object, sortSlice func(interface{}, func(int, int) bool)) object.Object {
+	s, err := sliceOf(name, x)
+	if err != nil {
+		return err
+	}
+	fn, ok := less.(*object.Function)
+	if !ok {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("cannot use %s as func(i, j int) bool value in argument to %s", typeName(less), name),
+		}
+	}
+
+	var failure object.Object
+	sortSlice(s.Elements, func(i, j int) bool {
+		if failure != nil {
+			return false
+		}
+		obj := e.callFunction(fn, []object.Object{newInteger(i), newInteger(j)}, nil)
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			failure = obj
+			if !isError(obj) {
+				failure = &object.Error{
+					Kind:    object.ErrType,
+					Message: fmt.Sprintf("cannot use %s as bool value in return statement", typeName(obj)),
+				}
+			}
+			return false
+		}
+		return b.Value
+	})
+	if failure != nil {
+		return failure
+	}
+
+	return &object.Nil{}
+}
+
+func sliceOf(name string, obj object.Object) (*object.Slice, object.Object) {
+	s, ok := obj.(*object.Slice)
+	if !ok {
+		return nil, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("in call to %s, S (type %s) does not satisfy ~[]E", name, typeName(obj)),
+		}
+	}
+
+	return s, nil
+}
+
+func orderedSliceOf(name string, obj object.Object) (*object.Slice, object.Object) {
+	s, err := sliceOf(name, obj)
+	if err != nil {
+		return nil, err
+	}
+	switch s.ElementType.Name {
+	case "int", "string":
+		return s, nil
+	default:
+		return nil, &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("in call to %s, E (type %s) does not satisfy cmp.Ordered", name, s.ElementType),
+		}
+	}
+}
+
+func mismatchedElementError(name string, obj object.Object, s *object.Slice) *object.Error {
+	return &object.Error{
+		Kind:    object.ErrType,
+		Message: fmt.Sprintf("in call to %s, type %s of %s does not match inferred type %s for E", name, typeName(obj), obj, s.ElementType),
+	}
+}
+
+func (e *Evaluator) indexOfElement(name string, x, v object.Object) (int, object.Object) {
+	s, err := sliceOf(name, x)
+	if err != nil {
+		return 0, err
+	}
+	if !isAssignable(v, s.ElementType) {
+		return 0, mismatchedElementError(name, v, s)
+	}
+
+	for i, elem := range s.Elements {
+		obj := e.evaluateEqual(elem, v)
+		if isError(obj) {
+			return 0, obj
+		}
+		if obj.(*object.Boolean).Value {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
+
+func (e *Evaluator) equalElements(x, y object.Object) object.Object {
+	s1, err := sliceOf("slices.Equal", x)
+	if err != nil {
+		return err
+	}
+	s2, err := sliceOf("slices.Equal", y)
+	if err != nil {
+		return err
+	}
+	if s1.ElementType.Name != s2.ElementType.Name {
+		return &object.Error{
+			Kind:    object.ErrType,
+			Message: fmt.Sprintf("in call to slices.Equal, type %s of %s does not match inferred type %s for S", typeName(s2), typeName(s2), typeName(s1)),
+		}
+	}
+	if len(s1.Elements) != len(s2.Elements) {
+		return newBoolean(false)
+	}
+
+	for i := range s1.Elements {
+		obj := e.evaluateEqual(s1.Elements[i], s2.Elements[i])
+		if isError(obj) {
+			return obj
+		}
+		if !obj.(*object.Boolean).Value {
+			return obj
+		}
+	}
+
+	return newBoolean(true)
+}
+
+func extremeElementOf(name string, x object.Object, prefers func(x, y object.Object) bool) object.Object {
+	s, err := orderedSliceOf(name, x)
+	if err != nil {
+		return err
+	}
+	if len(s.Elements) == 0 {
+		return &object.Panic{
+			Value: newString(fmt.Sprintf("%s: empty list", name)),
+		}
+	}
+
+	result := s.Elements[0]
+	for _, elem := range s.Elements[1:] {
+		if prefers(elem, result) {
+			result = elem
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+package evaluator
+
+import (
+	"strconv"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func init() {
+	boolType, intType, stringType := basicType("bool"), basicType("int"), basicType("string")
+	registerNativePackage(&nativePackage{
+		path: "strconv",
+		functions: map[string]*nativeFunction{
+			"Itoa": {
+				parameters: []*object.Type{intType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newString(strconv.Itoa(intOf(args[0])))
+				},
+			},
+			"Atoi": {
+				parameters: []*object.Type{stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					n, err := strconv.Atoi(stringOf(args[0]))
+					return newTuple(newInteger(n), newError(err))
+				},
+			},
+			"FormatInt": {
+				parameters: []*object.Type{intType, intType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newString(strconv.FormatInt(args[0].(*object.Integer).Value, intOf(args[1])))
+				},
+			},
+			"ParseInt": {
+				parameters: []*object.Type{stringType, intType, intType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					n, err := strconv.ParseInt(stringOf(args[0]), intOf(args[1]), intOf(args[2]))
+					return newTuple(object.NewInteger(n), newError(err))
+				},
+			},
+			"FormatBool": {
+				parameters: []*object.Type{boolType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newString(strconv.FormatBool(boolOf(args[0])))
+				},
+			},
+			"ParseBool": {
+				parameters: []*object.Type{stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					b, err := strconv.ParseBool(stringOf(args[0]))
+					return newTuple(newBoolean(b), newError(err))
+				},
+			},
+			"Quote": {
+				parameters: []*object.Type{stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return newString(strconv.Quote(stringOf(args[0])))
+				},
+			},
+			"Unquote": {
+				parameters: []*object.Type{stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					s, err := strconv.Unquote(stringOf(args[0]))
+					return newTuple(newString(s), newError(err))
+				},
+			},
+		},
+		constants: map[string]object.Object{
+			"ErrRange":  newError(strconv.ErrRange),
+			"ErrSyntax": newError(strconv.ErrSyntax),
+		},
+	})
+}
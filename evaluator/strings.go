@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"math"
+	"strings"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func init() {
+	intType, stringType := basicType("int"), basicType("string")
+	registerNativePackage(&nativePackage{
+		path: "strings",
+		functions: map[string]*nativeFunction{
+			"Contains":    stringsPredicate(strings.Contains),
+			"ContainsAny": stringsPredicate(strings.ContainsAny),
+			"EqualFold":   stringsPredicate(strings.EqualFold),
+			"HasPrefix":   stringsPredicate(strings.HasPrefix),
+			"HasSuffix":   stringsPredicate(strings.HasSuffix),
+			"Count":       stringsIndex(strings.Count),
+			"Index":       stringsIndex(strings.Index),
+			"IndexAny":    stringsIndex(strings.IndexAny),
+			"LastIndex":   stringsIndex(strings.LastIndex),
+			"Compare":     stringsIndex(strings.Compare),
+			"ToLower":     stringMapping(strings.ToLower),
+			"ToUpper":     stringMapping(strings.ToUpper),
+			"TrimSpace":   stringMapping(strings.TrimSpace),
+			"Trim":        stringsMapping(strings.Trim),
+			"TrimLeft":    stringsMapping(strings.TrimLeft),
+			"TrimRight":   stringsMapping(strings.TrimRight),
+			"TrimPrefix":  stringsMapping(strings.TrimPrefix),
+			"TrimSuffix":  stringsMapping(strings.TrimSuffix),
+			"Fields":      stringSplitting(strings.Fields),
+			"Split":       stringsSplitting(strings.Split),
+			"SplitAfter":  stringsSplitting(strings.SplitAfter),
+			"Join": {
+				parameters: []*object.Type{sliceTypeOf(stringType), stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.allocateString(strings.Join(stringsOf(args[0]), stringOf(args[1])))
+				},
+			},
+			"Repeat": {
+				parameters: []*object.Type{stringType, intType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					s, count := stringOf(args[0]), intOf(args[1])
+					if 0 < count && len(s) <= math.MaxInt/count {
+						if err := e.budget.allocate(int64(len(s) * count)); err != nil {
+							return err
+						}
+					}
+					return newString(strings.Repeat(s, count))
+				},
+			},
+			"Replace": {
+				parameters: []*object.Type{stringType, stringType, stringType, intType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.allocateString(strings.Replace(stringOf(args[0]), stringOf(args[1]), stringOf(args[2]), intOf(args[3])))
+				},
+			},
+			"ReplaceAll": {
+				parameters: []*object.Type{stringType, stringType, stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return e.allocateString(strings.ReplaceAll(stringOf(args[0]), stringOf(args[1]), stringOf(args[2])))
+				},
+			},
+			"Cut": {
+				parameters: []*object.Type{stringType, stringType},
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					s, sep := stringOf(args[0]), stringOf(args[1])
+					if i := strings.Index(s, sep); 0 <= i {
+						return newTuple(newString(s[:i]), newString(s[i+len(sep):]), newBoolean(true))
+					}
+					return newTuple(newString(s), newString(""), newBoolean(false))
+				},
+			},
+		},
+	})
+}
+
+func stringsPredicate(fn func(string, string) bool) *nativeFunction {
+	return &nativeFunction{
+		parameters: []*object.Type{basicType("string"), basicType("string")},
+		call: func(e *Evaluator, args []object.Object) object.Object {
+			return newBoolean(fn(stringOf(args[0]), stringOf(args[1])))
+		},
+	}
+}
+
+func stringsIndex(fn func(string, string) int) *nativeFunction {
+	return &nativeFunction{
+		parameters: []*object.Type{basicType("string"), basicType("string")},
+		call: func(e *Evaluator, args []object.Object) object.Object {
+			return newInteger(fn(stringOf(args[0]), stringOf(args[1])))
+		},
+	}
+}
+
+func stringMapping(fn func(string) string) *nativeFunction {
+	return &nativeFunction{
+		parameters: []*object.Type{basicType("string")},
+		call: func(e *Evaluator, args []object.Object) object.Object {
+			return e.allocateString(fn(stringOf(args[0])))
+		},
+	}
+}
+
+func stringsMapping(fn func(string, string) string) *nativeFunction {
+	return &nativeFunction{
+		parameters: []*object.Type{basicType("string"), basicType("string")},
+		call: func(e *Evaluator, args []object.Object) object.Object {
+			return e.allocateString(fn(stringOf(args[0]), stringOf(args[1])))
+		},
+	}
+}
+
+func stringSplitting(fn func(string) []string) *nativeFunction {
+	return &nativeFunction{
+		parameters: []*object.Type{basicType("string")},
+		call: func(e *Evaluator, args []object.Object) object.Object {
+			return newStrings(fn(stringOf(args[0])))
+		},
+	}
+}
+
+func stringsSplitting(fn func(string, string) []string) *nativeFunction {
+	return &nativeFunction{
+		parameters: []*object.Type{basicType("string"), basicType("string")},
+		call: func(e *Evaluator, args []object.Object) object.Object {
+			return newStrings(fn(stringOf(args[0]), stringOf(args[1])))
+		},
+	}
+}
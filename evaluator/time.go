@@ -0,0 +1,31 @@
+package evaluator
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/tomocy/kinako/object"
+)
+
+func init() {
+	registerNativePackage(&nativePackage{
+		path: "time",
+		functions: map[string]*nativeFunction{
+			"Now": {
+				call: func(e *Evaluator, args []object.Object) object.Object {
+					return &object.HostValue{
+						Value: reflect.ValueOf(e.sandbox.now()),
+					}
+				},
+			},
+		},
+		constants: map[string]object.Object{
+			"Nanosecond":  newInteger(int(time.Nanosecond)),
+			"Microsecond": newInteger(int(time.Microsecond)),
+			"Millisecond": newInteger(int(time.Millisecond)),
+			"Second":      newInteger(int(time.Second)),
+			"Minute":      newInteger(int(time.Minute)),
+			"Hour":        newInteger(int(time.Hour)),
+		},
+	})
+}
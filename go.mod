@@ -1 +1,3 @@
 module github.com/tomocy/kinako
+
+go 1.21
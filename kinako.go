@@ -0,0 +1,500 @@
+package kinako
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"time"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/checker"
+	"github.com/tomocy/kinako/compiler"
+	"github.com/tomocy/kinako/diagnostic"
+	"github.com/tomocy/kinako/evaluator"
+	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/optimizer"
+	"github.com/tomocy/kinako/parser"
+	"github.com/tomocy/kinako/resolver"
+	"github.com/tomocy/kinako/ssa"
+	"github.com/tomocy/kinako/token"
+	"github.com/tomocy/kinako/vm"
+)
+
+var (
+	ErrInvalidFormat      = compiler.ErrInvalidFormat
+	ErrUnsupportedVersion = compiler.ErrUnsupportedVersion
+	ErrChecksumMismatch   = compiler.ErrChecksumMismatch
+)
+
+type ErrorKind = object.ErrorKind
+
+const (
+	ErrUnknown         = object.ErrUnknown
+	ErrSyntax          = object.ErrSyntax
+	ErrName            = object.ErrName
+	ErrType            = object.ErrType
+	ErrImport          = object.ErrImport
+	ErrDivisionByZero  = object.ErrDivisionByZero
+	ErrIndexOutOfRange = object.ErrIndexOutOfRange
+	ErrNilDereference  = object.ErrNilDereference
+	ErrNilMap          = object.ErrNilMap
+	ErrClosedChannel   = object.ErrClosedChannel
+	ErrRuntime         = object.ErrRuntime
+	ErrPanic           = object.ErrPanic
+	ErrDeadlock        = object.ErrDeadlock
+	ErrRace            = object.ErrRace
+	ErrLimit           = object.ErrLimit
+	ErrCanceled        = object.ErrCanceled
+)
+
+type Frame = object.Frame
+
+type FieldAccess = evaluator.FieldAccess
+
+const (
+	FieldReadWrite = evaluator.FieldReadWrite
+	FieldReadOnly  = evaluator.FieldReadOnly
+	FieldHidden    = evaluator.FieldHidden
+)
+
+type Program struct {
+	bytecode *compiler.Bytecode
+}
+
+type Mode int
+
+const (
+	ScriptMode Mode = iota
+	StrictMode
+)
+
+type CompileOption func(*compileConfig)
+
+type compileConfig struct {
+	optimizes bool
+	mode      Mode
+	hostNames []string
+}
+
+func WithOptimization() CompileOption {
+	return func(c *compileConfig) {
+		c.optimizes = true
+	}
+}
+
+func WithMode(mode Mode) CompileOption {
+	return func(c *compileConfig) {
+		c.mode = mode
+	}
+}
+
+func WithHostNames(names ...string) CompileOption {
+	return func(c *compileConfig) {
+		c.hostNames = append(c.hostNames, names...)
+	}
+}
+
+func Compile(src string, opts ...CompileOption) (*Program, error) {
+	var conf compileConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	program, err := parse(src, conf.mode)
+	if err != nil {
+		return nil, err
+	}
+	if conf.mode == StrictMode {
+		if _, err := resolve(program, conf.hostNames, resolver.WithStrict()); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.optimizes {
+		optimizer.New().Optimize(program)
+	}
+
+	members, err := resolve(program, conf.hostNames)
+	if err != nil {
+		return nil, err
+	}
+
+	bytecode, err := compiler.New().Compile(program)
+	if err != nil {
+		return nil, err
+	}
+	bytecode.HostNames = conf.hostNames
+	bytecode.Members = members
+
+	return &Program{
+		bytecode: bytecode,
+	}, nil
+}
+
+func BuildSSA(src string) (*ssa.Program, error) {
+	program, err := parse(src, ScriptMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssa.New().Build(program), nil
+}
+
+func parse(src string, mode Mode) (*ast.Program, error) {
+	var lexOpts []lexer.Option
+	var checkOpts []checker.Option
+	if mode == StrictMode {
+		lexOpts = append(lexOpts, lexer.WithSemicolonInsertion())
+		checkOpts = append(checkOpts, checker.WithStrict(), checker.WithImporter(evaluator.LookUpPackage))
+	}
+
+	p := parser.New(lexer.New(src, lexOpts...))
+	program := p.ParseProgram()
+	if bads := p.ReportedBadStatements(); 0 < len(bads) {
+		return nil, &SyntaxError{
+			Message:  bads[0].Message,
+			Position: program.Positions[bads[0]],
+			End:      program.Ends[bads[0]],
+		}
+	}
+
+	if err := checker.New(checkOpts...).Check(program); err != nil {
+		var checkErr *checker.Error
+		if !errors.As(err, &checkErr) {
+			return nil, err
+		}
+		return nil, &TypeError{
+			Message:  checkErr.Message,
+			Position: checkErr.Position,
+			End:      checkErr.End,
+		}
+	}
+
+	return program, nil
+}
+
+func resolve(program *ast.Program, hostNames []string, opts ...resolver.Option) ([]resolver.Member, error) {
+	r := resolver.New(append([]resolver.Option{
+		resolver.WithPredeclared(evaluator.PredeclaredNames()...),
+		resolver.WithPackage(hostNames...),
+	}, opts...)...)
+	if err := r.Resolve(program); err != nil {
+		var resolveErr *resolver.Error
+		if !errors.As(err, &resolveErr) {
+			return nil, err
+		}
+		return nil, &TypeError{
+			Message:  resolveErr.Message,
+			Position: resolveErr.Position,
+			End:      resolveErr.End,
+		}
+	}
+
+	return r.Members(), nil
+}
+
+func Load(r io.Reader) (*Program, error) {
+	bytecode, err := compiler.Load(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{
+		bytecode: bytecode,
+	}, nil
+}
+
+func (p *Program) WriteTo(w io.Writer) (int64, error) {
+	data, err := p.bytecode.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+type Option func(*config)
+
+type config struct {
+	evaluatorOptions []evaluator.Option
+	funcs            map[string]interface{}
+	values           map[string]interface{}
+}
+
+func WithStdout(w io.Writer) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithStdout(w))
+	}
+}
+
+func WithStderr(w io.Writer) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithStderr(w))
+	}
+}
+
+func WithSeed(seed int64) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithSeed(seed))
+	}
+}
+
+func WithRaceDetector() Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithRaceDetector())
+	}
+}
+
+func WithMaxSteps(n int64) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithMaxSteps(n))
+	}
+}
+
+func WithMaxCallDepth(n int) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithMaxCallDepth(n))
+	}
+}
+
+func WithMaxAllocations(n int64) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithMaxAllocations(n))
+	}
+}
+
+// WithAllowedImports restricts scripts to the given packages ("strings") and
+// members ("strings.ToUpper"). Uses of denied members are reported before
+// any statement runs. Without this option every std package is allowed.
+func WithAllowedImports(specs ...string) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithImportPolicy(evaluator.AllowImports(specs...)))
+	}
+}
+
+func WithFS(fsys fs.FS) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithFS(fsys))
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithClock(now))
+	}
+}
+
+// WithFieldAccess decides how scripts may use each field of the Go structs
+// they are given. By default a field tagged kinako:"-" is hidden and one
+// tagged kinako:"readonly" is read-only.
+func WithFieldAccess(fn func(reflect.Type, reflect.StructField) FieldAccess) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithFieldAccess(fn))
+	}
+}
+
+// WithMethodAccess decides which methods of the Go values they are given
+// scripts may call. By default a method is hidden when it is named as the
+// getter or setter of a field scripts cannot see.
+func WithMethodAccess(fn func(reflect.Type, reflect.Method) FieldAccess) Option {
+	return func(c *config) {
+		c.evaluatorOptions = append(c.evaluatorOptions, evaluator.WithMethodAccess(fn))
+	}
+}
+
+func WithFunc(name string, fn interface{}) Option {
+	return func(c *config) {
+		c.funcs[name] = fn
+	}
+}
+
+func WithValue(name string, v interface{}) Option {
+	return func(c *config) {
+		c.values[name] = v
+	}
+}
+
+func (p *Program) Run(ctx context.Context, opts ...Option) (interface{}, error) {
+	c := &config{
+		funcs:  make(map[string]interface{}),
+		values: make(map[string]interface{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	m := vm.New(append(c.evaluatorOptions, evaluator.WithContext(ctx))...)
+	if err := p.register(m.Evaluator(), c); err != nil {
+		return nil, err
+	}
+
+	obj := m.Run(p.bytecode)
+	if err, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{
+			Kind:     err.Kind,
+			Message:  err.Message,
+			Position: err.Position,
+			End:      err.End,
+			Stack:    err.Stack,
+			Err:      err.Err,
+		}
+	}
+
+	return evaluator.GoValueOf(obj)
+}
+
+// Explore runs the program under the schedules seeded 0 through n-1 and
+// returns the seed and error of the first one that fails. The error is nil
+// when every schedule succeeds.
+func (p *Program) Explore(ctx context.Context, n int, opts ...Option) (int64, error) {
+	for seed := int64(0); seed < int64(n); seed++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if _, err := p.Run(ctx, append(opts, WithSeed(seed))...); err != nil {
+			return seed, err
+		}
+	}
+
+	return 0, nil
+}
+
+func (p *Program) register(e *evaluator.Evaluator, c *config) error {
+	declared := make(map[string]bool, len(p.bytecode.HostNames))
+	for _, name := range p.bytecode.HostNames {
+		declared[name] = true
+		if fn, ok := c.funcs[name]; ok {
+			if err := e.RegisterFunc(name, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		v, ok := c.values[name]
+		if !ok {
+			return fmt.Errorf("missing host value for %s", name)
+		}
+		if err := e.RegisterValue(name, v); err != nil {
+			return err
+		}
+	}
+	for _, names := range []map[string]interface{}{c.funcs, c.values} {
+		for name := range names {
+			if !declared[name] {
+				return fmt.Errorf("%s is not declared with WithHostNames", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+type SyntaxError struct {
+	Message  string
+	Position token.Position
+	End      token.Position
+}
+
+func (e *SyntaxError) Error() string {
+	return formatError(e.Position, e.Message)
+}
+
+func (e *SyntaxError) Span() token.Span {
+	return token.Span{Start: e.Position, End: e.End}
+}
+
+type TypeError struct {
+	Message  string
+	Position token.Position
+	End      token.Position
+}
+
+func (e *TypeError) Error() string {
+	return formatError(e.Position, e.Message)
+}
+
+func (e *TypeError) Span() token.Span {
+	return token.Span{Start: e.Position, End: e.End}
+}
+
+type RuntimeError struct {
+	Kind     ErrorKind
+	Message  string
+	Position token.Position
+	End      token.Position
+	Stack    []Frame
+	Err      error
+}
+
+func (e *RuntimeError) Error() string {
+	return formatError(e.Position, e.Message)
+}
+
+func (e *RuntimeError) Span() token.Span {
+	return token.Span{Start: e.Position, End: e.End}
+}
+
+func (e *RuntimeError) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && kind == e.Kind
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+var hints = map[ErrorKind]string{
+	ErrNilMap:         "initialize the map with make or a map literal before assigning to it",
+	ErrNilDereference: "compare the pointer with nil before dereferencing it",
+	ErrDeadlock:       "make sure every channel receive has a matching send or close",
+}
+
+func Diagnose(err error) *diagnostic.Diagnostic {
+	var (
+		syntaxErr  *SyntaxError
+		typeErr    *TypeError
+		runtimeErr *RuntimeError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &diagnostic.Diagnostic{
+			Message: syntaxErr.Message,
+			Span:    syntaxErr.Span(),
+		}
+	case errors.As(err, &typeErr):
+		return &diagnostic.Diagnostic{
+			Message: typeErr.Message,
+			Span:    typeErr.Span(),
+		}
+	case errors.As(err, &runtimeErr):
+		d := &diagnostic.Diagnostic{
+			Message: runtimeErr.Message,
+			Span:    runtimeErr.Span(),
+		}
+		if 1 < len(runtimeErr.Stack) {
+			for _, frame := range runtimeErr.Stack {
+				d.Notes = append(d.Notes, fmt.Sprintf("in %s", frame))
+			}
+		}
+		if hint, ok := hints[runtimeErr.Kind]; ok {
+			d.Hints = append(d.Hints, hint)
+		}
+		return d
+	default:
+		return &diagnostic.Diagnostic{
+			Message: err.Error(),
+		}
+	}
+}
+
+func formatError(pos token.Position, msg string) string {
+	if !pos.IsValid() {
+		return msg
+	}
+
+	return fmt.Sprintf("%s: %s", pos, msg)
+}
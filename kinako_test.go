@@ -0,0 +1,772 @@
+package kinako
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tomocy/kinako/diagnostic"
+	"github.com/tomocy/kinako/evaluator"
+	"github.com/tomocy/kinako/token"
+)
+
+func TestCompile(t *testing.T) {
+	tests := map[string]struct {
+		src      string
+		opts     []CompileOption
+		expected error
+	}{
+		"valid": {
+			src: "var x int = 1;",
+		},
+		"syntax error": {
+			src: `var x int = 1;
+var y int = 2`,
+			expected: &SyntaxError{
+				Message:  "failed to find semicolon",
+				Position: token.Position{Line: 2, Column: 13},
+				End:      token.Position{Line: 2, Column: 14},
+			},
+		},
+		"type error": {
+			src: `func F[T comparable](a, b T) bool { return a == b; }
+F(func() {}, func() {});`,
+			expected: &TypeError{
+				Message:  "func() does not satisfy comparable",
+				Position: token.Position{Line: 2, Column: 1},
+				End:      token.Position{Line: 2, Column: 24},
+			},
+		},
+		"undefined variable": {
+			src: `import "fmt"; fmt.Println("hello");
+fmt.Println(y);`,
+			expected: &TypeError{
+				Message:  "undefined variable: y",
+				Position: token.Position{Line: 2, Column: 13},
+				End:      token.Position{Line: 2, Column: 14},
+			},
+		},
+		"undefined in function": {
+			src: `func f() int { return g(); }`,
+			expected: &TypeError{
+				Message:  "undefined variable: g",
+				Position: token.Position{Line: 1, Column: 23},
+				End:      token.Position{Line: 1, Column: 24},
+			},
+		},
+		"host name": {
+			src:  `double(21);`,
+			opts: []CompileOption{WithHostNames("double")},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile(test.src, test.opts...)
+			if test.expected == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(err, test.expected) {
+				t.Errorf("unexpected error: got %#v, expected %#v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestCompileWithMode(t *testing.T) {
+	tests := map[string]struct {
+		src    string
+		mode   Mode
+		stdout string
+		err    error
+	}{
+		"script": {
+			src:    `import "fmt"; x := 1; 5; fmt.Print(x);`,
+			mode:   ScriptMode,
+			stdout: "1",
+		},
+		"script with main": {
+			src:    `package main; import "fmt"; func main() { fmt.Print("main"); } fmt.Print("init ");`,
+			mode:   ScriptMode,
+			stdout: "init main",
+		},
+		"strict": {
+			src: `package main;
+import "fmt";
+var greeting string = "hello";
+func main() { fmt.Print(greeting); }`,
+			mode:   StrictMode,
+			stdout: "hello",
+		},
+		"strict without semicolons": {
+			src: `package main
+
+import "fmt"
+
+// greeting is printed by main.
+var greeting string = "hello"
+
+func main() {
+	fmt.Print(greeting) /* no semicolon */
+}
+`,
+			mode:   StrictMode,
+			stdout: "hello",
+		},
+		"strict with gofmt'd source": {
+			src: `package main
+
+import "fmt"
+
+// count increments n.
+func count(n int) int {
+	n++
+	return n /* once */
+}
+
+func main() {
+	var x int = 1
+	x++
+	fmt.Print(count(x))
+}
+`,
+			mode:   StrictMode,
+			stdout: "3",
+		},
+		"strict with undefined name": {
+			src: `package main
+func main() { println(g) }`,
+			mode: StrictMode,
+			err: &TypeError{
+				Message:  "undefined: g",
+				Position: token.Position{Line: 2, Column: 23},
+				End:      token.Position{Line: 2, Column: 24},
+			},
+		},
+		"strict with wrong argument to package function": {
+			src: `package main
+
+import "strings"
+
+func main() { println(strings.ToUpper(1)) }`,
+			mode: StrictMode,
+			err: &TypeError{
+				Message:  "cannot use 1 (untyped int constant) as string value in argument to strings.ToUpper",
+				Position: token.Position{Line: 5, Column: 39},
+				End:      token.Position{Line: 5, Column: 40},
+			},
+		},
+		"strict with mismatched types": {
+			src: `package main
+func main() {
+	var s string = 1
+	println(s)
+}`,
+			mode: StrictMode,
+			err: &TypeError{
+				Message:  "cannot use 1 (untyped int constant) as string value in variable declaration",
+				Position: token.Position{Line: 3, Column: 17},
+				End:      token.Position{Line: 3, Column: 18},
+			},
+		},
+		"strict with top-level statement": {
+			src:  `import "fmt"; fmt.Print(1);`,
+			mode: StrictMode,
+			err: &TypeError{
+				Message:  "syntax error: package statement must be first",
+				Position: token.Position{Line: 1, Column: 1},
+				End:      token.Position{Line: 1, Column: 14},
+			},
+		},
+		"strict with unused variable": {
+			src: `package main;
+func main() { x := 1; }`,
+			mode: StrictMode,
+			err: &TypeError{
+				Message:  "declared and not used: x",
+				Position: token.Position{Line: 2, Column: 15},
+				End:      token.Position{Line: 2, Column: 16},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			prog, err := Compile(test.src, WithMode(test.mode), WithOptimization())
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("unexpected error: got %#v, expected %#v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+
+			var stdout bytes.Buffer
+			if _, err := prog.Run(context.Background(), WithStdout(&stdout)); err != nil {
+				t.Fatalf("unexpected error from Run: %s", err)
+			}
+			if actual := stdout.String(); actual != test.stdout {
+				t.Errorf("unexpected stdout: got %q, expected %q", actual, test.stdout)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		src         string
+		compileOpts []CompileOption
+		opts        []Option
+		expected    interface{}
+		err         error
+	}{
+		"value": {
+			src: `var x int = 1;
+x + 2;`,
+			expected: 3,
+		},
+		"runtime error": {
+			src: `var x []int = []int{1};
+var y int = x[3];`,
+			err: &RuntimeError{
+				Kind:     ErrIndexOutOfRange,
+				Message:  "panic: runtime error: index out of range [3] with length 1",
+				Position: token.Position{Line: 2, Column: 13},
+				End:      token.Position{Line: 2, Column: 17},
+				Stack: []Frame{
+					{Function: "main", Position: token.Position{Line: 2, Column: 13}},
+				},
+			},
+		},
+		"func": {
+			src:         `double(21);`,
+			compileOpts: []CompileOption{WithHostNames("double")},
+			opts: []Option{
+				WithFunc("double", func(x int) int { return x * 2 }),
+			},
+			expected: 42,
+		},
+		"value option": {
+			src:         `names[1];`,
+			compileOpts: []CompileOption{WithHostNames("names")},
+			opts: []Option{
+				WithValue("names", []string{"a", "b"}),
+			},
+			expected: "b",
+		},
+		"tuple": {
+			src: `import "strings";
+strings.Cut("a=1", "=");`,
+			expected: []interface{}{"a", "1", true},
+		},
+		"break outside loop": {
+			src: `func f() {
+	break;
+}
+f();`,
+			err: &RuntimeError{
+				Kind:     ErrType,
+				Message:  "break is not in a loop, switch, or select",
+				Position: token.Position{Line: 2, Column: 2},
+				End:      token.Position{Line: 2, Column: 8},
+				Stack: []Frame{
+					{Function: "f", Position: token.Position{Line: 2, Column: 2}},
+					{Function: "main", Position: token.Position{Line: 4, Column: 1}},
+				},
+			},
+		},
+		"unconvertible value": {
+			src: `func f() int { return 1; }
+f;`,
+			err: errors.New("cannot convert func to a Go value"),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			prog, err := Compile(test.src, test.compileOpts...)
+			if err != nil {
+				t.Fatalf("unexpected error from Compile: %s", err)
+			}
+			actual, err := prog.Run(context.Background(), test.opts...)
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("unexpected error: got %#v, expected %#v", err, test.err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("unexpected value: got %#v, expected %#v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestExplore(t *testing.T) {
+	src := `x := 0;
+done := make(chan int);
+go func() { x = 1; done <- 0; }();
+go func() { x = 2; done <- 0; }();
+<-done; <-done;
+switch x { case 1: panic("unexpected order"); }`
+	prog, err := Compile(src)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	if _, err := prog.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error from Run: %s", err)
+	}
+	seed, err := prog.Explore(context.Background(), 100)
+	if !errors.Is(err, ErrPanic) {
+		t.Fatalf("unexpected error: got %v, expected a panic", err)
+	}
+	if _, rerr := prog.Run(context.Background(), WithSeed(seed)); !reflect.DeepEqual(rerr, err) {
+		t.Errorf("unexpected error with seed %d: got %v, expected %v", seed, rerr, err)
+	}
+
+	if _, err := prog.Explore(context.Background(), 0); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestRunWithStdout(t *testing.T) {
+	prog, err := Compile(`import "fmt"; fmt.Println("hello");`)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := prog.Run(context.Background(), WithStdout(&stdout)); err != nil {
+		t.Fatalf("unexpected error from Run: %s", err)
+	}
+	if actual := stdout.String(); actual != "hello\n" {
+		t.Errorf("unexpected stdout: got %q, expected %q", actual, "hello\n")
+	}
+}
+
+func TestCompileWithOptimization(t *testing.T) {
+	tests := map[string]struct {
+		src      string
+		expected interface{}
+		err      error
+	}{
+		"folded": {
+			src:      `x := 2 * 3 + 1; x == 7;`,
+			expected: true,
+		},
+		"division by zero": {
+			src: `x := 1;
+x / (2 - 2);`,
+			err: &RuntimeError{
+				Kind:     ErrDivisionByZero,
+				Message:  "panic: runtime error: integer divide by zero",
+				Position: token.Position{Line: 2, Column: 1},
+				End:      token.Position{Line: 2, Column: 12},
+				Stack: []Frame{
+					{Function: "main", Position: token.Position{Line: 2, Column: 1}},
+				},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			prog, err := Compile(test.src, WithOptimization())
+			if err != nil {
+				t.Fatalf("unexpected error from Compile: %s", err)
+			}
+			actual, err := prog.Run(context.Background())
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("unexpected error: got %#v, expected %#v", err, test.err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("unexpected value: got %#v, expected %#v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestRunWithHostNames(t *testing.T) {
+	prog, err := Compile(`x := double(1); x;`, WithHostNames("double"))
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	tests := map[string]struct {
+		opts []Option
+		err  string
+	}{
+		"missing": {
+			err: "missing host value for double",
+		},
+		"undeclared": {
+			opts: []Option{
+				WithFunc("double", func(x int) int { return x * 2 }),
+				WithValue("half", 1),
+			},
+			err: "half is not declared with WithHostNames",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := prog.Run(context.Background(), test.opts...)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("unexpected error: got %v, expected %s", err, test.err)
+			}
+		})
+	}
+}
+
+type testUser struct {
+	Name     string
+	Password string
+}
+
+func (u *testUser) Greet() string {
+	return "hello, " + u.Name
+}
+
+func runWithUser(t *testing.T, src string, user *testUser, opts ...Option) (interface{}, error) {
+	t.Helper()
+
+	prog, err := Compile(src, WithHostNames("user"))
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	return prog.Run(context.Background(), append(opts, WithValue("user", user))...)
+}
+
+func TestRunWithFieldAccess(t *testing.T) {
+	opt := WithFieldAccess(func(typ reflect.Type, field reflect.StructField) FieldAccess {
+		switch field.Name {
+		case "Name":
+			return FieldReadOnly
+		case "Password":
+			return FieldHidden
+		default:
+			return FieldReadWrite
+		}
+	})
+
+	tests := map[string]struct {
+		src      string
+		expected interface{}
+		err      string
+	}{
+		"read": {
+			src:      "user.Name;",
+			expected: "kinako",
+		},
+		"write read-only": {
+			src: `user.Name = "tomocy";`,
+			err: "cannot assign to user.Name (field Name is read-only)",
+		},
+		"hidden": {
+			src: "user.Password;",
+			err: "*kinako.testUser has no field or method Password",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			user := &testUser{Name: "kinako", Password: "secret"}
+			actual, err := runWithUser(t, test.src, user, opt)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("unexpected error: got %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != test.expected {
+				t.Errorf("unexpected value: got %#v, expected %#v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestRunWithMethodAccess(t *testing.T) {
+	user := &testUser{Name: "kinako"}
+	src := "user.Greet();"
+
+	actual, err := runWithUser(t, src, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if actual != "hello, kinako" {
+		t.Errorf("unexpected value: got %#v, expected %#v", actual, "hello, kinako")
+	}
+
+	_, err = runWithUser(t, src, user, WithMethodAccess(func(typ reflect.Type, method reflect.Method) FieldAccess {
+		if method.Name == "Greet" {
+			return FieldHidden
+		}
+		return FieldReadWrite
+	}))
+	expected := "*kinako.testUser has no field or method Greet"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("unexpected error: got %v, expected %s", err, expected)
+	}
+}
+
+func TestRunConcurrently(t *testing.T) {
+	prog, err := Compile(`func fib(n int) int {
+	switch { case n < 2: return n; }
+	return fib(n - 1) + fib(n - 2);
+}
+x := fib(10); x;`)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	results := make(chan interface{}, 4)
+	for i := 0; i < cap(results); i++ {
+		go func() {
+			v, err := prog.Run(context.Background())
+			if err != nil {
+				v = err
+			}
+			results <- v
+		}()
+	}
+	for i := 0; i < cap(results); i++ {
+		if v := <-results; v != 55 {
+			t.Errorf("unexpected value: got %v, expected 55", v)
+		}
+	}
+}
+
+func TestRunWithCanceledContext(t *testing.T) {
+	prog, err := Compile("1;")
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = prog.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: got %v, expected %v", err, context.Canceled)
+	}
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("unexpected error: got %v, expected %v", err, ErrCanceled)
+	}
+}
+
+func TestRunWithStackTrace(t *testing.T) {
+	prog, err := Compile(`func div(a, b int) int { return a / b; }
+func half(n int) int { return div(n, 0); }
+half(4);`)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	_, err = prog.Run(context.Background())
+	if !errors.Is(err, ErrDivisionByZero) {
+		t.Fatalf("unexpected error: got %v, expected %v", err, ErrDivisionByZero)
+	}
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("unexpected error: got %#v, expected *RuntimeError", err)
+	}
+	expected := []Frame{
+		{Function: "div", Position: token.Position{Line: 1, Column: 33}},
+		{Function: "half", Position: token.Position{Line: 2, Column: 31}},
+		{Function: "main", Position: token.Position{Line: 3, Column: 1}},
+	}
+	if !reflect.DeepEqual(runtimeErr.Stack, expected) {
+		t.Errorf("unexpected stack: got %v, expected %v", runtimeErr.Stack, expected)
+	}
+	if actual, expected := runtimeErr.Span(), (token.Span{Start: token.Position{Line: 1, Column: 33}, End: token.Position{Line: 1, Column: 38}}); actual != expected {
+		t.Errorf("unexpected span: got %v, expected %v", actual, expected)
+	}
+}
+
+func TestRunWithLimits(t *testing.T) {
+	tests := map[string]struct {
+		src      string
+		opts     []Option
+		expected error
+	}{
+		"steps": {
+			src:      "for {}",
+			opts:     []Option{WithMaxSteps(100)},
+			expected: evaluator.ErrStepLimitExceeded,
+		},
+		"call depth": {
+			src:      "func f() { f(); } f();",
+			opts:     []Option{WithMaxCallDepth(10)},
+			expected: evaluator.ErrCallDepthLimitExceeded,
+		},
+		"allocations": {
+			src:      "s := make([]int, 100);",
+			opts:     []Option{WithMaxAllocations(10)},
+			expected: evaluator.ErrAllocationLimitExceeded,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			prog, err := Compile(test.src)
+			if err != nil {
+				t.Fatalf("unexpected error from Compile: %s", err)
+			}
+			_, err = prog.Run(context.Background(), test.opts...)
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("unexpected error: got %#v, expected *RuntimeError", err)
+			}
+			if !errors.Is(err, test.expected) || !errors.Is(err, ErrLimit) {
+				t.Errorf("unexpected error: got %v, expected %v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestRunWithAllowedImports(t *testing.T) {
+	prog, err := Compile(`import "os"; os.ReadFile("a.txt");`)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	_, err = prog.Run(context.Background(), WithAllowedImports("fmt", "strings"))
+	expected := &RuntimeError{
+		Kind:     ErrImport,
+		Message:  "use of package os is not allowed",
+		Position: token.Position{Line: 1, Column: 1},
+		End:      token.Position{Line: 1, Column: 13},
+		Stack: []Frame{
+			{Function: "main", Position: token.Position{Line: 1, Column: 1}},
+		},
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("unexpected error: got %#v, expected %#v", err, expected)
+	}
+}
+
+func TestRunWithDeniedMember(t *testing.T) {
+	prog, err := Compile(`import ("fmt"; "strings");
+fmt.Print(strings.ToUpper("a"));
+fmt.Print(strings.Repeat("a", 2));`)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+	var data bytes.Buffer
+	if _, err := prog.WriteTo(&data); err != nil {
+		t.Fatalf("unexpected error from WriteTo: %s", err)
+	}
+	loaded, err := Load(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error from Load: %s", err)
+	}
+
+	expected := &RuntimeError{
+		Kind:     ErrImport,
+		Message:  "use of strings.Repeat is not allowed",
+		Position: token.Position{Line: 3, Column: 11},
+		End:      token.Position{Line: 3, Column: 25},
+		Stack: []Frame{
+			{Function: "main", Position: token.Position{Line: 3, Column: 11}},
+		},
+	}
+	for name, prog := range map[string]*Program{"compiled": prog, "loaded": loaded} {
+		t.Run(name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			_, err := prog.Run(context.Background(), WithStdout(&stdout), WithAllowedImports("fmt", "strings.ToUpper"))
+			if !reflect.DeepEqual(err, expected) {
+				t.Errorf("unexpected error: got %#v, expected %#v", err, expected)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("unexpected output before the check: %q", stdout.String())
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	prog, err := Compile(`import "fmt"; x := 0; for i := 0; i < 4; i++ { x = x + i; } fmt.Print(x);`)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	var data bytes.Buffer
+	if _, err := prog.WriteTo(&data); err != nil {
+		t.Fatalf("unexpected error from WriteTo: %s", err)
+	}
+	loaded, err := Load(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error from Load: %s", err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := loaded.Run(context.Background(), WithStdout(&stdout)); err != nil {
+		t.Fatalf("unexpected error from Run: %s", err)
+	}
+	if actual := stdout.String(); actual != "6" {
+		t.Errorf("unexpected stdout: got %q, expected %q", actual, "6")
+	}
+
+	corrupted := data.Bytes()
+	corrupted[len(corrupted)-1]++
+	if _, err := Load(bytes.NewReader(corrupted)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("unexpected error: got %v, expected %v", err, ErrChecksumMismatch)
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	err := &RuntimeError{
+		Message:  "panic: boom",
+		Position: token.Position{Line: 3, Column: 5},
+	}
+	if actual := err.Error(); !strings.HasPrefix(actual, "3:5: ") {
+		t.Errorf("unexpected error string: %s", actual)
+	}
+
+	err.Position = token.Position{}
+	if actual := err.Error(); actual != "panic: boom" {
+		t.Errorf("unexpected error string: %s", actual)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	prog, err := Compile(`var m map[string]int;
+func set() { m["a"] = 1; }
+set();`)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s", err)
+	}
+
+	_, err = prog.Run(context.Background())
+	expected := &diagnostic.Diagnostic{
+		Message: "panic: assignment to entry in nil map",
+		Span: token.Span{
+			Start: token.Position{Line: 2, Column: 14},
+			End:   token.Position{Line: 2, Column: 25},
+		},
+		Notes: []string{"in set at 2:14", "in main at 3:1"},
+		Hints: []string{"initialize the map with make or a map literal before assigning to it"},
+	}
+	if actual := Diagnose(err); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected diagnostic: got %#v, expected %#v", actual, expected)
+	}
+}
+
+func TestBuildSSA(t *testing.T) {
+	prog, err := BuildSSA(`func double(x int) int { return x * 2; } double(2);`)
+	if err != nil {
+		t.Fatalf("unexpected error from BuildSSA: %s", err)
+	}
+	if len(prog.Functions) != 2 || prog.Functions[1].Name() != "double" {
+		t.Errorf("unexpected functions: %v", prog.Functions)
+	}
+
+	_, err = BuildSSA(`var x int = 1`)
+	expected := &SyntaxError{
+		Message:  "failed to find semicolon",
+		Position: token.Position{Line: 1, Column: 13},
+		End:      token.Position{Line: 1, Column: 14},
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("unexpected error: got %#v, expected %#v", err, expected)
+	}
+}
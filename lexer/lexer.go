@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/tomocy/kinako/token"
@@ -17,27 +18,83 @@ type Lexer struct {
 	currentCharacter rune
 	currentPosition  int
 	readingPosition  int
+	line             int
+	lineOffset       int
+	tokenPosition    token.Position
+	tokenEnd         token.Position
+	insertion        bool
+	insertsSemicolon bool
 }
 
-func New(input string) *Lexer {
-	return &Lexer{
+type Option func(*Lexer)
+
+// WithSemicolonInsertion makes the lexer insert a semicolon after the final
+// token of a line as Go does, so that gofmt'd source can be read.
+func WithSemicolonInsertion() Option {
+	return func(l *Lexer) {
+		l.insertion = true
+	}
+}
+
+func New(input string, opts ...Option) *Lexer {
+	l := &Lexer{
 		input: input,
+		line:  1,
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
 }
 
 func (l *Lexer) ReadNextToken() token.Token {
 	l.readCharacter()
-	l.skipWhitespaces()
+	newline := l.skipWhitespaces()
+	l.tokenPosition = token.Position{
+		Line:   l.line,
+		Column: l.currentPosition - l.lineOffset + 1,
+	}
+
+	var tok token.Token
+	if newline || l.insertsSemicolon && l.currentCharacter == eof {
+		tok = token.Token{
+			Type:    token.Semicolon,
+			Literal: "\n",
+		}
+	} else {
+		tok = l.readToken()
+	}
+	l.insertsSemicolon = l.insertion && insertsSemicolonAfter(tok.Type)
+	l.tokenEnd = token.Position{
+		Line:   l.line,
+		Column: l.readingPosition - l.lineOffset + 1,
+	}
+
+	return tok
+}
+
+func (l Lexer) Position() token.Position {
+	return l.tokenPosition
+}
 
-	return l.readToken()
+func (l Lexer) EndPosition() token.Position {
+	return l.tokenEnd
 }
 
 func (l *Lexer) readToken() token.Token {
 	switch l.currentCharacter {
+	case '+', '-', '!', '=', '<', '>', ':':
+		return l.readSingleOrDoubleToken()
 	case
-		'+', '-', '*', '/',
-		'!', '=', ';', '(', ')':
+		'*', '/',
+		'|', '~',
+		';', ',', '(', ')', '{', '}', '[', ']':
 		return l.readSingleToken()
+	case '.':
+		return l.readPeriodOrEllipsis()
+	case '"':
+		return l.readString()
 	case eof:
 		return l.readEOF()
 	default:
@@ -61,6 +118,32 @@ func (l *Lexer) readSingleToken() token.Token {
 	}
 }
 
+func (l *Lexer) readSingleOrDoubleToken() token.Token {
+	literal := string(l.currentCharacter) + string(l.peekCharacter())
+	if t := token.LookUpType(literal); t != token.Unknown {
+		l.readCharacter()
+		return token.Token{
+			Type:    t,
+			Literal: literal,
+		}
+	}
+
+	return l.readSingleToken()
+}
+
+func (l *Lexer) readPeriodOrEllipsis() token.Token {
+	if !strings.HasPrefix(l.input[l.currentPosition:], "...") {
+		return l.readSingleToken()
+	}
+
+	l.readCharacter()
+	l.readCharacter()
+	return token.Token{
+		Type:    token.Ellipsis,
+		Literal: "...",
+	}
+}
+
 func (l *Lexer) readEOF() token.Token {
 	return token.Token{
 		Type:    token.EOF,
@@ -84,6 +167,41 @@ func (l *Lexer) readNumber() string {
 	return l.input[begin:l.readingPosition]
 }
 
+func (l *Lexer) readString() token.Token {
+	begin := l.currentPosition
+	for {
+		next := l.peekCharacter()
+		if next == eof || next == '\n' {
+			return token.Token{
+				Type:    token.Unknown,
+				Literal: l.input[begin:l.readingPosition],
+			}
+		}
+
+		l.readCharacter()
+		if next == '\\' {
+			l.readCharacter()
+			continue
+		}
+		if next == '"' {
+			break
+		}
+	}
+
+	literal, err := strconv.Unquote(l.input[begin:l.readingPosition])
+	if err != nil {
+		return token.Token{
+			Type:    token.Unknown,
+			Literal: l.input[begin:l.readingPosition],
+		}
+	}
+
+	return token.Token{
+		Type:    token.String,
+		Literal: literal,
+	}
+}
+
 func (l *Lexer) readKeywordOrIdentifier() token.Token {
 	literal := l.readWord()
 	return token.Token{
@@ -109,6 +227,10 @@ func (l *Lexer) readWord() string {
 }
 
 func (l *Lexer) readCharacter() {
+	if l.currentCharacter == '\n' {
+		l.line++
+		l.lineOffset = l.readingPosition
+	}
 	if len(l.input) <= l.readingPosition {
 		l.currentCharacter = 0
 	} else {
@@ -127,9 +249,53 @@ func (l Lexer) peekCharacter() rune {
 	return rune(l.input[l.readingPosition])
 }
 
-func (l *Lexer) skipWhitespaces() {
-	for l.hasWhitespace() {
+// skipWhitespaces skips whitespaces and comments, and reports whether it stopped
+// at a newline after which a semicolon is inserted.
+func (l *Lexer) skipWhitespaces() bool {
+	for {
+		switch {
+		case l.currentCharacter == '\n' && l.insertsSemicolon:
+			return true
+		case l.hasWhitespace():
+			l.readCharacter()
+		case l.currentCharacter == '/' && l.peekCharacter() == '/':
+			for l.peekCharacter() != '\n' && l.peekCharacter() != eof {
+				l.readCharacter()
+			}
+			l.readCharacter()
+		case l.currentCharacter == '/' && l.peekCharacter() == '*':
+			if l.skipBlockComment() && l.insertsSemicolon {
+				return true
+			}
+			l.readCharacter()
+		default:
+			return false
+		}
+	}
+}
+
+func (l *Lexer) skipBlockComment() bool {
+	l.readCharacter()
+	hasNewline := false
+	for l.currentCharacter != eof && !(l.currentCharacter == '*' && l.peekCharacter() == '/') {
 		l.readCharacter()
+		hasNewline = hasNewline || l.currentCharacter == '\n'
+	}
+	if l.currentCharacter != eof {
+		l.readCharacter()
+	}
+
+	return hasNewline
+}
+
+func insertsSemicolonAfter(t token.Type) bool {
+	switch t {
+	case token.Identifier, token.Integer, token.String,
+		token.Break, token.Continue, token.Fallthrough, token.Return,
+		token.Increment, token.Decrement, token.RParen, token.RBracket, token.RBrace:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -170,5 +336,5 @@ func isDigit(r rune) bool {
 }
 
 func isAlphabet(r rune) bool {
-	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_'
 }
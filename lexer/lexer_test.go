@@ -13,6 +13,15 @@ func TestReadNextToken(t *testing.T) {
 	var x int = 10
 	!true
 	;
+	switch x := 1; x { case 1, 2: fallthrough; default: break }
+	== != < > <= >= v.(type)
+	func f() { defer g("a\"b"); return }
+	for i := range c { i++; i--; continue }
+	go f(); select { case c <- <-d: }; var e chan int
+	type N interface { ~int | string }; F[int]
+	m := map[string][]*int{}; f(x[1:]...)
+	import ("fmt"; s "strings"); fmt.Println
+	package main;
 	`
 	expects := []token.Token{
 		{token.Integer, "1"}, {token.Plus, "+"}, {token.Integer, "2"}, {token.Minus, "-"}, {token.Integer, "3"}, {token.Asterisk, "*"}, {token.Integer, "4"}, {token.Slash, "/"}, {token.Integer, "5"},
@@ -24,6 +33,25 @@ func TestReadNextToken(t *testing.T) {
 		{token.Var, "var"}, {token.Identifier, "x"}, {token.Identifier, "int"}, {token.Assign, "="}, {token.Integer, "10"},
 		{token.Not, "!"}, {token.Identifier, "true"},
 		{token.Semicolon, ";"},
+		{token.Switch, "switch"}, {token.Identifier, "x"}, {token.Define, ":="}, {token.Integer, "1"}, {token.Semicolon, ";"}, {token.Identifier, "x"}, {token.LBrace, "{"},
+		{token.Case, "case"}, {token.Integer, "1"}, {token.Comma, ","}, {token.Integer, "2"}, {token.Colon, ":"}, {token.Fallthrough, "fallthrough"}, {token.Semicolon, ";"},
+		{token.Default, "default"}, {token.Colon, ":"}, {token.Break, "break"}, {token.RBrace, "}"},
+		{token.Equal, "=="}, {token.NotEqual, "!="}, {token.LessThan, "<"}, {token.GreaterThan, ">"}, {token.LessThanOrEqual, "<="}, {token.GreaterThanOrEqual, ">="},
+		{token.Identifier, "v"}, {token.Period, "."}, {token.LParen, "("}, {token.TypeKeyword, "type"}, {token.RParen, ")"},
+		{token.Func, "func"}, {token.Identifier, "f"}, {token.LParen, "("}, {token.RParen, ")"}, {token.LBrace, "{"},
+		{token.Defer, "defer"}, {token.Identifier, "g"}, {token.LParen, "("}, {token.String, `a"b`}, {token.RParen, ")"}, {token.Semicolon, ";"},
+		{token.Return, "return"}, {token.RBrace, "}"},
+		{token.For, "for"}, {token.Identifier, "i"}, {token.Define, ":="}, {token.Range, "range"}, {token.Identifier, "c"}, {token.LBrace, "{"},
+		{token.Identifier, "i"}, {token.Increment, "++"}, {token.Semicolon, ";"}, {token.Identifier, "i"}, {token.Decrement, "--"}, {token.Semicolon, ";"}, {token.Continue, "continue"}, {token.RBrace, "}"},
+		{token.Go, "go"}, {token.Identifier, "f"}, {token.LParen, "("}, {token.RParen, ")"}, {token.Semicolon, ";"},
+		{token.Select, "select"}, {token.LBrace, "{"}, {token.Case, "case"}, {token.Identifier, "c"}, {token.Arrow, "<-"}, {token.Arrow, "<-"}, {token.Identifier, "d"}, {token.Colon, ":"}, {token.RBrace, "}"}, {token.Semicolon, ";"},
+		{token.Var, "var"}, {token.Identifier, "e"}, {token.Chan, "chan"}, {token.Identifier, "int"},
+		{token.TypeKeyword, "type"}, {token.Identifier, "N"}, {token.Interface, "interface"}, {token.LBrace, "{"}, {token.Tilde, "~"}, {token.Identifier, "int"}, {token.Pipe, "|"}, {token.Identifier, "string"}, {token.RBrace, "}"}, {token.Semicolon, ";"},
+		{token.Identifier, "F"}, {token.LBracket, "["}, {token.Identifier, "int"}, {token.RBracket, "]"},
+		{token.Identifier, "m"}, {token.Define, ":="}, {token.Map, "map"}, {token.LBracket, "["}, {token.Identifier, "string"}, {token.RBracket, "]"}, {token.LBracket, "["}, {token.RBracket, "]"}, {token.Asterisk, "*"}, {token.Identifier, "int"}, {token.LBrace, "{"}, {token.RBrace, "}"}, {token.Semicolon, ";"},
+		{token.Identifier, "f"}, {token.LParen, "("}, {token.Identifier, "x"}, {token.LBracket, "["}, {token.Integer, "1"}, {token.Colon, ":"}, {token.RBracket, "]"}, {token.Ellipsis, "..."}, {token.RParen, ")"},
+		{token.Import, "import"}, {token.LParen, "("}, {token.String, "fmt"}, {token.Semicolon, ";"}, {token.Identifier, "s"}, {token.String, "strings"}, {token.RParen, ")"}, {token.Semicolon, ";"}, {token.Identifier, "fmt"}, {token.Period, "."}, {token.Identifier, "Println"},
+		{token.Package, "package"}, {token.Identifier, "main"}, {token.Semicolon, ";"},
 		{token.EOF, ""},
 	}
 	lexer := New(input)
@@ -37,3 +65,42 @@ func TestReadNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestReadNextTokenInsertingSemicolons(t *testing.T) {
+	input := "x := f(1) // comment\n/* a\nb */ return\n\ty++ /* c */ }\nz"
+	expects := []token.Token{
+		{token.Identifier, "x"}, {token.Define, ":="}, {token.Identifier, "f"}, {token.LParen, "("}, {token.Integer, "1"}, {token.RParen, ")"}, {token.Semicolon, "\n"},
+		{token.Return, "return"}, {token.Semicolon, "\n"},
+		{token.Identifier, "y"}, {token.Increment, "++"}, {token.RBrace, "}"}, {token.Semicolon, "\n"},
+		{token.Identifier, "z"}, {token.Semicolon, "\n"},
+		{token.EOF, ""},
+	}
+	lexer := New(input, WithSemicolonInsertion())
+	for _, expect := range expects {
+		token := lexer.ReadNextToken()
+		if token.Type != expect.Type {
+			t.Errorf("unexpected token type: got %v, but expected %v", token.Type, expect.Type)
+		}
+		if token.Literal != expect.Literal {
+			t.Errorf("unexpected token literal: got %q, but expected %q", token.Literal, expect.Literal)
+		}
+	}
+}
+
+func TestReadNextTokenSkippingComments(t *testing.T) {
+	input := "x // comment\n/* a\nb */ y;"
+	expects := []token.Token{
+		{token.Identifier, "x"}, {token.Identifier, "y"}, {token.Semicolon, ";"},
+		{token.EOF, ""},
+	}
+	lexer := New(input)
+	for _, expect := range expects {
+		token := lexer.ReadNextToken()
+		if token.Type != expect.Type {
+			t.Errorf("unexpected token type: got %v, but expected %v", token.Type, expect.Type)
+		}
+		if token.Literal != expect.Literal {
+			t.Errorf("unexpected token literal: got %q, but expected %q", token.Literal, expect.Literal)
+		}
+	}
+}
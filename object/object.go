@@ -2,16 +2,52 @@ package object
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/token"
 )
 
 type Object interface {
 	object()
 }
 
+type Environment interface {
+	Get(name string) (Object, bool)
+	Declare(name string, obj Object) error
+	Assign(name string, obj Object) error
+}
+
 type Integer struct {
 	Value int64
 }
 
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 1023
+)
+
+var cachedIntegers [maxCachedInteger - minCachedInteger + 1]Integer
+
+func init() {
+	for i := range cachedIntegers {
+		cachedIntegers[i].Value = int64(i) + minCachedInteger
+	}
+}
+
+// NewInteger returns a shared instance for small values, so integers must never be modified in place.
+func NewInteger(v int64) *Integer {
+	if minCachedInteger <= v && v <= maxCachedInteger {
+		return &cachedIntegers[v-minCachedInteger]
+	}
+
+	return &Integer{
+		Value: v,
+	}
+}
+
 func (o Integer) object() {
 }
 
@@ -23,6 +59,23 @@ type Boolean struct {
 	Value bool
 }
 
+var (
+	True = &Boolean{
+		Value: true,
+	}
+	False = &Boolean{
+		Value: false,
+	}
+)
+
+func NewBoolean(v bool) *Boolean {
+	if v {
+		return True
+	}
+
+	return False
+}
+
 func (o Boolean) object() {
 }
 
@@ -30,13 +83,431 @@ func (o Boolean) String() string {
 	return fmt.Sprintf("%t", o.Value)
 }
 
-type Error struct {
+type String struct {
+	Value string
+}
+
+func (o String) object() {
+}
+
+func (o String) String() string {
+	return o.Value
+}
+
+type Function struct {
+	Name           string
+	TypeParameters []*ast.Parameter
+	TypeArguments  []*Type
+	Parameters     []*ast.Parameter
+	Results        []*ast.Parameter
+	Body           *ast.BlockStatement
+	Env            Environment
+}
+
+func (o Function) object() {
+}
+
+func (o Function) String() string {
+	if o.Name == "" {
+		return "func literal"
+	}
+
+	return fmt.Sprintf("func %s", o.Name)
+}
+
+type Builtin struct {
+	Name     string
+	Receiver Object
+}
+
+func (o Builtin) object() {
+}
+
+func (o Builtin) String() string {
+	return fmt.Sprintf("builtin %s", o.Name)
+}
+
+type HostFunction struct {
+	Name     string
+	Function reflect.Value
+}
+
+func (o HostFunction) object() {
+}
+
+func (o HostFunction) String() string {
+	return fmt.Sprintf("func %s", o.Name)
+}
+
+type HostValue struct {
+	Value reflect.Value
+}
+
+func (o HostValue) object() {
+}
+
+func (o HostValue) String() string {
+	return fmt.Sprint(o.Value.Interface())
+}
+
+type Package struct {
+	Name    string
+	Path    string
+	Members map[string]Object
+}
+
+func (o Package) object() {
+}
+
+func (o Package) String() string {
+	return fmt.Sprintf("package %s", o.Name)
+}
+
+type ErrorValue struct {
+	Value error
+}
+
+func (o ErrorValue) object() {
+}
+
+func (o ErrorValue) String() string {
+	return o.Value.Error()
+}
+
+type Tuple struct {
+	Values []Object
+}
+
+func (o Tuple) object() {
+}
+
+func (o Tuple) String() string {
+	values := make([]string, len(o.Values))
+	for i, value := range o.Values {
+		values[i] = fmt.Sprint(value)
+	}
+
+	return strings.Join(values, " ")
+}
+
+type RuntimeError struct {
+	Kind    ErrorKind
 	Message string
 }
 
+func (o RuntimeError) object() {
+}
+
+func (o RuntimeError) String() string {
+	return o.Message
+}
+
+type Error struct {
+	Kind     ErrorKind
+	Message  string
+	Position token.Position
+	End      token.Position
+	Stack    []Frame
+	Err      error
+}
+
 func (o Error) object() {
 }
 
 func (o Error) String() string {
 	return o.Message
 }
+
+func (o *Error) Error() string {
+	return o.Message
+}
+
+func (o *Error) Span() token.Span {
+	return token.Span{
+		Start: o.Position,
+		End:   o.End,
+	}
+}
+
+func (o *Error) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && o.Kind == kind
+}
+
+func (o *Error) Unwrap() error {
+	return o.Err
+}
+
+type ErrorKind int
+
+const (
+	ErrUnknown ErrorKind = iota
+	ErrSyntax
+	ErrName
+	ErrType
+	ErrImport
+	ErrDivisionByZero
+	ErrIndexOutOfRange
+	ErrNilDereference
+	ErrNilMap
+	ErrClosedChannel
+	ErrRuntime
+	ErrPanic
+	ErrDeadlock
+	ErrRace
+	ErrLimit
+	ErrCanceled
+)
+
+var errorKindNames = map[ErrorKind]string{
+	ErrUnknown:         "unknown error",
+	ErrSyntax:          "syntax error",
+	ErrName:            "name error",
+	ErrType:            "type error",
+	ErrImport:          "import error",
+	ErrDivisionByZero:  "division by zero",
+	ErrIndexOutOfRange: "index out of range",
+	ErrNilDereference:  "nil dereference",
+	ErrNilMap:          "assignment to nil map",
+	ErrClosedChannel:   "closed channel",
+	ErrRuntime:         "runtime error",
+	ErrPanic:           "panic",
+	ErrDeadlock:        "deadlock",
+	ErrRace:            "data race",
+	ErrLimit:           "limit exceeded",
+	ErrCanceled:        "canceled",
+}
+
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+func (k ErrorKind) Error() string {
+	return k.String()
+}
+
+type Frame struct {
+	Function string
+	Position token.Position
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%s at %s", f.Function, f.Position)
+}
+
+type Nil struct {
+}
+
+func (o Nil) object() {
+}
+
+func (o Nil) String() string {
+	return "<nil>"
+}
+
+type Break struct {
+	Statement *ast.BreakStatement
+}
+
+func (o Break) object() {
+}
+
+type Fallthrough struct {
+	Statement *ast.FallthroughStatement
+}
+
+func (o Fallthrough) object() {
+}
+
+type ReturnValue struct {
+	Value Object
+}
+
+func (o ReturnValue) object() {
+}
+
+type Panic struct {
+	Value    Object
+	Position token.Position
+	End      token.Position
+	Stack    []Frame
+}
+
+func (o *Panic) Kind() ErrorKind {
+	err, ok := o.Value.(*RuntimeError)
+	if !ok {
+		return ErrPanic
+	}
+	if err.Kind == ErrUnknown {
+		return ErrRuntime
+	}
+
+	return err.Kind
+}
+
+func (o Panic) object() {
+}
+
+func (o Panic) String() string {
+	return fmt.Sprintf("panic: %s", o.Value)
+}
+
+type Continue struct {
+	Statement *ast.ContinueStatement
+}
+
+func (o Continue) object() {
+}
+
+type Type struct {
+	Kind    TypeKind
+	Name    string
+	Key     *Type
+	Element *Type
+}
+
+type TypeKind string
+
+const (
+	BasicType   TypeKind = "basic"
+	SliceType            = "slice"
+	MapType              = "map"
+	PointerType          = "pointer"
+	ChannelType          = "chan"
+)
+
+func (o Type) object() {
+}
+
+func (o Type) String() string {
+	return o.Name
+}
+
+type GenericType struct {
+	Name       string
+	Parameters []*ast.Parameter
+	Type       ast.Expression
+	Env        Environment
+}
+
+func (o GenericType) object() {
+}
+
+func (o GenericType) String() string {
+	return o.Name
+}
+
+type Slice struct {
+	ElementType *Type
+	Elements    []Object
+}
+
+func (o Slice) object() {
+}
+
+func (o Slice) String() string {
+	elems := make([]string, len(o.Elements))
+	for i, elem := range o.Elements {
+		elems[i] = fmt.Sprint(elem)
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(elems, " "))
+}
+
+type Map struct {
+	KeyType   *Type
+	ValueType *Type
+	Pairs     map[interface{}]*MapPair
+}
+
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+func (o Map) object() {
+}
+
+func (o Map) String() string {
+	pairs := o.SortedPairs()
+	ss := make([]string, len(pairs))
+	for i, pair := range pairs {
+		ss[i] = fmt.Sprintf("%s:%s", pair.Key, pair.Value)
+	}
+
+	return fmt.Sprintf("map[%s]", strings.Join(ss, " "))
+}
+
+func (o Map) SortedPairs() []*MapPair {
+	pairs := make([]*MapPair, 0, len(o.Pairs))
+	for _, pair := range o.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return Less(pairs[i].Key, pairs[j].Key)
+	})
+
+	return pairs
+}
+
+func Less(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		if b, ok := b.(*Integer); ok {
+			return a.Value < b.Value
+		}
+	case *String:
+		if b, ok := b.(*String); ok {
+			return a.Value < b.Value
+		}
+	case *Boolean:
+		if b, ok := b.(*Boolean); ok {
+			return !a.Value && b.Value
+		}
+	}
+
+	return fmt.Sprintf("%T", a) < fmt.Sprintf("%T", b)
+}
+
+type Pointer struct {
+	ElementType *Type
+	Value       *Object
+}
+
+func (o Pointer) object() {
+}
+
+func (o Pointer) String() string {
+	if o.Value == nil {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("%p", o.Value)
+}
+
+type Channel struct {
+	ElementType *Type
+	Capacity    int
+	Buffer      []Object
+	Closed      bool
+	Senders     []ChannelWaiter
+	Receivers   []ChannelWaiter
+}
+
+func (o Channel) object() {
+}
+
+func (o Channel) String() string {
+	return fmt.Sprintf("chan %s", o.ElementType)
+}
+
+type ChannelWaiter interface {
+	Claim() bool
+	IsClaimed() bool
+	Value() Object
+	Wake(value Object, ok bool)
+}
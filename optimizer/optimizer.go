@@ -0,0 +1,505 @@
+package optimizer
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/token"
+)
+
+type Optimizer struct {
+	positions map[ast.Node]token.Position
+	ends      map[ast.Node]token.Position
+	shadowed  map[string]bool
+}
+
+func New() *Optimizer {
+	return new(Optimizer)
+}
+
+func (o *Optimizer) Optimize(program *ast.Program) {
+	if program.Positions == nil {
+		program.Positions = make(map[ast.Node]token.Position)
+	}
+	if program.Ends == nil {
+		program.Ends = make(map[ast.Node]token.Position)
+	}
+	o.positions, o.ends = program.Positions, program.Ends
+	o.shadowed = declaredNames(program)
+
+	program.Statements = o.optimizeStatements(program.Statements, false)
+}
+
+func (o *Optimizer) optimizeStatements(stmts []ast.Statement, eliminates bool) []ast.Statement {
+	for i, stmt := range stmts {
+		if _, ok := stmt.(*ast.BadStatement); ok {
+			return stmts
+		}
+
+		o.optimizeStatement(stmt)
+		if eliminates && o.terminates(stmt) {
+			return stmts[:i+1]
+		}
+	}
+
+	return stmts
+}
+
+func (o *Optimizer) terminates(node ast.Statement) bool {
+	switch node := node.(type) {
+	case *ast.ReturnStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	case *ast.ExpressionStatement:
+		call, ok := node.Expression.(*ast.CallExpression)
+		if !ok {
+			return false
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		return ok && ident.Name == "panic" && !o.shadowed["panic"]
+	default:
+		return false
+	}
+}
+
+func (o *Optimizer) optimizeStatement(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		node.Expression = o.fold(node.Expression)
+	case *ast.VariableDeclaration:
+		node.Expression = o.fold(node.Expression)
+	case *ast.ShortVariableDeclaration:
+		o.foldAll(node.Expressions)
+	case *ast.AssignmentStatement:
+		o.foldOperandsOf(node.LExpressions)
+		o.foldAll(node.RExpressions)
+	case *ast.IncDecStatement:
+		o.foldOperandsOf([]ast.Expression{node.Expression})
+	case *ast.SendStatement:
+		node.Channel = o.fold(node.Channel)
+		node.Expression = o.fold(node.Expression)
+	case *ast.GoStatement:
+		o.foldCall(node.Call)
+	case *ast.DeferStatement:
+		o.foldCall(node.Call)
+	case *ast.ReturnStatement:
+		node.Expression = o.fold(node.Expression)
+	case *ast.BlockStatement:
+		node.Statements = o.optimizeStatements(node.Statements, true)
+	case *ast.ForStatement:
+		o.optimizeForStatement(node)
+	case *ast.RangeStatement:
+		node.Expression = o.fold(node.Expression)
+		node.Body.Statements = o.optimizeStatements(node.Body.Statements, true)
+	case *ast.SwitchStatement:
+		o.optimizeSwitchStatement(node)
+	case *ast.TypeSwitchStatement:
+		if node.Initializer != nil {
+			o.optimizeStatement(node.Initializer)
+		}
+		node.Expression = o.fold(node.Expression)
+		for _, clause := range node.Clauses {
+			clause.Statements = o.optimizeStatements(clause.Statements, true)
+		}
+	case *ast.SelectStatement:
+		for _, clause := range node.Clauses {
+			if clause.Communication != nil {
+				o.optimizeStatement(clause.Communication)
+			}
+			clause.Statements = o.optimizeStatements(clause.Statements, true)
+		}
+	case *ast.FunctionDeclaration:
+		node.Body.Statements = o.optimizeStatements(node.Body.Statements, true)
+	}
+}
+
+func (o *Optimizer) optimizeForStatement(node *ast.ForStatement) {
+	if node.Initializer != nil {
+		o.optimizeStatement(node.Initializer)
+	}
+	node.Condition = o.fold(node.Condition)
+	if value, ok := o.booleanOf(node.Condition); ok && !value {
+		node.Post = nil
+		node.Body.Statements = node.Body.Statements[:0]
+		return
+	}
+
+	if node.Post != nil {
+		o.optimizeStatement(node.Post)
+	}
+	node.Body.Statements = o.optimizeStatements(node.Body.Statements, true)
+}
+
+func (o *Optimizer) optimizeSwitchStatement(node *ast.SwitchStatement) {
+	if node.Initializer != nil {
+		o.optimizeStatement(node.Initializer)
+	}
+	node.Tag = o.fold(node.Tag)
+
+	var tag ast.Expression = &ast.Identifier{
+		Name: "true",
+	}
+	if node.Tag != nil {
+		tag = node.Tag
+	}
+	clauses := make([]*ast.CaseClause, 0, len(node.Clauses))
+	for _, clause := range node.Clauses {
+		o.foldAll(clause.Expressions)
+		clause.Statements = o.optimizeStatements(clause.Statements, true)
+
+		fallenInto := 0 < len(clauses) && endsWithFallthrough(clauses[len(clauses)-1])
+		if !clause.IsDefault && !fallenInto && o.neverMatches(tag, clause.Expressions) {
+			continue
+		}
+		clauses = append(clauses, clause)
+	}
+	node.Clauses = clauses
+}
+
+func endsWithFallthrough(clause *ast.CaseClause) bool {
+	if len(clause.Statements) == 0 {
+		return false
+	}
+
+	_, ok := clause.Statements[len(clause.Statements)-1].(*ast.FallthroughStatement)
+	return ok
+}
+
+func (o *Optimizer) neverMatches(tag ast.Expression, exprs []ast.Expression) bool {
+	for _, expr := range exprs {
+		equal, ok := o.equal(tag, expr)
+		if !ok || equal {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o *Optimizer) equal(left, right ast.Expression) (bool, bool) {
+	switch left := left.(type) {
+	case *ast.Integer:
+		if right, ok := right.(*ast.Integer); ok {
+			return left.Value == right.Value, true
+		}
+	case *ast.String:
+		if right, ok := right.(*ast.String); ok {
+			return left.Value == right.Value, true
+		}
+	}
+
+	l, ok := o.booleanOf(left)
+	if !ok {
+		return false, false
+	}
+	r, ok := o.booleanOf(right)
+	if !ok {
+		return false, false
+	}
+
+	return l == r, true
+}
+
+func (o *Optimizer) booleanOf(node ast.Expression) (bool, bool) {
+	ident, ok := node.(*ast.Identifier)
+	if !ok || !o.hasBooleans() {
+		return false, false
+	}
+
+	switch ident.Name {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func (o *Optimizer) foldAll(nodes []ast.Expression) {
+	for i, node := range nodes {
+		nodes[i] = o.fold(node)
+	}
+}
+
+func (o *Optimizer) foldOperandsOf(nodes []ast.Expression) {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *ast.IndexExpression:
+			node.Expression = o.fold(node.Expression)
+			o.foldAll(node.Indices)
+		case *ast.SelectorExpression:
+			node.Expression = o.fold(node.Expression)
+		case *ast.PrefixExpression:
+			node.RExpression = o.fold(node.RExpression)
+		}
+	}
+}
+
+func (o *Optimizer) foldCall(node *ast.CallExpression) {
+	node.Function = o.fold(node.Function)
+	o.foldAll(node.Arguments)
+}
+
+func (o *Optimizer) fold(node ast.Expression) ast.Expression {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		node.RExpression = o.fold(node.RExpression)
+		return o.foldPrefixExpression(node)
+	case *ast.InfixExpression:
+		node.LExpression = o.fold(node.LExpression)
+		node.RExpression = o.fold(node.RExpression)
+		return o.foldInfixExpression(node)
+	case *ast.SelectorExpression:
+		node.Expression = o.fold(node.Expression)
+	case *ast.TypeAssertionExpression:
+		node.Expression = o.fold(node.Expression)
+	case *ast.IndexExpression:
+		node.Expression = o.fold(node.Expression)
+		o.foldAll(node.Indices)
+	case *ast.SliceExpression:
+		node.Expression = o.fold(node.Expression)
+		node.Low = o.fold(node.Low)
+		node.High = o.fold(node.High)
+	case *ast.CallExpression:
+		o.foldCall(node)
+	case *ast.FunctionLiteral:
+		node.Body.Statements = o.optimizeStatements(node.Body.Statements, true)
+	case *ast.CompositeLiteral:
+		for _, elem := range node.Elements {
+			elem.Key = o.fold(elem.Key)
+			elem.Value = o.fold(elem.Value)
+		}
+	}
+
+	return node
+}
+
+func (o *Optimizer) foldPrefixExpression(node *ast.PrefixExpression) ast.Expression {
+	switch node.Operator {
+	case ast.Negative:
+		if operand, ok := node.RExpression.(*ast.Integer); ok {
+			return o.replace(node, &ast.Integer{
+				Value: -1 * operand.Value,
+			})
+		}
+	case ast.Not:
+		if value, ok := o.booleanOf(node.RExpression); ok {
+			return o.replace(node, o.boolean(!value))
+		}
+		switch operand := node.RExpression.(type) {
+		case *ast.InfixExpression:
+			if negated, ok := negations[operand.Operator]; ok {
+				return o.replace(operand, &ast.InfixExpression{
+					LExpression: operand.LExpression,
+					Operator:    negated,
+					RExpression: operand.RExpression,
+				})
+			}
+		case *ast.PrefixExpression:
+			if operand.Operator != ast.Not {
+				break
+			}
+			if infix, ok := operand.RExpression.(*ast.InfixExpression); ok && isComparison(infix.Operator) {
+				return infix
+			}
+		}
+	}
+
+	return node
+}
+
+var negations = map[ast.InfixOperator]ast.InfixOperator{
+	ast.Equal:    ast.NotEqual,
+	ast.NotEqual: ast.Equal,
+}
+
+func isComparison(op ast.InfixOperator) bool {
+	switch op {
+	case ast.Equal, ast.NotEqual, ast.LessThan, ast.GreaterThan, ast.LessThanOrEqual, ast.GreaterThanOrEqual:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o *Optimizer) foldInfixExpression(node *ast.InfixExpression) ast.Expression {
+	switch left := node.LExpression.(type) {
+	case *ast.Integer:
+		if right, ok := node.RExpression.(*ast.Integer); ok {
+			if folded, ok := o.foldIntegers(node.Operator, left.Value, right.Value); ok {
+				return o.replace(node, folded)
+			}
+		}
+	case *ast.String:
+		if right, ok := node.RExpression.(*ast.String); ok {
+			if folded, ok := o.foldStrings(node.Operator, left.Value, right.Value); ok {
+				return o.replace(node, folded)
+			}
+		}
+	}
+
+	switch node.Operator {
+	case ast.Equal, ast.NotEqual:
+		left, ok := o.booleanOf(node.LExpression)
+		if !ok {
+			break
+		}
+		right, ok := o.booleanOf(node.RExpression)
+		if !ok {
+			break
+		}
+		return o.replace(node, o.boolean((left == right) == (node.Operator == ast.Equal)))
+	}
+
+	return node
+}
+
+func (o *Optimizer) foldIntegers(op ast.InfixOperator, left, right int64) (ast.Expression, bool) {
+	var value int64
+	switch op {
+	case ast.Plus:
+		value = left + right
+	case ast.Minus:
+		value = left - right
+	case ast.Asterisk:
+		value = left * right
+	case ast.Slash:
+		if right == 0 {
+			return nil, false
+		}
+		value = left / right
+	default:
+		return o.compare(op, compareIntegers(left, right))
+	}
+
+	return &ast.Integer{
+		Value: value,
+	}, true
+}
+
+func compareIntegers(left, right int64) int {
+	switch {
+	case left < right:
+		return -1
+	case right < left:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (o *Optimizer) foldStrings(op ast.InfixOperator, left, right string) (ast.Expression, bool) {
+	if op == ast.Plus {
+		return &ast.String{
+			Value: left + right,
+		}, true
+	}
+
+	return o.compare(op, strings.Compare(left, right))
+}
+
+func (o *Optimizer) hasBooleans() bool {
+	return !o.shadowed["true"] && !o.shadowed["false"]
+}
+
+func (o *Optimizer) compare(op ast.InfixOperator, compared int) (ast.Expression, bool) {
+	if !o.hasBooleans() {
+		return nil, false
+	}
+
+	switch op {
+	case ast.Equal:
+		return o.boolean(compared == 0), true
+	case ast.NotEqual:
+		return o.boolean(compared != 0), true
+	case ast.LessThan:
+		return o.boolean(compared < 0), true
+	case ast.GreaterThan:
+		return o.boolean(0 < compared), true
+	case ast.LessThanOrEqual:
+		return o.boolean(compared <= 0), true
+	case ast.GreaterThanOrEqual:
+		return o.boolean(0 <= compared), true
+	default:
+		return nil, false
+	}
+}
+
+func (o *Optimizer) boolean(value bool) *ast.Identifier {
+	if value {
+		return &ast.Identifier{
+			Name: "true",
+		}
+	}
+
+	return &ast.Identifier{
+		Name: "false",
+	}
+}
+
+func (o *Optimizer) replace(old, new ast.Expression) ast.Expression {
+	if pos, ok := o.positions[old]; ok {
+		o.positions[new] = pos
+	}
+	if end, ok := o.ends[old]; ok {
+		o.ends[new] = end
+	}
+
+	return new
+}
+
+func declaredNames(program *ast.Program) map[string]bool {
+	names := make(map[string]bool)
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if v.IsNil() {
+				return
+			}
+			switch node := v.Interface().(type) {
+			case *ast.VariableDeclaration:
+				names[node.Identifier.Name] = true
+			case *ast.ShortVariableDeclaration:
+				for _, ident := range node.Identifiers {
+					names[ident.Name] = true
+				}
+			case *ast.RangeStatement:
+				for _, ident := range []*ast.Identifier{node.Key, node.Value} {
+					if ident != nil {
+						names[ident.Name] = true
+					}
+				}
+			case *ast.TypeSwitchStatement:
+				if node.Identifier != nil {
+					names[node.Identifier.Name] = true
+				}
+			case *ast.FunctionDeclaration:
+				names[node.Identifier.Name] = true
+			case *ast.TypeDeclaration:
+				names[node.Identifier.Name] = true
+			case *ast.Parameter:
+				if node.Identifier != nil {
+					names[node.Identifier.Name] = true
+				}
+			case *ast.ImportSpec:
+				if node.Name != nil {
+					names[node.Name.Name] = true
+				}
+			}
+			visit(v.Elem())
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				visit(v.Field(i))
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				visit(v.Index(i))
+			}
+		}
+	}
+	visit(reflect.ValueOf(program.Statements))
+
+	return names
+}
@@ -0,0 +1,107 @@
+package optimizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/parser"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"1 + 2 * 3;",
+			"7;",
+		},
+		{
+			"x := -(2 - 5); x - (4 / 2);",
+			"x := 3; x - 2;",
+		},
+		{
+			"\"kina\" + \"ko\"; \"a\" < \"b\";",
+			"\"kinako\"; true;",
+		},
+		{
+			"1 + 1 == 2; 3 <= 2; !(1 == 1); true != false;",
+			"true; false; false; true;",
+		},
+		{
+			"x := 1; !(x == 1); !(x != 1); !!(x < 1);",
+			"x := 1; x != 1; x == 1; x < 1;",
+		},
+		{
+			"1 / 0; 1 / (1 - 1); 1 + \"a\";",
+			"1 / 0; 1 / 0; 1 + \"a\";",
+		},
+		{
+			"true := 0; 1 == 1;",
+			"true := 0; 1 == 1;",
+		},
+		{
+			"func f() int { return 1 + 1; x := 2; return x; }",
+			"func f() int { return 2; }",
+		},
+		{
+			"func f() { panic(\"a\"); f(); }",
+			"func f() { panic(\"a\"); }",
+		},
+		{
+			"for i := 0; i < 3; i++ { continue; i = 2; }",
+			"for i := 0; i < 3; i++ { continue; }",
+		},
+		{
+			"for x := 1; 1 < 0; x++ { x = 2; }",
+			"for x := 1; false; { }",
+		},
+		{
+			"switch { case 1 == 2: 1; case 2 == 2: 2; default: 3; }",
+			"switch { case true: 2; default: 3; }",
+		},
+		{
+			"switch 1 { case 0: fallthrough; case 2: 2; case 3: 3; }",
+			"switch 1 { }",
+		},
+		{
+			"x := 1; switch x { case 0: 0; }",
+			"x := 1; switch x { case 0: 0; }",
+		},
+		{
+			"switch { case false: x := 1; x; }",
+			"switch { }",
+		},
+		{
+			"x := 0; switch x { case 0: return; y := 1; }",
+			"x := 0; switch x { case 0: return; }",
+		},
+		{
+			"return; 1;",
+			"return; 1;",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			New().Optimize(program)
+			expected := parser.New(lexer.New(test.expected)).ParseProgram()
+			if !reflect.DeepEqual(program.Statements, expected.Statements) {
+				t.Errorf("unexpected program: got %#v, but expected %#v\n", program.Statements, expected.Statements)
+			}
+		})
+	}
+}
+
+func TestOptimizeKeepsPositions(t *testing.T) {
+	program := parser.New(lexer.New("x := 1;\n1 + 2;")).ParseProgram()
+	expected := program.Positions[program.Statements[1].(*ast.ExpressionStatement).Expression]
+
+	New().Optimize(program)
+	actual, ok := program.Positions[program.Statements[1].(*ast.ExpressionStatement).Expression]
+	if !ok || actual != expected {
+		t.Errorf("unexpected position: got %v, but expected %v\n", actual, expected)
+	}
+}
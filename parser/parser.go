@@ -19,17 +19,29 @@ type priority int
 const (
 	_ priority = iota
 	lowest
+	comparative
 	additive
 	multiplicative
 	prefix
 	infix
+	selector
+	call
 )
 
 var precedence = map[token.Type]priority{
-	token.Plus:     additive,
-	token.Minus:    additive,
-	token.Asterisk: multiplicative,
-	token.Slash:    multiplicative,
+	token.Equal:              comparative,
+	token.NotEqual:           comparative,
+	token.LessThan:           comparative,
+	token.GreaterThan:        comparative,
+	token.LessThanOrEqual:    comparative,
+	token.GreaterThanOrEqual: comparative,
+	token.Plus:               additive,
+	token.Minus:              additive,
+	token.Asterisk:           multiplicative,
+	token.Slash:              multiplicative,
+	token.Period:             selector,
+	token.LParen:             call,
+	token.LBracket:           call,
 }
 
 func (p priority) isHigherThan(prec priority) bool {
@@ -45,13 +57,22 @@ type Parser struct {
 	prefixParsers map[token.Type]prefixParser
 	infixParsers  map[token.Type]infixParser
 	badStatements []*ast.BadStatement
+	reported      []*ast.BadStatement
 	currentToken  token.Token
 	readingToken  token.Token
+	currentPos    token.Position
+	readingPos    token.Position
+	currentEnd    token.Position
+	readingEnd    token.Position
+	positions     map[ast.Node]token.Position
+	ends          map[ast.Node]token.Position
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		lexer: l,
+		lexer:     l,
+		positions: make(map[ast.Node]token.Position),
+		ends:      make(map[ast.Node]token.Position),
 	}
 	p.moveFirstTwoTokenForward()
 	p.registerPrefixParsers()
@@ -64,95 +85,1239 @@ func (p *Parser) registerPrefixParsers() {
 	p.prefixParsers = map[token.Type]prefixParser{
 		token.Minus:      p.parsePrefixExpression,
 		token.Not:        p.parsePrefixExpression,
+		token.Asterisk:   p.parsePrefixExpression,
+		token.Arrow:      p.parsePrefixExpressionOrChannelType,
+		token.LBracket:   p.parseSliceTypeOrCompositeLiteral,
+		token.Map:        p.parseMapTypeOrCompositeLiteral,
+		token.Chan:       p.parseChannelType,
+		token.Interface:  p.parseInterfaceType,
 		token.LParen:     p.parseGroupExpression,
+		token.Func:       p.parseFunctionLiteral,
 		token.Identifier: p.parseIdentifier,
 		token.Integer:    p.parseInteger,
+		token.String:     p.parseString,
 	}
 }
 
 func (p *Parser) registerInfixParsers() {
 	p.infixParsers = map[token.Type]infixParser{
-		token.Plus:     p.parseInfixExpression,
-		token.Minus:    p.parseInfixExpression,
-		token.Asterisk: p.parseInfixExpression,
-		token.Slash:    p.parseInfixExpression,
+		token.Plus:               p.parseInfixExpression,
+		token.Minus:              p.parseInfixExpression,
+		token.Asterisk:           p.parseInfixExpression,
+		token.Slash:              p.parseInfixExpression,
+		token.Equal:              p.parseInfixExpression,
+		token.NotEqual:           p.parseInfixExpression,
+		token.LessThan:           p.parseInfixExpression,
+		token.GreaterThan:        p.parseInfixExpression,
+		token.LessThanOrEqual:    p.parseInfixExpression,
+		token.GreaterThanOrEqual: p.parseInfixExpression,
+		token.Period:             p.parseSelectorOrTypeAssertionExpression,
+		token.LParen:             p.parseCallExpression,
+		token.LBracket:           p.parseIndexExpression,
 	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
 	return &ast.Program{
 		Statements: p.parseStatements(),
+		Positions:  p.positions,
+		Ends:       p.ends,
 	}
 }
 
+func (p *Parser) ReportedBadStatements() []*ast.BadStatement {
+	return p.reported
+}
+
 func (p *Parser) parseStatements() []ast.Statement {
 	stmts := make([]ast.Statement, 0)
+	allowsPackage, allowsImport := true, true
 	for !p.has(token.EOF) {
+		isPackage, isImport := p.has(token.Package), p.has(token.Import)
 		if stmt := p.parseStatement(); stmt != nil {
+			switch {
+			case isPackage && !allowsPackage:
+				stmt = p.reportBadStatement(p.positions[stmt], "syntax error: package statement must be first")
+			case isImport && !allowsImport:
+				stmt = p.reportBadStatement(p.positions[stmt], "syntax error: imports must appear before other declarations")
+			}
 			stmts = append(stmts, stmt)
 		}
+		allowsPackage = false
+		allowsImport = allowsImport && (isPackage || isImport)
+		p.moveTokenForward()
+	}
+
+	return stmts
+}
+
+func (p *Parser) parseStatement() ast.Statement {
+	pos := p.currentPos
+	stmt := p.parseStatementAt()
+	if stmt != nil {
+		p.mark(stmt, pos)
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseStatementAt() ast.Statement {
+	var stmt ast.Statement
+	switch p.currentToken.Type {
+	case token.Switch:
+		stmt = p.parseSwitchStatement()
+		p.skipSemicolon()
+	case token.Select:
+		stmt = p.parseSelectStatement()
+		p.skipSemicolon()
+	case token.For:
+		stmt = p.parseForStatement()
+		p.skipSemicolon()
+	case token.Func:
+		if !p.willHave(token.Identifier) {
+			stmt = p.parseStatementEndingWithSemicolon()
+			break
+		}
+		stmt = p.parseFunctionDeclaration()
+		p.skipSemicolon()
+	case token.Package:
+		stmt = p.parsePackageClause()
+		if err := p.expectAndMoveTokenForward(token.Semicolon); err != nil {
+			p.keepBadStatement("failed to find semicolon")
+		}
+	case token.Import:
+		stmt = p.parseImportDeclaration()
+		if p.has(token.RParen) {
+			p.skipSemicolon()
+			break
+		}
+		if err := p.expectAndMoveTokenForward(token.Semicolon); err != nil {
+			p.keepBadStatement("failed to find semicolon")
+		}
+	case token.TypeKeyword:
+		stmt = p.parseTypeDeclaration()
+		if p.has(token.RBrace) {
+			p.skipSemicolon()
+			break
+		}
+		if err := p.expectAndMoveTokenForward(token.Semicolon); err != nil {
+			p.keepBadStatement("failed to find semicolon")
+		}
+	default:
+		stmt = p.parseStatementEndingWithSemicolon()
+	}
+
+	if p.hasBadStatements() {
+		return p.takeOutLastBadStatement()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseStatementEndingWithSemicolon() ast.Statement {
+	var stmt ast.Statement
+	switch p.currentToken.Type {
+	case token.Var:
+		stmt = p.parseVariableDeclaration()
+	case token.Fallthrough:
+		stmt = &ast.FallthroughStatement{}
+	case token.Break:
+		stmt = &ast.BreakStatement{}
+	case token.Continue:
+		stmt = &ast.ContinueStatement{}
+	case token.Return:
+		stmt = p.parseReturnStatement()
+	case token.Defer:
+		stmt = p.parseDeferStatement()
+	case token.Go:
+		stmt = p.parseGoStatement()
+	default:
+		stmt = p.parseSimpleStatement()
+	}
+
+	if p.willHave(token.RBrace) || p.willHave(token.RParen) {
+		return stmt
+	}
+	if err := p.expectAndMoveTokenForward(token.Semicolon); err != nil {
+		p.keepBadStatement("failed to find semicolon")
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseSimpleStatement() ast.Statement {
+	return p.parseSimpleStatementOrRangeClause(false)
+}
+
+func (p *Parser) parseSimpleStatementOrRangeClause(allowsRange bool) ast.Statement {
+	lefts := p.parseExpressionList()
+	switch p.readingToken.Type {
+	case token.Define:
+		idents, ok := identifiersOf(lefts)
+		if !ok {
+			p.keepBadStatement("non-name on left side of :=")
+			return nil
+		}
+		p.moveTokenForward()
+		if allowsRange && p.willHave(token.Range) {
+			return p.parseRangeClause(idents, true)
+		}
+		p.moveTokenForward()
+
+		return &ast.ShortVariableDeclaration{
+			Identifiers: idents,
+			Expressions: p.parseExpressionList(),
+		}
+	case token.Assign:
+		p.moveTokenForward()
+		if allowsRange && p.willHave(token.Range) {
+			idents, ok := identifiersOf(lefts)
+			if !ok {
+				p.keepBadStatement("non-name on left side of range assignment")
+				return nil
+			}
+			return p.parseRangeClause(idents, false)
+		}
+		p.moveTokenForward()
+
+		return &ast.AssignmentStatement{
+			LExpressions: lefts,
+			RExpressions: p.parseExpressionList(),
+		}
+	case token.Increment, token.Decrement:
+		if len(lefts) != 1 {
+			p.keepBadStatement(fmt.Sprintf("unexpected %s", p.readingToken.Literal))
+			return nil
+		}
+		p.moveTokenForward()
+
+		return &ast.IncDecStatement{
+			Expression: lefts[0],
+			Operator:   ast.IncDecOperators[p.currentToken.Type],
+		}
+	case token.Arrow:
+		if len(lefts) != 1 {
+			p.keepBadStatement("unexpected <-")
+			return nil
+		}
+		p.moveTokenForward()
+		p.moveTokenForward()
+
+		return &ast.SendStatement{
+			Channel:    lefts[0],
+			Expression: p.parseExpression(lowest),
+		}
+	default:
+		if len(lefts) != 1 {
+			p.keepBadStatement("failed to find assignment operator")
+			return nil
+		}
+
+		return &ast.ExpressionStatement{
+			Expression: lefts[0],
+		}
+	}
+}
+
+func identifiersOf(exprs []ast.Expression) ([]*ast.Identifier, bool) {
+	idents := make([]*ast.Identifier, len(exprs))
+	for i, expr := range exprs {
+		ident, ok := expr.(*ast.Identifier)
+		if !ok {
+			return nil, false
+		}
+		idents[i] = ident
+	}
+
+	return idents, true
+}
+
+func (p *Parser) parseRangeClause(idents []*ast.Identifier, isDefine bool) *ast.RangeStatement {
+	if 2 < len(idents) {
+		p.keepBadStatement("range clause permits at most two iteration variables")
+		return nil
+	}
+
+	p.moveTokenForward()
+	p.moveTokenForward()
+	stmt := &ast.RangeStatement{
+		Key:        idents[0],
+		IsDefine:   isDefine,
+		Expression: p.parseExpression(lowest),
+	}
+	if len(idents) == 2 {
+		stmt.Value = idents[1]
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseVariableDeclaration() *ast.VariableDeclaration {
+	if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
+		p.keepBadStatement("failed to find identifier of variable")
+		return nil
+	}
+	stmt := &ast.VariableDeclaration{
+		Identifier: p.parseIdentifier().(*ast.Identifier),
+	}
+
+	if !p.willHaveType() {
+		p.keepBadStatement("failed to find type name of variable")
+		return nil
+	}
+	p.moveTokenForward()
+	stmt.Type = p.parseType()
+	if stmt.Type == nil {
+		return nil
+	}
+
+	if err := p.expectAndMoveTokenForward(token.Assign); err != nil {
+		return stmt
+	}
+
+	p.moveTokenForward()
+	stmt.Expression = p.parseExpression(lowest)
+
+	return stmt
+}
+
+func (p *Parser) parseType() ast.Expression {
+	switch p.currentToken.Type {
+	case token.Identifier:
+		ident := p.parseIdentifier()
+		p.mark(ident, p.currentPos)
+		if !p.willHave(token.LBracket) {
+			return ident
+		}
+		p.moveTokenForward()
+		return p.parseIndexExpression(ident)
+	case token.LBracket:
+		return p.parseSliceType()
+	case token.Map:
+		return p.parseMapType()
+	case token.Asterisk:
+		return p.parsePointerType()
+	case token.Chan, token.Arrow:
+		return p.parseChannelType()
+	case token.Interface:
+		return p.parseInterfaceType()
+	default:
+		p.keepBadStatement(fmt.Sprintf("failed to find type: %s", p.currentToken.Literal))
+		return nil
+	}
+}
+
+func (p *Parser) parseSliceType() ast.Expression {
+	if err := p.expectAndMoveTokenForward(token.RBracket); err != nil {
+		p.keepBadStatement("failed to find rbracket")
+		return nil
+	}
+	if !p.willHaveType() {
+		p.keepBadStatement("failed to find element type of slice")
+		return nil
+	}
+	p.moveTokenForward()
+	elem := p.parseType()
+	if elem == nil {
+		return nil
+	}
+
+	return &ast.SliceType{
+		Element: elem,
+	}
+}
+
+func (p *Parser) parseMapType() ast.Expression {
+	if err := p.expectAndMoveTokenForward(token.LBracket); err != nil {
+		p.keepBadStatement("failed to find lbracket")
+		return nil
+	}
+	if !p.willHaveType() {
+		p.keepBadStatement("failed to find key type of map")
+		return nil
+	}
+	p.moveTokenForward()
+	key := p.parseType()
+	if key == nil {
+		return nil
+	}
+	if err := p.expectAndMoveTokenForward(token.RBracket); err != nil {
+		p.keepBadStatement("failed to find rbracket")
+		return nil
+	}
+	if !p.willHaveType() {
+		p.keepBadStatement("failed to find value type of map")
+		return nil
+	}
+	p.moveTokenForward()
+	value := p.parseType()
+	if value == nil {
+		return nil
+	}
+
+	return &ast.MapType{
+		Key:   key,
+		Value: value,
+	}
+}
+
+func (p *Parser) parsePointerType() ast.Expression {
+	if !p.willHaveType() {
+		p.keepBadStatement("failed to find base type of pointer")
+		return nil
+	}
+	p.moveTokenForward()
+	elem := p.parseType()
+	if elem == nil {
+		return nil
+	}
+
+	return &ast.PointerType{
+		Element: elem,
+	}
+}
+
+func (p *Parser) parseChannelType() ast.Expression {
+	typ := &ast.ChannelType{
+		Direction: ast.Bidirectional,
+	}
+	if p.has(token.Arrow) {
+		if err := p.expectAndMoveTokenForward(token.Chan); err != nil {
+			p.keepBadStatement("failed to find chan")
+			return nil
+		}
+		typ.Direction = ast.ReceiveOnly
+	} else if p.willHave(token.Arrow) {
+		p.moveTokenForward()
+		typ.Direction = ast.SendOnly
+	}
+
+	if !p.willHaveType() {
+		p.keepBadStatement("failed to find element type of channel")
+		return nil
+	}
+	p.moveTokenForward()
+	typ.Element = p.parseType()
+	if typ.Element == nil {
+		return nil
+	}
+
+	return typ
+}
+
+func (p *Parser) parseInterfaceType() ast.Expression {
+	if err := p.expectAndMoveTokenForward(token.LBrace); err != nil {
+		p.keepBadStatement("failed to find lbrace")
+		return nil
+	}
+
+	typ := &ast.InterfaceType{
+		Elements: make([]*ast.UnionType, 0),
+	}
+	for !p.willHave(token.RBrace) {
+		if !p.willHaveConstraint() {
+			p.keepBadStatement(fmt.Sprintf("failed to find type: %s", p.readingToken.Literal))
+			return nil
+		}
+		p.moveTokenForward()
+		union := p.parseUnionType()
+		if union == nil {
+			return nil
+		}
+		typ.Elements = append(typ.Elements, union)
+
+		if p.willHave(token.Semicolon) {
+			p.moveTokenForward()
+		}
+	}
+	p.moveTokenForward()
+
+	return typ
+}
+
+func (p *Parser) parseUnionType() *ast.UnionType {
+	union := &ast.UnionType{
+		Terms: make([]*ast.TypeTerm, 0),
+	}
+	for {
+		term := &ast.TypeTerm{
+			IsTilde: p.has(token.Tilde),
+		}
+		if term.IsTilde {
+			if !p.willHaveType() {
+				p.keepBadStatement("failed to find type after ~")
+				return nil
+			}
+			p.moveTokenForward()
+		}
+		term.Type = p.parseType()
+		if term.Type == nil {
+			return nil
+		}
+		union.Terms = append(union.Terms, term)
+
+		if !p.willHave(token.Pipe) {
+			return union
+		}
+		p.moveTokenForward()
+		if !p.willHaveConstraint() {
+			p.keepBadStatement("failed to find type after |")
+			return nil
+		}
+		p.moveTokenForward()
+	}
+}
+
+func (p *Parser) parseConstraint() ast.Expression {
+	union := p.parseUnionType()
+	if union == nil {
+		return nil
+	}
+	if len(union.Terms) == 1 && !union.Terms[0].IsTilde {
+		return union.Terms[0].Type
+	}
+
+	return &ast.InterfaceType{
+		Elements: []*ast.UnionType{union},
+	}
+}
+
+func (p *Parser) parsePackageClause() *ast.PackageClause {
+	if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
+		p.keepBadStatement("failed to find package name")
+		return nil
+	}
+
+	return &ast.PackageClause{
+		Name: p.parseIdentifier().(*ast.Identifier),
+	}
+}
+
+func (p *Parser) parseImportDeclaration() *ast.ImportDeclaration {
+	decl := &ast.ImportDeclaration{
+		Specs: make([]*ast.ImportSpec, 0),
+	}
+	if !p.willHave(token.LParen) {
+		spec := p.parseImportSpec()
+		if spec == nil {
+			return nil
+		}
+		decl.Specs = append(decl.Specs, spec)
+		return decl
+	}
+
+	p.moveTokenForward()
+	for !p.willHave(token.RParen) {
+		spec := p.parseImportSpec()
+		if spec == nil {
+			return nil
+		}
+		decl.Specs = append(decl.Specs, spec)
+
+		if p.willHave(token.RParen) {
+			break
+		}
+		if err := p.expectAndMoveTokenForward(token.Semicolon); err != nil {
+			p.keepBadStatement("failed to find semicolon")
+			return nil
+		}
+	}
+	p.moveTokenForward()
+
+	return decl
+}
+
+func (p *Parser) parseImportSpec() *ast.ImportSpec {
+	pos := p.readingPos
+	spec := new(ast.ImportSpec)
+	if p.willHave(token.Identifier) {
+		p.moveTokenForward()
+		spec.Name = p.parseIdentifier().(*ast.Identifier)
+	}
+	if err := p.expectAndMoveTokenForward(token.String); err != nil {
+		p.keepBadStatement("failed to find import path")
+		return nil
+	}
+	spec.Path = p.parseString().(*ast.String)
+	p.mark(spec, pos)
+
+	return spec
+}
+
+func (p *Parser) parseTypeDeclaration() *ast.TypeDeclaration {
+	if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
+		p.keepBadStatement("failed to find identifier of type")
+		return nil
+	}
+	stmt := &ast.TypeDeclaration{
+		Identifier:     p.parseIdentifier().(*ast.Identifier),
+		TypeParameters: make([]*ast.Parameter, 0),
+	}
+
+	if p.willHave(token.LBracket) {
+		p.moveTokenForward()
+		if p.willHave(token.RBracket) {
+			stmt.Type = p.parseSliceType()
+			if stmt.Type == nil {
+				return nil
+			}
+			return stmt
+		}
+		stmt.TypeParameters = p.parseTypeParameters()
+		if stmt.TypeParameters == nil {
+			return nil
+		}
+	}
+
+	if !p.willHaveType() {
+		p.keepBadStatement("failed to find type")
+		return nil
+	}
+	p.moveTokenForward()
+	stmt.Type = p.parseType()
+	if stmt.Type == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseTypeParameters() []*ast.Parameter {
+	if p.willHave(token.RBracket) {
+		p.keepBadStatement("empty type parameter list")
+		return nil
+	}
+
+	params := make([]*ast.Parameter, 0)
+	idents := make([]*ast.Identifier, 0)
+	for {
+		if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
+			p.keepBadStatement("failed to find identifier of type parameter")
+			return nil
+		}
+		idents = append(idents, p.parseIdentifier().(*ast.Identifier))
+		p.mark(idents[len(idents)-1], p.currentPos)
+		if p.willHave(token.Comma) {
+			p.moveTokenForward()
+			continue
+		}
+
+		if !p.willHaveConstraint() {
+			p.keepBadStatement("failed to find constraint of type parameter")
+			return nil
+		}
+		p.moveTokenForward()
+		constraint := p.parseConstraint()
+		if constraint == nil {
+			return nil
+		}
+		for _, ident := range idents {
+			params = append(params, &ast.Parameter{
+				Identifier: ident,
+				Type:       constraint,
+			})
+		}
+		idents = idents[:0]
+
+		if !p.willHave(token.Comma) {
+			break
+		}
+		p.moveTokenForward()
+	}
+
+	if err := p.expectAndMoveTokenForward(token.RBracket); err != nil {
+		p.keepBadStatement("failed to find rbracket")
+		return nil
+	}
+
+	return params
+}
+
+func (p *Parser) parseGoStatement() *ast.GoStatement {
+	p.moveTokenForward()
+	call, ok := p.parseExpression(lowest).(*ast.CallExpression)
+	if !ok {
+		p.keepBadStatement("expression in go must be function call")
+		return nil
+	}
+
+	return &ast.GoStatement{
+		Call: call,
+	}
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+	var init, cond, post ast.Statement
+	switch p.readingToken.Type {
+	case token.LBrace, token.Semicolon:
+	case token.Range:
+		stmt := new(ast.RangeStatement)
+		p.moveTokenForward()
+		p.moveTokenForward()
+		stmt.Expression = p.parseExpression(lowest)
+		return p.parseForBody(stmt)
+	default:
+		p.moveTokenForward()
+		cond = p.parseSimpleStatementOrRangeClause(true)
+		if cond == nil {
+			return nil
+		}
+		if stmt, ok := cond.(*ast.RangeStatement); ok {
+			return p.parseForBody(stmt)
+		}
+	}
+
+	if p.willHave(token.Semicolon) {
+		init, cond = cond, nil
+		p.moveTokenForward()
+		if !p.willHave(token.Semicolon) {
+			p.moveTokenForward()
+			cond = p.parseSimpleStatement()
+		}
+		if err := p.expectAndMoveTokenForward(token.Semicolon); err != nil {
+			p.keepBadStatement("failed to find semicolon")
+			return nil
+		}
+		if !p.willHave(token.LBrace) {
+			p.moveTokenForward()
+			post = p.parseSimpleStatement()
+		}
+	}
+
+	stmt := &ast.ForStatement{
+		Initializer: init,
+		Post:        post,
+	}
+	if cond != nil {
+		exprStmt, ok := cond.(*ast.ExpressionStatement)
+		if !ok {
+			p.keepBadStatement("failed to find expression of for condition")
+			return nil
+		}
+		stmt.Condition = exprStmt.Expression
+	}
+
+	return p.parseForBody(stmt)
+}
+
+func (p *Parser) parseForBody(stmt ast.Statement) ast.Statement {
+	if err := p.expectAndMoveTokenForward(token.LBrace); err != nil {
+		p.keepBadStatement("failed to find lbrace")
+		return nil
+	}
+	body := p.parseBlockStatement()
+	if body == nil {
+		return nil
+	}
+
+	switch stmt := stmt.(type) {
+	case *ast.ForStatement:
+		stmt.Body = body
+	case *ast.RangeStatement:
+		stmt.Body = body
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseSelectStatement() *ast.SelectStatement {
+	if err := p.expectAndMoveTokenForward(token.LBrace); err != nil {
+		p.keepBadStatement("failed to find lbrace")
+		return nil
+	}
+
+	stmt := &ast.SelectStatement{
+		Clauses: make([]*ast.CommunicationClause, 0),
+	}
+	p.moveTokenForward()
+	for !p.has(token.RBrace) {
+		clause := new(ast.CommunicationClause)
+		switch p.currentToken.Type {
+		case token.Case:
+			p.moveTokenForward()
+			clause.Communication = p.parseSimpleStatement()
+			if !isCommunication(clause.Communication) {
+				p.keepBadStatement("select case must be receive, send or assign recv")
+				return nil
+			}
+		case token.Default:
+			clause.IsDefault = true
+		default:
+			p.keepBadStatement("failed to find case or default")
+			return nil
+		}
+
+		if err := p.expectAndMoveTokenForward(token.Colon); err != nil {
+			p.keepBadStatement("failed to find colon")
+			return nil
+		}
+		p.moveTokenForward()
+
+		clause.Statements = p.parseStatementsUntil(token.Case, token.Default, token.RBrace)
+		if p.has(token.EOF) {
+			p.keepBadStatement("failed to find rbrace")
+			return nil
+		}
+
+		stmt.Clauses = append(stmt.Clauses, clause)
+	}
+
+	var hasDefault bool
+	for _, clause := range stmt.Clauses {
+		if !clause.IsDefault {
+			continue
+		}
+		if hasDefault {
+			p.keepBadStatement("multiple defaults in select")
+			return nil
+		}
+		hasDefault = true
+	}
+
+	return stmt
+}
+
+func isCommunication(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.SendStatement:
+		return true
+	case *ast.ExpressionStatement:
+		return isReceive(stmt.Expression)
+	case *ast.ShortVariableDeclaration:
+		return len(stmt.Identifiers) <= 2 && len(stmt.Expressions) == 1 && isReceive(stmt.Expressions[0])
+	case *ast.AssignmentStatement:
+		return len(stmt.LExpressions) <= 2 && len(stmt.RExpressions) == 1 && isReceive(stmt.RExpressions[0])
+	default:
+		return false
+	}
+}
+
+func isReceive(expr ast.Expression) bool {
+	prefix, ok := expr.(*ast.PrefixExpression)
+	return ok && prefix.Operator == ast.Receive
+}
+
+func (p *Parser) parseFunctionDeclaration() *ast.FunctionDeclaration {
+	p.moveTokenForward()
+	stmt := &ast.FunctionDeclaration{
+		Identifier:     p.parseIdentifier().(*ast.Identifier),
+		TypeParameters: make([]*ast.Parameter, 0),
+	}
+	p.mark(stmt.Identifier, p.currentPos)
+
+	if p.willHave(token.LBracket) {
+		p.moveTokenForward()
+		stmt.TypeParameters = p.parseTypeParameters()
+		if stmt.TypeParameters == nil {
+			return nil
+		}
+	}
+
+	var ok bool
+	stmt.Parameters, stmt.Results, stmt.Body, ok = p.parseFunction()
+	if !ok {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseFunction() ([]*ast.Parameter, []*ast.Parameter, *ast.BlockStatement, bool) {
+	if err := p.expectAndMoveTokenForward(token.LParen); err != nil {
+		p.keepBadStatement("failed to find lparen")
+		return nil, nil, nil, false
+	}
+	params := p.parseParameters()
+	if params == nil {
+		return nil, nil, nil, false
+	}
+
+	results := p.parseResults()
+	if results == nil {
+		return nil, nil, nil, false
+	}
+
+	if err := p.expectAndMoveTokenForward(token.LBrace); err != nil {
+		p.keepBadStatement("failed to find lbrace")
+		return nil, nil, nil, false
+	}
+	body := p.parseBlockStatement()
+	if body == nil {
+		return nil, nil, nil, false
+	}
+
+	return params, results, body, true
+}
+
+func (p *Parser) parseParameters() []*ast.Parameter {
+	params := make([]*ast.Parameter, 0)
+	if p.willHave(token.RParen) {
+		p.moveTokenForward()
+		return params
+	}
+
+	idents := make([]*ast.Identifier, 0)
+	for {
+		if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
+			p.keepBadStatement("failed to find identifier of parameter")
+			return nil
+		}
+		idents = append(idents, p.parseIdentifier().(*ast.Identifier))
+		p.mark(idents[len(idents)-1], p.currentPos)
+		if p.willHave(token.Comma) {
+			p.moveTokenForward()
+			continue
+		}
+
+		if !p.willHaveType() {
+			p.keepBadStatement("failed to find type name of parameter")
+			return nil
+		}
+		p.moveTokenForward()
+		typ := p.parseType()
+		if typ == nil {
+			return nil
+		}
+		for _, ident := range idents {
+			params = append(params, &ast.Parameter{
+				Identifier: ident,
+				Type:       typ,
+			})
+		}
+		idents = idents[:0]
+
+		if !p.willHave(token.Comma) {
+			break
+		}
+		p.moveTokenForward()
+	}
+
+	if err := p.expectAndMoveTokenForward(token.RParen); err != nil {
+		p.keepBadStatement("failed to find rparen")
+		return nil
+	}
+
+	return params
+}
+
+func (p *Parser) parseResults() []*ast.Parameter {
+	switch p.readingToken.Type {
+	case token.Identifier, token.LBracket, token.Map, token.Asterisk, token.Chan, token.Arrow, token.Interface:
+		p.moveTokenForward()
+		typ := p.parseType()
+		if typ == nil {
+			return nil
+		}
+		return []*ast.Parameter{
+			{
+				Type: typ,
+			},
+		}
+	case token.LParen:
+		p.moveTokenForward()
+		if !p.willHaveType() {
+			p.keepBadStatement("failed to find type name of result")
+			return nil
+		}
+		p.moveTokenForward()
+		result := &ast.Parameter{
+			Type: p.parseType(),
+		}
+		if result.Type == nil {
+			return nil
+		}
+		if ident, ok := result.Type.(*ast.Identifier); ok && p.willHaveType() {
+			p.moveTokenForward()
+			result.Identifier, result.Type = ident, p.parseType()
+			if result.Type == nil {
+				return nil
+			}
+		}
+		if err := p.expectAndMoveTokenForward(token.RParen); err != nil {
+			p.keepBadStatement("failed to find rparen")
+			return nil
+		}
+
+		return []*ast.Parameter{result}
+	default:
+		return make([]*ast.Parameter, 0)
+	}
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	p.moveTokenForward()
+	pos := p.currentPos
+	stmt := &ast.BlockStatement{
+		Statements: p.parseStatementsUntil(token.RBrace),
+	}
+	if !p.has(token.RBrace) {
+		p.keepBadStatement("failed to find rbrace")
+		return nil
+	}
+	p.mark(stmt, pos)
+
+	return stmt
+}
+
+func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	stmt := new(ast.ReturnStatement)
+	if p.willHave(token.Semicolon) {
+		return stmt
+	}
+
+	p.moveTokenForward()
+	stmt.Expression = p.parseExpression(lowest)
+
+	return stmt
+}
+
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	p.moveTokenForward()
+	call, ok := p.parseExpression(lowest).(*ast.CallExpression)
+	if !ok {
+		p.keepBadStatement("expression in defer must be function call")
+		return nil
+	}
+
+	return &ast.DeferStatement{
+		Call: call,
+	}
+}
+
+func (p *Parser) parseSwitchStatement() ast.Statement {
+	var init, tag ast.Statement
+	if !p.willHave(token.LBrace) {
+		p.moveTokenForward()
+		tag = p.parseSimpleStatement()
+	}
+	if p.willHave(token.Semicolon) {
 		p.moveTokenForward()
+		init, tag = tag, nil
+		if !p.willHave(token.LBrace) {
+			p.moveTokenForward()
+			tag = p.parseSimpleStatement()
+		}
 	}
 
-	return stmts
+	if err := p.expectAndMoveTokenForward(token.LBrace); err != nil {
+		p.keepBadStatement("failed to find lbrace")
+		return nil
+	}
+
+	if ident, expr, ok := p.findTypeSwitchGuard(tag); ok {
+		clauses := p.parseCaseClauses(p.parseTypeList)
+		if clauses == nil {
+			return nil
+		}
+		if err := p.checkTypeSwitchClauses(clauses); err != nil {
+			p.keepBadStatement(err.Error())
+			return nil
+		}
+
+		return &ast.TypeSwitchStatement{
+			Initializer: init,
+			Identifier:  ident,
+			Expression:  expr,
+			Clauses:     clauses,
+		}
+	}
+
+	stmt := &ast.SwitchStatement{
+		Initializer: init,
+	}
+	if tag != nil {
+		exprStmt, ok := tag.(*ast.ExpressionStatement)
+		if !ok {
+			p.keepBadStatement("failed to find expression of switch tag")
+			return nil
+		}
+		stmt.Tag = exprStmt.Expression
+	}
+
+	stmt.Clauses = p.parseCaseClauses(p.parseExpressionList)
+	if stmt.Clauses == nil {
+		return nil
+	}
+	if err := p.checkSwitchClauses(stmt.Clauses); err != nil {
+		p.keepBadStatement(err.Error())
+		return nil
+	}
+
+	return stmt
 }
 
-func (p *Parser) parseStatement() ast.Statement {
-	var stmt ast.Statement
-	switch p.currentToken.Type {
-	case token.Var:
-		stmt = p.parseVariableDeclaration()
+func (p *Parser) findTypeSwitchGuard(stmt ast.Statement) (*ast.Identifier, ast.Expression, bool) {
+	var ident *ast.Identifier
+	var expr ast.Expression
+	switch stmt := stmt.(type) {
+	case *ast.ShortVariableDeclaration:
+		if len(stmt.Identifiers) != 1 || len(stmt.Expressions) != 1 {
+			return nil, nil, false
+		}
+		ident, expr = stmt.Identifiers[0], stmt.Expressions[0]
+	case *ast.ExpressionStatement:
+		expr = stmt.Expression
 	default:
-		stmt = p.parseExpressionStatement()
+		return nil, nil, false
 	}
 
-	if err := p.expectAndMoveTokenForward(token.Semicolon); err != nil {
-		p.keepBadStatement("failed to find semicolon")
+	assertion, ok := expr.(*ast.TypeAssertionExpression)
+	if !ok || assertion.Type != nil {
+		return nil, nil, false
 	}
 
-	if p.hasBadStatements() {
-		return p.takeOutLastBadStatement()
+	return ident, assertion.Expression, true
+}
+
+func (p *Parser) parseCaseClauses(parseList func() []ast.Expression) []*ast.CaseClause {
+	clauses := make([]*ast.CaseClause, 0)
+	p.moveTokenForward()
+	for !p.has(token.RBrace) {
+		clause := new(ast.CaseClause)
+		switch p.currentToken.Type {
+		case token.Case:
+			p.moveTokenForward()
+			clause.Expressions = parseList()
+			if clause.Expressions == nil {
+				return nil
+			}
+		case token.Default:
+			clause.IsDefault = true
+		default:
+			p.keepBadStatement("failed to find case or default")
+			return nil
+		}
+
+		if err := p.expectAndMoveTokenForward(token.Colon); err != nil {
+			p.keepBadStatement("failed to find colon")
+			return nil
+		}
+		p.moveTokenForward()
+
+		clause.Statements = p.parseStatementsUntil(token.Case, token.Default, token.RBrace)
+		if p.has(token.EOF) {
+			p.keepBadStatement("failed to find rbrace")
+			return nil
+		}
+
+		clauses = append(clauses, clause)
 	}
 
-	return stmt
+	return clauses
 }
 
-func (p *Parser) parseVariableDeclaration() *ast.VariableDeclaration {
-	if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
-		p.keepBadStatement("failed to find identifier of variable")
-		return nil
+func (p *Parser) parseStatementsUntil(ts ...token.Type) []ast.Statement {
+	stmts := make([]ast.Statement, 0)
+	for !p.has(token.EOF) && !p.hasAnyOf(ts...) {
+		if stmt := p.parseStatement(); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+		p.moveTokenForward()
 	}
-	stmt := &ast.VariableDeclaration{
-		Identifier: p.parseIdentifier().(*ast.Identifier),
+
+	return stmts
+}
+
+func (p *Parser) parseExpressionList() []ast.Expression {
+	exprs := []ast.Expression{
+		p.parseExpression(lowest),
+	}
+	for p.willHave(token.Comma) {
+		p.moveTokenForward()
+		p.moveTokenForward()
+		exprs = append(exprs, p.parseExpression(lowest))
 	}
 
-	if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
-		p.keepBadStatement("failed to find type name of variable")
+	return exprs
+}
+
+func (p *Parser) parseTypeList() []ast.Expression {
+	if !p.has(token.Identifier) {
+		p.keepBadStatement("failed to find type name")
 		return nil
 	}
-	stmt.Type = p.parseIdentifier().(*ast.Identifier)
+	types := []ast.Expression{
+		p.parseIdentifier(),
+	}
+	for p.willHave(token.Comma) {
+		p.moveTokenForward()
+		if err := p.expectAndMoveTokenForward(token.Identifier); err != nil {
+			p.keepBadStatement("failed to find type name")
+			return nil
+		}
+		types = append(types, p.parseIdentifier())
+	}
 
-	if err := p.expectAndMoveTokenForward(token.Assign); err != nil {
-		return stmt
+	return types
+}
+
+func (p *Parser) checkSwitchClauses(clauses []*ast.CaseClause) error {
+	if err := checkDefaultClauses(clauses); err != nil {
+		return err
 	}
 
-	p.moveTokenForward()
-	stmt.Expression = p.parseExpression(lowest)
+	for i, clause := range clauses {
+		for j, stmt := range clause.Statements {
+			if _, ok := stmt.(*ast.FallthroughStatement); !ok {
+				continue
+			}
+			if j != len(clause.Statements)-1 {
+				return errors.New("fallthrough statement out of place")
+			}
+			if i == len(clauses)-1 {
+				return errors.New("cannot fallthrough final case in switch")
+			}
+		}
+	}
 
-	return stmt
+	return nil
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	return &ast.ExpressionStatement{
-		Expression: p.parseExpression(lowest),
+func (p *Parser) checkTypeSwitchClauses(clauses []*ast.CaseClause) error {
+	if err := checkDefaultClauses(clauses); err != nil {
+		return err
+	}
+
+	for _, clause := range clauses {
+		for _, stmt := range clause.Statements {
+			if _, ok := stmt.(*ast.FallthroughStatement); ok {
+				return errors.New("cannot fallthrough in type switch")
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkDefaultClauses(clauses []*ast.CaseClause) error {
+	var found bool
+	for _, clause := range clauses {
+		if !clause.IsDefault {
+			continue
+		}
+		if found {
+			return errors.New("multiple defaults in switch")
+		}
+		found = true
 	}
+
+	return nil
 }
 
 func (p *Parser) parseExpression(prio priority) ast.Expression {
-	expr := p.prefixParsers[p.currentToken.Type]()
+	parsePrefix, ok := p.prefixParsers[p.currentToken.Type]
+	if !ok {
+		p.keepBadStatement(fmt.Sprintf("failed to find expression: %s", p.currentToken.Literal))
+		return nil
+	}
+
+	pos := p.currentPos
+	expr := parsePrefix()
+	p.mark(expr, pos)
 	for !p.willHave(token.Semicolon) && p.checkReadingTokenPriority().isHigherThan(prio) {
 		p.moveTokenForward()
 		expr = p.infixParsers[p.currentToken.Type](expr)
+		p.mark(expr, pos)
 	}
 
 	return expr
@@ -168,17 +1333,218 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expr
 }
 
+func (p *Parser) parsePrefixExpressionOrChannelType() ast.Expression {
+	if p.willHave(token.Chan) {
+		return p.parseChannelType()
+	}
+
+	return p.parsePrefixExpression()
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expr := &ast.InfixExpression{
 		LExpression: left,
 		Operator:    ast.InfixOperators[p.currentToken.Type],
 	}
+	prio := p.checkCurrentTokenPriority()
+	p.moveTokenForward()
+	expr.RExpression = p.parseExpression(prio)
+
+	return expr
+}
+
+func (p *Parser) parseSelectorOrTypeAssertionExpression(left ast.Expression) ast.Expression {
+	if p.willHave(token.Identifier) {
+		p.moveTokenForward()
+		selector := p.parseIdentifier().(*ast.Identifier)
+		p.mark(selector, p.currentPos)
+		return &ast.SelectorExpression{
+			Expression: left,
+			Selector:   selector,
+		}
+	}
+
+	return p.parseTypeAssertionExpression(left)
+}
+
+func (p *Parser) parseTypeAssertionExpression(left ast.Expression) ast.Expression {
+	if err := p.expectAndMoveTokenForward(token.LParen); err != nil {
+		p.keepBadStatement("failed to find lparen")
+		return nil
+	}
+	expr := &ast.TypeAssertionExpression{
+		Expression: left,
+	}
+
+	switch p.readingToken.Type {
+	case token.TypeKeyword:
+		p.moveTokenForward()
+	case token.Identifier:
+		p.moveTokenForward()
+		expr.Type = p.parseIdentifier().(*ast.Identifier)
+	default:
+		p.keepBadStatement("failed to find type name")
+		return nil
+	}
+
+	if err := p.expectAndMoveTokenForward(token.RParen); err != nil {
+		p.keepBadStatement("failed to find rparen")
+		return nil
+	}
+
+	return expr
+}
+
+func (p *Parser) parseCallExpression(left ast.Expression) ast.Expression {
+	expr := &ast.CallExpression{
+		Function:  left,
+		Arguments: make([]ast.Expression, 0),
+	}
+	if p.willHave(token.RParen) {
+		p.moveTokenForward()
+		return expr
+	}
+
+	p.moveTokenForward()
+	expr.Arguments = p.parseExpressionList()
+	if p.willHave(token.Ellipsis) {
+		p.moveTokenForward()
+		expr.HasEllipsis = true
+	}
+	if err := p.expectAndMoveTokenForward(token.RParen); err != nil {
+		p.keepBadStatement("failed to find rparen")
+		return nil
+	}
+
+	return expr
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	if p.willHave(token.RBracket) {
+		p.keepBadStatement("expected operand")
+		return nil
+	}
+
 	p.moveTokenForward()
-	expr.RExpression = p.parseExpression(p.checkCurrentTokenPriority())
+	if p.has(token.Colon) {
+		return p.parseSliceExpression(left, nil)
+	}
+	indices := p.parseExpressionList()
+	if p.willHave(token.Colon) {
+		if len(indices) != 1 {
+			p.keepBadStatement("unexpected comma in slice expression")
+			return nil
+		}
+		p.moveTokenForward()
+		return p.parseSliceExpression(left, indices[0])
+	}
+
+	expr := &ast.IndexExpression{
+		Expression: left,
+		Indices:    indices,
+	}
+	if err := p.expectAndMoveTokenForward(token.RBracket); err != nil {
+		p.keepBadStatement("failed to find rbracket")
+		return nil
+	}
+
+	return expr
+}
+
+func (p *Parser) parseSliceExpression(left, low ast.Expression) ast.Expression {
+	expr := &ast.SliceExpression{
+		Expression: left,
+		Low:        low,
+	}
+	if !p.willHave(token.RBracket) {
+		p.moveTokenForward()
+		expr.High = p.parseExpression(lowest)
+	}
+	if err := p.expectAndMoveTokenForward(token.RBracket); err != nil {
+		p.keepBadStatement("failed to find rbracket")
+		return nil
+	}
 
 	return expr
 }
 
+func (p *Parser) parseSliceTypeOrCompositeLiteral() ast.Expression {
+	typ := p.parseSliceType()
+	if typ == nil || !p.willHave(token.LBrace) {
+		return typ
+	}
+	p.moveTokenForward()
+
+	return p.parseCompositeLiteral(typ)
+}
+
+func (p *Parser) parseMapTypeOrCompositeLiteral() ast.Expression {
+	typ := p.parseMapType()
+	if typ == nil || !p.willHave(token.LBrace) {
+		return typ
+	}
+	p.moveTokenForward()
+
+	return p.parseCompositeLiteral(typ)
+}
+
+func (p *Parser) parseCompositeLiteral(typ ast.Expression) ast.Expression {
+	lit := &ast.CompositeLiteral{
+		Type:     typ,
+		Elements: make([]*ast.KeyedElement, 0),
+	}
+	for !p.willHave(token.RBrace) {
+		p.moveTokenForward()
+		elem := &ast.KeyedElement{
+			Value: p.parseElement(),
+		}
+		if elem.Value == nil {
+			return nil
+		}
+		if p.willHave(token.Colon) {
+			p.moveTokenForward()
+			p.moveTokenForward()
+			elem.Key, elem.Value = elem.Value, p.parseElement()
+			if elem.Value == nil {
+				return nil
+			}
+		}
+		lit.Elements = append(lit.Elements, elem)
+
+		if !p.willHave(token.Comma) {
+			break
+		}
+		p.moveTokenForward()
+	}
+	if err := p.expectAndMoveTokenForward(token.RBrace); err != nil {
+		p.keepBadStatement("failed to find rbrace")
+		return nil
+	}
+
+	return lit
+}
+
+func (p *Parser) parseElement() ast.Expression {
+	if p.has(token.LBrace) {
+		return p.parseCompositeLiteral(nil)
+	}
+
+	return p.parseExpression(lowest)
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	params, results, body, ok := p.parseFunction()
+	if !ok {
+		return nil
+	}
+
+	return &ast.FunctionLiteral{
+		Parameters: params,
+		Results:    results,
+		Body:       body,
+	}
+}
+
 func (p *Parser) parseGroupExpression() ast.Expression {
 	p.moveTokenForward()
 	expr := p.parseExpression(lowest)
@@ -207,6 +1573,12 @@ func (p *Parser) parseInteger() ast.Expression {
 	}
 }
 
+func (p *Parser) parseString() ast.Expression {
+	return &ast.String{
+		Value: p.currentToken.Literal,
+	}
+}
+
 func (p *Parser) hasBadStatements() bool {
 	return 0 < len(p.badStatements)
 }
@@ -215,19 +1587,43 @@ func (p *Parser) takeOutLastBadStatement() *ast.BadStatement {
 	i := len(p.badStatements) - 1
 	stmt := p.badStatements[i]
 	p.badStatements = p.badStatements[:i]
+	p.reported = append(p.reported, stmt)
 	return stmt
 }
 
 func (p *Parser) keepBadStatement(msg string) {
-	p.badStatements = append(p.badStatements, &ast.BadStatement{
+	stmt := &ast.BadStatement{
 		Message: msg,
-	})
+	}
+	p.mark(stmt, p.currentPos)
+	p.badStatements = append(p.badStatements, stmt)
 }
 
-func (p *Parser) reportBadStatement(msg string) *ast.BadStatement {
-	return &ast.BadStatement{
+func (p *Parser) reportBadStatement(pos token.Position, msg string) *ast.BadStatement {
+	stmt := &ast.BadStatement{
 		Message: msg,
 	}
+	p.mark(stmt, pos)
+	p.reported = append(p.reported, stmt)
+	return stmt
+}
+
+func (p *Parser) mark(node ast.Node, pos token.Position) {
+	if node == nil {
+		return
+	}
+	if _, ok := p.positions[node]; ok {
+		return
+	}
+
+	p.positions[node] = pos
+	p.ends[node] = p.currentEnd
+}
+
+func (p *Parser) skipSemicolon() {
+	if p.willHave(token.Semicolon) {
+		p.moveTokenForward()
+	}
 }
 
 func (p *Parser) moveFirstTwoTokenForward() {
@@ -245,8 +1641,9 @@ func (p *Parser) expectAndMoveTokenForward(t token.Type) error {
 }
 
 func (p *Parser) moveTokenForward() {
-	p.currentToken = p.readingToken
+	p.currentToken, p.currentPos, p.currentEnd = p.readingToken, p.readingPos, p.readingEnd
 	p.readingToken = p.lexer.ReadNextToken()
+	p.readingPos, p.readingEnd = p.lexer.Position(), p.lexer.EndPosition()
 }
 
 func (p Parser) checkCurrentTokenPriority() priority {
@@ -269,6 +1666,29 @@ func (p Parser) has(t token.Type) bool {
 	return p.currentToken.Type == t
 }
 
+func (p Parser) willHaveType() bool {
+	switch p.readingToken.Type {
+	case token.Identifier, token.LBracket, token.Map, token.Asterisk, token.Chan, token.Arrow, token.Interface:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p Parser) willHaveConstraint() bool {
+	return p.willHaveType() || p.willHave(token.Tilde)
+}
+
+func (p Parser) hasAnyOf(ts ...token.Type) bool {
+	for _, t := range ts {
+		if p.has(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p Parser) willHave(t token.Type) bool {
 	return p.readingToken.Type == t
 }
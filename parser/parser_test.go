@@ -9,6 +9,8 @@ import (
 
 func TestParseProgram(t *testing.T) {
 	input := `
+	package main;
+	import ("fmt"; s "strings");
 	5; -6;
 	7 + 8 - 9 * 10 / 11;
 	(12 + 13) / 14;
@@ -20,8 +22,54 @@ func TestParseProgram(t *testing.T) {
 	0; 0
 	var;
 	var x;
+	1 <= 2 == true;
+	switch x := 1; x { case 1, 2: x = 3; fallthrough; default: break; }
+	switch v := x.(type) { case int, bool: v; }
+	switch { case 1: fallthrough; }
+	func add(x, y int) (z int) { defer f(x); return x + y; }
+	add(1, "a");
+	func() {}();
+	x, y = y, x;
+	x++;
+	c <- <-d;
+	go f(x);
+	for i := 0; i < 3; i++ { continue; }
+	for v := range c {}
+	select { case v := <-c: v; default: }
+	var c chan<- int;
+	type Number interface { ~int | float64 }
+	func Max[K comparable, T Number](a T) T { return a; }
+	Max[string, int](1);
+	m := map[string][]int{"a": {1}};
+	*p = x[1:];
+	append(x, y...);
+	fmt.Println(s.ToUpper);
+	import "os";
+	package main;
 	`
 	expecteds := []ast.Statement{
+		&ast.PackageClause{
+			Name: &ast.Identifier{
+				Name: "main",
+			},
+		},
+		&ast.ImportDeclaration{
+			Specs: []*ast.ImportSpec{
+				{
+					Path: &ast.String{
+						Value: "fmt",
+					},
+				},
+				{
+					Name: &ast.Identifier{
+						Name: "s",
+					},
+					Path: &ast.String{
+						Value: "strings",
+					},
+				},
+			},
+		},
 		&ast.ExpressionStatement{
 			Expression: &ast.Integer{
 				Value: 5,
@@ -37,30 +85,30 @@ func TestParseProgram(t *testing.T) {
 		},
 		&ast.ExpressionStatement{
 			Expression: &ast.InfixExpression{
-				LExpression: &ast.Integer{
-					Value: 7,
-				},
-				Operator: ast.Plus,
-				RExpression: &ast.InfixExpression{
+				LExpression: &ast.InfixExpression{
 					LExpression: &ast.Integer{
+						Value: 7,
+					},
+					Operator: ast.Plus,
+					RExpression: &ast.Integer{
 						Value: 8,
 					},
-					Operator: ast.Minus,
-					RExpression: &ast.InfixExpression{
+				},
+				Operator: ast.Minus,
+				RExpression: &ast.InfixExpression{
+					LExpression: &ast.InfixExpression{
 						LExpression: &ast.Integer{
 							Value: 9,
 						},
 						Operator: ast.Asterisk,
-						RExpression: &ast.InfixExpression{
-							LExpression: &ast.Integer{
-								Value: 10,
-							},
-							Operator: ast.Slash,
-							RExpression: &ast.Integer{
-								Value: 11,
-							},
+						RExpression: &ast.Integer{
+							Value: 10,
 						},
 					},
+					Operator: ast.Slash,
+					RExpression: &ast.Integer{
+						Value: 11,
+					},
 				},
 			},
 		},
@@ -140,20 +188,666 @@ func TestParseProgram(t *testing.T) {
 		&ast.BadStatement{
 			Message: "failed to find type name of variable",
 		},
+		&ast.ExpressionStatement{
+			Expression: &ast.InfixExpression{
+				LExpression: &ast.InfixExpression{
+					LExpression: &ast.Integer{
+						Value: 1,
+					},
+					Operator: ast.LessThanOrEqual,
+					RExpression: &ast.Integer{
+						Value: 2,
+					},
+				},
+				Operator: ast.Equal,
+				RExpression: &ast.Identifier{
+					Name: "true",
+				},
+			},
+		},
+		&ast.SwitchStatement{
+			Initializer: &ast.ShortVariableDeclaration{
+				Identifiers: []*ast.Identifier{
+					{
+						Name: "x",
+					},
+				},
+				Expressions: []ast.Expression{
+					&ast.Integer{
+						Value: 1,
+					},
+				},
+			},
+			Tag: &ast.Identifier{
+				Name: "x",
+			},
+			Clauses: []*ast.CaseClause{
+				{
+					Expressions: []ast.Expression{
+						&ast.Integer{
+							Value: 1,
+						},
+						&ast.Integer{
+							Value: 2,
+						},
+					},
+					Statements: []ast.Statement{
+						&ast.AssignmentStatement{
+							LExpressions: []ast.Expression{
+								&ast.Identifier{
+									Name: "x",
+								},
+							},
+							RExpressions: []ast.Expression{
+								&ast.Integer{
+									Value: 3,
+								},
+							},
+						},
+						&ast.FallthroughStatement{},
+					},
+				},
+				{
+					IsDefault: true,
+					Statements: []ast.Statement{
+						&ast.BreakStatement{},
+					},
+				},
+			},
+		},
+		&ast.TypeSwitchStatement{
+			Identifier: &ast.Identifier{
+				Name: "v",
+			},
+			Expression: &ast.Identifier{
+				Name: "x",
+			},
+			Clauses: []*ast.CaseClause{
+				{
+					Expressions: []ast.Expression{
+						&ast.Identifier{
+							Name: "int",
+						},
+						&ast.Identifier{
+							Name: "bool",
+						},
+					},
+					Statements: []ast.Statement{
+						&ast.ExpressionStatement{
+							Expression: &ast.Identifier{
+								Name: "v",
+							},
+						},
+					},
+				},
+			},
+		},
+		&ast.BadStatement{
+			Message: "cannot fallthrough final case in switch",
+		},
+		&ast.FunctionDeclaration{
+			Identifier: &ast.Identifier{
+				Name: "add",
+			},
+			Parameters: []*ast.Parameter{
+				{
+					Identifier: &ast.Identifier{
+						Name: "x",
+					},
+					Type: &ast.Identifier{
+						Name: "int",
+					},
+				},
+				{
+					Identifier: &ast.Identifier{
+						Name: "y",
+					},
+					Type: &ast.Identifier{
+						Name: "int",
+					},
+				},
+			},
+			Results: []*ast.Parameter{
+				{
+					Identifier: &ast.Identifier{
+						Name: "z",
+					},
+					Type: &ast.Identifier{
+						Name: "int",
+					},
+				},
+			},
+			Body: &ast.BlockStatement{
+				Statements: []ast.Statement{
+					&ast.DeferStatement{
+						Call: &ast.CallExpression{
+							Function: &ast.Identifier{
+								Name: "f",
+							},
+							Arguments: []ast.Expression{
+								&ast.Identifier{
+									Name: "x",
+								},
+							},
+						},
+					},
+					&ast.ReturnStatement{
+						Expression: &ast.InfixExpression{
+							LExpression: &ast.Identifier{
+								Name: "x",
+							},
+							Operator: ast.Plus,
+							RExpression: &ast.Identifier{
+								Name: "y",
+							},
+						},
+					},
+				},
+			},
+		},
+		&ast.ExpressionStatement{
+			Expression: &ast.CallExpression{
+				Function: &ast.Identifier{
+					Name: "add",
+				},
+				Arguments: []ast.Expression{
+					&ast.Integer{
+						Value: 1,
+					},
+					&ast.String{
+						Value: "a",
+					},
+				},
+			},
+		},
+		&ast.ExpressionStatement{
+			Expression: &ast.CallExpression{
+				Function: &ast.FunctionLiteral{
+					Parameters: []*ast.Parameter{},
+					Results:    []*ast.Parameter{},
+					Body: &ast.BlockStatement{
+						Statements: []ast.Statement{},
+					},
+				},
+				Arguments: []ast.Expression{},
+			},
+		},
+		&ast.AssignmentStatement{
+			LExpressions: []ast.Expression{
+				&ast.Identifier{
+					Name: "x",
+				},
+				&ast.Identifier{
+					Name: "y",
+				},
+			},
+			RExpressions: []ast.Expression{
+				&ast.Identifier{
+					Name: "y",
+				},
+				&ast.Identifier{
+					Name: "x",
+				},
+			},
+		},
+		&ast.IncDecStatement{
+			Expression: &ast.Identifier{
+				Name: "x",
+			},
+			Operator: ast.Increment,
+		},
+		&ast.SendStatement{
+			Channel: &ast.Identifier{
+				Name: "c",
+			},
+			Expression: &ast.PrefixExpression{
+				Operator: ast.Receive,
+				RExpression: &ast.Identifier{
+					Name: "d",
+				},
+			},
+		},
+		&ast.GoStatement{
+			Call: &ast.CallExpression{
+				Function: &ast.Identifier{
+					Name: "f",
+				},
+				Arguments: []ast.Expression{
+					&ast.Identifier{
+						Name: "x",
+					},
+				},
+			},
+		},
+		&ast.ForStatement{
+			Initializer: &ast.ShortVariableDeclaration{
+				Identifiers: []*ast.Identifier{
+					{
+						Name: "i",
+					},
+				},
+				Expressions: []ast.Expression{
+					&ast.Integer{
+						Value: 0,
+					},
+				},
+			},
+			Condition: &ast.InfixExpression{
+				LExpression: &ast.Identifier{
+					Name: "i",
+				},
+				Operator: ast.LessThan,
+				RExpression: &ast.Integer{
+					Value: 3,
+				},
+			},
+			Post: &ast.IncDecStatement{
+				Expression: &ast.Identifier{
+					Name: "i",
+				},
+				Operator: ast.Increment,
+			},
+			Body: &ast.BlockStatement{
+				Statements: []ast.Statement{
+					&ast.ContinueStatement{},
+				},
+			},
+		},
+		&ast.RangeStatement{
+			Key: &ast.Identifier{
+				Name: "v",
+			},
+			IsDefine: true,
+			Expression: &ast.Identifier{
+				Name: "c",
+			},
+			Body: &ast.BlockStatement{
+				Statements: []ast.Statement{},
+			},
+		},
+		&ast.SelectStatement{
+			Clauses: []*ast.CommunicationClause{
+				{
+					Communication: &ast.ShortVariableDeclaration{
+						Identifiers: []*ast.Identifier{
+							{
+								Name: "v",
+							},
+						},
+						Expressions: []ast.Expression{
+							&ast.PrefixExpression{
+								Operator: ast.Receive,
+								RExpression: &ast.Identifier{
+									Name: "c",
+								},
+							},
+						},
+					},
+					Statements: []ast.Statement{
+						&ast.ExpressionStatement{
+							Expression: &ast.Identifier{
+								Name: "v",
+							},
+						},
+					},
+				},
+				{
+					IsDefault:  true,
+					Statements: []ast.Statement{},
+				},
+			},
+		},
+		&ast.VariableDeclaration{
+			Identifier: &ast.Identifier{
+				Name: "c",
+			},
+			Type: &ast.ChannelType{
+				Direction: ast.SendOnly,
+				Element: &ast.Identifier{
+					Name: "int",
+				},
+			},
+		},
+		&ast.TypeDeclaration{
+			Identifier: &ast.Identifier{
+				Name: "Number",
+			},
+			Type: &ast.InterfaceType{
+				Elements: []*ast.UnionType{
+					{
+						Terms: []*ast.TypeTerm{
+							{
+								IsTilde: true,
+								Type: &ast.Identifier{
+									Name: "int",
+								},
+							},
+							{
+								Type: &ast.Identifier{
+									Name: "float64",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		&ast.FunctionDeclaration{
+			Identifier: &ast.Identifier{
+				Name: "Max",
+			},
+			TypeParameters: []*ast.Parameter{
+				{
+					Identifier: &ast.Identifier{
+						Name: "K",
+					},
+					Type: &ast.Identifier{
+						Name: "comparable",
+					},
+				},
+				{
+					Identifier: &ast.Identifier{
+						Name: "T",
+					},
+					Type: &ast.Identifier{
+						Name: "Number",
+					},
+				},
+			},
+			Parameters: []*ast.Parameter{
+				{
+					Identifier: &ast.Identifier{
+						Name: "a",
+					},
+					Type: &ast.Identifier{
+						Name: "T",
+					},
+				},
+			},
+			Results: []*ast.Parameter{
+				{
+					Type: &ast.Identifier{
+						Name: "T",
+					},
+				},
+			},
+			Body: &ast.BlockStatement{
+				Statements: []ast.Statement{
+					&ast.ReturnStatement{
+						Expression: &ast.Identifier{
+							Name: "a",
+						},
+					},
+				},
+			},
+		},
+		&ast.ExpressionStatement{
+			Expression: &ast.CallExpression{
+				Function: &ast.IndexExpression{
+					Expression: &ast.Identifier{
+						Name: "Max",
+					},
+					Indices: []ast.Expression{
+						&ast.Identifier{
+							Name: "string",
+						},
+						&ast.Identifier{
+							Name: "int",
+						},
+					},
+				},
+				Arguments: []ast.Expression{
+					&ast.Integer{
+						Value: 1,
+					},
+				},
+			},
+		},
+		&ast.ShortVariableDeclaration{
+			Identifiers: []*ast.Identifier{
+				{
+					Name: "m",
+				},
+			},
+			Expressions: []ast.Expression{
+				&ast.CompositeLiteral{
+					Type: &ast.MapType{
+						Key: &ast.Identifier{
+							Name: "string",
+						},
+						Value: &ast.SliceType{
+							Element: &ast.Identifier{
+								Name: "int",
+							},
+						},
+					},
+					Elements: []*ast.KeyedElement{
+						{
+							Key: &ast.String{
+								Value: "a",
+							},
+							Value: &ast.CompositeLiteral{
+								Elements: []*ast.KeyedElement{
+									{
+										Value: &ast.Integer{
+											Value: 1,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		&ast.AssignmentStatement{
+			LExpressions: []ast.Expression{
+				&ast.PrefixExpression{
+					Operator: ast.Dereference,
+					RExpression: &ast.Identifier{
+						Name: "p",
+					},
+				},
+			},
+			RExpressions: []ast.Expression{
+				&ast.SliceExpression{
+					Expression: &ast.Identifier{
+						Name: "x",
+					},
+					Low: &ast.Integer{
+						Value: 1,
+					},
+				},
+			},
+		},
+		&ast.ExpressionStatement{
+			Expression: &ast.CallExpression{
+				Function: &ast.Identifier{
+					Name: "append",
+				},
+				Arguments: []ast.Expression{
+					&ast.Identifier{
+						Name: "x",
+					},
+					&ast.Identifier{
+						Name: "y",
+					},
+				},
+				HasEllipsis: true,
+			},
+		},
+		&ast.ExpressionStatement{
+			Expression: &ast.CallExpression{
+				Function: &ast.SelectorExpression{
+					Expression: &ast.Identifier{
+						Name: "fmt",
+					},
+					Selector: &ast.Identifier{
+						Name: "Println",
+					},
+				},
+				Arguments: []ast.Expression{
+					&ast.SelectorExpression{
+						Expression: &ast.Identifier{
+							Name: "s",
+						},
+						Selector: &ast.Identifier{
+							Name: "ToUpper",
+						},
+					},
+				},
+			},
+		},
+		&ast.BadStatement{
+			Message: "syntax error: imports must appear before other declarations",
+		},
+		&ast.BadStatement{
+			Message: "syntax error: package statement must be first",
+		},
 	}
 	parser := New(lexer.New(input))
 	program := parser.ParseProgram()
+	if len(program.Statements) != len(expecteds) {
+		t.Fatalf("unexpected number of statements: got %d, but expected %d\n", len(program.Statements), len(expecteds))
+	}
 	for i := 0; i < len(expecteds); i++ {
 		testParseStatement(t, program.Statements[i], expecteds[i])
 	}
 }
 
+func TestParseInfixExpressionAssociativity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ast.Expression
+	}{
+		{
+			"1 - 2 - 3;",
+			&ast.InfixExpression{
+				LExpression: &ast.InfixExpression{
+					LExpression: &ast.Integer{
+						Value: 1,
+					},
+					Operator: ast.Minus,
+					RExpression: &ast.Integer{
+						Value: 2,
+					},
+				},
+				Operator: ast.Minus,
+				RExpression: &ast.Integer{
+					Value: 3,
+				},
+			},
+		},
+		{
+			"8 / 4 * 2;",
+			&ast.InfixExpression{
+				LExpression: &ast.InfixExpression{
+					LExpression: &ast.Integer{
+						Value: 8,
+					},
+					Operator: ast.Slash,
+					RExpression: &ast.Integer{
+						Value: 4,
+					},
+				},
+				Operator: ast.Asterisk,
+				RExpression: &ast.Integer{
+					Value: 2,
+				},
+			},
+		},
+		{
+			"1 + 2 * 3 - 4;",
+			&ast.InfixExpression{
+				LExpression: &ast.InfixExpression{
+					LExpression: &ast.Integer{
+						Value: 1,
+					},
+					Operator: ast.Plus,
+					RExpression: &ast.InfixExpression{
+						LExpression: &ast.Integer{
+							Value: 2,
+						},
+						Operator: ast.Asterisk,
+						RExpression: &ast.Integer{
+							Value: 3,
+						},
+					},
+				},
+				Operator: ast.Minus,
+				RExpression: &ast.Integer{
+					Value: 4,
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		program := New(lexer.New(test.input)).ParseProgram()
+		if len(program.Statements) != 1 {
+			t.Fatalf("unexpected number of statements: got %d, but expected 1\n", len(program.Statements))
+		}
+		testParseStatement(t, program.Statements[0], &ast.ExpressionStatement{
+			Expression: test.expected,
+		})
+	}
+}
+
 func testParseStatement(t *testing.T, actual, expected ast.Statement) {
 	switch actual := actual.(type) {
 	case *ast.ExpressionStatement:
 		testParseExpressionStatement(t, actual, expected.(*ast.ExpressionStatement))
 	case *ast.VariableDeclaration:
 		testParseVariableDeclaration(t, actual, expected.(*ast.VariableDeclaration))
+	case *ast.ShortVariableDeclaration:
+		testParseShortVariableDeclaration(t, actual, expected.(*ast.ShortVariableDeclaration))
+	case *ast.AssignmentStatement:
+		testParseAssignmentStatement(t, actual, expected.(*ast.AssignmentStatement))
+	case *ast.SwitchStatement:
+		testParseSwitchStatement(t, actual, expected.(*ast.SwitchStatement))
+	case *ast.TypeSwitchStatement:
+		testParseTypeSwitchStatement(t, actual, expected.(*ast.TypeSwitchStatement))
+	case *ast.FallthroughStatement:
+		if _, ok := expected.(*ast.FallthroughStatement); !ok {
+			t.Errorf("unexpected statement: got %T, but expected %T\n", actual, expected)
+		}
+	case *ast.BreakStatement:
+		if _, ok := expected.(*ast.BreakStatement); !ok {
+			t.Errorf("unexpected statement: got %T, but expected %T\n", actual, expected)
+		}
+	case *ast.PackageClause:
+		testParseIdentifier(t, actual.Name, expected.(*ast.PackageClause).Name)
+	case *ast.ImportDeclaration:
+		testParseImportDeclaration(t, actual, expected.(*ast.ImportDeclaration))
+	case *ast.TypeDeclaration:
+		testParseIdentifier(t, actual.Identifier, expected.(*ast.TypeDeclaration).Identifier)
+		testParseParameters(t, actual.TypeParameters, expected.(*ast.TypeDeclaration).TypeParameters)
+		testParseExpression(t, actual.Type, expected.(*ast.TypeDeclaration).Type)
+	case *ast.FunctionDeclaration:
+		testParseFunctionDeclaration(t, actual, expected.(*ast.FunctionDeclaration))
+	case *ast.ReturnStatement:
+		testParseReturnStatement(t, actual, expected.(*ast.ReturnStatement))
+	case *ast.DeferStatement:
+		testParseCallExpression(t, actual.Call, expected.(*ast.DeferStatement).Call)
+	case *ast.IncDecStatement:
+		testParseExpression(t, actual.Expression, expected.(*ast.IncDecStatement).Expression)
+		if actual.Operator != expected.(*ast.IncDecStatement).Operator {
+			t.Errorf("unexpected operator: got %s, but expected %s\n", actual.Operator, expected.(*ast.IncDecStatement).Operator)
+		}
+	case *ast.SendStatement:
+		testParseExpression(t, actual.Channel, expected.(*ast.SendStatement).Channel)
+		testParseExpression(t, actual.Expression, expected.(*ast.SendStatement).Expression)
+	case *ast.GoStatement:
+		testParseCallExpression(t, actual.Call, expected.(*ast.GoStatement).Call)
+	case *ast.ForStatement:
+		testParseForStatement(t, actual, expected.(*ast.ForStatement))
+	case *ast.RangeStatement:
+		testParseRangeStatement(t, actual, expected.(*ast.RangeStatement))
+	case *ast.SelectStatement:
+		testParseCommunicationClauses(t, actual.Clauses, expected.(*ast.SelectStatement).Clauses)
+	case *ast.ContinueStatement:
+		if _, ok := expected.(*ast.ContinueStatement); !ok {
+			t.Errorf("unexpected statement: got %T, but expected %T\n", actual, expected)
+		}
 	case *ast.BadStatement:
 		testParseBadStatement(t, actual, expected.(*ast.BadStatement))
 	default:
@@ -161,6 +855,21 @@ func testParseStatement(t *testing.T, actual, expected ast.Statement) {
 	}
 }
 
+func testParseImportDeclaration(t *testing.T, actual, expected *ast.ImportDeclaration) {
+	if len(actual.Specs) != len(expected.Specs) {
+		t.Fatalf("unexpected number of specs: got %d, but expected %d\n", len(actual.Specs), len(expected.Specs))
+	}
+	for i, spec := range expected.Specs {
+		if (actual.Specs[i].Name == nil) != (spec.Name == nil) {
+			t.Fatalf("unexpected name: got %v, but expected %v\n", actual.Specs[i].Name, spec.Name)
+		}
+		if spec.Name != nil {
+			testParseIdentifier(t, actual.Specs[i].Name, spec.Name)
+		}
+		testParseExpression(t, actual.Specs[i].Path, spec.Path)
+	}
+}
+
 func testParseExpressionStatement(t *testing.T, actual, expected *ast.ExpressionStatement) {
 	testParseExpression(t, actual.Expression, expected.Expression)
 }
@@ -171,10 +880,44 @@ func testParseExpression(t *testing.T, actual, expected ast.Expression) {
 		testParsePrefixExpression(t, actual, expected.(*ast.PrefixExpression))
 	case *ast.InfixExpression:
 		testParseInfixExpression(t, actual, expected.(*ast.InfixExpression))
+	case *ast.TypeAssertionExpression:
+		testParseTypeAssertionExpression(t, actual, expected.(*ast.TypeAssertionExpression))
+	case *ast.SelectorExpression:
+		testParseExpression(t, actual.Expression, expected.(*ast.SelectorExpression).Expression)
+		testParseIdentifier(t, actual.Selector, expected.(*ast.SelectorExpression).Selector)
+	case *ast.CallExpression:
+		testParseCallExpression(t, actual, expected.(*ast.CallExpression))
+	case *ast.FunctionLiteral:
+		testParseFunctionLiteral(t, actual, expected.(*ast.FunctionLiteral))
 	case *ast.Identifier:
 		testParseIdentifier(t, actual, expected.(*ast.Identifier))
 	case *ast.Integer:
 		testParseInteger(t, actual, expected.(*ast.Integer))
+	case *ast.ChannelType:
+		if actual.Direction != expected.(*ast.ChannelType).Direction {
+			t.Errorf("unexpected direction: got %s, but expected %s\n", actual.Direction, expected.(*ast.ChannelType).Direction)
+		}
+		testParseExpression(t, actual.Element, expected.(*ast.ChannelType).Element)
+	case *ast.IndexExpression:
+		testParseExpression(t, actual.Expression, expected.(*ast.IndexExpression).Expression)
+		testParseExpressions(t, actual.Indices, expected.(*ast.IndexExpression).Indices)
+	case *ast.InterfaceType:
+		testParseInterfaceType(t, actual, expected.(*ast.InterfaceType))
+	case *ast.SliceExpression:
+		testParseSliceExpression(t, actual, expected.(*ast.SliceExpression))
+	case *ast.CompositeLiteral:
+		testParseCompositeLiteral(t, actual, expected.(*ast.CompositeLiteral))
+	case *ast.SliceType:
+		testParseExpression(t, actual.Element, expected.(*ast.SliceType).Element)
+	case *ast.MapType:
+		testParseExpression(t, actual.Key, expected.(*ast.MapType).Key)
+		testParseExpression(t, actual.Value, expected.(*ast.MapType).Value)
+	case *ast.PointerType:
+		testParseExpression(t, actual.Element, expected.(*ast.PointerType).Element)
+	case *ast.String:
+		if actual.Value != expected.(*ast.String).Value {
+			t.Errorf("unexpected value: got %s, but expected %s\n", actual.Value, expected.(*ast.String).Value)
+		}
 	default:
 		t.Fatalf("failed to assert type of expression: %T, did you forget to add the type in switch?\n", actual)
 	}
@@ -195,6 +938,16 @@ func testParseInfixExpression(t *testing.T, actual, expected *ast.InfixExpressio
 	testParseExpression(t, actual.RExpression, expected.RExpression)
 }
 
+func testParseTypeAssertionExpression(t *testing.T, actual, expected *ast.TypeAssertionExpression) {
+	testParseExpression(t, actual.Expression, expected.Expression)
+	if (actual.Type == nil) != (expected.Type == nil) {
+		t.Fatalf("unexpected type: got %v, but expected %v\n", actual.Type, expected.Type)
+	}
+	if actual.Type != nil {
+		testParseIdentifier(t, actual.Type, expected.Type)
+	}
+}
+
 func testParseIdentifier(t *testing.T, actual, expected *ast.Identifier) {
 	if actual.Name != expected.Name {
 		t.Errorf("unexpected name: got %s, but expected %s\n", actual.Name, expected.Name)
@@ -209,11 +962,9 @@ func testParseInteger(t *testing.T, actual, expected *ast.Integer) {
 
 func testParseVariableDeclaration(t *testing.T, actual, expected *ast.VariableDeclaration) {
 	if actual.Identifier.Name != expected.Identifier.Name {
-		t.Errorf("unexpected identifier name: got %s, but expected %s\n", actual.Identifier, expected.Identifier)
-	}
-	if actual.Type.Name != expected.Type.Name {
-		t.Errorf("unexpected type name: got %s, but expected %s\n", actual.Type, expected.Type)
+		t.Errorf("unexpected identifier name: got %s, but expected %s\n", actual.Identifier.Name, expected.Identifier.Name)
 	}
+	testParseExpression(t, actual.Type, expected.Type)
 }
 
 func testParseBadStatement(t *testing.T, actual, expected *ast.BadStatement) {
@@ -221,3 +972,231 @@ func testParseBadStatement(t *testing.T, actual, expected *ast.BadStatement) {
 		t.Errorf("unexpected message: got %s, but expected %s\n", actual.Message, expected.Message)
 	}
 }
+
+func testParseShortVariableDeclaration(t *testing.T, actual, expected *ast.ShortVariableDeclaration) {
+	if len(actual.Identifiers) != len(expected.Identifiers) {
+		t.Fatalf("unexpected number of identifiers: got %d, but expected %d\n", len(actual.Identifiers), len(expected.Identifiers))
+	}
+	for i := range expected.Identifiers {
+		testParseIdentifier(t, actual.Identifiers[i], expected.Identifiers[i])
+	}
+	testParseExpressions(t, actual.Expressions, expected.Expressions)
+}
+
+func testParseAssignmentStatement(t *testing.T, actual, expected *ast.AssignmentStatement) {
+	testParseExpressions(t, actual.LExpressions, expected.LExpressions)
+	testParseExpressions(t, actual.RExpressions, expected.RExpressions)
+}
+
+func testParseExpressions(t *testing.T, actual, expected []ast.Expression) {
+	if len(actual) != len(expected) {
+		t.Fatalf("unexpected number of expressions: got %d, but expected %d\n", len(actual), len(expected))
+	}
+	for i := range expected {
+		testParseExpression(t, actual[i], expected[i])
+	}
+}
+
+func testParseSwitchStatement(t *testing.T, actual, expected *ast.SwitchStatement) {
+	testParseOptionalStatement(t, actual.Initializer, expected.Initializer)
+	if (actual.Tag == nil) != (expected.Tag == nil) {
+		t.Fatalf("unexpected tag: got %v, but expected %v\n", actual.Tag, expected.Tag)
+	}
+	if actual.Tag != nil {
+		testParseExpression(t, actual.Tag, expected.Tag)
+	}
+	testParseCaseClauses(t, actual.Clauses, expected.Clauses)
+}
+
+func testParseTypeSwitchStatement(t *testing.T, actual, expected *ast.TypeSwitchStatement) {
+	testParseOptionalStatement(t, actual.Initializer, expected.Initializer)
+	if (actual.Identifier == nil) != (expected.Identifier == nil) {
+		t.Fatalf("unexpected identifier: got %v, but expected %v\n", actual.Identifier, expected.Identifier)
+	}
+	if actual.Identifier != nil {
+		testParseIdentifier(t, actual.Identifier, expected.Identifier)
+	}
+	testParseExpression(t, actual.Expression, expected.Expression)
+	testParseCaseClauses(t, actual.Clauses, expected.Clauses)
+}
+
+func testParseOptionalStatement(t *testing.T, actual, expected ast.Statement) {
+	if (actual == nil) != (expected == nil) {
+		t.Fatalf("unexpected statement: got %v, but expected %v\n", actual, expected)
+	}
+	if actual != nil {
+		testParseStatement(t, actual, expected)
+	}
+}
+
+func testParseCaseClauses(t *testing.T, actual, expected []*ast.CaseClause) {
+	if len(actual) != len(expected) {
+		t.Fatalf("unexpected number of clauses: got %d, but expected %d\n", len(actual), len(expected))
+	}
+	for i := range expected {
+		if actual[i].IsDefault != expected[i].IsDefault {
+			t.Errorf("unexpected default: got %t, but expected %t\n", actual[i].IsDefault, expected[i].IsDefault)
+		}
+		if len(actual[i].Expressions) != len(expected[i].Expressions) {
+			t.Fatalf("unexpected number of expressions: got %d, but expected %d\n", len(actual[i].Expressions), len(expected[i].Expressions))
+		}
+		for j := range expected[i].Expressions {
+			testParseExpression(t, actual[i].Expressions[j], expected[i].Expressions[j])
+		}
+		if len(actual[i].Statements) != len(expected[i].Statements) {
+			t.Fatalf("unexpected number of statements: got %d, but expected %d\n", len(actual[i].Statements), len(expected[i].Statements))
+		}
+		for j := range expected[i].Statements {
+			testParseStatement(t, actual[i].Statements[j], expected[i].Statements[j])
+		}
+	}
+}
+
+func testParseFunctionDeclaration(t *testing.T, actual, expected *ast.FunctionDeclaration) {
+	testParseIdentifier(t, actual.Identifier, expected.Identifier)
+	testParseParameters(t, actual.TypeParameters, expected.TypeParameters)
+	testParseParameters(t, actual.Parameters, expected.Parameters)
+	testParseParameters(t, actual.Results, expected.Results)
+	testParseBlockStatement(t, actual.Body, expected.Body)
+}
+
+func testParseFunctionLiteral(t *testing.T, actual, expected *ast.FunctionLiteral) {
+	testParseParameters(t, actual.Parameters, expected.Parameters)
+	testParseParameters(t, actual.Results, expected.Results)
+	testParseBlockStatement(t, actual.Body, expected.Body)
+}
+
+func testParseParameters(t *testing.T, actual, expected []*ast.Parameter) {
+	if len(actual) != len(expected) {
+		t.Fatalf("unexpected number of parameters: got %d, but expected %d\n", len(actual), len(expected))
+	}
+	for i := range expected {
+		if (actual[i].Identifier == nil) != (expected[i].Identifier == nil) {
+			t.Fatalf("unexpected identifier: got %v, but expected %v\n", actual[i].Identifier, expected[i].Identifier)
+		}
+		if actual[i].Identifier != nil {
+			testParseIdentifier(t, actual[i].Identifier, expected[i].Identifier)
+		}
+		testParseExpression(t, actual[i].Type, expected[i].Type)
+	}
+}
+
+func testParseBlockStatement(t *testing.T, actual, expected *ast.BlockStatement) {
+	if len(actual.Statements) != len(expected.Statements) {
+		t.Fatalf("unexpected number of statements: got %d, but expected %d\n", len(actual.Statements), len(expected.Statements))
+	}
+	for i := range expected.Statements {
+		testParseStatement(t, actual.Statements[i], expected.Statements[i])
+	}
+}
+
+func testParseReturnStatement(t *testing.T, actual, expected *ast.ReturnStatement) {
+	if (actual.Expression == nil) != (expected.Expression == nil) {
+		t.Fatalf("unexpected expression: got %v, but expected %v\n", actual.Expression, expected.Expression)
+	}
+	if actual.Expression != nil {
+		testParseExpression(t, actual.Expression, expected.Expression)
+	}
+}
+
+func testParseCallExpression(t *testing.T, actual, expected *ast.CallExpression) {
+	testParseExpression(t, actual.Function, expected.Function)
+	if len(actual.Arguments) != len(expected.Arguments) {
+		t.Fatalf("unexpected number of arguments: got %d, but expected %d\n", len(actual.Arguments), len(expected.Arguments))
+	}
+	for i := range expected.Arguments {
+		testParseExpression(t, actual.Arguments[i], expected.Arguments[i])
+	}
+	if actual.HasEllipsis != expected.HasEllipsis {
+		t.Errorf("unexpected has ellipsis: got %t, but expected %t\n", actual.HasEllipsis, expected.HasEllipsis)
+	}
+}
+
+func testParseSliceExpression(t *testing.T, actual, expected *ast.SliceExpression) {
+	testParseExpression(t, actual.Expression, expected.Expression)
+	testParseOptionalExpression(t, actual.Low, expected.Low)
+	testParseOptionalExpression(t, actual.High, expected.High)
+}
+
+func testParseOptionalExpression(t *testing.T, actual, expected ast.Expression) {
+	if (actual == nil) != (expected == nil) {
+		t.Fatalf("unexpected expression: got %v, but expected %v\n", actual, expected)
+	}
+	if actual != nil {
+		testParseExpression(t, actual, expected)
+	}
+}
+
+func testParseCompositeLiteral(t *testing.T, actual, expected *ast.CompositeLiteral) {
+	testParseOptionalExpression(t, actual.Type, expected.Type)
+	if len(actual.Elements) != len(expected.Elements) {
+		t.Fatalf("unexpected number of elements: got %d, but expected %d\n", len(actual.Elements), len(expected.Elements))
+	}
+	for i, elem := range expected.Elements {
+		testParseOptionalExpression(t, actual.Elements[i].Key, elem.Key)
+		testParseExpression(t, actual.Elements[i].Value, elem.Value)
+	}
+}
+
+func testParseForStatement(t *testing.T, actual, expected *ast.ForStatement) {
+	testParseOptionalStatement(t, actual.Initializer, expected.Initializer)
+	if (actual.Condition == nil) != (expected.Condition == nil) {
+		t.Fatalf("unexpected condition: got %v, but expected %v\n", actual.Condition, expected.Condition)
+	}
+	if actual.Condition != nil {
+		testParseExpression(t, actual.Condition, expected.Condition)
+	}
+	testParseOptionalStatement(t, actual.Post, expected.Post)
+	testParseBlockStatement(t, actual.Body, expected.Body)
+}
+
+func testParseRangeStatement(t *testing.T, actual, expected *ast.RangeStatement) {
+	for _, idents := range [][2]*ast.Identifier{{actual.Key, expected.Key}, {actual.Value, expected.Value}} {
+		if (idents[0] == nil) != (idents[1] == nil) {
+			t.Fatalf("unexpected identifier: got %v, but expected %v\n", idents[0], idents[1])
+		}
+		if idents[0] != nil {
+			testParseIdentifier(t, idents[0], idents[1])
+		}
+	}
+	if actual.IsDefine != expected.IsDefine {
+		t.Errorf("unexpected define: got %t, but expected %t\n", actual.IsDefine, expected.IsDefine)
+	}
+	testParseExpression(t, actual.Expression, expected.Expression)
+	testParseBlockStatement(t, actual.Body, expected.Body)
+}
+
+func testParseCommunicationClauses(t *testing.T, actual, expected []*ast.CommunicationClause) {
+	if len(actual) != len(expected) {
+		t.Fatalf("unexpected number of clauses: got %d, but expected %d\n", len(actual), len(expected))
+	}
+	for i := range expected {
+		if actual[i].IsDefault != expected[i].IsDefault {
+			t.Errorf("unexpected default: got %t, but expected %t\n", actual[i].IsDefault, expected[i].IsDefault)
+		}
+		testParseOptionalStatement(t, actual[i].Communication, expected[i].Communication)
+		if len(actual[i].Statements) != len(expected[i].Statements) {
+			t.Fatalf("unexpected number of statements: got %d, but expected %d\n", len(actual[i].Statements), len(expected[i].Statements))
+		}
+		for j := range expected[i].Statements {
+			testParseStatement(t, actual[i].Statements[j], expected[i].Statements[j])
+		}
+	}
+}
+
+func testParseInterfaceType(t *testing.T, actual, expected *ast.InterfaceType) {
+	if len(actual.Elements) != len(expected.Elements) {
+		t.Fatalf("unexpected number of elements: got %d, but expected %d\n", len(actual.Elements), len(expected.Elements))
+	}
+	for i := range expected.Elements {
+		if len(actual.Elements[i].Terms) != len(expected.Elements[i].Terms) {
+			t.Fatalf("unexpected number of terms: got %d, but expected %d\n", len(actual.Elements[i].Terms), len(expected.Elements[i].Terms))
+		}
+		for j, term := range expected.Elements[i].Terms {
+			if actual.Elements[i].Terms[j].IsTilde != term.IsTilde {
+				t.Errorf("unexpected tilde: got %t, but expected %t\n", actual.Elements[i].Terms[j].IsTilde, term.IsTilde)
+			}
+			testParseExpression(t, actual.Elements[i].Terms[j].Type, term.Type)
+		}
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/tomocy/kinako"
+	"github.com/tomocy/kinako/checker"
+	"github.com/tomocy/kinako/diagnostic"
 	"github.com/tomocy/kinako/evaluator"
 	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/object"
 	"github.com/tomocy/kinako/parser"
 )
 
@@ -15,15 +19,38 @@ const prompt = "> "
 type REPL struct {
 	reader    io.Reader
 	writer    io.Writer
+	colors    bool
+	checker   *checker.Checker
 	evaluator *evaluator.Evaluator
+	renderer  *diagnostic.Renderer
 }
 
-func New(r io.Reader, w io.Writer) *REPL {
-	return &REPL{
+type Option func(*REPL)
+
+func WithColor() Option {
+	return func(r *REPL) {
+		r.colors = true
+	}
+}
+
+func New(r io.Reader, w io.Writer, opts ...Option) *REPL {
+	repl := &REPL{
 		reader:    r,
 		writer:    w,
-		evaluator: evaluator.New(),
+		checker:   checker.New(),
+		evaluator: evaluator.New(evaluator.WithStdout(w), evaluator.WithStderr(w)),
+	}
+	for _, opt := range opts {
+		opt(repl)
 	}
+
+	var renderOpts []diagnostic.Option
+	if repl.colors {
+		renderOpts = append(renderOpts, diagnostic.WithColor())
+	}
+	repl.renderer = diagnostic.New(renderOpts...)
+
+	return repl
 }
 
 func (r REPL) Start() {
@@ -43,6 +70,43 @@ func (r REPL) printPrompt() {
 func (r REPL) printResult(input string) {
 	parser := parser.New(lexer.New(input))
 	program := parser.ParseProgram()
+	if err := r.checker.Check(program); err != nil {
+		r.printCheckError(input, err)
+		return
+	}
+	if err := r.evaluator.Resolve(program); err != nil {
+		r.renderer.Render(r.writer, input, diagnose(err))
+		return
+	}
 	result := r.evaluator.Evaluate(program)
+	if err, ok := result.(*object.Error); ok {
+		r.renderer.Render(r.writer, input, diagnose(err))
+		return
+	}
 	fmt.Fprintln(r.writer, result)
 }
+
+func (r REPL) printCheckError(input string, err error) {
+	cerr, ok := err.(*checker.Error)
+	if !ok {
+		fmt.Fprintln(r.writer, err.Error())
+		return
+	}
+
+	r.renderer.Render(r.writer, input, kinako.Diagnose(&kinako.TypeError{
+		Message:  cerr.Message,
+		Position: cerr.Position,
+		End:      cerr.End,
+	}))
+}
+
+func diagnose(err *object.Error) *diagnostic.Diagnostic {
+	return kinako.Diagnose(&kinako.RuntimeError{
+		Kind:     err.Kind,
+		Message:  err.Message,
+		Position: err.Position,
+		End:      err.End,
+		Stack:    err.Stack,
+		Err:      err.Err,
+	})
+}
@@ -2,7 +2,11 @@ package repl
 
 import (
 	"bytes"
+	"errors"
 	"testing"
+
+	"github.com/tomocy/kinako/checker"
+	"github.com/tomocy/kinako/token"
 )
 
 func TestPrintResult(t *testing.T) {
@@ -12,15 +16,23 @@ func TestPrintResult(t *testing.T) {
 	}{
 		{"5;", "5\n"},
 		{"-6;", "-6\n"},
-		{"7 + 8 - 9 * 10 / 11;", "15\n"},
+		{"7 + 8 - 9 * 10 / 11;", "7\n"},
 		{"(12 + 13) / 14;", "1\n"},
 		{"var x int = 15;", "15\n"},
 		{"var x int = 16; x;", "16\n"},
 		{"true;", "true\n"},
 		{"false;", "false\n"},
 		{"!true;", "false\n"},
-		{"0; 0", "failed to find semicolon\n"},
-		{"0 / 0;", "divided by zero\n"},
+		{"0; 0", "1:4: failed to find semicolon\n  |\n1 | 0; 0\n  |    ^\n"},
+		{"0 / 0;", "1:1: panic: runtime error: integer divide by zero\n  |\n1 | 0 / 0;\n  | ^^^^^\n"},
+		{"func f() int { return 1 / 0; } f();", "1:23: panic: runtime error: integer divide by zero\n  |\n1 | func f() int { return 1 / 0; } f();\n  |                       ^^^^^\n  = note: in f at 1:23\n  = note: in main at 1:32\n"},
+		{"1 + \"a\";", "1:1: mismatched types int and string\n  |\n1 | 1 + \"a\";\n  | ^^^^^^^\n"},
+		{"func f() (r int) { defer func() { recover(); r = 1; }(); return 0 / 0; } f();", "1\n"},
+		{"c := make(chan int); <-c;", "1:22: fatal error: all goroutines are asleep - deadlock!\n  |\n1 | c := make(chan int); <-c;\n  |                      ^^^\n  = hint: make sure every channel receive has a matching send or close\n"},
+		{"println(1, true, \"a\");", "1 true a\n<nil>\n"},
+		{"println(1); y;", "1:13: undefined variable: y\n  |\n1 | println(1); y;\n  |             ^\n"},
+		{"m := map[string][]int{\"a\": {1, 2}}; m;", "map[a:[1 2]]\n"},
+		{"import \"fmt\"; fmt.Println(\"a\");", "a\n2 <nil>\n"},
 	}
 	for _, test := range tests {
 		t.Run(test.input, func(t *testing.T) {
@@ -34,3 +46,34 @@ func TestPrintResult(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintCheckError(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected string
+	}{
+		"checker error": {
+			err: &checker.Error{
+				Message:  "mismatched types int and string",
+				Position: token.Position{Line: 1, Column: 1},
+				End:      token.Position{Line: 1, Column: 8},
+			},
+			expected: "1:1: mismatched types int and string\n  |\n1 | 1 + \"a\";\n  | ^^^^^^^\n",
+		},
+		"other error": {
+			err:      errors.New("failed to check"),
+			expected: "failed to check\n",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := new(bytes.Buffer)
+			mock := New(nil, w)
+			mock.printCheckError("1 + \"a\";", test.err)
+			actual := w.String()
+			if actual != test.expected {
+				t.Errorf("unexpected result: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
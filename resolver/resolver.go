@@ -0,0 +1,636 @@
+package resolver
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/token"
+)
+
+type scope struct {
+	outer    *scope
+	kind     scopeKind
+	bindings map[string]*binding
+	size     int
+}
+
+type scopeKind int
+
+const (
+	universeScope scopeKind = iota
+	packageScope
+	fileScope
+	localScope
+)
+
+type binding struct {
+	slot     int
+	visible  bool
+	path     string
+	variable *variable
+}
+
+type variable struct {
+	message  string
+	position token.Position
+	end      token.Position
+	used     bool
+}
+
+func newScope(kind scopeKind, outer *scope) *scope {
+	return &scope{
+		outer:    outer,
+		kind:     kind,
+		bindings: make(map[string]*binding),
+	}
+}
+
+func (s *scope) declare(name string) *binding {
+	if s.kind == fileScope {
+		return s.outer.declare(name)
+	}
+	if b, ok := s.bindings[name]; ok {
+		b.visible = true
+		return b
+	}
+
+	b := &binding{
+		slot:    -1,
+		visible: true,
+	}
+	if s.kind != universeScope {
+		b.slot = s.size
+		s.size++
+	}
+	s.bindings[name] = b
+	return b
+}
+
+func (s *scope) declares(name string) bool {
+	if s.kind == fileScope {
+		return s.outer.declares(name)
+	}
+
+	b, ok := s.bindings[name]
+	return ok && b.visible
+}
+
+func (s *scope) reserve(name string) {
+	if _, ok := s.bindings[name]; ok {
+		return
+	}
+
+	s.declare(name).visible = false
+}
+
+func (s *scope) import_(name, path string) *binding {
+	b := &binding{
+		slot:    -1,
+		visible: true,
+		path:    path,
+	}
+	s.bindings[name] = b
+	return b
+}
+
+type Resolver struct {
+	predeclared []string
+	pkg         []string
+	strict      bool
+	scope       *scope
+	functions   int
+	members     []Member
+	variables   []*variable
+	err         error
+	positions   map[ast.Node]token.Position
+	ends        map[ast.Node]token.Position
+	position    token.Position
+	end         token.Position
+}
+
+type Option func(*Resolver)
+
+func WithPredeclared(names ...string) Option {
+	return func(r *Resolver) {
+		r.predeclared = append(r.predeclared, names...)
+	}
+}
+
+func WithPackage(names ...string) Option {
+	return func(r *Resolver) {
+		r.pkg = append(r.pkg, names...)
+	}
+}
+
+// WithStrict makes Resolve report variables and imports which are never used,
+// and undefined names, as Go does.
+func WithStrict() Option {
+	return func(r *Resolver) {
+		r.strict = true
+	}
+}
+
+type Member struct {
+	Path     string
+	Selector *ast.SelectorExpression
+}
+
+type Error struct {
+	Message  string
+	Position token.Position
+	End      token.Position
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func New(opts ...Option) *Resolver {
+	r := new(Resolver)
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *Resolver) Resolve(program *ast.Program) error {
+	universe := newScope(universeScope, nil)
+	for _, name := range r.predeclared {
+		universe.declare(name)
+	}
+	pkg := newScope(packageScope, universe)
+	for _, name := range r.pkg {
+		pkg.declare(name)
+	}
+	r.scope = newScope(fileScope, pkg)
+	r.functions = 0
+	r.members = nil
+	r.variables = nil
+	r.err = nil
+	r.positions, r.position = program.Positions, token.Position{}
+	r.ends, r.end = program.Ends, token.Position{}
+
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.BadStatement); ok {
+			break
+		}
+		r.reserve(stmt)
+	}
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.BadStatement); ok {
+			break
+		}
+		r.resolveStatement(stmt)
+		if r.err != nil {
+			break
+		}
+	}
+	if r.strict && r.err == nil {
+		r.checkUsed()
+	}
+
+	return r.err
+}
+
+func (r *Resolver) checkUsed() {
+	var unused *variable
+	for _, v := range r.variables {
+		if v.used {
+			continue
+		}
+		if unused == nil || v.position.Line < unused.position.Line ||
+			v.position.Line == unused.position.Line && v.position.Column < unused.position.Column {
+			unused = v
+		}
+	}
+	if unused != nil {
+		r.position, r.end = unused.position, unused.end
+		r.errorf("%s", unused.message)
+	}
+}
+
+func (r *Resolver) Members() []Member {
+	return r.members
+}
+
+func (r *Resolver) reserve(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.VariableDeclaration:
+		r.scope.outer.reserve(node.Identifier.Name)
+	case *ast.ShortVariableDeclaration:
+		for _, ident := range node.Identifiers {
+			if ident.Name != "_" {
+				r.scope.outer.reserve(ident.Name)
+			}
+		}
+	case *ast.FunctionDeclaration:
+		r.scope.outer.reserve(node.Identifier.Name)
+	case *ast.TypeDeclaration:
+		r.scope.outer.reserve(node.Identifier.Name)
+	}
+}
+
+func (r *Resolver) errorf(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = &Error{
+			Message:  fmt.Sprintf(format, args...),
+			Position: r.position,
+			End:      r.end,
+		}
+	}
+}
+
+func (r *Resolver) enter(node ast.Node) func() {
+	pos, ok := r.positions[node]
+	if !ok {
+		return func() {}
+	}
+
+	outer, outerEnd := r.position, r.end
+	r.position, r.end = pos, r.ends[node]
+	return func() {
+		r.position, r.end = outer, outerEnd
+	}
+}
+
+func (r *Resolver) withScope(resolve func()) {
+	outer := r.scope
+	r.scope = newScope(localScope, outer)
+	defer func() {
+		r.scope = outer
+	}()
+
+	resolve()
+}
+
+func (r *Resolver) withFunctionScope(resolve func()) {
+	r.functions++
+	defer func() {
+		r.functions--
+	}()
+
+	r.withScope(resolve)
+}
+
+func (r *Resolver) lookUp(name string) (int, *binding, bool) {
+	depth := 0
+	for s := r.scope; s != nil; s = s.outer {
+		if b, ok := s.bindings[name]; ok && (b.visible || r.functions != 0) {
+			return depth, b, true
+		}
+		depth++
+	}
+
+	return 0, nil, false
+}
+
+func (r *Resolver) declare(ident *ast.Identifier) *binding {
+	b := r.scope.declare(ident.Name)
+	bind(ident, r.depthOfDeclarations(), b)
+	return b
+}
+
+func (r *Resolver) declareVariable(ident *ast.Identifier) {
+	b := r.declare(ident)
+	if r.scope.kind == localScope {
+		b.variable = r.track(ident, fmt.Sprintf("declared and not used: %s", ident.Name))
+	}
+}
+
+func (r *Resolver) track(node ast.Node, msg string) *variable {
+	defer r.enter(node)()
+
+	v := &variable{
+		message:  msg,
+		position: r.position,
+		end:      r.end,
+	}
+	r.variables = append(r.variables, v)
+	return v
+}
+
+func (r *Resolver) depthOfDeclarations() int {
+	if r.scope.kind == fileScope {
+		return 1
+	}
+
+	return 0
+}
+
+func bind(ident *ast.Identifier, depth int, b *binding) {
+	if b.slot < 0 {
+		return
+	}
+
+	ident.Resolved, ident.Depth, ident.Slot = true, depth, b.slot
+}
+
+func (r *Resolver) resolveStatements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		if r.err != nil {
+			return
+		}
+		r.resolveStatement(stmt)
+	}
+}
+
+func (r *Resolver) resolveStatement(node ast.Statement) {
+	defer r.enter(node)()
+
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		r.resolveExpression(node.Expression)
+	case *ast.VariableDeclaration:
+		r.resolveExpression(node.Expression)
+		r.declareVariable(node.Identifier)
+	case *ast.ShortVariableDeclaration:
+		r.resolveExpressions(node.Expressions)
+		for _, ident := range node.Identifiers {
+			if ident.Name == "_" {
+				continue
+			}
+			if r.scope.declares(ident.Name) {
+				r.resolveAssignee(ident)
+				continue
+			}
+			r.declareVariable(ident)
+		}
+	case *ast.AssignmentStatement:
+		r.resolveExpressions(node.RExpressions)
+		for _, expr := range node.LExpressions {
+			r.resolveAssignee(expr)
+		}
+	case *ast.IncDecStatement:
+		r.resolveExpression(node.Expression)
+	case *ast.SendStatement:
+		r.resolveExpression(node.Channel)
+		r.resolveExpression(node.Expression)
+	case *ast.GoStatement:
+		r.resolveExpression(node.Call)
+	case *ast.DeferStatement:
+		r.resolveExpression(node.Call)
+	case *ast.ReturnStatement:
+		r.resolveExpression(node.Expression)
+	case *ast.BlockStatement:
+		r.withScope(func() {
+			r.resolveStatements(node.Statements)
+		})
+	case *ast.ForStatement:
+		r.resolveForStatement(node)
+	case *ast.RangeStatement:
+		r.resolveRangeStatement(node)
+	case *ast.SwitchStatement:
+		r.resolveSwitchStatement(node)
+	case *ast.TypeSwitchStatement:
+		r.resolveTypeSwitchStatement(node)
+	case *ast.SelectStatement:
+		r.resolveSelectStatement(node)
+	case *ast.FunctionDeclaration:
+		r.resolveFunctionDeclaration(node)
+	case *ast.TypeDeclaration:
+		r.declare(node.Identifier)
+	case *ast.ImportDeclaration:
+		r.resolveImportDeclaration(node)
+	}
+}
+
+func (r *Resolver) resolveForStatement(node *ast.ForStatement) {
+	r.withScope(func() {
+		if node.Initializer != nil {
+			r.resolveStatement(node.Initializer)
+		}
+		r.resolveExpression(node.Condition)
+		if node.Post != nil {
+			r.resolveStatement(node.Post)
+		}
+		r.resolveStatement(node.Body)
+	})
+}
+
+func (r *Resolver) resolveRangeStatement(node *ast.RangeStatement) {
+	r.resolveExpression(node.Expression)
+	if !node.IsDefine {
+		for _, ident := range []*ast.Identifier{node.Key, node.Value} {
+			if ident != nil {
+				r.resolveAssignee(ident)
+			}
+		}
+	}
+
+	r.withScope(func() {
+		if node.IsDefine {
+			for _, ident := range []*ast.Identifier{node.Key, node.Value} {
+				if ident != nil && ident.Name != "_" {
+					r.declareVariable(ident)
+				}
+			}
+		}
+		r.resolveStatement(node.Body)
+	})
+}
+
+func (r *Resolver) resolveSwitchStatement(node *ast.SwitchStatement) {
+	r.withScope(func() {
+		if node.Initializer != nil {
+			r.resolveStatement(node.Initializer)
+		}
+		r.resolveExpression(node.Tag)
+		for _, clause := range node.Clauses {
+			r.resolveExpressions(clause.Expressions)
+			r.withScope(func() {
+				r.resolveStatements(clause.Statements)
+			})
+		}
+	})
+}
+
+func (r *Resolver) resolveTypeSwitchStatement(node *ast.TypeSwitchStatement) {
+	r.withScope(func() {
+		if node.Initializer != nil {
+			r.resolveStatement(node.Initializer)
+		}
+		r.resolveExpression(node.Expression)
+		var v *variable
+		if node.Identifier != nil && node.Identifier.Name != "_" {
+			v = r.track(node.Identifier, fmt.Sprintf("%s declared and not used", node.Identifier.Name))
+		}
+		for _, clause := range node.Clauses {
+			r.withScope(func() {
+				if v != nil {
+					r.declare(node.Identifier).variable = v
+				}
+				r.resolveStatements(clause.Statements)
+			})
+		}
+	})
+}
+
+func (r *Resolver) resolveSelectStatement(node *ast.SelectStatement) {
+	for _, clause := range node.Clauses {
+		switch stmt := clause.Communication.(type) {
+		case *ast.ShortVariableDeclaration:
+			r.resolveExpressions(stmt.Expressions)
+		case nil:
+		default:
+			r.resolveStatement(stmt)
+		}
+
+		r.withScope(func() {
+			if stmt, ok := clause.Communication.(*ast.ShortVariableDeclaration); ok {
+				for _, ident := range stmt.Identifiers {
+					if ident.Name != "_" {
+						r.declareVariable(ident)
+					}
+				}
+			}
+			r.resolveStatements(clause.Statements)
+		})
+	}
+}
+
+func (r *Resolver) resolveFunctionDeclaration(node *ast.FunctionDeclaration) {
+	r.declare(node.Identifier)
+	r.resolveFunction(node.TypeParameters, node.Parameters, node.Results, node.Body)
+}
+
+func (r *Resolver) resolveFunction(typeParams, params, results []*ast.Parameter, body *ast.BlockStatement) {
+	r.withFunctionScope(func() {
+		for _, param := range typeParams {
+			r.declare(param.Identifier)
+		}
+		for _, param := range params {
+			r.declare(param.Identifier)
+		}
+		for _, result := range results {
+			if result.Identifier != nil {
+				r.declare(result.Identifier)
+			}
+		}
+
+		if body != nil {
+			r.resolveStatements(body.Statements)
+		}
+	})
+}
+
+func (r *Resolver) resolveImportDeclaration(node *ast.ImportDeclaration) {
+	file := r.scope
+	for file.kind != fileScope {
+		if file.outer == nil {
+			return
+		}
+		file = file.outer
+	}
+
+	for _, spec := range node.Specs {
+		name, msg := path.Base(spec.Path.Value), fmt.Sprintf("%q imported and not used", spec.Path.Value)
+		if spec.Name != nil {
+			name = spec.Name.Name
+			msg = fmt.Sprintf("%q imported as %s and not used", spec.Path.Value, name)
+		}
+		if name != "_" {
+			file.import_(name, spec.Path.Value).variable = r.track(spec, msg)
+		}
+	}
+}
+
+func (r *Resolver) resolveMember(node *ast.SelectorExpression) {
+	ident, ok := node.Expression.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	if _, b, ok := r.lookUp(ident.Name); ok && b.path != "" {
+		r.members = append(r.members, Member{
+			Path:     b.path,
+			Selector: node,
+		})
+	}
+}
+
+func (r *Resolver) resolveExpressions(nodes []ast.Expression) {
+	for _, node := range nodes {
+		r.resolveExpression(node)
+	}
+}
+
+func (r *Resolver) resolveExpression(node ast.Expression) {
+	if node == nil || r.err != nil {
+		return
+	}
+	defer r.enter(node)()
+
+	switch node := node.(type) {
+	case *ast.Identifier:
+		r.resolveIdentifier(node)
+	case *ast.PrefixExpression:
+		r.resolveExpression(node.RExpression)
+	case *ast.InfixExpression:
+		r.resolveExpression(node.LExpression)
+		r.resolveExpression(node.RExpression)
+	case *ast.SelectorExpression:
+		r.resolveExpression(node.Expression)
+		r.resolveMember(node)
+	case *ast.TypeAssertionExpression:
+		r.resolveExpression(node.Expression)
+	case *ast.IndexExpression:
+		r.resolveExpression(node.Expression)
+		r.resolveExpressions(node.Indices)
+	case *ast.SliceExpression:
+		r.resolveExpression(node.Expression)
+		r.resolveExpression(node.Low)
+		r.resolveExpression(node.High)
+	case *ast.CallExpression:
+		r.resolveExpression(node.Function)
+		r.resolveExpressions(node.Arguments)
+	case *ast.FunctionLiteral:
+		r.resolveFunction(nil, node.Parameters, node.Results, node.Body)
+	case *ast.CompositeLiteral:
+		for _, elem := range node.Elements {
+			r.resolveExpression(elem.Key)
+			r.resolveExpression(elem.Value)
+		}
+	}
+}
+
+// resolveAssignee resolves an expression assigned to, which does not use the
+// variable it denotes.
+func (r *Resolver) resolveAssignee(node ast.Expression) {
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		r.resolveExpression(node)
+		return
+	}
+	if r.err != nil {
+		return
+	}
+	defer r.enter(ident)()
+
+	r.bindIdentifier(ident)
+}
+
+func (r *Resolver) resolveIdentifier(node *ast.Identifier) {
+	if b := r.bindIdentifier(node); b != nil && b.variable != nil {
+		b.variable.used = true
+	}
+}
+
+func (r *Resolver) bindIdentifier(node *ast.Identifier) *binding {
+	if node.Name == "_" {
+		return nil
+	}
+
+	depth, b, ok := r.lookUp(node.Name)
+	if !ok {
+		if r.strict {
+			r.errorf("undefined: %s", node.Name)
+		} else {
+			r.errorf("undefined variable: %s", node.Name)
+		}
+		return nil
+	}
+
+	bind(node, depth, b)
+	return b
+}
@@ -0,0 +1,312 @@
+package resolver
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tomocy/kinako/ast"
+	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/parser"
+	"github.com/tomocy/kinako/token"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"x := 1; x;",
+			"x@1:0 x@1:0",
+		},
+		{
+			"x := 1; y := 2; y + x;",
+			"x@1:0 y@1:1 y@1:1 x@1:0",
+		},
+		{
+			"x := 1; for i := 0; i < 3; i++ { x = x + i; }",
+			"x@1:0 i@0:0 i@0:0 i@0:0 x@3:0 x@3:0 i@1:0",
+		},
+		{
+			"s := []int{1}; for i, v := range s { i + v; }",
+			"s@1:0 i@0:0 v@0:1 s@1:0 i@1:0 v@1:1",
+		},
+		{
+			"func add(a, b int) int { c := a + b; return c; } add(1, 2);",
+			"add@1:0 a@0:0 b@0:1 c@0:2 a@0:0 b@0:1 c@0:2 add@1:0",
+		},
+		{
+			"func f() int { return g(); } func g() int { return 1; }",
+			"f@1:0 g@2:1 g@1:1",
+		},
+		{
+			"func Max[T any](a T) T { return a; } Max[int](1);",
+			"Max@1:0 T@0:0 a@0:1 a@0:1 Max@1:0",
+		},
+		{
+			"x := 1; f := func() int { x := 2; return x; }; f();",
+			"x@1:0 f@1:1 x@0:0 x@0:0 f@1:1",
+		},
+		{
+			"x := 1; x, y := 2, 3;",
+			"x@1:0 x@1:0 y@1:1",
+		},
+		{
+			"x := 1; switch x := 2; x { case 2: y := x; y; }",
+			"x@1:0 x@0:0 x@0:0 y@0:0 x@1:0 y@0:0",
+		},
+		{
+			"var x int = 1; switch v := x.(type) { case int: v; case bool: w := v; w; }",
+			"x@1:0 v@0:0 x@2:0 v@0:0 w@0:1 v@0:0 w@0:1",
+		},
+		{
+			"import \"fmt\"; var _ int = 1; len(\"a\"); fmt.Println(true);",
+			"_@1:0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			if err := New(WithPredeclared("true", "int", "len")).Resolve(program); err != nil {
+				t.Fatalf("unexpected error: %s\n", err)
+			}
+			if actual := bindingsOf(program); actual != test.expected {
+				t.Errorf("unexpected bindings: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestResolveWithPackage(t *testing.T) {
+	program := parser.New(lexer.New("y := x + 1; y;")).ParseProgram()
+	if err := New(WithPackage("x")).Resolve(program); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	if actual, expected := bindingsOf(program), "y@1:1 x@1:0 y@1:1"; actual != expected {
+		t.Errorf("unexpected bindings: got %q, but expected %q\n", actual, expected)
+	}
+}
+
+func TestResolveMembers(t *testing.T) {
+	input := `import (s "strings"; "fmt");
+func f(fmt int) int { return fmt; }
+s.Repeat("a", f(1));
+fmt.Print(s.ToUpper("a"));`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	r := New(WithPredeclared("int"))
+	if err := r.Resolve(program); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+
+	var members []string
+	for _, member := range r.Members() {
+		members = append(members, fmt.Sprintf("%s:%s", member.Path, ast.Format(member.Selector)))
+	}
+	if actual, expected := strings.Join(members, " "), "strings:s.Repeat fmt:fmt.Print strings:s.ToUpper"; actual != expected {
+		t.Errorf("unexpected members: got %q, but expected %q\n", actual, expected)
+	}
+}
+
+func TestResolveUndefined(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *Error
+	}{
+		{
+			"y;",
+			&Error{
+				Message:  "undefined variable: y",
+				Position: token.Position{Line: 1, Column: 1},
+				End:      token.Position{Line: 1, Column: 2},
+			},
+		},
+		{
+			"x := 1;\nx + y;",
+			&Error{
+				Message:  "undefined variable: y",
+				Position: token.Position{Line: 2, Column: 5},
+				End:      token.Position{Line: 2, Column: 6},
+			},
+		},
+		{
+			"y; y := 1;",
+			&Error{
+				Message:  "undefined variable: y",
+				Position: token.Position{Line: 1, Column: 1},
+				End:      token.Position{Line: 1, Column: 2},
+			},
+		},
+		{
+			"for i := 0; i < 3; i++ {} i;",
+			&Error{
+				Message:  "undefined variable: i",
+				Position: token.Position{Line: 1, Column: 27},
+				End:      token.Position{Line: 1, Column: 28},
+			},
+		},
+		{
+			"func f() { a := 1; } a;",
+			&Error{
+				Message:  "undefined variable: a",
+				Position: token.Position{Line: 1, Column: 22},
+				End:      token.Position{Line: 1, Column: 23},
+			},
+		},
+		{
+			"func f() int { return z; }",
+			&Error{
+				Message:  "undefined variable: z",
+				Position: token.Position{Line: 1, Column: 23},
+				End:      token.Position{Line: 1, Column: 24},
+			},
+		},
+		{
+			"switch z := 1; z { case 1: z; }\nz;",
+			&Error{
+				Message:  "undefined variable: z",
+				Position: token.Position{Line: 2, Column: 1},
+				End:      token.Position{Line: 2, Column: 2},
+			},
+		},
+		{
+			"x := 1; switch x { case 1: w := 1; w; }; w;",
+			&Error{
+				Message:  "undefined variable: w",
+				Position: token.Position{Line: 1, Column: 42},
+				End:      token.Position{Line: 1, Column: 43},
+			},
+		},
+		{
+			"x := 1; x.y;",
+			nil,
+		},
+		{
+			"func f() int { return g(); } func g() int { return 1; }",
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			err := New().Resolve(program)
+			if test.expected == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %s\n", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(err, test.expected) {
+				t.Errorf("unexpected error: got %#v, but expected %#v\n", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestResolveStrict(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`package main; import ("fmt"; s "strings"); var g int; func main() { x := 1; var y []int; y = append(y, x); fmt.Println(s.ToUpper("a"), y); g = 1; }`,
+			"",
+		},
+		{
+			"package main; func main() { c := make(chan int); go func() { c <- 1; }(); <-c; for i := range c { println(i); } switch v := interface{}(1).(type) { case int: println(v); } }",
+			"",
+		},
+		{
+			"package main; func main() { n := 0; f := func() { n++; }; f(); print(n); }",
+			"",
+		},
+		{
+			"package main; func main() { var x int = 1; x++; }",
+			"",
+		},
+		{
+			"package main; func main() { x := 1; }",
+			"1:29: declared and not used: x",
+		},
+		{
+			"package main; func main() { x := 1; x = 2; }",
+			"1:29: declared and not used: x",
+		},
+		{
+			"package main; func main() { x := 1; x, y := 2, 3; println(y); }",
+			"1:29: declared and not used: x",
+		},
+		{
+			"package main; func main() { for i, v := range []int{1} { println(v); } }",
+			"1:33: declared and not used: i",
+		},
+		{
+			"package main; func main() { switch v := interface{}(1).(type) { case int: } }",
+			"1:36: v declared and not used",
+		},
+		{
+			"package main; func main() { select { case v := <-make(chan int): } }",
+			"1:43: declared and not used: v",
+		},
+		{
+			`package main; import "fmt"; func main() {}`,
+			`1:22: "fmt" imported and not used`,
+		},
+		{
+			`package main; import s "strings"; func main() { s := 1; println(s); }`,
+			`1:22: "strings" imported as s and not used`,
+		},
+		{
+			"package main; func main() { println(g); }",
+			"1:37: undefined: g",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			err := New(WithPredeclared("append", "make", "print", "println"), WithStrict()).Resolve(program)
+			actual := ""
+			if err != nil {
+				err := err.(*Error)
+				actual = fmt.Sprintf("%s: %s", err.Position, err.Message)
+			}
+			if actual != test.expected {
+				t.Errorf("unexpected error: got %q, but expected %q\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func bindingsOf(program *ast.Program) string {
+	var bindings []string
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr:
+			if v.IsNil() {
+				return
+			}
+			if ident, ok := v.Interface().(*ast.Identifier); ok {
+				if ident.Resolved {
+					bindings = append(bindings, fmt.Sprintf("%s@%d:%d", ident.Name, ident.Depth, ident.Slot))
+				}
+				return
+			}
+			walk(v.Elem())
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				walk(v.Field(i))
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		}
+	}
+	for _, stmt := range program.Statements {
+		walk(reflect.ValueOf(stmt))
+	}
+
+	return strings.Join(bindings, " ")
+}
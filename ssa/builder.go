@@ -0,0 +1,1163 @@
+package ssa
+
+import (
+	"fmt"
+
+	"github.com/tomocy/kinako/ast"
+)
+
+var builtins = map[string]bool{
+	"append": true, "cap": true, "close": true, "copy": true, "delete": true,
+	"len": true, "make": true, "new": true, "panic": true, "print": true,
+	"println": true, "recover": true,
+}
+
+type Builder struct {
+	objects   map[*ast.Identifier]*object
+	defines   map[*ast.Identifier]bool
+	globals   map[*object]*Global
+	functions map[*object]*Function
+}
+
+func New() *Builder {
+	return new(Builder)
+}
+
+func (b *Builder) Build(program *ast.Program) *Program {
+	a := analyze(program)
+	b.objects, b.defines = a.objects, a.defines
+	b.globals = make(map[*object]*Global)
+	b.functions = make(map[*object]*Function)
+
+	prog := new(Program)
+	init := &Function{
+		name: "init",
+	}
+	prog.Functions = append(prog.Functions, init)
+
+	var decls []*ast.FunctionDeclaration
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.BadStatement); ok {
+			break
+		}
+
+		var idents []*ast.Identifier
+		switch stmt := stmt.(type) {
+		case *ast.VariableDeclaration:
+			idents = []*ast.Identifier{stmt.Identifier}
+		case *ast.ShortVariableDeclaration:
+			idents = stmt.Identifiers
+		case *ast.FunctionDeclaration:
+			fn := &Function{
+				name: stmt.Identifier.Name,
+			}
+			b.functions[b.objects[stmt.Identifier]] = fn
+			prog.Functions = append(prog.Functions, fn)
+			decls = append(decls, stmt)
+		}
+		for _, ident := range idents {
+			obj := b.objects[ident]
+			if obj == nil || b.globals[obj] != nil {
+				continue
+			}
+			g := &Global{
+				name: obj.name,
+			}
+			b.globals[obj] = g
+			prog.Globals = append(prog.Globals, g)
+		}
+	}
+
+	b.buildFunction(init, nil, nil, nil, program.Statements)
+	for _, decl := range decls {
+		b.buildFunction(b.functions[b.objects[decl.Identifier]], decl, decl.Parameters, decl.Results, decl.Body.Statements)
+	}
+
+	return prog
+}
+
+func (b *Builder) buildFunction(fn *Function, node ast.Node, params, results []*ast.Parameter, body []ast.Statement) *functionBuilder {
+	f := &functionBuilder{
+		Builder:    b,
+		fn:         fn,
+		node:       node,
+		defs:       make(map[*object]map[*BasicBlock]Value),
+		incomplete: make(map[*BasicBlock][]*Phi),
+		sealed:     make(map[*BasicBlock]bool),
+		addrs:      make(map[*object]Value),
+	}
+	f.block = f.newBlock("entry")
+
+	for _, param := range params {
+		p := &Parameter{
+			name: param.Identifier.Name,
+		}
+		fn.Params = append(fn.Params, p)
+		f.define(param.Identifier, p)
+	}
+	for _, result := range results {
+		if result.Identifier == nil {
+			continue
+		}
+		f.results = append(f.results, result.Identifier)
+		f.define(result.Identifier, &Zero{
+			Type: ast.Format(result.Type),
+		})
+	}
+
+	for _, stmt := range body {
+		if _, ok := stmt.(*ast.BadStatement); ok {
+			break
+		}
+		f.statement(stmt)
+	}
+	f.emitReturn()
+
+	f.finish()
+	return f
+}
+
+type functionBuilder struct {
+	*Builder
+	fn         *Function
+	node       ast.Node
+	block      *BasicBlock
+	defs       map[*object]map[*BasicBlock]Value
+	incomplete map[*BasicBlock][]*Phi
+	sealed     map[*BasicBlock]bool
+	addrs      map[*object]Value
+	freeVars   []*object
+	results    []*ast.Identifier
+	targets    *targets
+}
+
+type targets struct {
+	outer        *targets
+	break_       *BasicBlock
+	continue_    *BasicBlock
+	fallthrough_ *BasicBlock
+}
+
+func (f *functionBuilder) newBlock(comment string) *BasicBlock {
+	block := &BasicBlock{
+		Index:   len(f.fn.Blocks),
+		Comment: comment,
+		parent:  f.fn,
+	}
+	f.fn.Blocks = append(f.fn.Blocks, block)
+
+	return block
+}
+
+func (f *functionBuilder) withTargets(t *targets, build func()) {
+	t.outer = f.targets
+	f.targets = t
+	defer func() {
+		f.targets = t.outer
+	}()
+
+	build()
+}
+
+func (f *functionBuilder) emit(instr Instruction) Instruction {
+	instr.setBlock(f.block)
+	f.block.Instrs = append(f.block.Instrs, instr)
+
+	return instr
+}
+
+func (f *functionBuilder) emitValue(instr Instruction) Value {
+	return f.emit(instr).(Value)
+}
+
+func addEdge(from, to *BasicBlock) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+func (f *functionBuilder) emitJump(target *BasicBlock) {
+	f.emit(new(Jump))
+	addEdge(f.block, target)
+	f.block = f.newBlock("unreachable")
+}
+
+func (f *functionBuilder) emitIf(cond Value, then, els *BasicBlock) {
+	f.emit(&If{
+		Cond: cond,
+	})
+	addEdge(f.block, then)
+	addEdge(f.block, els)
+	f.block = f.newBlock("unreachable")
+}
+
+func (f *functionBuilder) emitReturn(results ...Value) {
+	if len(results) == 0 {
+		for _, ident := range f.results {
+			results = append(results, f.load(ident))
+		}
+	}
+
+	f.emit(&Return{
+		Results: results,
+	})
+	f.block = f.newBlock("unreachable")
+}
+
+func (f *functionBuilder) writeVariable(obj *object, block *BasicBlock, v Value) {
+	defs, ok := f.defs[obj]
+	if !ok {
+		defs = make(map[*BasicBlock]Value)
+		f.defs[obj] = defs
+	}
+	defs[block] = v
+}
+
+func (f *functionBuilder) readVariable(obj *object, block *BasicBlock) Value {
+	if v, ok := f.defs[obj][block]; ok {
+		return v
+	}
+
+	if f.sealed[block] && len(block.Preds) == 1 {
+		v := f.readVariable(obj, block.Preds[0])
+		f.writeVariable(obj, block, v)
+		return v
+	}
+
+	phi := &Phi{
+		Comment: obj.name,
+		object:  obj,
+	}
+	phi.setBlock(block)
+	n := 0
+	for n < len(block.Instrs) {
+		if _, ok := block.Instrs[n].(*Phi); !ok {
+			break
+		}
+		n++
+	}
+	block.Instrs = append(block.Instrs, nil)
+	copy(block.Instrs[n+1:], block.Instrs[n:])
+	block.Instrs[n] = phi
+
+	f.writeVariable(obj, block, phi)
+	if !f.sealed[block] {
+		f.incomplete[block] = append(f.incomplete[block], phi)
+		return phi
+	}
+
+	f.addPhiOperands(phi)
+	return phi
+}
+
+func (f *functionBuilder) addPhiOperands(phi *Phi) {
+	for _, pred := range phi.block.Preds {
+		phi.Edges = append(phi.Edges, f.readVariable(phi.object, pred))
+	}
+}
+
+func (f *functionBuilder) finish() {
+	f.removeUnreachableBlocks()
+
+	for _, block := range f.fn.Blocks {
+		for i := 0; i < len(f.incomplete[block]); i++ {
+			f.addPhiOperands(f.incomplete[block][i])
+		}
+		f.sealed[block] = true
+	}
+
+	f.removeTrivialPhis()
+
+	num := 0
+	for i, block := range f.fn.Blocks {
+		block.Index = i
+		for _, instr := range block.Instrs {
+			if r, ok := instr.(interface{ setNum(int) }); ok {
+				r.setNum(num)
+				num++
+			}
+		}
+	}
+}
+
+func (f *functionBuilder) removeUnreachableBlocks() {
+	reachable := make(map[*BasicBlock]bool)
+	var visit func(block *BasicBlock)
+	visit = func(block *BasicBlock) {
+		if reachable[block] {
+			return
+		}
+		reachable[block] = true
+		for _, succ := range block.Succs {
+			visit(succ)
+		}
+	}
+	visit(f.fn.Blocks[0])
+
+	blocks := f.fn.Blocks[:0]
+	for _, block := range f.fn.Blocks {
+		if reachable[block] {
+			blocks = append(blocks, block)
+			continue
+		}
+		for _, succ := range block.Succs {
+			preds := succ.Preds[:0]
+			for _, pred := range succ.Preds {
+				if pred != block {
+					preds = append(preds, pred)
+				}
+			}
+			succ.Preds = preds
+		}
+	}
+	f.fn.Blocks = blocks
+}
+
+func (f *functionBuilder) removeTrivialPhis() {
+	for changed := true; changed; {
+		changed = false
+		for _, block := range f.fn.Blocks {
+			for _, instr := range block.Instrs {
+				phi, ok := instr.(*Phi)
+				if !ok {
+					continue
+				}
+				same, trivial := trivialValueOf(phi)
+				if !trivial {
+					continue
+				}
+				f.replaceAll(phi, same)
+				f.removeInstr(phi)
+				changed = true
+				break
+			}
+		}
+	}
+}
+
+func trivialValueOf(phi *Phi) (Value, bool) {
+	var same Value
+	for _, edge := range phi.Edges {
+		if edge == same || edge == phi {
+			continue
+		}
+		if same != nil {
+			return nil, false
+		}
+		same = edge
+	}
+	if same == nil {
+		same = &Zero{
+			Type: "invalid",
+		}
+	}
+
+	return same, true
+}
+
+func (f *functionBuilder) replaceAll(old, new Value) {
+	var rands []*Value
+	for _, block := range f.fn.Blocks {
+		for _, instr := range block.Instrs {
+			rands = instr.Operands(rands[:0])
+			for _, rand := range rands {
+				if *rand == old {
+					*rand = new
+				}
+			}
+		}
+	}
+}
+
+func (f *functionBuilder) removeInstr(instr Instruction) {
+	block := instr.Block()
+	instrs := block.Instrs[:0]
+	for _, i := range block.Instrs {
+		if i != instr {
+			instrs = append(instrs, i)
+		}
+	}
+	block.Instrs = instrs
+}
+
+func (f *functionBuilder) addressOf(obj *object) Value {
+	if addr, ok := f.addrs[obj]; ok {
+		return addr
+	}
+
+	fv := &FreeVar{
+		name: obj.name,
+	}
+	f.fn.FreeVars = append(f.fn.FreeVars, fv)
+	f.freeVars = append(f.freeVars, obj)
+	f.addrs[obj] = fv
+
+	return fv
+}
+
+func (f *functionBuilder) define(ident *ast.Identifier, v Value) {
+	obj := f.objects[ident]
+	if obj == nil {
+		return
+	}
+	if obj.kind == localObject && obj.captured && f.defines[ident] {
+		f.addrs[obj] = f.emitValue(&Alloc{
+			Comment: obj.name,
+		})
+	}
+
+	f.store(obj, v)
+}
+
+func (f *functionBuilder) assign(ident *ast.Identifier, v Value) {
+	if obj := f.objects[ident]; obj != nil {
+		f.store(obj, v)
+	}
+}
+
+func (f *functionBuilder) store(obj *object, v Value) {
+	switch {
+	case obj.kind == globalObject:
+		f.emit(&Store{
+			Addr: f.globals[obj],
+			Val:  v,
+		})
+	case obj.kind == localObject && obj.captured:
+		f.emit(&Store{
+			Addr: f.addressOf(obj),
+			Val:  v,
+		})
+	case obj.kind == localObject:
+		f.writeVariable(obj, f.block, v)
+	}
+}
+
+func (f *functionBuilder) load(ident *ast.Identifier) Value {
+	obj := f.objects[ident]
+	if obj == nil {
+		return universe(ident.Name)
+	}
+
+	switch obj.kind {
+	case globalObject:
+		return f.emitValue(&UnOp{
+			Op: "*",
+			X:  f.globals[obj],
+		})
+	case functionObject:
+		return f.functions[obj]
+	case localObject:
+		if obj.captured {
+			return f.emitValue(&UnOp{
+				Op: "*",
+				X:  f.addressOf(obj),
+			})
+		}
+		return f.readVariable(obj, f.block)
+	default:
+		return &External{
+			name: obj.name,
+		}
+	}
+}
+
+func universe(name string) Value {
+	switch {
+	case name == "true":
+		return &Const{
+			Value: true,
+		}
+	case name == "false":
+		return &Const{
+			Value: false,
+		}
+	case name == "nil":
+		return &Const{
+			Value: nil,
+		}
+	case builtins[name]:
+		return &Builtin{
+			name: name,
+		}
+	default:
+		return &External{
+			name: name,
+		}
+	}
+}
+
+func (f *functionBuilder) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		f.statement(stmt)
+	}
+}
+
+func (f *functionBuilder) statement(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		if call, ok := node.Expression.(*ast.CallExpression); ok && f.isBuiltin(call.Function, "panic") && len(call.Arguments) == 1 {
+			f.emit(&Panic{
+				X: f.expression(call.Arguments[0]),
+			})
+			f.block = f.newBlock("unreachable")
+			return
+		}
+		f.expression(node.Expression)
+	case *ast.VariableDeclaration:
+		var v Value
+		if node.Expression != nil {
+			v = f.expression(node.Expression)
+		} else {
+			v = &Zero{
+				Type: ast.Format(node.Type),
+			}
+		}
+		f.define(node.Identifier, v)
+	case *ast.ShortVariableDeclaration:
+		values := f.expressions(node.Expressions, len(node.Identifiers))
+		for i, ident := range node.Identifiers {
+			f.define(ident, values[i])
+		}
+	case *ast.AssignmentStatement:
+		f.assignmentStatement(node)
+	case *ast.IncDecStatement:
+		op := "+"
+		if node.Operator == ast.Decrement {
+			op = "-"
+		}
+		lhs := f.lvalue(node.Expression)
+		lhs.store(f.emitValue(&BinOp{
+			Op: op,
+			X:  f.expression(node.Expression),
+			Y: &Const{
+				Value: int64(1),
+			},
+		}))
+	case *ast.SendStatement:
+		f.emit(&Send{
+			Chan: f.expression(node.Channel),
+			X:    f.expression(node.Expression),
+		})
+	case *ast.GoStatement:
+		f.emit(&Go{
+			Call: f.callCommon(node.Call),
+		})
+	case *ast.DeferStatement:
+		f.emit(&Defer{
+			Call: f.callCommon(node.Call),
+		})
+	case *ast.ReturnStatement:
+		if node.Expression == nil {
+			f.emitReturn()
+			return
+		}
+		f.emitReturn(f.expression(node.Expression))
+	case *ast.BlockStatement:
+		f.statements(node.Statements)
+	case *ast.ForStatement:
+		f.forStatement(node)
+	case *ast.RangeStatement:
+		f.rangeStatement(node)
+	case *ast.SwitchStatement:
+		f.switchStatement(node)
+	case *ast.TypeSwitchStatement:
+		f.typeSwitchStatement(node)
+	case *ast.SelectStatement:
+		f.selectStatement(node)
+	case *ast.BreakStatement:
+		for t := f.targets; t != nil; t = t.outer {
+			if t.break_ != nil {
+				f.emitJump(t.break_)
+				return
+			}
+		}
+	case *ast.ContinueStatement:
+		for t := f.targets; t != nil; t = t.outer {
+			if t.continue_ != nil {
+				f.emitJump(t.continue_)
+				return
+			}
+		}
+	case *ast.FallthroughStatement:
+		if f.targets != nil && f.targets.fallthrough_ != nil {
+			f.emitJump(f.targets.fallthrough_)
+		}
+	}
+}
+
+func (f *functionBuilder) isBuiltin(node ast.Expression, name string) bool {
+	ident, ok := node.(*ast.Identifier)
+	return ok && ident.Name == name && f.objects[ident] == nil
+}
+
+func (f *functionBuilder) assignmentStatement(node *ast.AssignmentStatement) {
+	lhs := make([]lvalue, len(node.LExpressions))
+	for i, expr := range node.LExpressions {
+		lhs[i] = f.lvalue(expr)
+	}
+	values := f.expressions(node.RExpressions, len(lhs))
+	for i, l := range lhs {
+		l.store(values[i])
+	}
+}
+
+type lvalue struct {
+	store func(v Value)
+}
+
+func (f *functionBuilder) lvalue(node ast.Expression) lvalue {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return lvalue{
+			store: func(v Value) {
+				f.assign(node, v)
+			},
+		}
+	case *ast.IndexExpression:
+		x, index := f.expression(node.Expression), f.index(node.Indices)
+		return lvalue{
+			store: func(v Value) {
+				f.emit(&SetIndex{
+					X:     x,
+					Index: index,
+					Val:   v,
+				})
+			},
+		}
+	case *ast.SelectorExpression:
+		x := f.expression(node.Expression)
+		return lvalue{
+			store: func(v Value) {
+				f.emit(&SetField{
+					X:     x,
+					Field: node.Selector.Name,
+					Val:   v,
+				})
+			},
+		}
+	case *ast.PrefixExpression:
+		addr := f.expression(node.RExpression)
+		return lvalue{
+			store: func(v Value) {
+				f.emit(&Store{
+					Addr: addr,
+					Val:  v,
+				})
+			},
+		}
+	default:
+		return lvalue{
+			store: func(Value) {},
+		}
+	}
+}
+
+func (f *functionBuilder) expressions(nodes []ast.Expression, n int) []Value {
+	if len(nodes) != 1 || n <= 1 {
+		values := make([]Value, len(nodes))
+		for i, node := range nodes {
+			values[i] = f.expression(node)
+		}
+		return values
+	}
+
+	tuple := f.commaOk(nodes[0])
+	values := make([]Value, n)
+	for i := range values {
+		values[i] = f.emitValue(&Extract{
+			Tuple: tuple,
+			Index: i,
+		})
+	}
+
+	return values
+}
+
+func (f *functionBuilder) commaOk(node ast.Expression) Value {
+	switch node := node.(type) {
+	case *ast.TypeAssertionExpression:
+		return f.emitValue(&TypeAssert{
+			X:       f.expression(node.Expression),
+			Type:    assertedType(node),
+			CommaOk: true,
+		})
+	case *ast.IndexExpression:
+		return f.emitValue(&Index{
+			X:       f.expression(node.Expression),
+			Index:   f.index(node.Indices),
+			CommaOk: true,
+		})
+	case *ast.PrefixExpression:
+		if node.Operator == ast.Receive {
+			return f.emitValue(&UnOp{
+				Op:      "<-",
+				X:       f.expression(node.RExpression),
+				CommaOk: true,
+			})
+		}
+	}
+
+	return f.expression(node)
+}
+
+func assertedType(node *ast.TypeAssertionExpression) string {
+	if node.Type == nil {
+		return "type"
+	}
+
+	return node.Type.Name
+}
+
+func (f *functionBuilder) index(nodes []ast.Expression) Value {
+	if len(nodes) == 1 {
+		return f.expression(nodes[0])
+	}
+
+	return &External{
+		name: ast.FormatList(nodes),
+	}
+}
+
+func (f *functionBuilder) forStatement(node *ast.ForStatement) {
+	if node.Initializer != nil {
+		f.statement(node.Initializer)
+	}
+
+	loop, body, done := f.newBlock("for.loop"), f.newBlock("for.body"), f.newBlock("for.done")
+	post := loop
+	if node.Post != nil {
+		post = f.newBlock("for.post")
+	}
+
+	f.emitJump(loop)
+	f.block = loop
+	if node.Condition != nil {
+		f.emitIf(f.expression(node.Condition), body, done)
+	} else {
+		f.emitJump(body)
+	}
+
+	f.block = body
+	f.withTargets(&targets{
+		break_:    done,
+		continue_: post,
+	}, func() {
+		f.statement(node.Body)
+	})
+	f.emitJump(post)
+
+	if node.Post != nil {
+		f.block = post
+		f.statement(node.Post)
+		f.emitJump(loop)
+	}
+
+	f.block = done
+}
+
+func (f *functionBuilder) rangeStatement(node *ast.RangeStatement) {
+	iter := f.emitValue(&Range{
+		X: f.expression(node.Expression),
+	})
+
+	loop, body, done := f.newBlock("range.loop"), f.newBlock("range.body"), f.newBlock("range.done")
+	f.emitJump(loop)
+
+	f.block = loop
+	next := f.emitValue(&Next{
+		Iter: iter,
+	})
+	var key, value Value
+	if node.Key != nil && node.Key.Name != "_" {
+		key = f.emitValue(&Extract{
+			Tuple: next,
+			Index: 1,
+		})
+	}
+	if node.Value != nil && node.Value.Name != "_" {
+		value = f.emitValue(&Extract{
+			Tuple: next,
+			Index: 2,
+		})
+	}
+	f.emitIf(f.emitValue(&Extract{
+		Tuple: next,
+		Index: 0,
+	}), body, done)
+
+	f.block = body
+	for _, bind := range []struct {
+		ident *ast.Identifier
+		value Value
+	}{{node.Key, key}, {node.Value, value}} {
+		if bind.value == nil {
+			continue
+		}
+		if node.IsDefine {
+			f.define(bind.ident, bind.value)
+		} else {
+			f.assign(bind.ident, bind.value)
+		}
+	}
+	f.withTargets(&targets{
+		break_:    done,
+		continue_: loop,
+	}, func() {
+		f.statement(node.Body)
+	})
+	f.emitJump(loop)
+
+	f.block = done
+}
+
+func (f *functionBuilder) switchStatement(node *ast.SwitchStatement) {
+	if node.Initializer != nil {
+		f.statement(node.Initializer)
+	}
+	var tag Value
+	if node.Tag != nil {
+		tag = f.expression(node.Tag)
+	}
+
+	bodies, done := f.newClauseBlocks("switch.body", len(node.Clauses)), f.newBlock("switch.done")
+	deflt := done
+	for i, clause := range node.Clauses {
+		if clause.IsDefault {
+			deflt = bodies[i]
+			continue
+		}
+		for _, expr := range clause.Expressions {
+			cond := f.expression(expr)
+			if tag != nil {
+				cond = f.emitValue(&BinOp{
+					Op: "==",
+					X:  tag,
+					Y:  cond,
+				})
+			}
+			next := f.newBlock("switch.next")
+			f.emitIf(cond, bodies[i], next)
+			f.block = next
+		}
+	}
+	f.emitJump(deflt)
+
+	f.clauses(node.Clauses, bodies, done, nil)
+	f.block = done
+}
+
+func (f *functionBuilder) newClauseBlocks(comment string, n int) []*BasicBlock {
+	blocks := make([]*BasicBlock, n)
+	for i := range blocks {
+		blocks[i] = f.newBlock(comment)
+	}
+
+	return blocks
+}
+
+func (f *functionBuilder) clauses(clauses []*ast.CaseClause, bodies []*BasicBlock, done *BasicBlock, bind func(i int)) {
+	for i, clause := range clauses {
+		f.block = bodies[i]
+		if bind != nil {
+			bind(i)
+		}
+		t := &targets{
+			break_: done,
+		}
+		if i+1 < len(bodies) {
+			t.fallthrough_ = bodies[i+1]
+		}
+		f.withTargets(t, func() {
+			f.statements(clause.Statements)
+		})
+		f.emitJump(done)
+	}
+}
+
+func (f *functionBuilder) typeSwitchStatement(node *ast.TypeSwitchStatement) {
+	if node.Initializer != nil {
+		f.statement(node.Initializer)
+	}
+	x := f.expression(node.Expression)
+
+	bodies, done := f.newClauseBlocks("typeswitch.body", len(node.Clauses)), f.newBlock("typeswitch.done")
+	deflt := done
+	bindings := make([]Value, len(node.Clauses))
+	for i, clause := range node.Clauses {
+		bindings[i] = x
+		if clause.IsDefault {
+			deflt = bodies[i]
+			continue
+		}
+		for _, expr := range clause.Expressions {
+			var cond Value
+			if f.isBuiltin(expr, "nil") {
+				cond = f.emitValue(&BinOp{
+					Op: "==",
+					X:  x,
+					Y: &Const{
+						Value: nil,
+					},
+				})
+			} else {
+				tuple := f.emitValue(&TypeAssert{
+					X:       x,
+					Type:    ast.Format(expr),
+					CommaOk: true,
+				})
+				if len(clause.Expressions) == 1 {
+					bindings[i] = f.emitValue(&Extract{
+						Tuple: tuple,
+						Index: 0,
+					})
+				}
+				cond = f.emitValue(&Extract{
+					Tuple: tuple,
+					Index: 1,
+				})
+			}
+			next := f.newBlock("typeswitch.next")
+			f.emitIf(cond, bodies[i], next)
+			f.block = next
+		}
+	}
+	f.emitJump(deflt)
+
+	f.clauses(node.Clauses, bodies, done, func(i int) {
+		if node.Identifier != nil {
+			f.define(node.Identifier, bindings[i])
+		}
+	})
+	f.block = done
+}
+
+func (f *functionBuilder) selectStatement(node *ast.SelectStatement) {
+	sel := &Select{
+		Blocking: true,
+	}
+	type receipt struct {
+		index int
+		lhs   []*ast.Identifier
+		defn  bool
+		exprs []ast.Expression
+	}
+	receipts := make([]*receipt, len(node.Clauses))
+	cases := make([]int, 0, len(node.Clauses))
+	deflt := -1
+	recvs := 0
+	for i, clause := range node.Clauses {
+		if clause.IsDefault {
+			sel.Blocking = false
+			deflt = i
+			continue
+		}
+
+		var recv ast.Expression
+		r := new(receipt)
+		switch stmt := clause.Communication.(type) {
+		case *ast.SendStatement:
+			sel.States = append(sel.States, &SelectState{
+				Dir:  SendDir,
+				Chan: f.expression(stmt.Channel),
+				Send: f.expression(stmt.Expression),
+			})
+			cases = append(cases, i)
+			continue
+		case *ast.ExpressionStatement:
+			recv = stmt.Expression
+		case *ast.ShortVariableDeclaration:
+			recv, r.lhs, r.defn = stmt.Expressions[0], stmt.Identifiers, true
+		case *ast.AssignmentStatement:
+			recv, r.exprs = stmt.RExpressions[0], stmt.LExpressions
+		}
+		if prefix, ok := recv.(*ast.PrefixExpression); ok {
+			recv = prefix.RExpression
+		}
+		sel.States = append(sel.States, &SelectState{
+			Dir:  RecvDir,
+			Chan: f.expression(recv),
+		})
+		r.index = recvs
+		recvs++
+		receipts[i] = r
+		cases = append(cases, i)
+	}
+
+	result := f.emitValue(sel)
+	index := f.emitValue(&Extract{
+		Tuple: result,
+		Index: 0,
+	})
+
+	bodies, done := f.newClauseBlocks("select.body", len(node.Clauses)), f.newBlock("select.done")
+	for n, i := range cases {
+		cond := f.emitValue(&BinOp{
+			Op: "==",
+			X:  index,
+			Y: &Const{
+				Value: int64(n),
+			},
+		})
+		next := f.newBlock("select.next")
+		f.emitIf(cond, bodies[i], next)
+		f.block = next
+	}
+	if deflt < 0 {
+		f.emitJump(done)
+	} else {
+		f.emitJump(bodies[deflt])
+	}
+
+	for i, clause := range node.Clauses {
+		f.block = bodies[i]
+		if r := receipts[i]; r != nil {
+			values := []Value{
+				f.emitValue(&Extract{
+					Tuple: result,
+					Index: 2 + r.index,
+				}),
+			}
+			if 1 < len(r.lhs) || 1 < len(r.exprs) {
+				values = append(values, f.emitValue(&Extract{
+					Tuple: result,
+					Index: 1,
+				}))
+			}
+			for j, ident := range r.lhs {
+				f.define(ident, values[j])
+			}
+			for j, expr := range r.exprs {
+				f.lvalue(expr).store(values[j])
+			}
+		}
+		f.withTargets(&targets{
+			break_: done,
+		}, func() {
+			f.statements(clause.Statements)
+		})
+		f.emitJump(done)
+	}
+
+	f.block = done
+}
+
+func (f *functionBuilder) expression(node ast.Expression) Value {
+	switch node := node.(type) {
+	case *ast.Integer:
+		return &Const{
+			Value: node.Value,
+		}
+	case *ast.String:
+		return &Const{
+			Value: node.Value,
+		}
+	case *ast.Identifier:
+		return f.load(node)
+	case *ast.PrefixExpression:
+		return f.emitValue(&UnOp{
+			Op: string(node.Operator),
+			X:  f.expression(node.RExpression),
+		})
+	case *ast.InfixExpression:
+		return f.emitValue(&BinOp{
+			Op: string(node.Operator),
+			X:  f.expression(node.LExpression),
+			Y:  f.expression(node.RExpression),
+		})
+	case *ast.SelectorExpression:
+		if ident, ok := node.Expression.(*ast.Identifier); ok {
+			if obj := f.objects[ident]; obj != nil && obj.kind == packageObject {
+				return &External{
+					name: ast.Format(node),
+				}
+			}
+		}
+		return f.emitValue(&Field{
+			X:     f.expression(node.Expression),
+			Field: node.Selector.Name,
+		})
+	case *ast.TypeAssertionExpression:
+		return f.emitValue(&TypeAssert{
+			X:    f.expression(node.Expression),
+			Type: assertedType(node),
+		})
+	case *ast.IndexExpression:
+		return f.emitValue(&Index{
+			X:     f.expression(node.Expression),
+			Index: f.index(node.Indices),
+		})
+	case *ast.SliceExpression:
+		v := &Slice{
+			X: f.expression(node.Expression),
+		}
+		if node.Low != nil {
+			v.Low = f.expression(node.Low)
+		}
+		if node.High != nil {
+			v.High = f.expression(node.High)
+		}
+		return f.emitValue(v)
+	case *ast.CallExpression:
+		return f.emitValue(&Call{
+			Call: f.callCommon(node),
+		})
+	case *ast.FunctionLiteral:
+		return f.functionLiteral(node)
+	case *ast.CompositeLiteral:
+		v := new(CompositeLit)
+		if node.Type != nil {
+			v.Type = ast.Format(node.Type)
+		}
+		for _, elem := range node.Elements {
+			var key Value
+			if elem.Key != nil {
+				key = f.expression(elem.Key)
+			}
+			v.Keys = append(v.Keys, key)
+			v.Values = append(v.Values, f.expression(elem.Value))
+		}
+		return f.emitValue(v)
+	default:
+		return &External{
+			name: ast.Format(node),
+		}
+	}
+}
+
+func (f *functionBuilder) callCommon(node *ast.CallExpression) CallCommon {
+	call := CallCommon{
+		Value:       f.expression(node.Function),
+		HasEllipsis: node.HasEllipsis,
+	}
+	for _, arg := range node.Arguments {
+		call.Args = append(call.Args, f.expression(arg))
+	}
+
+	return call
+}
+
+func (f *functionBuilder) functionLiteral(node *ast.FunctionLiteral) Value {
+	fn := &Function{
+		name:   fmt.Sprintf("%s$%d", f.fn.name, len(f.fn.AnonFuncs)+1),
+		Parent: f.fn,
+	}
+	f.fn.AnonFuncs = append(f.fn.AnonFuncs, fn)
+
+	var body []ast.Statement
+	if node.Body != nil {
+		body = node.Body.Statements
+	}
+	closure := f.buildFunction(fn, node, node.Parameters, node.Results, body)
+	if len(closure.freeVars) == 0 {
+		return fn
+	}
+
+	bindings := make([]Value, len(closure.freeVars))
+	for i, obj := range closure.freeVars {
+		bindings[i] = f.addressOf(obj)
+	}
+
+	return f.emitValue(&MakeClosure{
+		Fn:       fn,
+		Bindings: bindings,
+	})
+}
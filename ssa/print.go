@@ -0,0 +1,68 @@
+package ssa
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func (p *Program) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, g := range p.Globals {
+		fmt.Fprintf(&buf, "var %s\n", g.Name())
+	}
+	if 0 < len(p.Globals) {
+		buf.WriteString("\n")
+	}
+	for i, fn := range p.Functions {
+		if 0 < i {
+			buf.WriteString("\n")
+		}
+		writeFunction(&buf, fn)
+	}
+
+	return buf.WriteTo(w)
+}
+
+func (f *Function) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	writeFunction(&buf, f)
+
+	return buf.WriteTo(w)
+}
+
+func writeFunction(buf *bytes.Buffer, f *Function) {
+	if f.Parent != nil {
+		fmt.Fprintf(buf, "# Parent: %s\n", f.Parent.Name())
+	}
+	if 0 < len(f.FreeVars) {
+		buf.WriteString("# Free variables:\n")
+		for i, fv := range f.FreeVars {
+			fmt.Fprintf(buf, "# % 3d:\t%s\n", i, fv.Name())
+		}
+	}
+
+	params := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		params[i] = param.Name()
+	}
+	fmt.Fprintf(buf, "func %s(%s):\n", f.Name(), strings.Join(params, ", "))
+
+	for _, block := range f.Blocks {
+		fmt.Fprintf(buf, "%d: %s P:%d S:%d\n", block.Index, block.Comment, len(block.Preds), len(block.Succs))
+		for _, instr := range block.Instrs {
+			buf.WriteString("\t")
+			if v, ok := instr.(Value); ok {
+				fmt.Fprintf(buf, "%s = ", v.Name())
+			}
+			buf.WriteString(instr.String())
+			buf.WriteString("\n")
+		}
+	}
+
+	for _, anon := range f.AnonFuncs {
+		buf.WriteString("\n")
+		writeFunction(buf, anon)
+	}
+}
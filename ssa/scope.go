@@ -0,0 +1,344 @@
+package ssa
+
+import (
+	"path"
+
+	"github.com/tomocy/kinako/ast"
+)
+
+type object struct {
+	kind     objectKind
+	name     string
+	owner    ast.Node
+	captured bool
+}
+
+type objectKind int
+
+const (
+	localObject objectKind = iota
+	globalObject
+	functionObject
+	typeObject
+	packageObject
+)
+
+type scope struct {
+	outer   *scope
+	objects map[string]*object
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{
+		outer:   outer,
+		objects: make(map[string]*object),
+	}
+}
+
+func (s *scope) lookUp(name string) *object {
+	for ; s != nil; s = s.outer {
+		if obj, ok := s.objects[name]; ok {
+			return obj
+		}
+	}
+
+	return nil
+}
+
+type analyzer struct {
+	scope   *scope
+	owner   ast.Node
+	objects map[*ast.Identifier]*object
+	defines map[*ast.Identifier]bool
+}
+
+func analyze(program *ast.Program) *analyzer {
+	a := &analyzer{
+		scope:   newScope(nil),
+		objects: make(map[*ast.Identifier]*object),
+		defines: make(map[*ast.Identifier]bool),
+	}
+
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.BadStatement); ok {
+			break
+		}
+		a.predeclare(stmt)
+	}
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.BadStatement); ok {
+			break
+		}
+		a.analyzeStatement(stmt)
+	}
+
+	return a
+}
+
+func (a *analyzer) predeclare(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.VariableDeclaration:
+		a.declare(node.Identifier, globalObject)
+	case *ast.ShortVariableDeclaration:
+		for _, ident := range node.Identifiers {
+			a.declare(ident, globalObject)
+		}
+	case *ast.FunctionDeclaration:
+		a.declare(node.Identifier, functionObject)
+	case *ast.TypeDeclaration:
+		a.declare(node.Identifier, typeObject)
+	case *ast.ImportDeclaration:
+		for _, spec := range node.Specs {
+			name := path.Base(spec.Path.Value)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name != "_" {
+				a.scope.objects[name] = &object{
+					kind: packageObject,
+					name: name,
+				}
+			}
+		}
+	}
+}
+
+func (a *analyzer) declare(ident *ast.Identifier, kind objectKind) {
+	if ident.Name == "_" {
+		return
+	}
+
+	obj, ok := a.scope.objects[ident.Name]
+	if !ok {
+		obj = &object{
+			kind:  kind,
+			name:  ident.Name,
+			owner: a.owner,
+		}
+		a.scope.objects[ident.Name] = obj
+	}
+	a.objects[ident] = obj
+	a.defines[ident] = !ok || a.scope.outer == nil
+}
+
+func (a *analyzer) withScope(analyze func()) {
+	outer := a.scope
+	a.scope = newScope(outer)
+	defer func() {
+		a.scope = outer
+	}()
+
+	analyze()
+}
+
+func (a *analyzer) analyzeStatements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		a.analyzeStatement(stmt)
+	}
+}
+
+func (a *analyzer) analyzeStatement(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		a.analyzeExpression(node.Expression)
+	case *ast.VariableDeclaration:
+		a.analyzeExpression(node.Expression)
+		a.declare(node.Identifier, localObject)
+	case *ast.ShortVariableDeclaration:
+		a.analyzeExpressions(node.Expressions)
+		for _, ident := range node.Identifiers {
+			a.declare(ident, localObject)
+		}
+	case *ast.AssignmentStatement:
+		a.analyzeExpressions(node.RExpressions)
+		a.analyzeExpressions(node.LExpressions)
+	case *ast.IncDecStatement:
+		a.analyzeExpression(node.Expression)
+	case *ast.SendStatement:
+		a.analyzeExpression(node.Channel)
+		a.analyzeExpression(node.Expression)
+	case *ast.GoStatement:
+		a.analyzeExpression(node.Call)
+	case *ast.DeferStatement:
+		a.analyzeExpression(node.Call)
+	case *ast.ReturnStatement:
+		a.analyzeExpression(node.Expression)
+	case *ast.BlockStatement:
+		a.withScope(func() {
+			a.analyzeStatements(node.Statements)
+		})
+	case *ast.ForStatement:
+		a.withScope(func() {
+			if node.Initializer != nil {
+				a.analyzeStatement(node.Initializer)
+			}
+			a.analyzeExpression(node.Condition)
+			if node.Post != nil {
+				a.analyzeStatement(node.Post)
+			}
+			a.analyzeStatement(node.Body)
+		})
+	case *ast.RangeStatement:
+		a.analyzeRangeStatement(node)
+	case *ast.SwitchStatement:
+		a.analyzeSwitchStatement(node)
+	case *ast.TypeSwitchStatement:
+		a.analyzeTypeSwitchStatement(node)
+	case *ast.SelectStatement:
+		a.analyzeSelectStatement(node)
+	case *ast.FunctionDeclaration:
+		a.declare(node.Identifier, functionObject)
+		a.analyzeFunction(node, node.TypeParameters, node.Parameters, node.Results, node.Body)
+	case *ast.TypeDeclaration:
+		a.declare(node.Identifier, typeObject)
+	}
+}
+
+func (a *analyzer) analyzeRangeStatement(node *ast.RangeStatement) {
+	a.analyzeExpression(node.Expression)
+	if !node.IsDefine {
+		for _, ident := range []*ast.Identifier{node.Key, node.Value} {
+			if ident != nil {
+				a.analyzeExpression(ident)
+			}
+		}
+	}
+
+	a.withScope(func() {
+		if node.IsDefine {
+			for _, ident := range []*ast.Identifier{node.Key, node.Value} {
+				if ident != nil {
+					a.declare(ident, localObject)
+				}
+			}
+		}
+		a.analyzeStatement(node.Body)
+	})
+}
+
+func (a *analyzer) analyzeSwitchStatement(node *ast.SwitchStatement) {
+	a.withScope(func() {
+		if node.Initializer != nil {
+			a.analyzeStatement(node.Initializer)
+		}
+		a.analyzeExpression(node.Tag)
+		for _, clause := range node.Clauses {
+			a.analyzeExpressions(clause.Expressions)
+			a.withScope(func() {
+				a.analyzeStatements(clause.Statements)
+			})
+		}
+	})
+}
+
+func (a *analyzer) analyzeTypeSwitchStatement(node *ast.TypeSwitchStatement) {
+	a.withScope(func() {
+		if node.Initializer != nil {
+			a.analyzeStatement(node.Initializer)
+		}
+		a.analyzeExpression(node.Expression)
+		a.withScope(func() {
+			if node.Identifier != nil {
+				a.declare(node.Identifier, localObject)
+			}
+			for _, clause := range node.Clauses {
+				a.withScope(func() {
+					a.analyzeStatements(clause.Statements)
+				})
+			}
+		})
+	})
+}
+
+func (a *analyzer) analyzeSelectStatement(node *ast.SelectStatement) {
+	for _, clause := range node.Clauses {
+		switch stmt := clause.Communication.(type) {
+		case *ast.ShortVariableDeclaration:
+			a.analyzeExpressions(stmt.Expressions)
+		case nil:
+		default:
+			a.analyzeStatement(stmt)
+		}
+
+		a.withScope(func() {
+			if stmt, ok := clause.Communication.(*ast.ShortVariableDeclaration); ok {
+				for _, ident := range stmt.Identifiers {
+					a.declare(ident, localObject)
+				}
+			}
+			a.analyzeStatements(clause.Statements)
+		})
+	}
+}
+
+func (a *analyzer) analyzeFunction(node ast.Node, typeParams, params, results []*ast.Parameter, body *ast.BlockStatement) {
+	outer := a.owner
+	a.owner = node
+	defer func() {
+		a.owner = outer
+	}()
+
+	a.withScope(func() {
+		for _, param := range typeParams {
+			a.declare(param.Identifier, typeObject)
+		}
+		for _, param := range params {
+			a.declare(param.Identifier, localObject)
+		}
+		for _, result := range results {
+			if result.Identifier != nil {
+				a.declare(result.Identifier, localObject)
+			}
+		}
+
+		if body != nil {
+			a.analyzeStatements(body.Statements)
+		}
+	})
+}
+
+func (a *analyzer) analyzeExpressions(nodes []ast.Expression) {
+	for _, node := range nodes {
+		a.analyzeExpression(node)
+	}
+}
+
+func (a *analyzer) analyzeExpression(node ast.Expression) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		obj := a.scope.lookUp(node.Name)
+		if obj == nil {
+			return
+		}
+		if obj.kind == localObject && obj.owner != a.owner {
+			obj.captured = true
+		}
+		a.objects[node] = obj
+	case *ast.PrefixExpression:
+		a.analyzeExpression(node.RExpression)
+	case *ast.InfixExpression:
+		a.analyzeExpression(node.LExpression)
+		a.analyzeExpression(node.RExpression)
+	case *ast.SelectorExpression:
+		a.analyzeExpression(node.Expression)
+	case *ast.TypeAssertionExpression:
+		a.analyzeExpression(node.Expression)
+	case *ast.IndexExpression:
+		a.analyzeExpression(node.Expression)
+		a.analyzeExpressions(node.Indices)
+	case *ast.SliceExpression:
+		a.analyzeExpression(node.Expression)
+		a.analyzeExpression(node.Low)
+		a.analyzeExpression(node.High)
+	case *ast.CallExpression:
+		a.analyzeExpression(node.Function)
+		a.analyzeExpressions(node.Arguments)
+	case *ast.FunctionLiteral:
+		a.analyzeFunction(node, nil, node.Parameters, node.Results, node.Body)
+	case *ast.CompositeLiteral:
+		for _, elem := range node.Elements {
+			a.analyzeExpression(elem.Key)
+			a.analyzeExpression(elem.Value)
+		}
+	}
+}
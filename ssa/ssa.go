@@ -0,0 +1,641 @@
+package ssa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Program struct {
+	Globals   []*Global
+	Functions []*Function
+}
+
+type Function struct {
+	name      string
+	Params    []*Parameter
+	FreeVars  []*FreeVar
+	Blocks    []*BasicBlock
+	AnonFuncs []*Function
+	Parent    *Function
+}
+
+func (f *Function) Name() string {
+	return f.name
+}
+
+func (f *Function) String() string {
+	return f.name
+}
+
+type BasicBlock struct {
+	Index   int
+	Comment string
+	Instrs  []Instruction
+	Preds   []*BasicBlock
+	Succs   []*BasicBlock
+	parent  *Function
+}
+
+func (b *BasicBlock) String() string {
+	return strconv.Itoa(b.Index)
+}
+
+type Value interface {
+	Name() string
+	String() string
+}
+
+type Instruction interface {
+	String() string
+	Block() *BasicBlock
+	Operands(rands []*Value) []*Value
+	setBlock(*BasicBlock)
+}
+
+type anInstruction struct {
+	block *BasicBlock
+}
+
+func (i *anInstruction) Block() *BasicBlock {
+	return i.block
+}
+
+func (i *anInstruction) setBlock(b *BasicBlock) {
+	i.block = b
+}
+
+type register struct {
+	anInstruction
+	num int
+}
+
+func (r *register) Name() string {
+	return fmt.Sprintf("t%d", r.num)
+}
+
+func (r *register) setNum(num int) {
+	r.num = num
+}
+
+type Const struct {
+	Value interface{}
+}
+
+func (c *Const) Name() string {
+	switch v := c.Value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (c *Const) String() string {
+	return c.Name()
+}
+
+type Parameter struct {
+	name string
+}
+
+func (p *Parameter) Name() string {
+	return p.name
+}
+
+func (p *Parameter) String() string {
+	return p.name
+}
+
+type FreeVar struct {
+	name string
+}
+
+func (v *FreeVar) Name() string {
+	return v.name
+}
+
+func (v *FreeVar) String() string {
+	return v.name
+}
+
+type Global struct {
+	name string
+}
+
+func (g *Global) Name() string {
+	return g.name
+}
+
+func (g *Global) String() string {
+	return g.name
+}
+
+type Builtin struct {
+	name string
+}
+
+func (b *Builtin) Name() string {
+	return b.name
+}
+
+func (b *Builtin) String() string {
+	return b.name
+}
+
+type External struct {
+	name string
+}
+
+func (e *External) Name() string {
+	return e.name
+}
+
+func (e *External) String() string {
+	return e.name
+}
+
+type Zero struct {
+	Type string
+}
+
+func (z *Zero) Name() string {
+	return fmt.Sprintf("zero(%s)", z.Type)
+}
+
+func (z *Zero) String() string {
+	return z.Name()
+}
+
+type Alloc struct {
+	register
+	Comment string
+}
+
+func (v *Alloc) String() string {
+	return fmt.Sprintf("local %s", v.Comment)
+}
+
+func (v *Alloc) Operands(rands []*Value) []*Value {
+	return rands
+}
+
+type Phi struct {
+	register
+	Edges   []Value
+	Comment string
+	object  *object
+}
+
+func (v *Phi) String() string {
+	edges := make([]string, len(v.Edges))
+	for i, edge := range v.Edges {
+		edges[i] = fmt.Sprintf("%d: %s", v.block.Preds[i].Index, nameOf(edge))
+	}
+
+	return fmt.Sprintf("phi [%s] #%s", strings.Join(edges, ", "), v.Comment)
+}
+
+func (v *Phi) Operands(rands []*Value) []*Value {
+	for i := range v.Edges {
+		rands = append(rands, &v.Edges[i])
+	}
+
+	return rands
+}
+
+type UnOp struct {
+	register
+	Op      string
+	X       Value
+	CommaOk bool
+}
+
+func (v *UnOp) String() string {
+	if v.CommaOk {
+		return fmt.Sprintf("%s%s,ok", v.Op, nameOf(v.X))
+	}
+
+	return v.Op + nameOf(v.X)
+}
+
+func (v *UnOp) Operands(rands []*Value) []*Value {
+	return append(rands, &v.X)
+}
+
+type BinOp struct {
+	register
+	Op   string
+	X, Y Value
+}
+
+func (v *BinOp) String() string {
+	return fmt.Sprintf("%s %s %s", nameOf(v.X), v.Op, nameOf(v.Y))
+}
+
+func (v *BinOp) Operands(rands []*Value) []*Value {
+	return append(rands, &v.X, &v.Y)
+}
+
+type CallCommon struct {
+	Value       Value
+	Args        []Value
+	HasEllipsis bool
+}
+
+func (c *CallCommon) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = nameOf(arg)
+	}
+	if c.HasEllipsis {
+		args[len(args)-1] += "..."
+	}
+
+	return fmt.Sprintf("%s(%s)", nameOf(c.Value), strings.Join(args, ", "))
+}
+
+func (c *CallCommon) operands(rands []*Value) []*Value {
+	rands = append(rands, &c.Value)
+	for i := range c.Args {
+		rands = append(rands, &c.Args[i])
+	}
+
+	return rands
+}
+
+type Call struct {
+	register
+	Call CallCommon
+}
+
+func (v *Call) String() string {
+	return v.Call.String()
+}
+
+func (v *Call) Operands(rands []*Value) []*Value {
+	return v.Call.operands(rands)
+}
+
+type Extract struct {
+	register
+	Tuple Value
+	Index int
+}
+
+func (v *Extract) String() string {
+	return fmt.Sprintf("extract %s #%d", nameOf(v.Tuple), v.Index)
+}
+
+func (v *Extract) Operands(rands []*Value) []*Value {
+	return append(rands, &v.Tuple)
+}
+
+type Index struct {
+	register
+	X, Index Value
+	CommaOk  bool
+}
+
+func (v *Index) String() string {
+	s := fmt.Sprintf("%s[%s]", nameOf(v.X), nameOf(v.Index))
+	if v.CommaOk {
+		s += ",ok"
+	}
+
+	return s
+}
+
+func (v *Index) Operands(rands []*Value) []*Value {
+	return append(rands, &v.X, &v.Index)
+}
+
+type Slice struct {
+	register
+	X, Low, High Value
+}
+
+func (v *Slice) String() string {
+	var low, high string
+	if v.Low != nil {
+		low = nameOf(v.Low)
+	}
+	if v.High != nil {
+		high = nameOf(v.High)
+	}
+
+	return fmt.Sprintf("slice %s[%s:%s]", nameOf(v.X), low, high)
+}
+
+func (v *Slice) Operands(rands []*Value) []*Value {
+	return append(rands, &v.X, &v.Low, &v.High)
+}
+
+type Field struct {
+	register
+	X     Value
+	Field string
+}
+
+func (v *Field) String() string {
+	return fmt.Sprintf("%s.%s", nameOf(v.X), v.Field)
+}
+
+func (v *Field) Operands(rands []*Value) []*Value {
+	return append(rands, &v.X)
+}
+
+type TypeAssert struct {
+	register
+	X       Value
+	Type    string
+	CommaOk bool
+}
+
+func (v *TypeAssert) String() string {
+	s := fmt.Sprintf("typeassert %s.(%s)", nameOf(v.X), v.Type)
+	if v.CommaOk {
+		s += ",ok"
+	}
+
+	return s
+}
+
+func (v *TypeAssert) Operands(rands []*Value) []*Value {
+	return append(rands, &v.X)
+}
+
+type CompositeLit struct {
+	register
+	Type   string
+	Keys   []Value
+	Values []Value
+}
+
+func (v *CompositeLit) String() string {
+	elems := make([]string, len(v.Values))
+	for i, value := range v.Values {
+		elems[i] = nameOf(value)
+		if v.Keys[i] != nil {
+			elems[i] = fmt.Sprintf("%s: %s", nameOf(v.Keys[i]), elems[i])
+		}
+	}
+
+	return fmt.Sprintf("%s{%s}", v.Type, strings.Join(elems, ", "))
+}
+
+func (v *CompositeLit) Operands(rands []*Value) []*Value {
+	for i := range v.Keys {
+		rands = append(rands, &v.Keys[i], &v.Values[i])
+	}
+
+	return rands
+}
+
+type MakeClosure struct {
+	register
+	Fn       *Function
+	Bindings []Value
+}
+
+func (v *MakeClosure) String() string {
+	bindings := make([]string, len(v.Bindings))
+	for i, binding := range v.Bindings {
+		bindings[i] = nameOf(binding)
+	}
+
+	return fmt.Sprintf("make closure %s [%s]", v.Fn.Name(), strings.Join(bindings, ", "))
+}
+
+func (v *MakeClosure) Operands(rands []*Value) []*Value {
+	for i := range v.Bindings {
+		rands = append(rands, &v.Bindings[i])
+	}
+
+	return rands
+}
+
+type Range struct {
+	register
+	X Value
+}
+
+func (v *Range) String() string {
+	return "range " + nameOf(v.X)
+}
+
+func (v *Range) Operands(rands []*Value) []*Value {
+	return append(rands, &v.X)
+}
+
+type Next struct {
+	register
+	Iter Value
+}
+
+func (v *Next) String() string {
+	return "next " + nameOf(v.Iter)
+}
+
+func (v *Next) Operands(rands []*Value) []*Value {
+	return append(rands, &v.Iter)
+}
+
+type SelectDir int
+
+const (
+	RecvDir SelectDir = iota
+	SendDir
+)
+
+type SelectState struct {
+	Dir  SelectDir
+	Chan Value
+	Send Value
+}
+
+type Select struct {
+	register
+	States   []*SelectState
+	Blocking bool
+}
+
+func (v *Select) String() string {
+	states := make([]string, len(v.States))
+	for i, state := range v.States {
+		if state.Dir == RecvDir {
+			states[i] = "<-" + nameOf(state.Chan)
+			continue
+		}
+		states[i] = fmt.Sprintf("%s<-%s", nameOf(state.Chan), nameOf(state.Send))
+	}
+	kind := "nonblocking"
+	if v.Blocking {
+		kind = "blocking"
+	}
+
+	return fmt.Sprintf("select %s [%s]", kind, strings.Join(states, ", "))
+}
+
+func (v *Select) Operands(rands []*Value) []*Value {
+	for _, state := range v.States {
+		rands = append(rands, &state.Chan, &state.Send)
+	}
+
+	return rands
+}
+
+type Store struct {
+	anInstruction
+	Addr Value
+	Val  Value
+}
+
+func (i *Store) String() string {
+	return fmt.Sprintf("*%s = %s", nameOf(i.Addr), nameOf(i.Val))
+}
+
+func (i *Store) Operands(rands []*Value) []*Value {
+	return append(rands, &i.Addr, &i.Val)
+}
+
+type SetIndex struct {
+	anInstruction
+	X, Index, Val Value
+}
+
+func (i *SetIndex) String() string {
+	return fmt.Sprintf("%s[%s] = %s", nameOf(i.X), nameOf(i.Index), nameOf(i.Val))
+}
+
+func (i *SetIndex) Operands(rands []*Value) []*Value {
+	return append(rands, &i.X, &i.Index, &i.Val)
+}
+
+type SetField struct {
+	anInstruction
+	X     Value
+	Field string
+	Val   Value
+}
+
+func (i *SetField) String() string {
+	return fmt.Sprintf("%s.%s = %s", nameOf(i.X), i.Field, nameOf(i.Val))
+}
+
+func (i *SetField) Operands(rands []*Value) []*Value {
+	return append(rands, &i.X, &i.Val)
+}
+
+type Send struct {
+	anInstruction
+	Chan, X Value
+}
+
+func (i *Send) String() string {
+	return fmt.Sprintf("send %s <- %s", nameOf(i.Chan), nameOf(i.X))
+}
+
+func (i *Send) Operands(rands []*Value) []*Value {
+	return append(rands, &i.Chan, &i.X)
+}
+
+type Go struct {
+	anInstruction
+	Call CallCommon
+}
+
+func (i *Go) String() string {
+	return "go " + i.Call.String()
+}
+
+func (i *Go) Operands(rands []*Value) []*Value {
+	return i.Call.operands(rands)
+}
+
+type Defer struct {
+	anInstruction
+	Call CallCommon
+}
+
+func (i *Defer) String() string {
+	return "defer " + i.Call.String()
+}
+
+func (i *Defer) Operands(rands []*Value) []*Value {
+	return i.Call.operands(rands)
+}
+
+type Jump struct {
+	anInstruction
+}
+
+func (i *Jump) String() string {
+	return fmt.Sprintf("jump %d", i.block.Succs[0].Index)
+}
+
+func (i *Jump) Operands(rands []*Value) []*Value {
+	return rands
+}
+
+type If struct {
+	anInstruction
+	Cond Value
+}
+
+func (i *If) String() string {
+	return fmt.Sprintf("if %s goto %d else %d", nameOf(i.Cond), i.block.Succs[0].Index, i.block.Succs[1].Index)
+}
+
+func (i *If) Operands(rands []*Value) []*Value {
+	return append(rands, &i.Cond)
+}
+
+type Return struct {
+	anInstruction
+	Results []Value
+}
+
+func (i *Return) String() string {
+	if len(i.Results) == 0 {
+		return "return"
+	}
+
+	results := make([]string, len(i.Results))
+	for j, result := range i.Results {
+		results[j] = nameOf(result)
+	}
+
+	return "return " + strings.Join(results, ", ")
+}
+
+func (i *Return) Operands(rands []*Value) []*Value {
+	for j := range i.Results {
+		rands = append(rands, &i.Results[j])
+	}
+
+	return rands
+}
+
+type Panic struct {
+	anInstruction
+	X Value
+}
+
+func (i *Panic) String() string {
+	return "panic " + nameOf(i.X)
+}
+
+func (i *Panic) Operands(rands []*Value) []*Value {
+	return append(rands, &i.X)
+}
+
+func nameOf(v Value) string {
+	if v == nil {
+		return "nil"
+	}
+
+	return v.Name()
+}
@@ -0,0 +1,172 @@
+package ssa
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/parser"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"func add(a, b int) int { return a + b; }",
+			`
+func add(a, b):
+0: entry P:0 S:0
+	t0 = a + b
+	return t0
+`,
+		},
+		{
+			"func sum(n int) int { s := 0; for i := 0; i < n; i++ { s = s + i; } return s; }",
+			`
+func sum(n):
+0: entry P:0 S:1
+	jump 1
+1: for.loop P:2 S:2
+	t0 = phi [0: 0, 4: t4] #i
+	t1 = phi [0: 0, 4: t3] #s
+	t2 = t0 < n
+	if t2 goto 2 else 3
+2: for.body P:1 S:1
+	t3 = t1 + t0
+	jump 4
+3: for.done P:1 S:0
+	return t1
+4: for.post P:1 S:1
+	t4 = t0 + 1
+	jump 1
+`,
+		},
+		{
+			"func f(x int) int { switch x { case 1: x = 10; fallthrough; case 2: return x; } return 0; }",
+			`
+func f(x):
+0: entry P:0 S:2
+	t0 = x == 1
+	if t0 goto 1 else 4
+1: switch.body P:1 S:1
+	jump 2
+2: switch.body P:2 S:0
+	t1 = phi [4: x, 1: 10] #x
+	return t1
+3: switch.done P:1 S:0
+	return 0
+4: switch.next P:1 S:2
+	t2 = x == 2
+	if t2 goto 2 else 5
+5: switch.next P:1 S:1
+	jump 3
+`,
+		},
+		{
+			"func f() int { y := 1; switch y := 100; y { case 100: return y; } return y; }",
+			`
+func f():
+0: entry P:0 S:2
+	t0 = 100 == 100
+	if t0 goto 1 else 3
+1: switch.body P:1 S:0
+	return 100
+2: switch.done P:1 S:0
+	return 1
+3: switch.next P:1 S:1
+	jump 2
+`,
+		},
+		{
+			"func f(s []int) (n int) { for _, v := range s { n = n + v; } return; }",
+			`
+func f(s):
+0: entry P:0 S:1
+	t0 = range s
+	jump 1
+1: range.loop P:2 S:2
+	t1 = phi [0: zero(int), 2: t5] #n
+	t2 = next t0
+	t3 = extract t2 #2
+	t4 = extract t2 #0
+	if t4 goto 2 else 3
+2: range.body P:1 S:1
+	t5 = t1 + t3
+	jump 1
+3: range.done P:1 S:0
+	return t1
+`,
+		},
+		{
+			"func f() int { c := 0; g := func() { c++; }; g(); return c; }",
+			`
+func f():
+0: entry P:0 S:0
+	t0 = local c
+	*t0 = 0
+	t1 = make closure f$1 [t0]
+	t2 = t1()
+	t3 = *t0
+	return t3
+
+# Parent: f
+# Free variables:
+#   0:	c
+func f$1():
+0: entry P:0 S:0
+	t0 = *c
+	t1 = t0 + 1
+	*c = t1
+	return
+`,
+		},
+		{
+			"func f() { panic(\"a\"); g(); }",
+			`
+func f():
+0: entry P:0 S:0
+	panic "a"
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+
+			var buf bytes.Buffer
+			if _, err := New().Build(program).Functions[1].WriteTo(&buf); err != nil {
+				t.Fatalf("unexpected error: %s\n", err)
+			}
+			if actual, expected := buf.String(), strings.TrimPrefix(test.expected, "\n"); actual != expected {
+				t.Errorf("unexpected function: got\n%s\nbut expected\n%s\n", actual, expected)
+			}
+		})
+	}
+}
+
+func TestBuildTopLevel(t *testing.T) {
+	program := parser.New(lexer.New(`import "fmt"; x := 1; x++; fmt.Println(x);`)).ParseProgram()
+
+	var buf bytes.Buffer
+	if _, err := New().Build(program).WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %s\n", err)
+	}
+	expected := `var x
+
+func init():
+0: entry P:0 S:0
+	*x = 1
+	t0 = *x
+	t1 = t0 + 1
+	*x = t1
+	t2 = *x
+	t3 = fmt.Println(t2)
+	return
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("unexpected program: got\n%s\nbut expected\n%s\n", actual, expected)
+	}
+}
@@ -1,10 +1,36 @@
 package token
 
+import (
+	"fmt"
+)
+
 type Token struct {
 	Type    Type
 	Literal string
 }
 
+type Position struct {
+	Line   int
+	Column int
+}
+
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (p Position) IsValid() bool {
+	return 0 < p.Line
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 type Type string
 
 const (
@@ -19,29 +45,91 @@ const (
 
 	Not = "Not"
 
-	Assign = "Assign"
+	Equal              = "Equal"
+	NotEqual           = "NotEqual"
+	LessThan           = "LessThan"
+	GreaterThan        = "GreaterThan"
+	LessThanOrEqual    = "LessThanOrEqual"
+	GreaterThanOrEqual = "GreaterThanOrEqual"
 
-	Semicolon = ";"
+	Assign    = "Assign"
+	Define    = "Define"
+	Increment = "Increment"
+	Decrement = "Decrement"
+	Arrow     = "Arrow"
+
+	Pipe  = "Pipe"
+	Tilde = "Tilde"
 
-	LParen = "LParen"
-	RParen = "RParen"
+	Semicolon = ";"
+	Colon     = "Colon"
+	Comma     = "Comma"
+	Period    = "Period"
+	Ellipsis  = "Ellipsis"
+
+	LParen   = "LParen"
+	RParen   = "RParen"
+	LBrace   = "LBrace"
+	RBrace   = "RBrace"
+	LBracket = "LBracket"
+	RBracket = "RBracket"
 
 	Identifier = "Identifier"
 	Integer    = "Integer"
-
-	Var = "var"
+	String     = "String"
+
+	Var         = "var"
+	TypeKeyword = "type"
+	Switch      = "switch"
+	Case        = "case"
+	Default     = "default"
+	Fallthrough = "fallthrough"
+	Break       = "break"
+	Func        = "func"
+	Return      = "return"
+	Defer       = "defer"
+	Go          = "go"
+	Chan        = "chan"
+	Select      = "select"
+	For         = "for"
+	Range       = "range"
+	Continue    = "continue"
+	Interface   = "interface"
+	Map         = "map"
+	Import      = "import"
+	Package     = "package"
 )
 
 var types = map[string]Type{
-	"+": Plus,
-	"-": Minus,
-	"*": Asterisk,
-	"/": Slash,
-	"!": Not,
-	"=": Assign,
-	";": Semicolon,
-	"(": LParen,
-	")": RParen,
+	"+":   Plus,
+	"-":   Minus,
+	"*":   Asterisk,
+	"/":   Slash,
+	"!":   Not,
+	"==":  Equal,
+	"!=":  NotEqual,
+	"<":   LessThan,
+	">":   GreaterThan,
+	"<=":  LessThanOrEqual,
+	">=":  GreaterThanOrEqual,
+	"=":   Assign,
+	":=":  Define,
+	"++":  Increment,
+	"--":  Decrement,
+	"<-":  Arrow,
+	"|":   Pipe,
+	"~":   Tilde,
+	";":   Semicolon,
+	":":   Colon,
+	",":   Comma,
+	".":   Period,
+	"...": Ellipsis,
+	"(":   LParen,
+	")":   RParen,
+	"{":   LBrace,
+	"}":   RBrace,
+	"[":   LBracket,
+	"]":   RBracket,
 }
 
 func LookUpType(s string) Type {
@@ -53,7 +141,26 @@ func LookUpType(s string) Type {
 }
 
 var keywords = map[string]Type{
-	"var": Var,
+	"var":         Var,
+	"type":        TypeKeyword,
+	"switch":      Switch,
+	"case":        Case,
+	"default":     Default,
+	"fallthrough": Fallthrough,
+	"break":       Break,
+	"func":        Func,
+	"return":      Return,
+	"defer":       Defer,
+	"go":          Go,
+	"chan":        Chan,
+	"select":      Select,
+	"for":         For,
+	"range":       Range,
+	"continue":    Continue,
+	"interface":   Interface,
+	"map":         Map,
+	"import":      Import,
+	"package":     Package,
 }
 
 func LookUpKeywordOrIdentifier(s string) Type {
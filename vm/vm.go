@@ -0,0 +1,25 @@
+package vm
+
+import (
+	"github.com/tomocy/kinako/compiler"
+	"github.com/tomocy/kinako/evaluator"
+	"github.com/tomocy/kinako/object"
+)
+
+type VM struct {
+	evaluator *evaluator.Evaluator
+}
+
+func New(opts ...evaluator.Option) *VM {
+	return &VM{
+		evaluator: evaluator.New(opts...),
+	}
+}
+
+func (vm *VM) Evaluator() *evaluator.Evaluator {
+	return vm.evaluator
+}
+
+func (vm *VM) Run(bytecode *compiler.Bytecode) object.Object {
+	return vm.evaluator.RunBytecode(bytecode)
+}
@@ -0,0 +1,111 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tomocy/kinako/compiler"
+	"github.com/tomocy/kinako/evaluator"
+	"github.com/tomocy/kinako/lexer"
+	"github.com/tomocy/kinako/object"
+	"github.com/tomocy/kinako/parser"
+	"github.com/tomocy/kinako/token"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x := 0; for i := 0; i < 10; i++ { x = x + i; } x;", "45"},
+		{"x := 0; for i := 0; i < 10; i++ { switch i { case 5: break; } x = x + 1; } x;", "10"},
+		{"x := 0; for i := 0; i < 10; i++ { x = x + 1; switch i { case 5: continue; } break; } x;", "1"},
+		{"x := 0; for i := 0; x < 10; i++ { x++; switch x { case 3: continue; } for j := 0; j < 2; j++ { x++; } } x;", "12"},
+		{"f := func() int { return 0; }; for i := 0; i < 3; i++ { switch i { case 1: f = func() int { return i; }; } } f();", "1"},
+		{"func f() int { for i := 0; i < 10; i++ { return i; } return -1; } f();", "0"},
+		{"s := \"\"; for i := 0; i < 3; i++ { s = s + \"a\"; } s;", "aaa"},
+		{"x := 1; !(x == 1);", "false"},
+		{"x := 10; x / 0;", "panic: runtime error: integer divide by zero"},
+		{"switch x := 2; x { case 1: 10; case 2: fallthrough; case 3: 30; default: 40; }", "30"},
+		{"x := 0; for i := 0; i < 5; i++ { switch { case i == 3: break; default: x = x + i; } } x;", "7"},
+		{"var a any = \"s\"; switch v := a.(type) { case int: v + 1; case string: v + \"!\"; }", "s!"},
+		{"func fib(n int) int { switch { case n < 2: return n; } return fib(n - 1) + fib(n - 2); } fib(15);", "610"},
+		{"n := 0; inc := func() int { n++; return n; }; inc(); inc();", "2"},
+		{"func f(a, b int) int { return a - b; } f(1);", "not enough arguments in call to f"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			program := parser.New(lexer.New(test.input)).ParseProgram()
+			if actual := fmt.Sprint(evaluator.New().Evaluate(program)); actual != test.expected {
+				t.Fatalf("unexpected object from evaluator: got %s, but expected %s\n", actual, test.expected)
+			}
+			if actual := fmt.Sprint(run(t, test.input)); actual != test.expected {
+				t.Errorf("unexpected object: got %s, but expected %s\n", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestRunWithPosition(t *testing.T) {
+	obj := run(t, "x := 0;\nfor i := 0; i < 3; i++ {\n\tx = x + \"a\";\n}")
+	err, ok := obj.(*object.Error)
+	if !ok {
+		t.Fatalf("unexpected object: got %s, but expected error\n", obj)
+	}
+	expected := token.Position{Line: 3, Column: 6}
+	if err.Position != expected {
+		t.Errorf("unexpected position: got %s, but expected %s\n", err.Position, expected)
+	}
+}
+
+func TestRunWithStdout(t *testing.T) {
+	w := new(bytes.Buffer)
+	run(t, "import \"fmt\"; done := make(chan int); go func() { fmt.Println(1); done <- 0; }(); for i := 0; i < 3; i++ {} <-done;", evaluator.WithStdout(w))
+	if actual := w.String(); actual != "1\n" {
+		t.Errorf("unexpected output: got %q, but expected %q\n", actual, "1\n")
+	}
+}
+
+func TestRunWithMaxSteps(t *testing.T) {
+	obj := run(t, "for {}", evaluator.WithMaxSteps(100))
+	err, ok := obj.(*object.Error)
+	if !ok {
+		t.Fatalf("unexpected object: got %s, but expected error\n", obj)
+	}
+	if !errors.Is(err.Err, evaluator.ErrStepLimitExceeded) {
+		t.Errorf("unexpected error: got %v, but expected %v\n", err.Err, evaluator.ErrStepLimitExceeded)
+	}
+}
+
+func run(t *testing.T, input string, opts ...evaluator.Option) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	bytecode, err := compiler.New().Compile(program)
+	if err != nil {
+		t.Fatalf("unexpected error from Compile: %s\n", err)
+	}
+
+	return New(opts...).Run(bytecode)
+}
+
+func BenchmarkRun(b *testing.B) {
+	inputs := map[string]string{
+		"Loop":       "sum := 0; for i := 0; i < 1000; i++ { sum = sum + i * 2 - i; } sum;",
+		"Comparison": "n := 0; for i := 0; i < 1000; i++ { n = n + 1; m := n < 500 == !(n >= 500); m; } n;",
+	}
+	for name, input := range inputs {
+		bytecode, err := compiler.New().Compile(parser.New(lexer.New(input)).ParseProgram())
+		if err != nil {
+			b.Fatalf("unexpected error from Compile: %s\n", err)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if obj, ok := New().Run(bytecode).(*object.Error); ok {
+					b.Fatalf("unexpected error: %s\n", obj)
+				}
+			}
+		})
+	}
+}